@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"amem/keyagent"
+	"amem/keyring"
+	"github.com/urfave/cli/v3"
+)
+
+// agentCommand builds the 'agent' command, which caches encryption keys so the keychain isn't asked on every command
+func agentCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "agent",
+		Usage: "Cache unlocked encryption keys for other amem commands (like ssh-agent)",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "ttl",
+				Value: keyagent.DefaultTTL,
+				Usage: "How long to keep each key after it's fetched from the keychain",
+			},
+			&cli.BoolFlag{
+				Name:  "clear",
+				Usage: "Make the running agent forget all cached keys",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Bool("clear") {
+				if err := keyagent.Clear(); err != nil {
+					return fmt.Errorf("failed to clear agent: %w", err)
+				}
+				fmt.Println("✓ Cleared cached keys")
+				return nil
+			}
+
+			ttl := cmd.Duration("ttl")
+			if ttl <= 0 {
+				return fmt.Errorf("--ttl must be positive")
+			}
+
+			path := keyagent.SocketPath()
+			listener, err := keyagent.Listen(path)
+			if err != nil {
+				return err
+			}
+			defer func() { _ = os.Remove(path) }()
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			fmt.Fprintf(os.Stderr, "Caching keys for %s on %s\n", ttl, path)
+			return keyagent.New(ttl, keyring.Get).Serve(ctx, listener)
+		},
+	}
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"amem/config"
+	"amem/gitrepo"
+)
+
+// Markers delimiting the amem block in installed instruction files
+const (
+	agentDocsStartMarker = "<!-- amem:start -->"
+	agentDocsEndMarker   = "<!-- amem:end -->"
+)
+
+// agentDocsContent is the documentation shown by 'amem agent-docs'
+const agentDocsContent = `<memory>
+	- If the user instructs you to use your memory, use the 'amem' utility.
+	- Run 'amem help' to see the available commands.
+	- Use 'amem search' to find relevant memories based on the conversation and user's request.
+	- As the conversation progresses, use 'amem add' to add new memories.
+	- Be judicious with the memories you add, making sure each is likely to have long-term value.
+	- Prefer proper relationships over relational observations.
+</memory>`
+
+// claudeDocsContent is tailored for CLAUDE.md, where amem runs through the Bash tool
+const claudeDocsContent = `<memory>
+	- You have a long-term memory available through the 'amem' CLI. Run it with the Bash tool.
+	- At the start of each task, run 'amem search' with a few keywords from the request to recall relevant context.
+	- Run 'amem search observations --about "<entity>"' before making decisions about a known person, project, or tool.
+	- When you learn a durable fact, preference, or decision, record it right away:
+		- 'amem add observation --entity "<entity>" --text "<fact>"'
+		- 'amem add relationship --from "<entity>" --to "<entity>" --type "<type>"'
+	- Be judicious with the memories you add, making sure each is likely to have long-term value.
+	- Prefer proper relationships over relational observations.
+	- Run 'amem help' to see all available commands.
+</memory>`
+
+// cursorDocsContent is a Cursor project rule, e.g. for .cursor/rules/amem.mdc
+const cursorDocsContent = `---
+description: Long-term memory with the amem CLI
+alwaysApply: true
+---
+
+# Memory
+
+- You have a long-term memory available through the ` + "`amem`" + ` CLI. Run it in the terminal.
+- Before starting a task, run ` + "`amem search <keywords>`" + ` to recall relevant context.
+- When you learn a durable fact, preference, or decision, record it with ` + "`amem add observation --entity \"<entity>\" --text \"<fact>\"`" + `.
+- Record connections with ` + "`amem add relationship --from \"<entity>\" --to \"<entity>\" --type \"<type>\"`" + `.
+- Be judicious with the memories you add, making sure each is likely to have long-term value.
+- Prefer proper relationships over relational observations.
+`
+
+// copilotDocsContent is for .github/copilot-instructions.md
+const copilotDocsContent = `## Memory
+
+This project keeps long-term memory in the ` + "`amem`" + ` CLI.
+
+- Before answering questions about the project, run ` + "`amem search <keywords>`" + ` in the terminal to recall relevant context.
+- When a durable fact, preference, or decision comes up, record it with ` + "`amem add observation --entity \"<entity>\" --text \"<fact>\"`" + `.
+- Record connections between things with ` + "`amem add relationship --from \"<entity>\" --to \"<entity>\" --type \"<type>\"`" + `.
+- Be judicious with the memories you add, making sure each is likely to have long-term value.
+- Run ` + "`amem help`" + ` to see all available commands.
+`
+
+// agentDocsTargets maps each agent-docs target to its built-in content
+var agentDocsTargets = map[string]string{
+	"agents-md": agentDocsContent,
+	"claude":    claudeDocsContent,
+	"cursor":    cursorDocsContent,
+	"copilot":   copilotDocsContent,
+}
+
+// agentDocsTemplatePath returns where a user can override the docs for a target.
+// Templates live in the global config directory: agent-docs/<target>.md
+func agentDocsTemplatePath(target string) (string, error) {
+	dir, err := config.GlobalDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "agent-docs", target+".md"), nil
+}
+
+// agentDocs returns the documentation for a target, preferring a user template
+func agentDocs(target string) (string, error) {
+	content, ok := agentDocsTargets[target]
+	if !ok {
+		targets := make([]string, 0, len(agentDocsTargets))
+		for t := range agentDocsTargets {
+			targets = append(targets, t)
+		}
+		sort.Strings(targets)
+		return "", fmt.Errorf("unknown target '%s' (use one of: %s)", target, strings.Join(targets, ", "))
+	}
+
+	templatePath, err := agentDocsTemplatePath(target)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(templatePath)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read agent-docs template: %w", err)
+	}
+
+	return content, nil
+}
+
+// agentDocsInstallPath picks the instruction file to install docs into for a target.
+// Existing AGENTS.md or CLAUDE.md files in root are preferred over creating new ones.
+func agentDocsInstallPath(root, target string) string {
+	switch target {
+	case "claude":
+		return filepath.Join(root, "CLAUDE.md")
+	case "cursor":
+		return filepath.Join(root, ".cursor", "rules", "amem.mdc")
+	case "copilot":
+		return filepath.Join(root, ".github", "copilot-instructions.md")
+	}
+
+	for _, name := range []string{"AGENTS.md", "CLAUDE.md"} {
+		path := filepath.Join(root, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(root, "AGENTS.md")
+}
+
+// upsertAgentDocsBlock inserts content between the amem markers in existing,
+// replacing a previous block if there is one. Returns the new text.
+func upsertAgentDocsBlock(existing, content string) (string, error) {
+	block := agentDocsStartMarker + "\n" + strings.TrimRight(content, "\n") + "\n" + agentDocsEndMarker + "\n"
+
+	start := strings.Index(existing, agentDocsStartMarker)
+	if start == -1 {
+		if existing == "" {
+			return block, nil
+		}
+		separator := "\n"
+		if !strings.HasSuffix(existing, "\n") {
+			separator = "\n\n"
+		}
+		return existing + separator + block, nil
+	}
+
+	end := strings.Index(existing[start:], agentDocsEndMarker)
+	if end == -1 {
+		return "", fmt.Errorf("found %s without a matching %s", agentDocsStartMarker, agentDocsEndMarker)
+	}
+	end += start + len(agentDocsEndMarker)
+	// Consume the newline that followed the old block
+	if end < len(existing) && existing[end] == '\n' {
+		end++
+	}
+
+	return existing[:start] + block + existing[end:], nil
+}
+
+// installAgentDocs writes the docs for target into the repository containing dir
+// (or dir itself outside a repository). Returns the file path and a description of the change.
+func installAgentDocs(dir, target string) (string, string, error) {
+	content, err := agentDocs(target)
+	if err != nil {
+		return "", "", err
+	}
+
+	root, err := gitrepo.FindRoot(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+		root = dir
+	}
+
+	path := agentDocsInstallPath(root, target)
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	created := err != nil
+
+	var updated string
+	if target == "cursor" {
+		// Cursor rules need their frontmatter first, so amem owns the whole file
+		updated = content
+	} else {
+		updated, err = upsertAgentDocsBlock(string(existing), content)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+
+	if !created && updated == string(existing) {
+		return path, "already up to date", nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(updated), 0o644); err != nil {
+		return "", "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	if created {
+		return path, "created", nil
+	}
+	return path, "updated", nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+	"go.yaml.in/yaml/v3"
+)
+
+// applyCommand builds the 'apply' command, which makes sure the records listed in a file exist
+func applyCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "apply",
+		Usage: "Add the entities, observations, and relationships listed in a YAML or JSON file that don't exist yet",
+		Description: `The file lists what should exist:
+
+  entities:
+    - Project X
+  observations:
+    - entity: Project X
+      text: Ships in March
+  relationships:
+    - from: Project X
+      to: Postgres
+      type: depends on
+
+Records that already exist are left alone, so applying a file again changes
+nothing. Everything is added in one transaction: if one record can't be added,
+none are. Use it to seed a project's memory from a checked-in file.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     "file",
+				Aliases:  []string{"f"},
+				Usage:    "YAML or JSON file to apply, or - for stdin",
+				Required: true,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			doc, err := readDocument(cmd.String("file"))
+			if err != nil {
+				return err
+			}
+
+			return withWriteDB(func(database *db.DB) error {
+				result, err := database.Apply(doc)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("✓ Added %d entities, %d observations, %d relationships (%d already existed)\n",
+					result.EntitiesAdded, result.ObservationsAdded, result.RelationshipsAdded, result.Unchanged)
+				return nil
+			})
+		},
+	}
+}
+
+// readDocument reads the document at path, or stdin for "-". YAML is a superset of
+// JSON, so one decoder reads both. Unknown fields are errors, to catch typos.
+func readDocument(path string) (*db.Document, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		// Share the reader --key-stdin used, so nothing it buffered is lost
+		if stdinReader == nil {
+			stdinReader = bufio.NewReader(os.Stdin)
+		}
+		data, err = io.ReadAll(stdinReader)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseDocument(data, path)
+}
+
+// parseDocument parses data, read from name, as a YAML or JSON document
+func parseDocument(data []byte, name string) (*db.Document, error) {
+	var doc db.Document
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&doc); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid document %s: %w", name, err)
+	}
+	return &doc, nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// archiveCommand builds the 'archive' command, which moves cold observations out of searches
+func archiveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "archive",
+		Usage: "Archive observations so searches leave them out (see --include-archived) without deleting them",
+		Flags: []cli.Flag{
+			&cli.IntSliceFlag{
+				Name:  "ids",
+				Usage: "Archive observations by IDs",
+			},
+			&cli.StringFlag{
+				Name:  "before",
+				Usage: "Archive observations last updated before this date or duration ago (e.g. 2025-01-31 or 2160h)",
+			},
+			&cli.BoolFlag{
+				Name:  "restore",
+				Usage: "Return the observations given by --ids to searches instead",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ids := make([]int64, len(cmd.IntSlice("ids")))
+			for i, id := range cmd.IntSlice("ids") {
+				ids[i] = int64(id)
+			}
+			before := cmd.String("before")
+
+			if cmd.Bool("restore") {
+				if len(ids) == 0 || before != "" {
+					return fmt.Errorf("--restore takes --ids")
+				}
+				return withWriteDB(func(database *db.DB) error {
+					n, err := database.Unarchive(ids)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("Restored %d observations\n", n)
+					return nil
+				})
+			}
+
+			if (len(ids) == 0) == (before == "") {
+				return fmt.Errorf("specify either --ids or --before")
+			}
+			var cutoff time.Time
+			if before != "" {
+				var err error
+				if cutoff, err = parseTimeFlag("before", before, time.Now()); err != nil {
+					return err
+				}
+			}
+
+			return withWriteDB(func(database *db.DB) error {
+				var n int
+				var err error
+				if before != "" {
+					n, err = database.ArchiveBefore(cutoff)
+				} else {
+					n, err = database.Archive(ids)
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Archived %d observations\n", n)
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"amem/config"
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// backupCommand builds the 'backup' command, which takes a backup and prunes old ones
+func backupCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "backup",
+		Usage: "Save a backup of the database, then prune old backups as the config's retention allows",
+		Description: `Backups are snapshots named "backup" (see 'amem snapshot list'), so run this
+from cron to back up on a schedule. With "backup": {"keep_daily": 7, "keep_weekly": 4}
+in the config, only the newest backup of each of the last 7 days and 4 weeks is kept.
+Without it, every backup is kept. Other snapshots are never pruned.
+
+The backup is copied from the open database, so it can be taken while 'amem serve'
+is running. With --output, it's written to that file instead and isn't pruned.
+
+Each backup gets a manifest with its checksum and record counts; check a backup
+against it with 'amem backup verify <file>'.`,
+		Commands: []*cli.Command{
+			{
+				Name:      "verify",
+				Usage:     "Check that a backup matches its manifest and opens with this database's key",
+				ArgsUsage: "<file>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path := cmd.Args().First()
+					if path == "" {
+						return fmt.Errorf("a backup file is required")
+					}
+
+					return withDB(func(database *db.DB) error {
+						m, err := database.VerifyBackup(path)
+						if err != nil {
+							return err
+						}
+						fmt.Printf("✓ %s matches its manifest: %s\n", path, formatManifest(m))
+						return nil
+					})
+				},
+			},
+		},
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "output",
+				Aliases: []string{"o"},
+				Usage:   "Write the backup to this new file instead of the snapshots directory (it's never pruned)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+				if path := cmd.String("output"); path != "" {
+					if err := database.BackupTo(path); err != nil {
+						return err
+					}
+					fmt.Printf("✓ Backed up to %s\n", path)
+					m, err := database.WriteManifest(path)
+					if err != nil {
+						return err
+					}
+					fmt.Printf("✓ Wrote manifest: %s\n", formatManifest(m))
+					return nil
+				}
+				return saveBackup(os.Stdout, database, cfg.Backup)
+			})
+		},
+	}
+}
+
+// saveBackup backs up database as a snapshot named "backup" with a manifest, then prunes old
+// backups as retention allows, if set. It reports what it did to w.
+func saveBackup(w io.Writer, database *db.DB, retention *db.Retention) error {
+	snap, err := database.CreateSnapshot(db.BackupSnapshotName)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "✓ Backed up to %s\n", snap.Path)
+
+	// A manifest lets 'amem backup verify' show the backup will restore
+	m, err := database.WriteManifest(snap.Path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "✓ Wrote manifest: %s\n", formatManifest(m))
+
+	if retention == nil {
+		return nil
+	}
+	pruned, err := database.PruneBackups(retention)
+	for _, s := range pruned {
+		fmt.Fprintf(w, "Pruned backup from %s\n", s.Created.Local().Format("2006-01-02 15:04:05"))
+	}
+	return err
+}
+
+// formatManifest describes what a backup's manifest records
+func formatManifest(m *db.Manifest) string {
+	return fmt.Sprintf("%d entities, %d observations, %d relationships (sha256 %s)", m.Entities, m.Observations, m.Relationships, m.SHA256[:12])
+}
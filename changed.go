@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// changedCommand builds the 'changed' command, which lists recently created or updated records
+func changedCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "changed",
+		Usage: "Show entities, observations, and relationships created or updated recently",
+		Flags: append([]cli.Flag{
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "How far back to look: a duration (e.g. 2h, 30m) or a date/time (e.g. 2025-01-31, 2025-01-31 14:00)",
+				Value: "24h",
+			},
+			&cli.BoolFlag{
+				Name:  "with-ids",
+				Usage: "Show database IDs with results",
+			},
+		}, outputFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			useOutputFlags(cmd)
+
+			since, err := parseTimeFlag("since", cmd.String("since"), time.Now())
+			if err != nil {
+				return err
+			}
+
+			return withDB(func(database *db.DB) error {
+				entities, observations, relationships, err := database.ChangedSince(since)
+				if err != nil {
+					return err
+				}
+				view.FormatAll(entities, observations, relationships, cmd.Bool("with-ids"))
+				return nil
+			})
+		},
+	}
+}
+
+// parseTimeFlag parses the value of a time flag such as --since, either a duration before now or a local date/time
+func parseTimeFlag(name, value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("--%s duration cannot be negative", name)
+		}
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid --%s %q: use a duration like 2h or a date like 2025-01-31", name, value)
+}
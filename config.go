@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// configCommand builds the 'config' command, which manages settings kept out of the
+// plaintext config files: secrets, stored encrypted in the database
+func configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Manage sensitive settings, like API keys, stored encrypted in the database",
+		Commands: []*cli.Command{
+			{
+				Name:      "set",
+				Usage:     "Set a setting, prompting for the value if it's not given so it stays out of shell history",
+				ArgsUsage: "<name> [value]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "secret",
+						Usage: "Store the value encrypted in the database instead of in a config file",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name, value := cmd.Args().Get(0), cmd.Args().Get(1)
+					if name == "" {
+						return fmt.Errorf("setting name is required")
+					}
+					if !cmd.Bool("secret") {
+						return fmt.Errorf("only secrets can be set here: use --secret, or edit the config file for other settings")
+					}
+					if value == "" {
+						var err error
+						if value, err = securePrompt(fmt.Sprintf("Value for %s", name)); err != nil {
+							return err
+						}
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						if err := database.SetConfigSecret(name, value); err != nil {
+							return err
+						}
+						fmt.Printf("✓ Set secret %s\n", name)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "get",
+				Usage:     "Print a secret's value",
+				ArgsUsage: "<name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("setting name is required")
+					}
+					return withDB(func(database *db.DB) error {
+						value, err := database.ConfigSecret(name)
+						if err != nil {
+							return err
+						}
+						fmt.Println(value)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "unset",
+				Usage:     "Delete a secret",
+				ArgsUsage: "<name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("setting name is required")
+					}
+					return withWriteDB(func(database *db.DB) error {
+						if err := database.DeleteConfigSecret(name); err != nil {
+							return err
+						}
+						fmt.Printf("✓ Deleted secret %s\n", name)
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List the names of stored secrets, without their values",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return withDB(func(database *db.DB) error {
+						names, err := database.ConfigSecretNames()
+						if err != nil {
+							return err
+						}
+						if len(names) == 0 {
+							fmt.Println("No secrets set")
+							return nil
+						}
+						for _, name := range names {
+							fmt.Println(name)
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
@@ -6,19 +6,46 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 
+	"amem/db"
+	"amem/embed"
+	"amem/gitrepo"
+	"amem/keyagent"
 	"amem/keyring"
+	"amem/redact"
+	"amem/server"
 )
 
 // Config represents configuration at either ~/.config/amem/config.json or .amem/config.json
+// In local configs, a relative db_path is relative to the .amem directory.
 type Config struct {
-	DBPath string `json:"db_path"`
+	DBPath string        `json:"db_path"`
+	Quota  *db.Quota     `json:"quota,omitempty"`
+	Rules  *db.Rules     `json:"rules,omitempty"`
+	Backup *db.Retention `json:"backup,omitempty"`
+	// Redact masks sensitive values in exports
+	Redact redact.Rules `json:"redact,omitempty"`
+	// Maintenance schedules upkeep while 'amem serve' runs
+	Maintenance *db.Maintenance `json:"maintenance,omitempty"`
+	Server      *server.Config  `json:"server,omitempty"`
+	// RecordRetrievals logs which observations each search returns, for 'amem stats retrieval'
+	RecordRetrievals bool `json:"record_retrievals,omitempty"`
+	// Embeddings selects how text is turned into vectors for semantic features
+	Embeddings *embed.Config `json:"embeddings,omitempty"`
+	// SkipMigrationBackup upgrades the schema without snapshotting the database first
+	SkipMigrationBackup bool `json:"skip_migration_backup,omitempty"`
+	// CheckIntegrity verifies every page of the database each time it's opened
+	CheckIntegrity bool `json:"check_integrity,omitempty"`
 }
 
-// LoadedConfig contains both the database path and encryption key ready for use.
+// LoadedConfig contains the config and encryption key ready for use.
 type LoadedConfig struct {
-	DBPath        string
+	Config
 	EncryptionKey string
+	// KeyFromEnv is true when the keychain had no key and AMEM_ENCRYPTION_KEY was used instead
+	KeyFromEnv bool
 }
 
 // Read reads a config file from the given path.
@@ -38,9 +65,90 @@ func Read(path string) (*Config, error) {
 		return nil, fmt.Errorf("config missing required field: db_path")
 	}
 
+	if err := cfg.expandPaths(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Quota != nil {
+		if err := cfg.Quota.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid quota: %w", err)
+		}
+	}
+
+	if cfg.Rules != nil {
+		if err := cfg.Rules.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid rules: %w", err)
+		}
+	}
+
+	if cfg.Backup != nil {
+		if err := cfg.Backup.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid backup retention: %w", err)
+		}
+	}
+
+	if cfg.Maintenance != nil {
+		if err := cfg.Maintenance.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid maintenance: %w", err)
+		}
+	}
+
+	if err := cfg.Redact.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid redact: %w", err)
+	}
+
+	if cfg.Server != nil {
+		if err := cfg.Server.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid server: %w", err)
+		}
+	}
+
+	if cfg.Embeddings != nil {
+		if err := cfg.Embeddings.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid embeddings: %w", err)
+		}
+	}
+
 	return &cfg, nil
 }
 
+// expandPaths expands ~ and environment variables in the config's path fields.
+func (cfg *Config) expandPaths() error {
+	dbPath, err := ExpandPath(cfg.DBPath)
+	if err != nil {
+		return fmt.Errorf("invalid db_path: %w", err)
+	}
+	cfg.DBPath = dbPath
+	return nil
+}
+
+// ExpandPath expands a leading ~ to the home directory and $VAR or ${VAR} to
+// environment variables. Referencing an unset variable is an error, since
+// silently dropping it would point the path somewhere unexpected.
+func ExpandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = home + path[1:]
+	}
+
+	var missing []string
+	expanded := os.Expand(path, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, "$"+name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set in %q", strings.Join(missing, ", "), path)
+	}
+
+	return expanded, nil
+}
+
 // Write writes a config file to the given path.
 // Creates parent directories if needed.
 func Write(path string, cfg *Config) error {
@@ -58,15 +166,23 @@ func Write(path string, cfg *Config) error {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
 
-	// Atomic write: write to temp file then rename
+	if err := writeAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// writeAtomic writes data to a temp file beside path, then renames it over path,
+// so readers never see a partly written file.
+func writeAtomic(path string, data []byte) error {
 	tmpPath := path + ".tmp"
 	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+		return err
 	}
 
 	if err := os.Rename(tmpPath, path); err != nil {
 		_ = os.Remove(tmpPath) // cleanup on failure
-		return fmt.Errorf("failed to rename config file: %w", err)
+		return err
 	}
 
 	return nil
@@ -85,15 +201,52 @@ func xdgConfigHome() (string, error) {
 	return filepath.Join(home, ".config"), nil
 }
 
+// GlobalDir returns the global config directory.
+// Uses XDG config directory: $XDG_CONFIG_HOME/amem or ~/.config/amem
+func GlobalDir() (string, error) {
+	configDir, err := xdgConfigHome()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+
+	return filepath.Join(configDir, "amem"), nil
+}
+
 // GlobalPath returns the path to the global config file.
 // Uses XDG config directory: $XDG_CONFIG_HOME/amem/config.json or ~/.config/amem/config.json
 func GlobalPath() (string, error) {
-	configDir, err := xdgConfigHome()
+	dir, err := GlobalDir()
 	if err != nil {
-		return "", fmt.Errorf("failed to get config directory: %w", err)
+		return "", err
 	}
 
-	return filepath.Join(configDir, "amem", "config.json"), nil
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// ResolveDBPath returns dbPath as an absolute path, resolving a relative path
+// against amemDir, the .amem directory of a local config.
+func ResolveDBPath(amemDir, dbPath string) string {
+	if filepath.IsAbs(dbPath) {
+		return dbPath
+	}
+	return filepath.Join(amemDir, dbPath)
+}
+
+// RelativeDBPath returns dbPath relative to amemDir if the database is inside
+// the project directory containing it, so the project can move without
+// breaking its config. Otherwise it returns dbPath unchanged.
+func RelativeDBPath(amemDir, dbPath string) string {
+	projectDir := filepath.Dir(amemDir)
+	inProject, err := filepath.Rel(projectDir, dbPath)
+	if err != nil || inProject == ".." || strings.HasPrefix(inProject, ".."+string(filepath.Separator)) {
+		return dbPath
+	}
+
+	rel, err := filepath.Rel(amemDir, dbPath)
+	if err != nil {
+		return dbPath
+	}
+	return rel
 }
 
 // LocalPath returns the path to a local config file in the given directory.
@@ -102,9 +255,26 @@ func LocalPath(dir string) string {
 	return filepath.Join(dir, ".amem", "config.json")
 }
 
+// StopAtGitRootEnv names the environment variable that, when true, keeps FindLocal
+// from looking above the enclosing git repository.
+const StopAtGitRootEnv = "AMEM_STOP_AT_GIT_ROOT"
+
 // FindLocal walks up the directory tree from startDir looking for .amem/config.json.
 // Returns the path to the config file if found, or an error if not found.
+// If $AMEM_STOP_AT_GIT_ROOT is true, the search stops at the root of the git repository
+// containing startDir, so a project never picks up the memory of a directory above it.
 func FindLocal(startDir string) (string, error) {
+	stop, _ := strconv.ParseBool(os.Getenv(StopAtGitRootEnv))
+	return findLocal(startDir, stop)
+}
+
+func findLocal(startDir string, stopAtGitRoot bool) (string, error) {
+	// Outside a repository there's no root to stop at, so the whole tree is searched
+	root := ""
+	if stopAtGitRoot {
+		root, _ = gitrepo.FindRoot(startDir)
+	}
+
 	current := startDir
 
 	for {
@@ -114,18 +284,53 @@ func FindLocal(startDir string) (string, error) {
 		}
 
 		parent := filepath.Dir(current)
-		// Reached filesystem root
-		if parent == current {
+		// Reached filesystem root, or the repository's
+		if parent == current || current == root {
 			return "", fmt.Errorf("no local config found: %w", os.ErrNotExist)
 		}
 		current = parent
 	}
 }
 
+// ProjectDir returns the directory a local config created from startDir belongs in:
+// the root of the git repository containing startDir, or startDir if it isn't in one.
+func ProjectDir(startDir string) string {
+	if root, err := gitrepo.FindRoot(startDir); err == nil {
+		return root
+	}
+	return startDir
+}
+
+// keyFunc gets the encryption key for account, reporting whether it came from
+// AMEM_ENCRYPTION_KEY instead of where keys are normally kept.
+type keyFunc func(account string) (key string, fromEnv bool, err error)
+
+// loadKey gets an encryption key from a running 'amem agent', or from the keychain if there isn't one.
+func loadKey(account string) (string, bool, error) {
+	if key, err := keyagent.Get(account); err == nil {
+		return key, false, nil
+	}
+	return keyring.Lookup(account)
+}
+
+// fixedKey is a keyFunc that always returns key.
+func fixedKey(key string) keyFunc {
+	return func(string) (string, bool, error) { return key, false, nil }
+}
+
 // Load discovers and loads config with encryption key.
 // Searches for local config first (walking up from cwd), then falls back to global config.
 // Returns helpful error if no config exists.
 func Load() (*LoadedConfig, error) {
+	return load(loadKey)
+}
+
+// LoadWithKey is Load using key instead of looking one up in the agent, keychain, or environment.
+func LoadWithKey(key string) (*LoadedConfig, error) {
+	return load(fixedKey(key))
+}
+
+func load(getKey keyFunc) (*LoadedConfig, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current directory: %w", err)
@@ -134,25 +339,7 @@ func Load() (*LoadedConfig, error) {
 	// Try local config first
 	localPath, err := FindLocal(cwd)
 	if err == nil {
-		cfg, err := Read(localPath)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read local config at %s: %w", localPath, err)
-		}
-
-		// Get directory containing .amem (parent of config file's parent)
-		configDir := filepath.Dir(localPath)  // .amem directory
-		projectDir := filepath.Dir(configDir) // project directory
-		account := "local:" + projectDir
-
-		key, err := keyring.Get(account)
-		if err != nil {
-			return nil, fmt.Errorf("failed to load encryption key for local config at %s: %w", projectDir, err)
-		}
-
-		return &LoadedConfig{
-			DBPath:        cfg.DBPath,
-			EncryptionKey: key,
-		}, nil
+		return loadLocal(localPath, getKey)
 	}
 
 	// If local not found, try global
@@ -165,21 +352,55 @@ func Load() (*LoadedConfig, error) {
 		return nil, fmt.Errorf("failed to get global config path: %w", err)
 	}
 
+	cfg, err := loadGlobal(globalPath, getKey)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no config found: run 'amem init' to create one")
+	}
+	return cfg, err
+}
+
+// loadLocal loads the local config at localPath with the key for its project.
+func loadLocal(localPath string, getKey keyFunc) (*LoadedConfig, error) {
+	cfg, err := Read(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read local config at %s: %w", localPath, err)
+	}
+
+	// Get directory containing .amem (parent of config file's parent)
+	configDir := filepath.Dir(localPath)  // .amem directory
+	projectDir := filepath.Dir(configDir) // project directory
+	account := "local:" + projectDir
+
+	cfg.DBPath = ResolveDBPath(configDir, cfg.DBPath)
+
+	key, fromEnv, err := getKey(account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption key for local config at %s: %w", projectDir, err)
+	}
+
+	return &LoadedConfig{
+		Config:        *cfg,
+		EncryptionKey: key,
+		KeyFromEnv:    fromEnv,
+	}, nil
+}
+
+// loadGlobal loads the global config at globalPath with the global key.
+// Returns an error wrapping os.ErrNotExist if there is no global config.
+func loadGlobal(globalPath string, getKey keyFunc) (*LoadedConfig, error) {
 	cfg, err := Read(globalPath)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return nil, fmt.Errorf("no config found: run 'amem init' to create one")
-		}
 		return nil, fmt.Errorf("failed to read global config at %s: %w", globalPath, err)
 	}
 
-	key, err := keyring.Get("global")
+	key, fromEnv, err := getKey("global")
 	if err != nil {
 		return nil, fmt.Errorf("failed to load encryption key for global config: %w", err)
 	}
 
 	return &LoadedConfig{
-		DBPath:        cfg.DBPath,
+		Config:        *cfg,
 		EncryptionKey: key,
+		KeyFromEnv:    fromEnv,
 	}, nil
 }
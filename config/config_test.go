@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestConfigJSON(t *testing.T) {
@@ -226,6 +227,67 @@ func TestFindLocalNotFound(t *testing.T) {
 	}
 }
 
+func TestFindLocalStopsAtGitRoot(t *testing.T) {
+	// A config above a repository, which a search from inside it shouldn't reach when told to stop
+	tmpDir := t.TempDir()
+	configPath := LocalPath(tmpDir)
+	if err := Write(configPath, &Config{DBPath: "/test/path.db"}); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	repo := filepath.Join(tmpDir, "repo")
+	subDir := filepath.Join(repo, "src")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(subDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, err := FindLocal(subDir); err != nil || found != configPath {
+		t.Errorf("FindLocal() = %q, %v; want %q by default", found, err, configPath)
+	}
+
+	t.Setenv(StopAtGitRootEnv, "1")
+	if _, err := FindLocal(subDir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("FindLocal() error = %v, want os.ErrNotExist when stopping at the repository root", err)
+	}
+	// The repository's own config is still found
+	repoConfig := LocalPath(repo)
+	if err := Write(repoConfig, &Config{DBPath: "/test/repo.db"}); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if found, err := FindLocal(subDir); err != nil || found != repoConfig {
+		t.Errorf("FindLocal() = %q, %v; want %q", found, err, repoConfig)
+	}
+	// Outside any repository, the whole tree is searched
+	outside := filepath.Join(tmpDir, "other")
+	if err := os.Mkdir(outside, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if found, err := FindLocal(outside); err != nil || found != configPath {
+		t.Errorf("FindLocal() = %q, %v; want %q outside a repository", found, err, configPath)
+	}
+}
+
+func TestProjectDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	repo := filepath.Join(tmpDir, "repo")
+	subDir := filepath.Join(repo, "src")
+	if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(subDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := ProjectDir(subDir); got != repo {
+		t.Errorf("ProjectDir(%q) = %q, want the repository root %q", subDir, got, repo)
+	}
+	if got := ProjectDir(tmpDir); got != tmpDir {
+		t.Errorf("ProjectDir(%q) = %q, want itself outside a repository", tmpDir, got)
+	}
+}
+
 func TestLoadLocalConfig(t *testing.T) {
 	// Skip if keyring access fails (e.g., in CI)
 	if err := testKeyringAccess(); err != nil {
@@ -280,6 +342,54 @@ func TestLoadLocalConfig(t *testing.T) {
 	}
 }
 
+func TestRelativeDBPath(t *testing.T) {
+	project := t.TempDir()
+	amemDir := filepath.Join(project, ".amem")
+
+	inProject := filepath.Join(project, "data", "amem.db")
+	rel := RelativeDBPath(amemDir, inProject)
+	if rel != filepath.Join("..", "data", "amem.db") {
+		t.Errorf("expected path relative to .amem, got %s", rel)
+	}
+	if resolved := ResolveDBPath(amemDir, rel); resolved != inProject {
+		t.Errorf("expected %s, got %s", inProject, resolved)
+	}
+
+	outside := filepath.Join(filepath.Dir(project), "elsewhere.db")
+	if got := RelativeDBPath(amemDir, outside); got != outside {
+		t.Errorf("expected path outside the project to stay absolute, got %s", got)
+	}
+	if got := ResolveDBPath(amemDir, outside); got != outside {
+		t.Errorf("expected absolute path unchanged, got %s", got)
+	}
+}
+
+func TestLoadResolvesRelativeDBPath(t *testing.T) {
+	project := t.TempDir()
+	if err := Write(LocalPath(project), &Config{DBPath: "../amem.db"}); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	// Falls back to the environment when the keyring has no key for this project
+	t.Setenv("AMEM_ENCRYPTION_KEY", "test-relative-key")
+
+	oldDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get cwd: %v", err)
+	}
+	defer func() { _ = os.Chdir(oldDir) }()
+	if err := os.Chdir(project); err != nil {
+		t.Fatalf("failed to chdir: %v", err)
+	}
+
+	loaded, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if want := filepath.Join(project, "amem.db"); loaded.DBPath != want {
+		t.Errorf("expected DBPath %s, got %s", want, loaded.DBPath)
+	}
+}
+
 func TestLoadNoConfigFound(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -331,3 +441,170 @@ func cleanupKeyring(account string) {
 	// Clean up test keys
 	_ = os.Unsetenv("AMEM_ENCRYPTION_KEY")
 }
+
+func TestExpandPath(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("no home directory: %v", err)
+	}
+	t.Setenv("AMEM_TEST_DATA", "/data")
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"~", home},
+		{"~/amem.db", filepath.Join(home, "amem.db")},
+		{"$AMEM_TEST_DATA/amem.db", "/data/amem.db"},
+		{"${AMEM_TEST_DATA}/amem.db", "/data/amem.db"},
+		{"~other/amem.db", "~other/amem.db"},
+		{"../amem.db", "../amem.db"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandPath(tt.path)
+		if err != nil {
+			t.Errorf("ExpandPath(%q) failed: %v", tt.path, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("ExpandPath(%q) = %q, want %q", tt.path, got, tt.expected)
+		}
+	}
+
+	if _, err := ExpandPath("$AMEM_TEST_UNSET_VARIABLE/amem.db"); err == nil {
+		t.Error("expected error for unset variable")
+	}
+}
+
+func TestReadExpandsDBPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	t.Setenv("AMEM_TEST_DATA", "/data")
+	if err := os.WriteFile(path, []byte(`{"db_path":"$AMEM_TEST_DATA/amem.db"}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if cfg.DBPath != "/data/amem.db" {
+		t.Errorf("expected expanded db_path, got %s", cfg.DBPath)
+	}
+}
+
+func TestReadRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "rules.json")
+
+	data := `{"db_path":"/tmp/amem.db","rules":{"max_observation_length":280,"banned_characters":"|","relationship_type_pattern":"[a-z_]+"}}`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if cfg.Rules == nil || cfg.Rules.MaxObservationLength != 280 || cfg.Rules.BannedCharacters != "|" || cfg.Rules.RelationshipTypePattern != "[a-z_]+" {
+		t.Errorf("Read() rules = %+v", cfg.Rules)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","rules":{"relationship_type_pattern":"("}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for an invalid relationship_type_pattern")
+	}
+}
+
+func TestReadBackupRetention(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "backup.json")
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","backup":{"keep_daily":7,"keep_weekly":4}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if cfg.Backup == nil || cfg.Backup.KeepDaily != 7 || cfg.Backup.KeepWeekly != 4 {
+		t.Errorf("Read() backup = %+v", cfg.Backup)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","backup":{}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for a retention that keeps nothing")
+	}
+}
+
+func TestReadMaintenance(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "maintenance.json")
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","maintenance":{"interval":"6h"}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if cfg.Maintenance == nil || cfg.Maintenance.Every() != 6*time.Hour {
+		t.Errorf("Read() maintenance = %+v", cfg.Maintenance)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","maintenance":{"interval":"daily"}}`), 0o644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for an invalid maintenance interval")
+	}
+}
+
+func TestReadRedact(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "redact.json")
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","redact":[{"name":"email"},{"name":"ticket","pattern":"T-[0-9]+"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if got := cfg.Redact.Apply("bob@example.com on T-12"); got != "[REDACTED email] on [REDACTED ticket]" {
+		t.Errorf("Read() redact applied = %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","redact":[{"name":"ssn"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for an unknown builtin redact rule")
+	}
+}
+
+func TestReadEmbeddings(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "embeddings.json")
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","embeddings":{"provider":"ollama","model":"all-minilm"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Read(path)
+	if err != nil {
+		t.Fatalf("Read failed: %v", err)
+	}
+	if cfg.Embeddings == nil || cfg.Embeddings.Provider != "ollama" || cfg.Embeddings.Model != "all-minilm" {
+		t.Errorf("Read() embeddings = %+v", cfg.Embeddings)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"db_path":"/tmp/amem.db","embeddings":{"provider":"cohere"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Read(path); err == nil {
+		t.Fatal("expected error for an unknown embeddings provider")
+	}
+}
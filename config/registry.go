@@ -0,0 +1,199 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// ErrNotRegistered is returned when no database in the registry matches a path.
+var ErrNotRegistered = errors.New("not in the registry")
+
+// Database scopes, saying which kind of config points at a database.
+const (
+	ScopeGlobal = "global"
+	ScopeLocal  = "local"
+)
+
+// Registry lists the databases 'amem init' has created on this machine, so commands
+// can find local databases outside the current directory and users can see every
+// memory store they have. It lives beside the global config.
+type Registry struct {
+	Databases []RegisteredDB `json:"databases"`
+}
+
+// RegisteredDB is a database in the registry.
+type RegisteredDB struct {
+	// ConfigPath is the config pointing at the database, which also says which key unlocks it
+	ConfigPath string `json:"config_path"`
+	DBPath     string `json:"db_path"`
+	// Scope is ScopeGlobal or ScopeLocal
+	Scope   string    `json:"scope"`
+	Created time.Time `json:"created"`
+}
+
+// RegistryPath returns the path to the registry file.
+// Uses XDG config directory: $XDG_CONFIG_HOME/amem/databases.json or ~/.config/amem/databases.json
+func RegistryPath() (string, error) {
+	dir, err := GlobalDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "databases.json"), nil
+}
+
+// ReadRegistry reads the registry, which is empty if no database has been registered yet.
+func ReadRegistry() (*Registry, error) {
+	path, err := RegistryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Registry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read registry: %w", err)
+	}
+
+	var reg Registry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("invalid registry JSON in %s: %w", path, err)
+	}
+	return &reg, nil
+}
+
+// writeRegistry writes reg to the registry file, creating its directory if needed.
+func writeRegistry(reg *Registry) error {
+	path, err := RegistryPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal registry: %w", err)
+	}
+
+	if err := writeAtomic(path, data); err != nil {
+		return fmt.Errorf("failed to write registry: %w", err)
+	}
+	return nil
+}
+
+// Register adds a database to the registry, replacing any entry for the same config.
+func Register(entry RegisteredDB) error {
+	reg, err := ReadRegistry()
+	if err != nil {
+		return err
+	}
+
+	reg.Databases = slices.DeleteFunc(reg.Databases, func(d RegisteredDB) bool {
+		return d.ConfigPath == entry.ConfigPath
+	})
+	reg.Databases = append(reg.Databases, entry)
+	return writeRegistry(reg)
+}
+
+// Forget removes the databases whose database path, config path, or project directory is path
+// from the registry, returning what was removed. The databases and their configs are left alone.
+func Forget(path string) ([]RegisteredDB, error) {
+	reg, err := ReadRegistry()
+	if err != nil {
+		return nil, err
+	}
+
+	var forgotten []RegisteredDB
+	reg.Databases = slices.DeleteFunc(reg.Databases, func(d RegisteredDB) bool {
+		matches := d.DBPath == path || d.ConfigPath == path ||
+			(d.Scope == ScopeLocal && filepath.Dir(filepath.Dir(d.ConfigPath)) == path)
+		if matches {
+			forgotten = append(forgotten, d)
+		}
+		return matches
+	})
+	if len(forgotten) == 0 {
+		return nil, fmt.Errorf("%s is %w", path, ErrNotRegistered)
+	}
+
+	if err := writeRegistry(reg); err != nil {
+		return nil, err
+	}
+	return forgotten, nil
+}
+
+// Source is a database found by LoadAll.
+type Source struct {
+	// Name is "global" for the global database, or the project directory of a local one
+	Name       string
+	ConfigPath string
+	// Config is the loaded config, or nil if Err says why it couldn't be loaded
+	Config *LoadedConfig
+	Err    error
+}
+
+// LoadAll loads the config of every database amem knows about: the global one, the
+// local one for the current directory, and every one in the registry. Databases whose
+// config or key can't be loaded are still returned, with Err set, so callers can warn
+// about them and carry on with the rest.
+func LoadAll() ([]Source, error) {
+	return loadAll(loadKey)
+}
+
+// LoadAllWithKey is LoadAll using key for every database.
+func LoadAllWithKey(key string) ([]Source, error) {
+	return loadAll(fixedKey(key))
+}
+
+func loadAll(getKey keyFunc) ([]Source, error) {
+	globalPath, err := GlobalPath()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get global config path: %w", err)
+	}
+
+	var sources []Source
+	if _, err := os.Stat(globalPath); err == nil {
+		cfg, err := loadGlobal(globalPath, getKey)
+		sources = append(sources, Source{Name: ScopeGlobal, ConfigPath: globalPath, Config: cfg, Err: err})
+	}
+
+	var localPaths []string
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if localPath, err := FindLocal(cwd); err == nil {
+		localPaths = append(localPaths, localPath)
+	}
+
+	reg, err := ReadRegistry()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range reg.Databases {
+		if d.ConfigPath != globalPath && !slices.Contains(localPaths, d.ConfigPath) {
+			localPaths = append(localPaths, d.ConfigPath)
+		}
+	}
+
+	for _, localPath := range localPaths {
+		cfg, err := loadLocal(localPath, getKey)
+		projectDir := filepath.Dir(filepath.Dir(localPath))
+		sources = append(sources, Source{Name: projectDir, ConfigPath: localPath, Config: cfg, Err: err})
+	}
+
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no config found: run 'amem init' to create one")
+	}
+	return sources, nil
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	reg, err := ReadRegistry()
+	if err != nil || len(reg.Databases) != 0 {
+		t.Fatalf("ReadRegistry() = %+v, %v; want an empty registry", reg, err)
+	}
+
+	entries := []RegisteredDB{
+		{ConfigPath: "/a/.amem/config.json", DBPath: "/a/amem.db"},
+		{ConfigPath: "/b/.amem/config.json", DBPath: "/b/amem.db"},
+		{ConfigPath: "/a/.amem/config.json", DBPath: "/a/other.db"},
+	}
+	for _, e := range entries {
+		if err := Register(e); err != nil {
+			t.Fatalf("Register(%+v) failed: %v", e, err)
+		}
+	}
+
+	reg, err = ReadRegistry()
+	if err != nil {
+		t.Fatalf("ReadRegistry failed: %v", err)
+	}
+	if len(reg.Databases) != 2 || reg.Databases[0] != entries[1] || reg.Databases[1] != entries[2] {
+		t.Errorf("Registry = %+v, want the second entry and the replaced first", reg.Databases)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	globalPath, err := GlobalPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(globalPath, &Config{DBPath: filepath.Join(home, "global.db")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The current directory's project, which isn't registered, and a registered one elsewhere
+	current := t.TempDir()
+	elsewhere := t.TempDir()
+	missing := t.TempDir()
+	for _, dir := range []string{current, elsewhere} {
+		if err := Write(LocalPath(dir), &Config{DBPath: "amem.db"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{elsewhere, current, missing} {
+		if err := Register(RegisteredDB{ConfigPath: LocalPath(dir), DBPath: filepath.Join(dir, "amem.db")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldWd, _ := os.Getwd()
+	defer func() { _ = os.Chdir(oldWd) }()
+	if err := os.Chdir(current); err != nil {
+		t.Fatal(err)
+	}
+
+	sources, err := LoadAllWithKey("secret")
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+
+	want := []string{"global", current, elsewhere, missing}
+	if len(sources) != len(want) {
+		t.Fatalf("LoadAll() returned %d sources, want %d: %+v", len(sources), len(want), sources)
+	}
+	for i, s := range sources {
+		if s.Name != want[i] {
+			t.Errorf("sources[%d].Name = %q, want %q", i, s.Name, want[i])
+		}
+	}
+	if s := sources[2]; s.Err != nil || s.Config.DBPath != filepath.Join(elsewhere, ".amem", "amem.db") || s.Config.EncryptionKey != "secret" {
+		t.Errorf("sources[2] = %+v, want the registered project's config", s)
+	}
+	if sources[3].Err == nil {
+		t.Errorf("Expected an error for a registered project whose config is gone")
+	}
+}
+
+func TestForget(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	entries := []RegisteredDB{
+		{ConfigPath: "/home/me/.config/amem/config.json", DBPath: "/home/me/amem.db", Scope: ScopeGlobal},
+		{ConfigPath: "/a/.amem/config.json", DBPath: "/a/amem.db", Scope: ScopeLocal},
+		{ConfigPath: "/b/.amem/config.json", DBPath: "/data/b.db", Scope: ScopeLocal},
+	}
+	for _, e := range entries {
+		if err := Register(e); err != nil {
+			t.Fatalf("Register(%+v) failed: %v", e, err)
+		}
+	}
+
+	for _, path := range []string{"/a", "/data/b.db", "/home/me/.config/amem/config.json"} {
+		forgotten, err := Forget(path)
+		if err != nil || len(forgotten) != 1 {
+			t.Errorf("Forget(%q) = %+v, %v; want one database", path, forgotten, err)
+		}
+	}
+	if _, err := Forget("/a"); !errors.Is(err, ErrNotRegistered) {
+		t.Errorf("Forget of a forgotten database error = %v, want ErrNotRegistered", err)
+	}
+
+	reg, err := ReadRegistry()
+	if err != nil || len(reg.Databases) != 0 {
+		t.Errorf("ReadRegistry() = %+v, %v; want an empty registry", reg, err)
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Document lists entities, observations, and relationships that should exist,
+// as read from a file by 'amem apply'.
+type Document struct {
+	Entities      []string               `json:"entities,omitempty" yaml:"entities,omitempty"`
+	Observations  []DocumentObservation  `json:"observations,omitempty" yaml:"observations,omitempty"`
+	Relationships []DocumentRelationship `json:"relationships,omitempty" yaml:"relationships,omitempty"`
+}
+
+// DocumentObservation is an observation in a Document.
+type DocumentObservation struct {
+	Entity string `json:"entity" yaml:"entity"`
+	Text   string `json:"text" yaml:"text"`
+}
+
+// DocumentRelationship is a relationship in a Document.
+type DocumentRelationship struct {
+	From string `json:"from" yaml:"from"`
+	To   string `json:"to" yaml:"to"`
+	Type string `json:"type" yaml:"type"`
+}
+
+// ApplyResult counts what Apply added and what already existed.
+type ApplyResult struct {
+	EntitiesAdded      int `json:"entities_added"`
+	ObservationsAdded  int `json:"observations_added"`
+	RelationshipsAdded int `json:"relationships_added"`
+	Unchanged          int `json:"unchanged"`
+}
+
+// Apply adds whatever in doc doesn't exist yet, in one transaction: entities by text,
+// observations by entity and text, and relationships by their from, to, and type.
+// Applying the same document again changes nothing. If any record can't be added,
+// none are. Should be run under Locked.
+func (db *DB) Apply(doc *Document) (*ApplyResult, error) {
+	result := &ApplyResult{}
+	err := db.Transaction(func(tx *DB) error {
+		// Entities named only by observations and relationships count when they're added
+		implied := []string{}
+		for _, o := range doc.Observations {
+			implied = append(implied, o.Entity)
+		}
+		for _, r := range doc.Relationships {
+			implied = append(implied, r.From, r.To)
+		}
+		for i, text := range append(slices.Clone(doc.Entities), implied...) {
+			added, err := tx.ensure("SELECT id FROM entities WHERE text = ?", []interface{}{tx.trimInput(text)}, func() error {
+				_, err := tx.AddEntity(text)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("failed to add entity '%s': %w", text, err)
+			}
+			if added {
+				result.EntitiesAdded++
+			} else if i < len(doc.Entities) {
+				result.Unchanged++
+			}
+		}
+
+		for _, o := range doc.Observations {
+			added, err := tx.ensure(
+				"SELECT o.id FROM observations o JOIN entities e ON e.id = o.entity_id WHERE e.text = ? AND o.text = ?",
+				[]interface{}{tx.trimInput(o.Entity), tx.trimInput(o.Text)},
+				func() error {
+					_, err := tx.AddObservation(o.Entity, o.Text)
+					return err
+				})
+			if err != nil {
+				return fmt.Errorf("failed to add observation about '%s': %w", o.Entity, err)
+			}
+			if added {
+				result.ObservationsAdded++
+			} else {
+				result.Unchanged++
+			}
+		}
+
+		for _, r := range doc.Relationships {
+			added, err := tx.ensure(
+				`SELECT r.id FROM relationships r JOIN entities f ON f.id = r.from_id JOIN entities t ON t.id = r.to_id
+				WHERE f.text = ? AND t.text = ? AND r.type = ?`,
+				[]interface{}{tx.trimInput(r.From), tx.trimInput(r.To), tx.trimInput(r.Type)},
+				func() error {
+					_, err := tx.AddRelationship(r.From, r.To, r.Type)
+					return err
+				})
+			if err != nil {
+				return fmt.Errorf("failed to add relationship %s -[%s]-> %s: %w", r.From, r.Type, r.To, err)
+			}
+			if added {
+				result.RelationshipsAdded++
+			} else {
+				result.Unchanged++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ensure runs add unless query, given args, finds a row. It reports whether add ran.
+func (db *DB) ensure(query string, args []interface{}, add func() error) (bool, error) {
+	var id int64
+	err := db.queryRow(query, args...).Scan(&id)
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, fmt.Errorf("failed to look up existing record: %w", err)
+	}
+	return true, add()
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	path := t.TempDir() + "/test_apply.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddObservation("Project X", "Ships in March"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	doc := &Document{
+		Entities: []string{"Project X", "Alice"},
+		Observations: []DocumentObservation{
+			{Entity: "Project X", Text: "Ships in March"},
+			{Entity: "Project X", Text: "Written in Go"},
+		},
+		Relationships: []DocumentRelationship{
+			{From: "Alice", To: "Project X", Type: "works on"},
+			{From: "Project X", To: "Postgres", Type: "depends on"},
+		},
+	}
+	apply := func() (*ApplyResult, error) {
+		var result *ApplyResult
+		err := db.Locked(func() error {
+			var err error
+			result, err = db.Apply(doc)
+			return err
+		})
+		return result, err
+	}
+
+	result, err := apply()
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	want := ApplyResult{EntitiesAdded: 2, ObservationsAdded: 1, RelationshipsAdded: 2, Unchanged: 2}
+	if *result != want {
+		t.Errorf("Apply() = %+v, want %+v", *result, want)
+	}
+
+	// Applying again changes nothing
+	result, err = apply()
+	if err != nil {
+		t.Fatalf("Apply again failed: %v", err)
+	}
+	if want := (ApplyResult{Unchanged: 6}); *result != want {
+		t.Errorf("Apply() again = %+v, want %+v", *result, want)
+	}
+	if count, _ := db.CountObservations(); count != 2 {
+		t.Errorf("Expected 2 observations, got %d", count)
+	}
+
+	// A record that breaks a rule leaves the whole document unapplied
+	if err := db.SetRules(&Rules{MaxObservationLength: 10}); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+	doc = &Document{
+		Entities:     []string{"Carol"},
+		Observations: []DocumentObservation{{Entity: "Carol", Text: "Has a very long observation"}},
+	}
+	if _, err := apply(); !errors.Is(err, ErrRuleViolation) {
+		t.Fatalf("Apply breaking a rule = %v, want a rule violation", err)
+	}
+	if entities, _ := db.SearchEntities([]string{"Carol"}, false); len(entities) != 0 {
+		t.Errorf("Expected nothing applied, got %+v", entities)
+	}
+}
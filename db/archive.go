@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// SetIncludeArchived sets whether searches return archived observations.
+func (db *DB) SetIncludeArchived(on bool) {
+	db.includeArchived = on
+}
+
+// archivedClause returns the condition leaving archived observations (aliased o) out of
+// a search, or "" if searches include them.
+func (db *DB) archivedClause() string {
+	if db.includeArchived {
+		return ""
+	}
+	return "o.id NOT IN (SELECT observation_id FROM archived)"
+}
+
+// Archive archives the observations with the given IDs, returning how many weren't
+// already archived. Archived observations are kept, but searches leave them out.
+func (db *DB) Archive(ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := db.checkObservationsExist(ids); err != nil {
+		return 0, err
+	}
+
+	placeholders, args := idList(ids)
+	return db.archiveWhere("id IN ("+placeholders+")", args...)
+}
+
+// checkObservationsExist returns ErrNotFound unless there's an observation with each of ids.
+func (db *DB) checkObservationsExist(ids []int64) error {
+	placeholders, args := idList(ids)
+
+	var found int
+	if err := db.queryRow("SELECT COUNT(*) FROM observations WHERE id IN ("+placeholders+")", args...).Scan(&found); err != nil {
+		return fmt.Errorf("failed to find observations: %w", err)
+	}
+	if found < len(slices.Compact(slices.Sorted(slices.Values(ids)))) {
+		return fmt.Errorf("some observation IDs were %w", ErrNotFound)
+	}
+	return nil
+}
+
+// ArchiveBefore archives observations last updated before t, returning how many weren't
+// already archived.
+func (db *DB) ArchiveBefore(t time.Time) (int, error) {
+	// updated_at is stored in UTC as 'YYYY-MM-DD HH:MM:SS', so strings compare in time order
+	return db.archiveWhere("updated_at < ?", t.UTC().Format(time.DateTime))
+}
+
+func (db *DB) archiveWhere(condition string, args ...interface{}) (int, error) {
+	result, err := db.exec("INSERT OR IGNORE INTO archived (observation_id) SELECT id FROM observations WHERE "+condition, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive observations: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n), nil
+}
+
+// Unarchive returns archived observations to searches, returning how many were archived.
+func (db *DB) Unarchive(ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders, args := idList(ids)
+	result, err := db.exec("DELETE FROM archived WHERE observation_id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to unarchive observations: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n), nil
+}
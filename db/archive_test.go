@@ -0,0 +1,56 @@
+package db
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestArchive(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_archive.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tea, _ := db.AddObservation("Alice", "Likes tea")
+	cake, _ := db.AddObservation("Alice", "Likes cake")
+
+	if _, err := db.Archive([]int64{tea, 999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound archiving a missing observation, got %v", err)
+	}
+	if n, err := db.Archive([]int64{tea, tea}); err != nil || n != 1 {
+		t.Fatalf("Archive = %d, %v; want 1", n, err)
+	}
+
+	observations, err := db.SearchObservations("", []string{"likes"}, true)
+	if err != nil || len(observations) != 1 || observations[0].ID != cake {
+		t.Errorf("Expected searches to leave out the archived observation, got %+v (%v)", observations, err)
+	}
+	if count, _ := db.CountSearchObservations("Alice", nil, true); count != 1 {
+		t.Errorf("Expected a count of 1, got %d", count)
+	}
+	if page, _ := db.SearchObservationsPage("", nil, true, Page{Limit: 10}); len(page) != 1 {
+		t.Errorf("Expected pages to leave out the archived observation, got %+v", page)
+	}
+	if _, err := db.GetObservation(tea); err != nil {
+		t.Errorf("Expected archived observations to still be found by ID, got %v", err)
+	}
+
+	db.SetIncludeArchived(true)
+	if observations, _ := db.SearchObservations("", nil, true); len(observations) != 2 {
+		t.Errorf("Expected both observations with archived ones included, got %+v", observations)
+	}
+	db.SetIncludeArchived(false)
+
+	// Everything was updated before tomorrow
+	if n, err := db.ArchiveBefore(time.Now().Add(24 * time.Hour)); err != nil || n != 1 {
+		t.Errorf("ArchiveBefore = %d, %v; want 1", n, err)
+	}
+	if n, err := db.Unarchive([]int64{tea, cake}); err != nil || n != 2 {
+		t.Errorf("Unarchive = %d, %v; want 2", n, err)
+	}
+	if observations, _ := db.SearchObservations("", nil, true); len(observations) != 2 {
+		t.Errorf("Expected both observations back in searches, got %+v", observations)
+	}
+}
@@ -0,0 +1,173 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Manifest records what a backup should contain, so it can be checked before it's needed.
+type Manifest struct {
+	SHA256        string `json:"sha256"`
+	Size          int64  `json:"size"`
+	Entities      int    `json:"entities"`
+	Observations  int    `json:"observations"`
+	Relationships int    `json:"relationships"`
+}
+
+// BackupTo copies the database, encrypted with the same key, to a new file at path. The copy
+// is taken from the open connection, so it's consistent even while others are writing and
+// the database needn't be closed. A database's key slots are copied beside it, so the copy
+// opens with the same keys. Fails if path already exists.
+func (db *DB) BackupTo(path string) error {
+	if err := backupTo(db.exec, db.path, path); err != nil {
+		return fmt.Errorf("failed to back up database: %w", err)
+	}
+	return nil
+}
+
+// backupTo copies the database at dbPath, using exec to run statements on it, and its key
+// slots to a new file at path.
+func backupTo(exec func(string, ...interface{}) (sql.Result, error), dbPath, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	// VACUUM INTO writes a consistent, compacted copy in one read transaction, and
+	// SQLCipher encrypts it with the connection's key
+	if _, err := exec("VACUUM INTO ?", path); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	// The copy is encrypted with the data key, which only the key slots unwrap
+	if err := copyKeySlots(dbPath, path); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+// ManifestPath returns the path of the manifest written alongside the backup at backupPath.
+func ManifestPath(backupPath string) string {
+	return backupPath + ".manifest.json"
+}
+
+// WriteManifest hashes the backup at path, counts its records, and saves the result
+// alongside it. The backup must be encrypted with the database's key.
+func (db *DB) WriteManifest(path string) (*Manifest, error) {
+	m, err := db.readBackup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if err := os.WriteFile(ManifestPath(path), append(data, '\n'), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return m, nil
+}
+
+// VerifyBackup checks the backup at path against its manifest: the file must hash the
+// same, open with the database's key, pass an integrity check, and hold the same
+// number of records. It returns the manifest.
+func (db *DB) VerifyBackup(path string) (*Manifest, error) {
+	data, err := os.ReadFile(ManifestPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var want Manifest
+	if err := json.Unmarshal(data, &want); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", ManifestPath(path), err)
+	}
+
+	got, err := checksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if got.SHA256 != want.SHA256 || got.Size != want.Size {
+		return nil, fmt.Errorf("backup %s has changed since it was taken (checksum mismatch)", path)
+	}
+	if err := db.countBackup(path, got); err != nil {
+		return nil, err
+	}
+	if *got != want {
+		return nil, fmt.Errorf("backup %s holds %d entities, %d observations, and %d relationships; manifest says %d, %d, and %d",
+			path, got.Entities, got.Observations, got.Relationships, want.Entities, want.Observations, want.Relationships)
+	}
+	return &want, nil
+}
+
+// readBackup hashes the backup at path and opens it to check its integrity and count its records.
+func (db *DB) readBackup(path string) (*Manifest, error) {
+	m, err := checksum(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.countBackup(path, m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// checksum returns a manifest with the size and SHA-256 of the backup at path.
+func checksum(path string) (*Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	return &Manifest{SHA256: hex.EncodeToString(hash.Sum(nil)), Size: size}, nil
+}
+
+// countBackup checks the integrity of the backup at path and fills in m's record counts.
+func (db *DB) countBackup(path string, m *Manifest) error {
+	// Open read-only so checking a backup can't change its checksum
+	conn, err := sql.Open("sqlite3", dsn(path, db.key)+"&mode=ro")
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	var result string
+	if err := conn.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("failed to open backup %s (is it encrypted with this database's key?): %w", path, err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup %s failed its integrity check: %s", path, result)
+	}
+
+	for table, count := range map[string]*int{
+		"entities":      &m.Entities,
+		"observations":  &m.Observations,
+		"relationships": &m.Relationships,
+	} {
+		if err := conn.QueryRow("SELECT COUNT(*) FROM " + table).Scan(count); err != nil {
+			return fmt.Errorf("failed to count %s in backup: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// removeManifest deletes the manifest of the backup at path, if it has one.
+func removeManifest(path string) error {
+	err := os.Remove(ManifestPath(path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove manifest: %w", err)
+	}
+	return nil
+}
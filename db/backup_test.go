@@ -0,0 +1,180 @@
+package db
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestBackupManifest(t *testing.T) {
+	path := t.TempDir() + "/test_manifest.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+	snap, err := db.CreateSnapshot(BackupSnapshotName)
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+
+	if _, err := db.VerifyBackup(snap.Path); err == nil {
+		t.Error("VerifyBackup without a manifest should fail")
+	}
+
+	written, err := db.WriteManifest(snap.Path)
+	if err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+	if written.Entities != 2 || written.Observations != 1 || written.Relationships != 1 || len(written.SHA256) != 64 || written.Size != snap.Size {
+		t.Errorf("WriteManifest() = %+v, want 2 entities, 1 observation, 1 relationship, and the backup's checksum", written)
+	}
+
+	verified, err := db.VerifyBackup(snap.Path)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	if *verified != *written {
+		t.Errorf("VerifyBackup() = %+v, want %+v", verified, written)
+	}
+
+	// Changes to the live database don't affect the backup
+	if _, err := db.AddObservation("Bob", "Likes coffee"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.VerifyBackup(snap.Path); err != nil {
+		t.Errorf("VerifyBackup after writing to the database failed: %v", err)
+	}
+
+	// A corrupted backup fails its checksum
+	data, err := os.ReadFile(snap.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[len(data)/2] ^= 0xff
+	if err := os.WriteFile(snap.Path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.VerifyBackup(snap.Path); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("VerifyBackup of a corrupted backup = %v, want a checksum error", err)
+	}
+
+	// Pruning a backup removes its manifest too
+	newer, err := db.CreateSnapshot(BackupSnapshotName)
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if _, err := db.WriteManifest(newer.Path); err != nil {
+		t.Fatalf("WriteManifest failed: %v", err)
+	}
+	if _, err := db.PruneBackups(&Retention{KeepDaily: 1}); err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if _, err := os.Stat(ManifestPath(snap.Path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the pruned backup's manifest to be removed, got %v", err)
+	}
+	if _, err := os.Stat(ManifestPath(newer.Path)); err != nil {
+		t.Errorf("Expected the kept backup's manifest to remain: %v", err)
+	}
+}
+
+func TestBackupTo(t *testing.T) {
+	dir := t.TempDir()
+	key := "testkey123456789012"
+
+	db, err := Init(dir+"/test_backup_to.db", key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	// The backup is taken with the database open, and the connection keeps working after
+	path := dir + "/backup.db"
+	if err := db.BackupTo(path); err != nil {
+		t.Fatalf("BackupTo failed: %v", err)
+	}
+	if _, err := db.AddObservation("Bob", "Likes coffee"); err != nil {
+		t.Errorf("AddObservation after BackupTo failed: %v", err)
+	}
+	if err := db.BackupTo(path); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("BackupTo an existing file = %v, want an error", err)
+	}
+
+	backup, err := Open(path, key)
+	if err != nil {
+		t.Fatalf("Failed to open backup with the database's key: %v", err)
+	}
+	defer func() { _ = backup.Close() }()
+	observations, err := backup.SearchObservations("", nil, false)
+	if err != nil || len(observations) != 1 || observations[0].Text != "Likes tea" {
+		t.Errorf("Backup holds %+v (err %v), want just the observation from before it was taken", observations, err)
+	}
+}
+
+func TestBackupToKeySlots(t *testing.T) {
+	fastKeySlots(t)
+	dir := t.TempDir()
+	key := "testkey123456789012"
+	passphrase := "correct horse battery"
+
+	db, err := Init(dir+"/test_backup_slots.db", key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.AddKeySlot("laptop", passphrase); err != nil {
+		t.Fatalf("AddKeySlot failed: %v", err)
+	}
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	path := dir + "/backup.db"
+	if err := db.BackupTo(path); err != nil {
+		t.Fatalf("BackupTo failed: %v", err)
+	}
+	older, err := db.CreateSnapshot(BackupSnapshotName)
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+
+	// The database is encrypted with a data key, so the backups need its slots to open
+	for _, backupPath := range []string{path, older.Path} {
+		for _, k := range []string{key, passphrase} {
+			backup, err := Open(backupPath, k)
+			if err != nil {
+				t.Fatalf("Failed to open %s with a key of the database: %v", backupPath, err)
+			}
+			count, err := backup.CountObservations()
+			_ = backup.Close()
+			if err != nil || count != 1 {
+				t.Errorf("Backup %s holds %d observations (err %v), want 1", backupPath, count, err)
+			}
+		}
+	}
+
+	// Pruning a backup removes its key slots too
+	if _, err := db.CreateSnapshot(BackupSnapshotName); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if _, err := db.PruneBackups(&Retention{KeepDaily: 1}); err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if _, err := os.Stat(KeySlotsPath(older.Path)); !os.IsNotExist(err) {
+		t.Errorf("Expected the pruned backup's key slots removed, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package db
+
+// Cascade is an entity together with everything deleting it removes.
+type Cascade struct {
+	Entity        Entity         `json:"entity"`
+	Observations  []Observation  `json:"observations"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// EntityCascade returns the entity with the given ID along with the observations and
+// relationships, archived or not, that the database would cascade delete with it.
+func (db *DB) EntityCascade(id int64) (*Cascade, error) {
+	entity, err := db.GetEntity(id)
+	if err != nil {
+		return nil, err
+	}
+
+	observations, err := collect(scanRows(db, observationsSelect+" WHERE o.entity_id = ? ORDER BY o.id", []interface{}{id}, nil, "observations", scanObservation))
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := relationshipsQuery("", "", "", "", nil, nil, false)
+	relationships, err := collect(scanRows(db, query+" WHERE r.from_id = ? OR r.to_id = ? ORDER BY r.id", append(args, id, id), nil, "relationships", scanRelationship))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Cascade{Entity: *entity, Observations: observations, Relationships: relationships}, nil
+}
+
+// EntityCascadeByText is EntityCascade for the entity named text.
+func (db *DB) EntityCascadeByText(text string) (*Cascade, error) {
+	id, err := db.entityID(text)
+	if err != nil {
+		return nil, err
+	}
+	return db.EntityCascade(id)
+}
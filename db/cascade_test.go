@@ -0,0 +1,50 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEntityCascade(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_cascade.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	obsID, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Bob", "Likes coffee")
+	outID, _ := db.AddRelationship("Alice", "Bob", "knows")
+	inID, _ := db.AddRelationship("Carol", "Alice", "manages")
+	_, _ = db.AddRelationship("Bob", "Carol", "knows")
+
+	cascade, err := db.EntityCascadeByText("Alice")
+	if err != nil {
+		t.Fatalf("EntityCascadeByText failed: %v", err)
+	}
+	if cascade.Entity.Text != "Alice" {
+		t.Errorf("Expected entity Alice, got %q", cascade.Entity.Text)
+	}
+	if len(cascade.Observations) != 1 || cascade.Observations[0].ID != obsID {
+		t.Errorf("Expected only observation %d, got %+v", obsID, cascade.Observations)
+	}
+	if len(cascade.Relationships) != 2 || cascade.Relationships[0].ID != outID || cascade.Relationships[1].ID != inID {
+		t.Errorf("Expected relationships %d and %d, got %+v", outID, inID, cascade.Relationships)
+	}
+
+	// What the cascade reports is exactly what deleting removes
+	if err := db.DeleteEntity(cascade.Entity.ID); err != nil {
+		t.Fatalf("DeleteEntity failed: %v", err)
+	}
+	if found, _ := db.ObservationExists(obsID); found {
+		t.Error("Expected observation to be deleted with its entity")
+	}
+	rels, _ := db.SearchRelationships("", "", "", nil, false)
+	if len(rels) != 1 {
+		t.Errorf("Expected 1 relationship left, got %d", len(rels))
+	}
+
+	if _, err := db.EntityCascadeByText("Alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a deleted entity, got %v", err)
+	}
+}
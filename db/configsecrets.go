@@ -0,0 +1,63 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ConfigSecret returns the config secret called name, stored with SetConfigSecret.
+// Returns an error wrapping ErrNotFound if there's none.
+func (db *DB) ConfigSecret(name string) (string, error) {
+	var value string
+	err := db.queryRow("SELECT value FROM config_secrets WHERE name = ?", name).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("secret '%s' %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret: %w", err)
+	}
+	return value, nil
+}
+
+// SetConfigSecret stores a sensitive setting, like a provider's API key, in the database,
+// where it's encrypted with the database key rather than left in a plaintext config file.
+// Secrets aren't records: they aren't searched, exported, or undone.
+func (db *DB) SetConfigSecret(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("secret name is required")
+	}
+	if value == "" {
+		return fmt.Errorf("secret value is required")
+	}
+	_, err := db.exec(`INSERT INTO config_secrets (name, value) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET value = excluded.value, updated_at = CURRENT_TIMESTAMP`, name, value)
+	if err != nil {
+		return fmt.Errorf("failed to set secret: %w", err)
+	}
+	return nil
+}
+
+// DeleteConfigSecret removes the config secret called name.
+// Returns an error wrapping ErrNotFound if there's none.
+func (db *DB) DeleteConfigSecret(name string) error {
+	result, err := db.exec("DELETE FROM config_secrets WHERE name = ?", name)
+	if err != nil {
+		return fmt.Errorf("failed to delete secret: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("secret '%s' %w", name, ErrNotFound)
+	}
+	return nil
+}
+
+// ConfigSecretNames returns the names of the stored config secrets, in order.
+func (db *DB) ConfigSecretNames() ([]string, error) {
+	return collect(scanRows(db, "SELECT name FROM config_secrets ORDER BY name", nil, nil, "secrets", func(rows *sql.Rows, name *string) error {
+		return rows.Scan(name)
+	}))
+}
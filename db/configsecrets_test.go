@@ -0,0 +1,45 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestConfigSecrets(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_config_secrets.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.ConfigSecret("openai_api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound before it's set, got %v", err)
+	}
+
+	if err := db.SetConfigSecret("openai_api_key", "sk-one"); err != nil {
+		t.Fatalf("SetConfigSecret failed: %v", err)
+	}
+	if err := db.SetConfigSecret("openai_api_key", "sk-two"); err != nil {
+		t.Fatalf("SetConfigSecret failed to replace: %v", err)
+	}
+	if err := db.SetConfigSecret("webhook_secret", "hunter2"); err != nil {
+		t.Fatalf("SetConfigSecret failed: %v", err)
+	}
+	if value, err := db.ConfigSecret("openai_api_key"); err != nil || value != "sk-two" {
+		t.Errorf("ConfigSecret = %q, %v; want sk-two", value, err)
+	}
+	if names, _ := db.ConfigSecretNames(); !slices.Equal(names, []string{"openai_api_key", "webhook_secret"}) {
+		t.Errorf("ConfigSecretNames = %v", names)
+	}
+	if err := db.SetConfigSecret("empty", ""); err == nil {
+		t.Error("Expected error setting an empty secret")
+	}
+
+	if err := db.DeleteConfigSecret("openai_api_key"); err != nil {
+		t.Fatalf("DeleteConfigSecret failed: %v", err)
+	}
+	if err := db.DeleteConfigSecret("openai_api_key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound deleting twice, got %v", err)
+	}
+}
@@ -1,49 +1,141 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"os"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/mutecomm/go-sqlcipher/v4"
 )
 
 type DB struct {
 	conn *sql.DB
-	path string
-	key  string
+	tx   *sql.Tx // set on the DB passed to a Transaction callback
+
+	// settings are shared with the DB a Transaction runs on
+	settings
+
+	// quotaPending is set on a Transaction's DB when an add needs the quota enforced after
+	// the commit, never evicting quotaKeepID
+	quotaPending bool
+	quotaKeepID  int64
+
+	// migrationBackup is the snapshot taken before Open migrated the schema, if it did
+	migrationBackup *Snapshot
+
+	// lockMu serializes Locked within this process; the lock file covers other processes
+	lockMu sync.Mutex
+
+	// queue, when set, runs all Locked writes on a single goroutine
+	queueMu sync.RWMutex
+	queue   *writeQueue
+}
+
+// settings are a DB's options, copied whole to the DB a Transaction runs on.
+type settings struct {
+	path  string
+	key   string // the key SQLCipher decrypts with
+	slot  string // the key slot that unlocked key, if the database uses key slots
+	quota *Quota
+	rules *Rules
+
+	// explain, when set, receives the SQL, query plan, and timing of each search
+	explain io.Writer
+
+	// history is true once the schema has history tables, so writes can be undone
+	history bool
+
+	// recordRetrievals, when set, logs each search's results for retrieval stats
+	recordRetrievals bool
+
+	// includeArchived, when set, has searches return archived observations too
+	includeArchived bool
+
+	// required are keywords every search result must contain, set with SetRequired
+	required []string
+
+	// lang, when set, limits observation searches to that language, set with SetLang
+	lang string
+
+	// lockTimeout is how long Locked waits for another process's lock
+	lockTimeout time.Duration
 }
 
 type Entity struct {
-	ID   int64
-	Text string
+	ID        int64  `json:"id"`
+	Text      string `json:"text"`
+	CreatedAt string `json:"created_at"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 type Observation struct {
-	ID         int64
-	EntityID   int64
-	EntityText string
-	Text       string
-	Timestamp  string
+	ID         int64  `json:"id"`
+	EntityID   int64  `json:"entity_id"`
+	EntityText string `json:"entity"`
+	Text       string `json:"text"`
+	Timestamp  string `json:"timestamp"`
+	UpdatedAt  string `json:"updated_at"`
+	Importance int    `json:"importance"`
+	// Lang is the ISO 639-1 code of the language the observation is written in, if known
+	Lang string `json:"lang,omitempty"`
+}
+
+// ObservationOptions holds optional attributes for a new observation.
+type ObservationOptions struct {
+	Importance int
+	// AllowSecrets adds the observation even if the rules refuse secrets
+	AllowSecrets bool
+	// Timestamp is when the observation was made, for backfilling; the zero time means now
+	Timestamp time.Time
+	// Lang is the ISO 639-1 code of the language the observation is written in, like "de",
+	// LangAuto to detect it from the text, or "" if unknown
+	Lang string
+}
+
+// RelationshipOptions holds optional attributes for a new relationship.
+type RelationshipOptions struct {
+	// Timestamp is when the relationship was recorded, for backfilling; the zero time means now
+	Timestamp time.Time
+}
+
+// timestampArg returns t as a timestamp column value, or nil for the zero time so
+// COALESCE falls back to the current time.
+func timestampArg(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t.UTC().Format(time.DateTime)
 }
 
 type Relationship struct {
-	ID        int64
-	FromID    int64
-	FromText  string
-	ToID      int64
-	ToText    string
-	Type      string
-	Timestamp string
+	ID        int64  `json:"id"`
+	FromID    int64  `json:"from_id"`
+	FromText  string `json:"from"`
+	ToID      int64  `json:"to_id"`
+	ToText    string `json:"to"`
+	Type      string `json:"type"`
+	Timestamp string `json:"timestamp"`
+	UpdatedAt string `json:"updated_at"`
 }
 
 // Format returns a formatted string representation of the entity.
 func (e Entity) Format(withID bool) string {
+	text := e.Text
+	if e.CreatedAt != "" {
+		text = fmt.Sprintf("%s (%s)", e.Text, e.CreatedAt)
+	}
 	if withID {
-		return fmt.Sprintf("[%d] %s", e.ID, e.Text)
+		return fmt.Sprintf("[%d] %s", e.ID, text)
 	}
-	return e.Text
+	return text
 }
 
 // Format returns a formatted string representation of the observation.
@@ -62,45 +154,172 @@ func (r Relationship) Format(withID bool) string {
 	return fmt.Sprintf("%s -[%s]-> %s (%s)", r.FromText, r.Type, r.ToText, r.Timestamp)
 }
 
+// Options tunes how an opened database behaves.
+type Options struct {
+	// LockTimeout bounds how long writes wait for locks held by other processes
+	LockTimeout time.Duration
+	// SkipMigrations opens the database without bringing its schema up to date,
+	// so a database whose migrations fail can still be opened and repaired
+	SkipMigrations bool
+	// SkipMigrationBackup migrates the schema without first taking a MigrationSnapshotName snapshot
+	SkipMigrationBackup bool
+	// CheckIntegrity verifies every page against its HMAC before using the database,
+	// failing with ErrCorrupt if any are damaged. It reads the whole file, so it's opt-in.
+	CheckIntegrity bool
+	// MaxOpenConns limits the connections open to the database at once, 0 for no limit
+	MaxOpenConns int
+	// MaxIdleConns limits the connections kept open between uses, 0 for database/sql's default
+	MaxIdleConns int
+}
+
+// DefaultOptions returns the options used by Open: a single connection, so a command's
+// statements all see the same state and never wait on each other for SQLite's locks.
+func DefaultOptions() Options {
+	return Options{LockTimeout: DefaultLockTimeout, MaxOpenConns: 1, MaxIdleConns: 1}
+}
+
+// Concurrent returns o for a long-running process with concurrent readers, like
+// 'amem serve': a connection per CPU, so reads run in parallel. Writes still go one
+// at a time through Locked.
+func (o Options) Concurrent() Options {
+	o.MaxOpenConns = max(runtime.NumCPU(), 2)
+	o.MaxIdleConns = o.MaxOpenConns
+	return o
+}
+
 func Open(path, key string) (*DB, error) {
+	return OpenWithOptions(path, key, DefaultOptions())
+}
+
+// OpenWithOptions opens the database at path like Open, with the given options.
+func OpenWithOptions(path, key string, opts Options) (*DB, error) {
 	if key == "" {
 		return nil, fmt.Errorf("encryption key is required")
 	}
+	if opts.LockTimeout < 0 {
+		return nil, fmt.Errorf("lock timeout cannot be negative")
+	}
+
+	// With key slots, the given key unlocks the database's data key
+	dbKey, slot, err := unlockKey(path, key)
+	if err != nil {
+		return nil, err
+	}
 
-	dsn := fmt.Sprintf("file:%s?_pragma_key=%s&_pragma_cipher_page_size=4096", path, key)
-	conn, err := sql.Open("sqlite3", dsn)
+	conn, err := sql.Open("sqlite3", dsn(path, dbKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	conn.SetMaxOpenConns(opts.MaxOpenConns)
+	if opts.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(opts.MaxIdleConns)
+	}
 
 	if err := conn.Ping(); err != nil {
 		_ = conn.Close()
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
-	// Enable foreign key constraints
-	if _, err := conn.Exec("PRAGMA foreign_keys = ON"); err != nil {
+	// A damaged database shouldn't be migrated, or backed up as if it were sound
+	if opts.CheckIntegrity {
+		if err := checkIntegrity(conn, path); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+
+	// Bring existing databases up to the current schema
+	initialized, err := isInitialized(conn)
+	if err != nil {
 		_ = conn.Close()
-		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	if initialized {
+		if err := checkSchemaVersion(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
+	}
+	var backup *Snapshot
+	if initialized && !opts.SkipMigrations {
+		if !opts.SkipMigrationBackup {
+			if backup, err = backupBeforeMigrating(conn, path); err != nil {
+				_ = conn.Close()
+				return nil, err
+			}
+		}
+		if err := migrate(conn); err != nil {
+			_ = conn.Close()
+			if backup != nil {
+				return nil, fmt.Errorf("failed to run migrations (the database was backed up to %s first): %w", backup.Path, err)
+			}
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+		if err := refreshFeatures(conn); err != nil {
+			_ = conn.Close()
+			return nil, err
+		}
 	}
 
 	return &DB{
 		conn: conn,
-		path: path,
-		key:  key,
+		settings: settings{
+			path:        path,
+			key:         dbKey,
+			slot:        slot,
+			lockTimeout: opts.LockTimeout,
+			history:     initialized && !opts.SkipMigrations,
+		},
+		migrationBackup: backup,
 	}, nil
 }
 
+// backupBeforeMigrating snapshots the database on conn if it has migrations pending,
+// returning the snapshot, or nil if its schema is already current.
+func backupBeforeMigrating(conn *sql.DB, path string) (*Snapshot, error) {
+	current, err := getCurrentVersion(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	if current >= LatestSchemaVersion() {
+		return nil, nil
+	}
+	snap, err := writeSnapshot(conn.Exec, path, MigrationSnapshotName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up before migrating: %w", err)
+	}
+	return snap, nil
+}
+
+// MigrationBackup returns the snapshot Open took before upgrading the database's schema,
+// or nil if it didn't need upgrading.
+func (db *DB) MigrationBackup() *Snapshot {
+	return db.migrationBackup
+}
+
+// dsn returns the connection string for the database at path encrypted with key.
+// Settings are in it rather than run as PRAGMAs so every pooled connection gets them.
+func dsn(path, key string) string {
+	return fmt.Sprintf("file:%s?_pragma_key=%s&_pragma_cipher_page_size=4096&_busy_timeout=%d&_foreign_keys=1", path, key, busyTimeoutMillis)
+}
+
 func Init(path, key string) (*DB, error) {
 	db, err := Open(path, key)
 	if err != nil {
 		return nil, err
 	}
 
+	// Vacuuming incrementally lets Maintain return unused pages to the filesystem.
+	// It only takes effect before the first table is created, so existing databases keep their setting
+	if _, err := db.conn.Exec("PRAGMA auto_vacuum = INCREMENTAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to enable incremental vacuum: %w", err)
+	}
 	if err := migrate(db.conn); err != nil {
 		_ = db.Close()
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
+	db.history = true
 
 	return db, nil
 }
@@ -116,10 +335,24 @@ func (db *DB) Path() string {
 func (db *DB) IsEncrypted() (bool, error) {
 	// Check if we can query the database
 	var result int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&result)
+	err := db.queryRow("SELECT COUNT(*) FROM sqlite_master").Scan(&result)
 	return err == nil, err
 }
 
+// Ping verifies the database connection is alive.
+func (db *DB) Ping(ctx context.Context) error {
+	return db.conn.PingContext(ctx)
+}
+
+// SchemaVersion returns the database's current schema version.
+func (db *DB) SchemaVersion() (int, error) {
+	version, err := getCurrentVersion(db.conn)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get schema version: %w", err)
+	}
+	return version, nil
+}
+
 func (db *DB) Exists() bool {
 	_, err := os.Stat(db.path)
 	return err == nil
@@ -130,18 +363,22 @@ func (db *DB) Conn() *sql.DB {
 }
 
 // Rekey changes the encryption key for the database.
+// With key slots, only the key of the slot the database was unlocked with changes.
 // The database connection remains valid after rekeying.
 func (db *DB) Rekey(newKey string) error {
 	if newKey == "" {
 		return fmt.Errorf("new encryption key cannot be empty")
 	}
+	if db.slot != "" {
+		return db.changeSlotKey(newKey)
+	}
 
 	// PRAGMA commands don't support parameterized queries in SQLCipher
 	// We need to use string formatting, but we escape single quotes to prevent SQL injection
 	escapedKey := strings.ReplaceAll(newKey, "'", "''")
 	query := fmt.Sprintf("PRAGMA rekey = '%s'", escapedKey)
 
-	_, err := db.conn.Exec(query)
+	_, err := db.exec(query)
 	if err != nil {
 		return fmt.Errorf("failed to rekey database: %w", err)
 	}
@@ -154,15 +391,63 @@ func (db *DB) Rekey(newKey string) error {
 // AddEntity adds an entity to the database.
 // Returns the entity ID (existing or new).
 func (db *DB) AddEntity(text string) (int64, error) {
-	// Use INSERT OR IGNORE to avoid duplicate key errors
-	_, err := db.conn.Exec("INSERT OR IGNORE INTO entities (text) VALUES (?)", text)
+	var id int64
+	err := db.Transaction(func(tx *DB) error {
+		var err error
+		id, err = tx.addEntity(text)
+		return err
+	})
+	return id, err
+}
+
+// AddEntities adds the entities that don't exist yet, all in one transaction, so either
+// all are added or none are. Returns each entity's ID (existing or new), in order.
+func (db *DB) AddEntities(texts []string) ([]int64, error) {
+	ids := make([]int64, 0, len(texts))
+	err := db.Transaction(func(tx *DB) error {
+		for _, text := range texts {
+			id, err := tx.addEntity(text)
+			if err != nil {
+				return fmt.Errorf("failed to add entity '%s': %w", text, err)
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// addEntity adds an entity if it doesn't exist and returns its ID. It must run in a
+// transaction, so the entity can't be deleted or renamed by another process between
+// the insert and looking up its ID. (An upsert with RETURNING would do both in one
+// statement, but the SQLite the driver bundles, 3.33, predates RETURNING.)
+func (db *DB) addEntity(text string) (int64, error) {
+	text = db.trimInput(text)
+	if err := db.checkEntity(text); err != nil {
+		// Entities added before the rule can still be referenced
+		var id int64
+		if db.queryRow("SELECT id FROM entities WHERE text = ?", text).Scan(&id) == nil {
+			return id, nil
+		}
+		return 0, err
+	}
+
+	result, err := db.exec("INSERT INTO entities (text) VALUES (?) ON CONFLICT (text) DO NOTHING", text)
 	if err != nil {
 		return 0, fmt.Errorf("failed to insert entity: %w", err)
 	}
 
-	// Always fetch the ID (works for both new and existing entities)
+	if inserted, err := result.RowsAffected(); err == nil && inserted > 0 {
+		if err := db.enforceQuotaOnAdd(0); err != nil {
+			return 0, err
+		}
+	}
+
 	var id int64
-	err = db.conn.QueryRow("SELECT id FROM entities WHERE text = ?", text).Scan(&id)
+	err = db.queryRow("SELECT id FROM entities WHERE text = ?", text).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get entity id: %w", err)
 	}
@@ -178,45 +463,115 @@ func (db *DB) getEntityID(text string) (int64, error) {
 // AddObservation adds an observation about an entity.
 // Creates the entity if it doesn't exist. Returns the observation ID.
 func (db *DB) AddObservation(entityText, observationText string) (int64, error) {
-	entityID, err := db.getEntityID(entityText)
+	return db.AddObservationWithOptions(entityText, observationText, ObservationOptions{})
+}
+
+// AddObservationWithOptions adds an observation about an entity with optional attributes.
+// Creates the entity if it doesn't exist. Returns the observation ID.
+func (db *DB) AddObservationWithOptions(entityText, observationText string, opts ObservationOptions) (int64, error) {
+	observationText = db.trimInput(observationText)
+	if err := db.checkObservation(observationText); err != nil {
+		return 0, err
+	}
+	lang, err := observationLang(opts.Lang, observationText)
 	if err != nil {
 		return 0, err
 	}
+	if !opts.AllowSecrets {
+		if err := db.checkSecrets(observationText); err != nil {
+			return 0, err
+		}
+	}
+
+	// The entity is added in the same transaction, so a failed insert doesn't leave it behind
+	var id int64
+	err = db.Transaction(func(tx *DB) error {
+		entityID, err := tx.addEntity(entityText)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.exec("INSERT INTO observations (entity_id, text, importance, timestamp, lang) VALUES (?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP), ?)",
+			entityID, observationText, opts.Importance, timestampArg(opts.Timestamp), langArg(lang))
+		if err != nil {
+			return fmt.Errorf("failed to insert observation: %w", err)
+		}
 
-	result, err := db.conn.Exec("INSERT INTO observations (entity_id, text) VALUES (?, ?)", entityID, observationText)
+		id, err = result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+
+		if err := tx.indexObservation(id, observationText); err != nil {
+			return err
+		}
+
+		return tx.enforceQuotaOnAdd(id)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to insert observation: %w", err)
+		return 0, err
 	}
 
-	id, err := result.LastInsertId()
+	return id, nil
+}
+
+// FindObservation returns the ID of the observation about the entity named entityText
+// with exactly the text observationText (as it would be stored), or ErrNotFound.
+func (db *DB) FindObservation(entityText, observationText string) (int64, error) {
+	var id int64
+	err := db.queryRow(`SELECT o.id FROM observations o JOIN entities e ON o.entity_id = e.id
+		WHERE e.text = ? AND o.text = ? ORDER BY o.id LIMIT 1`, entityText, db.trimInput(observationText)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("observation about '%s' %w", entityText, ErrNotFound)
+	}
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, fmt.Errorf("failed to find observation: %w", err)
 	}
-
 	return id, nil
 }
 
 // AddRelationship adds a relationship between two entities.
 // Creates entities if they don't exist. Returns the relationship ID.
 func (db *DB) AddRelationship(fromText, toText, relType string) (int64, error) {
-	fromID, err := db.getEntityID(fromText)
-	if err != nil {
-		return 0, err
-	}
+	return db.AddRelationshipWithOptions(fromText, toText, relType, RelationshipOptions{})
+}
 
-	toID, err := db.getEntityID(toText)
-	if err != nil {
+// AddRelationshipWithOptions adds a relationship between two entities with optional attributes.
+// Creates entities if they don't exist. Returns the relationship ID.
+func (db *DB) AddRelationshipWithOptions(fromText, toText, relType string, opts RelationshipOptions) (int64, error) {
+	relType = db.trimInput(relType)
+	if err := db.checkRelationshipType(relType); err != nil {
 		return 0, err
 	}
 
-	result, err := db.conn.Exec("INSERT INTO relationships (from_id, to_id, type) VALUES (?, ?, ?)", fromID, toID, relType)
-	if err != nil {
-		return 0, fmt.Errorf("failed to insert relationship: %w", err)
-	}
+	// The entities are added in the same transaction, so a failed insert doesn't leave them behind
+	var id int64
+	err := db.Transaction(func(tx *DB) error {
+		fromID, err := tx.addEntity(fromText)
+		if err != nil {
+			return err
+		}
+
+		toID, err := tx.addEntity(toText)
+		if err != nil {
+			return err
+		}
 
-	id, err := result.LastInsertId()
+		result, err := tx.exec("INSERT INTO relationships (from_id, to_id, type, timestamp) VALUES (?, ?, ?, COALESCE(?, CURRENT_TIMESTAMP))",
+			fromID, toID, relType, timestampArg(opts.Timestamp))
+		if err != nil {
+			return fmt.Errorf("failed to insert relationship: %w", err)
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get last insert id: %w", err)
+		}
+
+		return tx.enforceQuotaOnAdd(0)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to get last insert id: %w", err)
+		return 0, err
 	}
 
 	return id, nil
@@ -250,7 +605,7 @@ func buildWhereClause(keywords []string, columns []string, useUnion bool) (strin
 // DeleteEntity deletes an entity by ID.
 // Observations and relationships are cascade deleted by the database.
 func (db *DB) DeleteEntity(id int64) error {
-	result, err := db.conn.Exec("DELETE FROM entities WHERE id = ?", id)
+	result, err := db.exec("DELETE FROM entities WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
@@ -270,7 +625,7 @@ func (db *DB) DeleteEntity(id int64) error {
 // DeleteEntityByText deletes an entity by text.
 // Observations and relationships are cascade deleted by the database.
 func (db *DB) DeleteEntityByText(text string) error {
-	result, err := db.conn.Exec("DELETE FROM entities WHERE text = ?", text)
+	result, err := db.exec("DELETE FROM entities WHERE text = ?", text)
 	if err != nil {
 		return fmt.Errorf("failed to delete entity: %w", err)
 	}
@@ -289,7 +644,7 @@ func (db *DB) DeleteEntityByText(text string) error {
 
 // DeleteObservation deletes an observation by ID.
 func (db *DB) DeleteObservation(id int64) error {
-	result, err := db.conn.Exec("DELETE FROM observations WHERE id = ?", id)
+	result, err := db.exec("DELETE FROM observations WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete observation: %w", err)
 	}
@@ -308,7 +663,7 @@ func (db *DB) DeleteObservation(id int64) error {
 
 // DeleteRelationship deletes a relationship by ID.
 func (db *DB) DeleteRelationship(id int64) error {
-	result, err := db.conn.Exec("DELETE FROM relationships WHERE id = ?", id)
+	result, err := db.exec("DELETE FROM relationships WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("failed to delete relationship: %w", err)
 	}
@@ -325,54 +680,157 @@ func (db *DB) DeleteRelationship(id int64) error {
 	return nil
 }
 
+// SetRequired has searches return only records containing every one of keywords, on top
+// of matching their own keywords, so a search for any of several qualifiers can be kept
+// to one topic. Nil or empty keywords require nothing.
+func (db *DB) SetRequired(keywords []string) {
+	db.required = keywords
+}
+
+// EntityFilter narrows an entity search to those created in a time range.
+// A zero time leaves that end of the range open.
+type EntityFilter struct {
+	CreatedSince  time.Time
+	CreatedBefore time.Time
+}
+
 // SearchEntities searches entities by keywords.
 func (db *DB) SearchEntities(keywords []string, useUnion bool) ([]Entity, error) {
-	query := "SELECT id, text FROM entities"
+	return collect(db.SearchEntitiesIter(keywords, useUnion))
+}
+
+// SearchEntitiesIter is SearchEntities, yielding entities as they are read.
+func (db *DB) SearchEntitiesIter(keywords []string, useUnion bool) iter.Seq2[Entity, error] {
+	return db.SearchEntitiesFilteredIter(keywords, useUnion, EntityFilter{})
+}
+
+// SearchEntitiesFilteredIter is SearchEntitiesIter, limited to entities matching filter.
+func (db *DB) SearchEntitiesFilteredIter(keywords []string, useUnion bool, filter EntityFilter) iter.Seq2[Entity, error] {
+	query, args := entitiesQuery(keywords, db.required, useUnion, filter)
+	return scanRows(db, query+entitiesOrder, args, nil, "entities", scanEntity)
+}
+
+// CountSearchEntities returns how many entities SearchEntities would return.
+func (db *DB) CountSearchEntities(keywords []string, useUnion bool) (int, error) {
+	return db.CountSearchEntitiesFiltered(keywords, useUnion, EntityFilter{})
+}
+
+// CountSearchEntitiesFiltered returns how many entities SearchEntitiesFilteredIter would yield.
+func (db *DB) CountSearchEntitiesFiltered(keywords []string, useUnion bool, filter EntityFilter) (int, error) {
+	query, args := entitiesQuery(keywords, db.required, useUnion, filter)
+	return db.countQuery(query, args, nil, "entities")
+}
+
+func scanEntity(rows *sql.Rows, e *Entity) error {
+	return rows.Scan(&e.ID, &e.Text, &e.CreatedAt, &e.UpdatedAt)
+}
+
+func entitiesQuery(keywords, required []string, useUnion bool, filter EntityFilter) (string, []interface{}) {
+	query := "SELECT id, text, created_at, updated_at FROM entities"
+	var conditions []string
 	var args []interface{}
 
 	if len(keywords) > 0 {
 		whereClause, whereArgs := buildWhereClause(keywords, []string{"text"}, useUnion)
-		query += " WHERE " + whereClause
-		args = whereArgs
+		conditions = append(conditions, "("+whereClause+")")
+		args = append(args, whereArgs...)
 	}
 
-	query += " ORDER BY text"
+	if len(required) > 0 {
+		whereClause, whereArgs := buildWhereClause(required, []string{"text"}, false)
+		conditions = append(conditions, "("+whereClause+")")
+		args = append(args, whereArgs...)
+	}
 
-	rows, err := db.conn.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search entities: %w", err)
+	// created_at is stored in UTC as 'YYYY-MM-DD HH:MM:SS', so strings compare in time order
+	if !filter.CreatedSince.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedSince.UTC().Format(time.DateTime))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UTC().Format(time.DateTime))
 	}
-	defer func() { _ = rows.Close() }()
 
-	var results []Entity
-	for rows.Next() {
-		var e Entity
-		if err := rows.Scan(&e.ID, &e.Text); err != nil {
-			return nil, fmt.Errorf("failed to scan entity: %w", err)
-		}
-		results = append(results, e)
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	return results, rows.Err()
+	return query, args
 }
 
 // SearchObservations searches observations with optional entity filter and keywords.
 func (db *DB) SearchObservations(entityText string, keywords []string, useUnion bool) ([]Observation, error) {
-	query := `
-		SELECT o.id, o.entity_id, e.text, o.text, o.timestamp
+	return collect(db.SearchObservationsIter(entityText, keywords, useUnion))
+}
+
+// SearchObservationsIter is SearchObservations, yielding observations as they are read.
+func (db *DB) SearchObservationsIter(entityText string, keywords []string, useUnion bool) iter.Seq2[Observation, error] {
+	query, args, err := db.observationsQuery(entityText, keywords, useUnion)
+	return scanRows(db, query+observationsOrder, args, err, "observations", scanObservation)
+}
+
+// CountSearchObservations returns how many observations SearchObservations would return.
+func (db *DB) CountSearchObservations(entityText string, keywords []string, useUnion bool) (int, error) {
+	query, args, err := db.observationsQuery(entityText, keywords, useUnion)
+	return db.countQuery(query, args, err, "observations")
+}
+
+func scanObservation(rows *sql.Rows, o *Observation) error {
+	return rows.Scan(&o.ID, &o.EntityID, &o.EntityText, &o.Text, &o.Timestamp, &o.UpdatedAt, &o.Importance, &o.Lang)
+}
+
+// observationsSelect selects every observation, archived or not, for scanObservation.
+const observationsSelect = `
+		SELECT o.id, o.entity_id, e.text, o.text, o.timestamp, o.updated_at, o.importance, COALESCE(o.lang, '')
 		FROM observations o
 		JOIN entities e ON o.entity_id = e.id
 	`
+
+// Searches return results in a fixed order, so paging through them and comparing output
+// between runs are reliable: entities by text, which is unique, and observations and
+// relationships newest first, with ties on equal timestamps broken by ID, newest first.
+const (
+	entitiesOrder      = " ORDER BY text"
+	observationsOrder  = " ORDER BY " + feedbackRank + " DESC, o.updated_at DESC, o.id DESC"
+	relationshipsOrder = " ORDER BY r.timestamp DESC, r.id DESC"
+)
+
+// observationsQuery selects the observations a search for entityText and keywords finds,
+// leaving out archived ones unless SetIncludeArchived is on.
+func (db *DB) observationsQuery(entityText string, keywords []string, useUnion bool) (string, []interface{}, error) {
+	query := observationsSelect
 	var args []interface{}
 	var whereClauses []string
 
+	if clause := db.archivedClause(); clause != "" {
+		whereClauses = append(whereClauses, clause)
+	}
+
 	if entityText != "" {
 		whereClauses = append(whereClauses, "e.text LIKE ?")
 		args = append(args, "%"+entityText+"%")
 	}
 
+	if db.lang != "" {
+		whereClauses = append(whereClauses, "o.lang = ?")
+		args = append(args, db.lang)
+	}
+
 	if len(keywords) > 0 {
-		whereClause, whereArgs := buildWhereClause(keywords, []string{"o.text", "e.text"}, useUnion)
+		whereClause, whereArgs, err := db.observationKeywordClause(keywords, useUnion)
+		if err != nil {
+			return "", nil, err
+		}
+		whereClauses = append(whereClauses, "("+whereClause+")")
+		args = append(args, whereArgs...)
+	}
+
+	if len(db.required) > 0 {
+		whereClause, whereArgs, err := db.observationKeywordClause(db.required, false)
+		if err != nil {
+			return "", nil, err
+		}
 		whereClauses = append(whereClauses, "("+whereClause+")")
 		args = append(args, whereArgs...)
 	}
@@ -381,30 +839,46 @@ func (db *DB) SearchObservations(entityText string, keywords []string, useUnion
 		query += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
 
-	query += " ORDER BY o.timestamp DESC"
+	return query, args, nil
+}
 
-	rows, err := db.conn.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to search observations: %w", err)
-	}
-	defer func() { _ = rows.Close() }()
+// SearchRelationships searches relationships with optional filters.
+func (db *DB) SearchRelationships(fromText, toText, relType string, keywords []string, useUnion bool) ([]Relationship, error) {
+	return collect(db.SearchRelationshipsIter(fromText, toText, relType, keywords, useUnion))
+}
 
-	var results []Observation
-	for rows.Next() {
-		var o Observation
-		if err := rows.Scan(&o.ID, &o.EntityID, &o.EntityText, &o.Text, &o.Timestamp); err != nil {
-			return nil, fmt.Errorf("failed to scan observation: %w", err)
-		}
-		results = append(results, o)
-	}
+// SearchRelationshipsIter is SearchRelationships, yielding relationships as they are read.
+func (db *DB) SearchRelationshipsIter(fromText, toText, relType string, keywords []string, useUnion bool) iter.Seq2[Relationship, error] {
+	query, args := relationshipsQuery(fromText, toText, "", relType, keywords, db.required, useUnion)
+	return scanRows(db, query+relationshipsOrder, args, nil, "relationships", scanRelationship)
+}
 
-	return results, rows.Err()
+// SearchRelationshipsWithIter is SearchRelationshipsIter for relationships with an entity
+// matching withText at either end, so it doesn't matter which way they point.
+func (db *DB) SearchRelationshipsWithIter(withText, relType string, keywords []string, useUnion bool) iter.Seq2[Relationship, error] {
+	query, args := relationshipsQuery("", "", withText, relType, keywords, db.required, useUnion)
+	return scanRows(db, query+relationshipsOrder, args, nil, "relationships", scanRelationship)
 }
 
-// SearchRelationships searches relationships with optional filters.
-func (db *DB) SearchRelationships(fromText, toText, relType string, keywords []string, useUnion bool) ([]Relationship, error) {
+// CountSearchRelationships returns how many relationships SearchRelationships would return.
+func (db *DB) CountSearchRelationships(fromText, toText, relType string, keywords []string, useUnion bool) (int, error) {
+	query, args := relationshipsQuery(fromText, toText, "", relType, keywords, db.required, useUnion)
+	return db.countQuery(query, args, nil, "relationships")
+}
+
+// CountSearchRelationshipsWith returns how many relationships SearchRelationshipsWithIter would yield.
+func (db *DB) CountSearchRelationshipsWith(withText, relType string, keywords []string, useUnion bool) (int, error) {
+	query, args := relationshipsQuery("", "", withText, relType, keywords, db.required, useUnion)
+	return db.countQuery(query, args, nil, "relationships")
+}
+
+func scanRelationship(rows *sql.Rows, r *Relationship) error {
+	return rows.Scan(&r.ID, &r.FromID, &r.FromText, &r.ToID, &r.ToText, &r.Type, &r.Timestamp, &r.UpdatedAt)
+}
+
+func relationshipsQuery(fromText, toText, withText, relType string, keywords, required []string, useUnion bool) (string, []interface{}) {
 	query := `
-		SELECT r.id, r.from_id, e1.text, r.to_id, e2.text, r.type, r.timestamp
+		SELECT r.id, r.from_id, e1.text, r.to_id, e2.text, r.type, r.timestamp, r.updated_at
 		FROM relationships r
 		JOIN entities e1 ON r.from_id = e1.id
 		JOIN entities e2 ON r.to_id = e2.id
@@ -422,6 +896,11 @@ func (db *DB) SearchRelationships(fromText, toText, relType string, keywords []s
 		args = append(args, "%"+toText+"%")
 	}
 
+	if withText != "" {
+		whereClauses = append(whereClauses, "(e1.text LIKE ? OR e2.text LIKE ?)")
+		args = append(args, "%"+withText+"%", "%"+withText+"%")
+	}
+
 	if relType != "" {
 		whereClauses = append(whereClauses, "r.type LIKE ?")
 		args = append(args, "%"+relType+"%")
@@ -433,43 +912,56 @@ func (db *DB) SearchRelationships(fromText, toText, relType string, keywords []s
 		args = append(args, whereArgs...)
 	}
 
+	if len(required) > 0 {
+		whereClause, whereArgs := buildWhereClause(required, []string{"e1.text", "e2.text", "r.type"}, false)
+		whereClauses = append(whereClauses, "("+whereClause+")")
+		args = append(args, whereArgs...)
+	}
+
 	if len(whereClauses) > 0 {
 		query += " WHERE " + strings.Join(whereClauses, " AND ")
 	}
 
-	query += " ORDER BY r.timestamp DESC"
+	return query, args
+}
+
+// SearchAll searches across all types (entities, observations, relationships).
+func (db *DB) SearchAll(keywords []string, useUnion bool) ([]Entity, []Observation, []Relationship, error) {
+	entities, err := db.SearchEntities(keywords, useUnion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
-	rows, err := db.conn.Query(query, args...)
+	observations, err := db.SearchObservations("", keywords, useUnion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to search relationships: %w", err)
+		return nil, nil, nil, err
 	}
-	defer func() { _ = rows.Close() }()
 
-	var results []Relationship
-	for rows.Next() {
-		var r Relationship
-		if err := rows.Scan(&r.ID, &r.FromID, &r.FromText, &r.ToID, &r.ToText, &r.Type, &r.Timestamp); err != nil {
-			return nil, fmt.Errorf("failed to scan relationship: %w", err)
-		}
-		results = append(results, r)
+	relationships, err := db.SearchRelationships("", "", "", keywords, useUnion)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	return results, rows.Err()
+	return entities, observations, relationships, nil
 }
 
-// SearchAll searches across all types (entities, observations, relationships).
-func (db *DB) SearchAll(keywords []string, useUnion bool) ([]Entity, []Observation, []Relationship, error) {
-	entities, err := db.SearchEntities(keywords, useUnion)
+// ChangedSince returns records created or updated at or after since, most recently changed first.
+func (db *DB) ChangedSince(since time.Time) ([]Entity, []Observation, []Relationship, error) {
+	cutoff := since.UTC().Format(time.DateTime)
+
+	query, _ := entitiesQuery(nil, nil, false, EntityFilter{})
+	entities, err := collect(scanRows(db, query+" WHERE updated_at >= ? ORDER BY updated_at DESC, id DESC", []interface{}{cutoff}, nil, "entities", scanEntity))
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	observations, err := db.SearchObservations("", keywords, useUnion)
+	observations, err := collect(scanRows(db, observationsSelect+" WHERE o.updated_at >= ? ORDER BY o.updated_at DESC, o.id DESC", []interface{}{cutoff}, nil, "observations", scanObservation))
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	relationships, err := db.SearchRelationships("", "", "", keywords, useUnion)
+	query, _ = relationshipsQuery("", "", "", "", nil, nil, false)
+	relationships, err := collect(scanRows(db, query+" WHERE r.updated_at >= ? ORDER BY r.updated_at DESC, r.id DESC", []interface{}{cutoff}, nil, "relationships", scanRelationship))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -480,7 +972,7 @@ func (db *DB) SearchAll(keywords []string, useUnion bool) ([]Entity, []Observati
 // CountEntities returns the total number of entities.
 func (db *DB) CountEntities() (int, error) {
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM entities").Scan(&count)
+	err := db.queryRow("SELECT COUNT(*) FROM entities").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count entities: %w", err)
 	}
@@ -490,7 +982,7 @@ func (db *DB) CountEntities() (int, error) {
 // CountObservations returns the total number of observations.
 func (db *DB) CountObservations() (int, error) {
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM observations").Scan(&count)
+	err := db.queryRow("SELECT COUNT(*) FROM observations").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count observations: %w", err)
 	}
@@ -500,7 +992,7 @@ func (db *DB) CountObservations() (int, error) {
 // CountRelationships returns the total number of relationships.
 func (db *DB) CountRelationships() (int, error) {
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM relationships").Scan(&count)
+	err := db.queryRow("SELECT COUNT(*) FROM relationships").Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count relationships: %w", err)
 	}
@@ -509,7 +1001,12 @@ func (db *DB) CountRelationships() (int, error) {
 
 // UpdateEntity updates an entity's text by its current text.
 func (db *DB) UpdateEntity(text, newText string) error {
-	result, err := db.conn.Exec("UPDATE entities SET text = ? WHERE text = ?", newText, text)
+	newText = db.trimInput(newText)
+	if err := db.checkEntity(newText); err != nil {
+		return err
+	}
+
+	result, err := db.exec("UPDATE entities SET text = ? WHERE text = ?", newText, text)
 	if err != nil {
 		return fmt.Errorf("failed to update entity: %w", err)
 	}
@@ -528,7 +1025,12 @@ func (db *DB) UpdateEntity(text, newText string) error {
 
 // UpdateObservation updates an observation's text by ID.
 func (db *DB) UpdateObservation(id int64, newText string) error {
-	result, err := db.conn.Exec("UPDATE observations SET text = ? WHERE id = ?", newText, id)
+	newText = db.trimInput(newText)
+	if err := db.checkObservation(newText); err != nil {
+		return err
+	}
+
+	result, err := db.exec("UPDATE observations SET text = ? WHERE id = ?", newText, id)
 	if err != nil {
 		return fmt.Errorf("failed to update observation: %w", err)
 	}
@@ -542,14 +1044,14 @@ func (db *DB) UpdateObservation(id int64, newText string) error {
 		return fmt.Errorf("observation with ID %d not found", id)
 	}
 
-	return nil
+	return db.indexObservation(id, newText)
 }
 
 // UpdateObservationEntity updates which entity an observation is about.
 func (db *DB) UpdateObservationEntity(id int64, newEntityID int64) error {
 	// Validate the new entity exists
 	var count int
-	err := db.conn.QueryRow("SELECT COUNT(*) FROM entities WHERE id = ?", newEntityID).Scan(&count)
+	err := db.queryRow("SELECT COUNT(*) FROM entities WHERE id = ?", newEntityID).Scan(&count)
 	if err != nil {
 		return fmt.Errorf("failed to check entity: %w", err)
 	}
@@ -558,7 +1060,7 @@ func (db *DB) UpdateObservationEntity(id int64, newEntityID int64) error {
 	}
 
 	// Update the observation's entity_id
-	result, err := db.conn.Exec("UPDATE observations SET entity_id = ? WHERE id = ?", newEntityID, id)
+	result, err := db.exec("UPDATE observations SET entity_id = ? WHERE id = ?", newEntityID, id)
 	if err != nil {
 		return fmt.Errorf("failed to update observation: %w", err)
 	}
@@ -574,3 +1076,16 @@ func (db *DB) UpdateObservationEntity(id int64, newEntityID int64) error {
 
 	return nil
 }
+
+// UpdateObservationEntityByText moves an observation to the entity named entityText,
+// creating the entity if it doesn't exist.
+func (db *DB) UpdateObservationEntityByText(id int64, entityText string) error {
+	return db.Transaction(func(tx *DB) error {
+		// In the transaction, so a missing observation doesn't leave a new entity behind
+		entityID, err := tx.getEntityID(entityText)
+		if err != nil {
+			return err
+		}
+		return tx.UpdateObservationEntity(id, entityID)
+	})
+}
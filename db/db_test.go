@@ -1,7 +1,12 @@
 package db
 
 import (
+	"errors"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestMigrations(t *testing.T) {
@@ -22,13 +27,13 @@ func TestMigrations(t *testing.T) {
 		t.Fatalf("schema_migrations table doesn't exist: %v", err)
 	}
 
-	// Verify current version is 1
+	// Verify current version is the latest migration
 	version, err := getCurrentVersion(db.conn)
 	if err != nil {
 		t.Fatalf("Failed to get current version: %v", err)
 	}
-	if version != 1 {
-		t.Errorf("Expected version 1, got %d", version)
+	if latest := migrations[len(migrations)-1].Version; version != latest {
+		t.Errorf("Expected version %d, got %d", latest, version)
 	}
 
 	// Verify tables exist
@@ -59,6 +64,37 @@ func TestMigrations(t *testing.T) {
 	}
 }
 
+func TestOpenNewerSchema(t *testing.T) {
+	dbPath := t.TempDir() + "/test_amem_newer.db"
+	key := "testkey123456789012"
+
+	SetAppVersion("v1.2.0")
+	defer SetAppVersion("")
+	db, err := Init(dbPath, key)
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	var recorded string
+	if err := db.conn.QueryRow("SELECT amem_version FROM schema_migrations WHERE version = ?", LatestSchemaVersion()).Scan(&recorded); err != nil || recorded != "v1.2.0" {
+		t.Errorf("Expected the migrations to record v1.2.0, got %q (err %v)", recorded, err)
+	}
+
+	// Pretend a newer amem migrated the database further
+	if _, err := db.conn.Exec("INSERT INTO schema_migrations (version, amem_version) VALUES (?, 'v9.0.0')", LatestSchemaVersion()+1); err != nil {
+		t.Fatalf("Failed to add a newer migration: %v", err)
+	}
+	_ = db.Close()
+
+	_, err = Open(dbPath, key)
+	if !errors.Is(err, ErrSchemaTooNew) || !strings.Contains(err.Error(), "upgrade amem to v9.0.0 or later") {
+		t.Errorf("Expected ErrSchemaTooNew naming v9.0.0, got %v", err)
+	}
+	_, err = OpenWithOptions(dbPath, key, Options{SkipMigrations: true})
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("Expected ErrSchemaTooNew without migrations too, got %v", err)
+	}
+}
+
 func TestMigrationsIdempotent(t *testing.T) {
 	dbPath := t.TempDir() + "/test_amem_idempotent.db"
 	key := "testkey123456789012"
@@ -78,23 +114,61 @@ func TestMigrationsIdempotent(t *testing.T) {
 	}
 	defer func() { _ = db2.Close() }()
 
-	// Verify version is still 1
+	// Verify version is still the latest migration
 	version, err := getCurrentVersion(db2.conn)
 	if err != nil {
 		t.Fatalf("Failed to get current version: %v", err)
 	}
-	if version != 1 {
-		t.Errorf("Expected version 1 after re-init, got %d", version)
+	if latest := migrations[len(migrations)-1].Version; version != latest {
+		t.Errorf("Expected version %d after re-init, got %d", latest, version)
 	}
 
-	// Verify migration was only applied once
+	// Verify each migration was only applied once
 	var migrationCount int
 	err = db2.conn.QueryRow("SELECT COUNT(*) FROM schema_migrations").Scan(&migrationCount)
 	if err != nil {
 		t.Fatalf("Failed to count migrations: %v", err)
 	}
-	if migrationCount != 1 {
-		t.Errorf("Expected 1 migration record, got %d", migrationCount)
+	if migrationCount != len(migrations) {
+		t.Errorf("Expected %d migration records, got %d", len(migrations), migrationCount)
+	}
+}
+
+func TestOpenConnections(t *testing.T) {
+	dbPath := t.TempDir() + "/test_connections.db"
+	key := "testkey123456789012"
+
+	db, err := Init(dbPath, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	if open := db.conn.Stats().MaxOpenConnections; open != 1 {
+		t.Errorf("Expected a single connection by default, got %d", open)
+	}
+	_ = db.Close()
+
+	db, err = OpenWithOptions(dbPath, key, DefaultOptions().Concurrent())
+	if err != nil {
+		t.Fatalf("Failed to open with concurrent options: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if open := db.conn.Stats().MaxOpenConnections; open < 2 {
+		t.Errorf("Expected a pool of connections, got %d", open)
+	}
+
+	// Every pooled connection enforces foreign keys, not just the first
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Go(func() {
+			_, errs[i] = db.conn.Exec("INSERT INTO observations (entity_id, text) VALUES (999, 'orphan')")
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err == nil {
+			t.Error("Expected every connection to refuse an observation of a missing entity")
+		}
 	}
 }
 
@@ -147,6 +221,55 @@ func TestAddEntity(t *testing.T) {
 	}
 }
 
+func TestAddEntities(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_add_entities.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	alice, _ := db.AddEntity("Alice")
+	ids, err := db.AddEntities([]string{"Bob", "Alice", "Carol", "Bob"})
+	if err != nil {
+		t.Fatalf("AddEntities failed: %v", err)
+	}
+	if len(ids) != 4 || ids[1] != alice || ids[0] != ids[3] || ids[0] == ids[2] {
+		t.Errorf("Expected existing and repeated entities to keep their IDs, got %v (Alice is %d)", ids, alice)
+	}
+
+	if err := db.SetRules(&Rules{MaxEntityLength: 5}); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+	if _, err := db.AddEntities([]string{"Dave", "Christopher"}); err == nil || !strings.Contains(err.Error(), "Christopher") {
+		t.Errorf("Expected the too-long entity to fail, got %v", err)
+	}
+	if exists, _ := db.EntityExists("Dave"); exists {
+		t.Error("Expected no entity added when one fails")
+	}
+}
+
+func TestAddEntityConcurrent(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_add_entity_concurrent.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ids := make([]int64, 8)
+	errs := make([]error, len(ids))
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Go(func() { ids[i], errs[i] = db.AddEntity("Alice") })
+	}
+	wg.Wait()
+
+	for i := range ids {
+		if errs[i] != nil || ids[i] != ids[0] {
+			t.Errorf("Expected every adder to get the same ID, got %d (err %v), want %d", ids[i], errs[i], ids[0])
+		}
+	}
+}
+
 func TestAddObservation(t *testing.T) {
 	dbPath := t.TempDir() + "/test_add_observation.db"
 	key := "testkey123456789012"
@@ -309,6 +432,117 @@ func TestAddRelationship(t *testing.T) {
 	}
 }
 
+func TestAddFailureLeavesNoEntity(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_add_failure.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Make every insert fail after its entities are added
+	for _, table := range []string{"observations", "relationships"} {
+		_, err := db.conn.Exec("CREATE TRIGGER fail_" + table + " BEFORE INSERT ON " + table + " BEGIN SELECT RAISE(ABORT, 'failed'); END")
+		if err != nil {
+			t.Fatalf("Failed to create trigger: %v", err)
+		}
+	}
+
+	if _, err := db.AddObservation("Alice", "likes tea"); err == nil {
+		t.Error("Expected AddObservation to fail")
+	}
+	if _, err := db.AddRelationship("Bob", "Carol", "knows"); err == nil {
+		t.Error("Expected AddRelationship to fail")
+	}
+
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM entities").Scan(&count); err != nil {
+		t.Fatalf("Failed to count entities: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected no entities after failed adds, got %d", count)
+	}
+}
+
+func TestAddWithTimestamp(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_add_timestamp.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	when := time.Date(2023, 6, 1, 9, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	obsID, err := db.AddObservationWithOptions("Alice", "Likes tea", ObservationOptions{Timestamp: when})
+	if err != nil {
+		t.Fatalf("AddObservationWithOptions failed: %v", err)
+	}
+	relID, err := db.AddRelationshipWithOptions("Alice", "Bob", "knows", RelationshipOptions{Timestamp: when})
+	if err != nil {
+		t.Fatalf("AddRelationshipWithOptions failed: %v", err)
+	}
+	nowID, _ := db.AddObservation("Alice", "Lives in Oslo")
+
+	o, err := db.GetObservation(obsID)
+	if err != nil || o.Timestamp != "2023-06-01T07:30:00Z" {
+		t.Errorf("Expected the given time in UTC, got %+v (err %v)", o, err)
+	}
+	r, err := db.GetRelationship(relID)
+	if err != nil || r.Timestamp != "2023-06-01T07:30:00Z" {
+		t.Errorf("Expected the given time in UTC, got %+v (err %v)", r, err)
+	}
+	o, _ = db.GetObservation(nowID)
+	if added, err := time.Parse(time.RFC3339, o.Timestamp); err != nil || time.Since(added) > time.Minute {
+		t.Errorf("Expected the current time without a timestamp, got %q", o.Timestamp)
+	}
+}
+
+func TestSearchOrderTies(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_search_order.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	when := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	var obsIDs, relIDs []int64
+	for _, name := range []string{"Bob", "Carol", "Dave"} {
+		id, _ := db.AddObservationWithOptions("Alice", "Knows "+name, ObservationOptions{Timestamp: when})
+		obsIDs = append(obsIDs, id)
+		id, _ = db.AddRelationshipWithOptions("Alice", name, "knows", RelationshipOptions{Timestamp: when})
+		relIDs = append(relIDs, id)
+	}
+	if _, err := db.conn.Exec("UPDATE observations SET updated_at = '2024-01-15 10:30:00'"); err != nil {
+		t.Fatalf("Failed to set updated_at: %v", err)
+	}
+	slices.Reverse(obsIDs)
+	slices.Reverse(relIDs)
+
+	for range 3 {
+		observations, err := db.SearchObservations("Alice", nil, true)
+		if err != nil {
+			t.Fatalf("SearchObservations failed: %v", err)
+		}
+		var ids []int64
+		for _, o := range observations {
+			ids = append(ids, o.ID)
+		}
+		if !slices.Equal(ids, obsIDs) {
+			t.Errorf("Expected observations with equal timestamps by ID, newest first: got %v, want %v", ids, obsIDs)
+		}
+
+		relationships, err := db.SearchRelationships("Alice", "", "", nil, true)
+		if err != nil {
+			t.Fatalf("SearchRelationships failed: %v", err)
+		}
+		ids = nil
+		for _, r := range relationships {
+			ids = append(ids, r.ID)
+		}
+		if !slices.Equal(ids, relIDs) {
+			t.Errorf("Expected relationships with equal timestamps by ID, newest first: got %v, want %v", ids, relIDs)
+		}
+	}
+}
+
 func TestAddRelationshipSelfReference(t *testing.T) {
 	dbPath := t.TempDir() + "/test_self_reference.db"
 	key := "testkey123456789012"
@@ -737,6 +971,39 @@ func TestUpdateObservationEntity(t *testing.T) {
 	}
 }
 
+func TestUpdateObservationEntityByText(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_update_observation_entity_by_text.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	obsID, err := db.AddObservation("Alice", "Likes tea")
+	if err != nil {
+		t.Fatalf("Failed to add observation: %v", err)
+	}
+
+	// Moving to an entity that doesn't exist yet creates it
+	if err := db.UpdateObservationEntityByText(obsID, "Bob"); err != nil {
+		t.Fatalf("UpdateObservationEntityByText failed: %v", err)
+	}
+	o, err := db.GetObservation(obsID)
+	if err != nil {
+		t.Fatalf("GetObservation failed: %v", err)
+	}
+	if o.EntityText != "Bob" {
+		t.Errorf("Expected observation about Bob, got %q", o.EntityText)
+	}
+
+	// A missing observation doesn't leave the new entity behind
+	if err := db.UpdateObservationEntityByText(99999, "Carol"); err == nil {
+		t.Error("Expected error when moving a non-existent observation")
+	}
+	if found, _ := db.EntityExists("Carol"); found {
+		t.Error("Expected Carol not to be created for a failed move")
+	}
+}
+
 func TestSearchEntities(t *testing.T) {
 	dbPath := t.TempDir() + "/test_search_entities.db"
 	key := "testkey123456789012"
@@ -973,6 +1240,18 @@ func TestSearchRelationships(t *testing.T) {
 		t.Errorf("Expected 1 relationship to Alice, got %d", len(relationships))
 	}
 
+	// Search by either end
+	relationships, err = collect(db.SearchRelationshipsWithIter("Alice", "", nil, false))
+	if err != nil {
+		t.Fatalf("Failed to search relationships: %v", err)
+	}
+	if len(relationships) != 3 {
+		t.Errorf("Expected 3 relationships with Alice, got %d", len(relationships))
+	}
+	if count, err := db.CountSearchRelationshipsWith("Alice", "knows", nil, false); err != nil || count != 1 {
+		t.Errorf("Expected 1 'knows' relationship with Alice, got %d (%v)", count, err)
+	}
+
 	// Search by relationship type
 	relationships, err = db.SearchRelationships("", "", "knows", nil, false)
 	if err != nil {
@@ -1525,6 +1804,14 @@ func TestEntityFormat(t *testing.T) {
 	if result != expected {
 		t.Errorf("Expected '%s', got '%s'", expected, result)
 	}
+
+	// Test with creation time
+	created := Entity{ID: 7, Text: "Bob", CreatedAt: "2025-01-31T14:00:00Z"}
+	result = created.Format(true)
+	expected = "[7] Bob (2025-01-31T14:00:00Z)"
+	if result != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, result)
+	}
 }
 
 func TestObservationFormat(t *testing.T) {
@@ -1710,3 +1997,186 @@ func TestRekeyEmptyKey(t *testing.T) {
 		t.Fatalf("Database should still work after failed rekey: %v", err)
 	}
 }
+
+func TestTimestamps(t *testing.T) {
+	path := t.TempDir() + "/test_timestamps.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	entities, err := db.SearchEntities([]string{"Alice"}, false)
+	if err != nil || len(entities) != 1 {
+		t.Fatalf("SearchEntities = %v, %v", entities, err)
+	}
+	if entities[0].CreatedAt == "" || entities[0].UpdatedAt != entities[0].CreatedAt {
+		t.Errorf("New entity timestamps = %q, %q; want equal and set", entities[0].CreatedAt, entities[0].UpdatedAt)
+	}
+	observations, _ := db.SearchObservations("Alice", nil, false)
+	if len(observations) != 1 || observations[0].UpdatedAt != observations[0].Timestamp {
+		t.Errorf("New observation updated_at = %+v, want its timestamp", observations)
+	}
+
+	// Age everything, then change some of it
+	for _, table := range []string{"entities", "observations", "relationships"} {
+		if _, err := db.conn.Exec("UPDATE " + table + " SET updated_at = '2020-01-01 00:00:00'"); err != nil {
+			t.Fatalf("Failed to age %s: %v", table, err)
+		}
+	}
+	if _, err := db.conn.Exec("UPDATE observations SET timestamp = '2020-01-01 00:00:00'"); err != nil {
+		t.Fatalf("Failed to age observations: %v", err)
+	}
+	if err := db.UpdateEntity("Bob", "Robert"); err != nil {
+		t.Fatalf("UpdateEntity failed: %v", err)
+	}
+	if err := db.UpdateObservation(observations[0].ID, "Likes green tea"); err != nil {
+		t.Fatalf("UpdateObservation failed: %v", err)
+	}
+
+	entities, changedObs, relationships, err := db.ChangedSince(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ChangedSince failed: %v", err)
+	}
+	if len(entities) != 1 || entities[0].Text != "Robert" {
+		t.Errorf("ChangedSince entities = %+v, want only Robert", entities)
+	}
+	if len(changedObs) != 1 || changedObs[0].Text != "Likes green tea" {
+		t.Errorf("ChangedSince observations = %+v, want the edited observation", changedObs)
+	}
+	if len(relationships) != 0 {
+		t.Errorf("ChangedSince relationships = %+v, want none", relationships)
+	}
+	if !strings.HasPrefix(changedObs[0].Timestamp, "2020-01-01") {
+		t.Error("Editing an observation should change updated_at but not timestamp")
+	}
+}
+
+func TestSearchEntitiesFiltered(t *testing.T) {
+	path := t.TempDir() + "/test_entity_filter.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, text := range []string{"Alice", "Bob", "Carol"} {
+		if _, err := db.AddObservation(text, "Exists"); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	for text, created := range map[string]string{"Alice": "2025-01-01 09:00:00", "Bob": "2025-01-02 09:00:00"} {
+		if _, err := db.conn.Exec("UPDATE entities SET created_at = ? WHERE text = ?", created, text); err != nil {
+			t.Fatalf("Failed to set created_at: %v", err)
+		}
+	}
+
+	names := func(filter EntityFilter, keywords ...string) []string {
+		t.Helper()
+		var names []string
+		for e, err := range db.SearchEntitiesFilteredIter(keywords, true, filter) {
+			if err != nil {
+				t.Fatalf("SearchEntitiesFilteredIter failed: %v", err)
+			}
+			names = append(names, e.Text)
+		}
+		count, err := db.CountSearchEntitiesFiltered(keywords, true, filter)
+		if err != nil || count != len(names) {
+			t.Errorf("CountSearchEntitiesFiltered = %d, %v; want %d", count, err, len(names))
+		}
+		return names
+	}
+
+	day := func(d int) time.Time { return time.Date(2025, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		filter   EntityFilter
+		keywords []string
+		want     []string
+	}{
+		{EntityFilter{}, nil, []string{"Alice", "Bob", "Carol"}},
+		{EntityFilter{CreatedBefore: day(2)}, nil, []string{"Alice"}},
+		{EntityFilter{CreatedSince: day(2)}, nil, []string{"Bob", "Carol"}},
+		{EntityFilter{CreatedSince: day(1), CreatedBefore: day(3)}, nil, []string{"Alice", "Bob"}},
+		{EntityFilter{CreatedSince: day(2)}, []string{"Alice", "Bob"}, []string{"Bob"}},
+	}
+	for _, tt := range tests {
+		if got := names(tt.filter, tt.keywords...); !slices.Equal(got, tt.want) {
+			t.Errorf("search %v with %+v = %v, want %v", tt.keywords, tt.filter, got, tt.want)
+		}
+	}
+}
+
+func TestFindObservation(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_find_observation.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	id, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Bob", "Likes coffee")
+
+	if found, err := db.FindObservation("Alice", "Likes tea"); err != nil || found != id {
+		t.Errorf("FindObservation = %d, %v; want %d", found, err, id)
+	}
+	if _, err := db.FindObservation("Alice", "Likes coffee"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for another entity's observation, got %v", err)
+	}
+	if _, err := db.FindObservation("Alice", "Likes"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a partial match, got %v", err)
+	}
+}
+
+func TestSetRequired(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_required.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	_, _ = db.AddObservation("Initech", "Billing moved to Fly")
+	_, _ = db.AddObservation("Initech", "Billing used to run on Heroku")
+	_, _ = db.AddObservation("Initech", "Deploys run on Fly")
+	_, _ = db.AddEntity("Billing on Fly")
+	_, _ = db.AddEntity("Fly")
+	_, _ = db.AddRelationship("Billing", "Fly", "runs on")
+	_, _ = db.AddRelationship("Search", "Fly", "runs on")
+
+	db.SetRequired([]string{"billing"})
+	keywords := []string{"fly", "heroku"}
+
+	observations, err := db.SearchObservations("", keywords, true)
+	if err != nil {
+		t.Fatalf("SearchObservations failed: %v", err)
+	}
+	if len(observations) != 2 {
+		t.Errorf("Expected 2 billing observations mentioning Fly or Heroku, got %+v", observations)
+	}
+	if count, _ := db.CountSearchObservations("", keywords, true); count != len(observations) {
+		t.Errorf("CountSearchObservations = %d, want %d", count, len(observations))
+	}
+
+	entities, _ := db.SearchEntities(keywords, true)
+	if len(entities) != 1 || entities[0].Text != "Billing on Fly" {
+		t.Errorf("Expected only Billing on Fly, got %+v", entities)
+	}
+
+	relationships, _ := db.SearchRelationships("", "", "", keywords, true)
+	if len(relationships) != 1 || relationships[0].FromText != "Billing" {
+		t.Errorf("Expected only Billing's relationship, got %+v", relationships)
+	}
+
+	db.SetRequired(nil)
+	if observations, _ := db.SearchObservations("", keywords, true); len(observations) != 3 {
+		t.Errorf("Expected 3 observations without a requirement, got %d", len(observations))
+	}
+}
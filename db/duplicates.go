@@ -0,0 +1,343 @@
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DuplicateCandidate is a pair of entities that look like the same thing.
+type DuplicateCandidate struct {
+	// Keep is the entity to merge into: the one with more observations and relationships
+	Keep Entity `json:"keep"`
+	// Duplicate is the entity to merge away
+	Duplicate Entity `json:"duplicate"`
+	// Reason says why the two look the same
+	Reason string `json:"reason"`
+}
+
+// normalizeEntityText lowercases text and reduces punctuation and runs of spaces to single spaces,
+// so "Bob  Smith", "bob smith", and "Bob-Smith" all compare equal.
+func normalizeEntityText(text string) string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// FindDuplicateEntities returns pairs of entities whose text differs only in case and
+// punctuation, abbreviates a word to an initial ("Bob S." and "Bob Smith"), or differs by a typo.
+// Pairs are ordered by the entity to keep, then the duplicate.
+func (db *DB) FindDuplicateEntities() ([]DuplicateCandidate, error) {
+	entities, err := db.SearchEntities(nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	links := map[int64]int{}
+	for _, query := range []string{
+		"SELECT entity_id, COUNT(*) FROM observations GROUP BY entity_id",
+		"SELECT from_id, COUNT(*) FROM relationships GROUP BY from_id",
+		"SELECT to_id, COUNT(*) FROM relationships GROUP BY to_id",
+	} {
+		rows, err := db.query(query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count entity links: %w", err)
+		}
+		for rows.Next() {
+			var id int64
+			var count int
+			if err := rows.Scan(&id, &count); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("failed to scan entity links: %w", err)
+			}
+			links[id] += count
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read entity links: %w", err)
+		}
+	}
+
+	normalized := make([]string, len(entities))
+	for i, e := range entities {
+		normalized[i] = normalizeEntityText(e.Text)
+	}
+
+	var candidates []DuplicateCandidate
+	for i := range entities {
+		for j := i + 1; j < len(entities); j++ {
+			reason := duplicateReason(normalized[i], normalized[j])
+			if reason == "" {
+				continue
+			}
+
+			keep, dup := entities[i], entities[j]
+			if links[dup.ID] > links[keep.ID] || (links[dup.ID] == links[keep.ID] && dup.ID < keep.ID) {
+				keep, dup = dup, keep
+			}
+			candidates = append(candidates, DuplicateCandidate{Keep: keep, Duplicate: dup, Reason: reason})
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b DuplicateCandidate) int {
+		return cmp.Or(cmp.Compare(a.Keep.Text, b.Keep.Text), cmp.Compare(a.Duplicate.Text, b.Duplicate.Text))
+	})
+	return candidates, nil
+}
+
+// duplicateReason says why two normalized entity texts look like the same entity, or returns "" if they don't.
+func duplicateReason(a, b string) string {
+	if a == "" || b == "" {
+		return ""
+	}
+	if a == b {
+		return "same text ignoring case and punctuation"
+	}
+	if abbreviates(a, b) || abbreviates(b, a) {
+		return "one abbreviates a word of the other"
+	}
+
+	if similarSpelling(a, b) {
+		return "similar spelling"
+	}
+	return ""
+}
+
+// minTypoWordLength is the shortest word a typo is looked for in. Shorter words are too
+// often different things a letter apart, like "Bob" and "Rob" or "Project X" and "Project Y".
+const minTypoWordLength = 4
+
+// similarSpelling reports whether a and b differ by a typo in each of some of their words,
+// or, if they have different numbers of words, by a single character, like "project x" and "projectx".
+func similarSpelling(a, b string) bool {
+	wa, wb := strings.Fields(a), strings.Fields(b)
+	if len(wa) != len(wb) {
+		return min(len(a), len(b)) >= minTypoWordLength && editDistance(a, b, 1) <= 1
+	}
+
+	typos := 0
+	for i := range wa {
+		if wa[i] == wb[i] {
+			continue
+		}
+		if min(len([]rune(wa[i])), len([]rune(wb[i]))) < minTypoWordLength || editDistance(wa[i], wb[i], 1) > 1 {
+			return false
+		}
+		typos++
+	}
+	return typos > 0
+}
+
+// abbreviates reports whether short has the same words as long, except that some
+// are initials of long's words, as in "bob s" and "bob smith".
+func abbreviates(short, long string) bool {
+	s, l := strings.Fields(short), strings.Fields(long)
+	if len(s) != len(l) || len(s) < 2 {
+		return false
+	}
+	initials := 0
+	for i := range s {
+		switch {
+		case s[i] == l[i]:
+		case len([]rune(s[i])) == 1 && strings.HasPrefix(l[i], s[i]):
+			initials++
+		default:
+			return false
+		}
+	}
+	// At least one full word must match, so "b s" doesn't match everyone named B. S-something
+	return initials > 0 && initials < len(s)
+}
+
+// editDistance returns the Levenshtein distance between a and b, or limit+1 once it's sure to exceed limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if d := len(ra) - len(rb); d > limit || d < -limit {
+		return limit + 1
+	}
+
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// MergeEntities moves the observations and relationships of the entity with ID mergeID
+// onto the entity with ID keepID, then deletes it. Relationships between the two, which
+// would become an entity related to itself, are dropped, as are exact duplicate relationships.
+// Its note is kept only if keepID has none. Should be run under Locked.
+func (db *DB) MergeEntities(keepID, mergeID int64) error {
+	if keepID == mergeID {
+		return fmt.Errorf("cannot merge an entity into itself")
+	}
+	for _, id := range []int64{keepID, mergeID} {
+		if _, err := db.GetEntity(id); err != nil {
+			return err
+		}
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		"DELETE FROM relationships WHERE (from_id = ?1 AND to_id = ?2) OR (from_id = ?2 AND to_id = ?1)",
+		"UPDATE observations SET entity_id = ?1 WHERE entity_id = ?2",
+		"UPDATE relationships SET from_id = ?1 WHERE from_id = ?2",
+		"UPDATE relationships SET to_id = ?1 WHERE to_id = ?2",
+		"INSERT OR IGNORE INTO entity_notes (entity_id, note, updated_at) SELECT ?1, note, updated_at FROM entity_notes WHERE entity_id = ?2",
+		`DELETE FROM relationships WHERE (from_id = ?1 OR to_id = ?1) AND id NOT IN (
+			SELECT MIN(id) FROM relationships WHERE from_id = ?1 OR to_id = ?1 GROUP BY from_id, to_id, type
+		)`,
+		"DELETE FROM entities WHERE id = ?2",
+	} {
+		if _, err := tx.Exec(stmt, keepID, mergeID); err != nil {
+			return fmt.Errorf("failed to merge entities: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return nil
+}
+
+// MergeEntityInto merges the entity named text into the one named intoText, as
+// MergeEntities does. Should be run under Locked.
+func (db *DB) MergeEntityInto(text, intoText string) error {
+	mergeID, err := db.entityID(text)
+	if err != nil {
+		return err
+	}
+	keepID, err := db.entityID(intoText)
+	if err != nil {
+		return err
+	}
+	return db.MergeEntities(keepID, mergeID)
+}
+
+// MergePreview is what MergeEntities would change, so a merge can be checked before it's made.
+type MergePreview struct {
+	Keep  Entity `json:"keep"`
+	Merge Entity `json:"merge"`
+	// Observations are the merged entity's observations, which move to Keep
+	Observations []Observation `json:"observations"`
+	// Repointed are the relationships that move to Keep
+	Repointed []Relationship `json:"repointed"`
+	// Dropped are the relationships between the two, which would relate Keep to itself
+	Dropped []Relationship `json:"dropped"`
+	// Collapsed are the relationships deleted as duplicates of another once re-pointed
+	Collapsed []CollapsedRelationship `json:"collapsed"`
+	// NoteMoved is whether the merged entity's note replaces Keep's missing one
+	NoteMoved bool `json:"note_moved"`
+}
+
+// CollapsedRelationship is a relationship a merge deletes as a duplicate of the one with ID Into.
+type CollapsedRelationship struct {
+	Relationship
+	Into int64 `json:"into"`
+}
+
+// PreviewMerge returns what MergeEntities(keepID, mergeID) would change, without changing anything.
+func (db *DB) PreviewMerge(keepID, mergeID int64) (*MergePreview, error) {
+	if keepID == mergeID {
+		return nil, fmt.Errorf("cannot merge an entity into itself")
+	}
+	keep, err := db.GetEntity(keepID)
+	if err != nil {
+		return nil, err
+	}
+	merge, err := db.GetEntity(mergeID)
+	if err != nil {
+		return nil, err
+	}
+	preview := &MergePreview{Keep: *keep, Merge: *merge}
+
+	preview.Observations, err = collect(scanRows(db, observationsSelect+" WHERE o.entity_id = ? ORDER BY o.id", []interface{}{mergeID}, nil, "observations", scanObservation))
+	if err != nil {
+		return nil, err
+	}
+
+	query, args := relationshipsQuery("", "", "", "", nil, nil, false)
+	relationships, err := collect(scanRows(db, query+" WHERE r.from_id IN (?1, ?2) OR r.to_id IN (?1, ?2) ORDER BY r.id", append(args, keepID, mergeID), nil, "relationships", scanRelationship))
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-point as MergeEntities does, then keep the lowest ID of each from, to, and type
+	repoint := func(id int64) int64 {
+		if id == mergeID {
+			return keepID
+		}
+		return id
+	}
+	type key struct {
+		from, to int64
+		relType  string
+	}
+	kept := map[key]int64{}
+	for _, r := range relationships {
+		from, to := repoint(r.FromID), repoint(r.ToID)
+		if from == keepID && to == keepID {
+			preview.Dropped = append(preview.Dropped, r)
+			continue
+		}
+		k := key{from, to, r.Type}
+		if into, ok := kept[k]; ok {
+			preview.Collapsed = append(preview.Collapsed, CollapsedRelationship{Relationship: r, Into: into})
+			continue
+		}
+		kept[k] = r.ID
+		if r.FromID == mergeID || r.ToID == mergeID {
+			preview.Repointed = append(preview.Repointed, r)
+		}
+	}
+
+	keepHasNote, err := db.exists("note", "SELECT 1 FROM entity_notes WHERE entity_id = ?", keepID)
+	if err != nil {
+		return nil, err
+	}
+	mergeHasNote, err := db.exists("note", "SELECT 1 FROM entity_notes WHERE entity_id = ?", mergeID)
+	if err != nil {
+		return nil, err
+	}
+	preview.NoteMoved = mergeHasNote && !keepHasNote
+
+	return preview, nil
+}
+
+// PreviewMergeInto is PreviewMerge for merging the entity named text into the one named intoText.
+func (db *DB) PreviewMergeInto(text, intoText string) (*MergePreview, error) {
+	mergeID, err := db.entityID(text)
+	if err != nil {
+		return nil, err
+	}
+	keepID, err := db.entityID(intoText)
+	if err != nil {
+		return nil, err
+	}
+	return db.PreviewMerge(keepID, mergeID)
+}
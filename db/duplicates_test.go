@@ -0,0 +1,181 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestDuplicateReason(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"Bob Smith", "bob smith", true},
+		{"Bob-Smith", "Bob  Smith", true},
+		{"Bob S.", "Bob Smith", true},
+		{"B. Smith", "Bob Smith", true},
+		{"Postgres", "Postgress", true},
+		{"Kubernetes", "Kubernets", true},
+		{"B. S.", "Bob Smith", false},
+		{"Bob", "Rob", false},
+		{"Alice", "Bob", false},
+		{"Bob Smith", "Bob Jones", false},
+		{"Project X", "Project Y", false},
+	}
+	for _, tt := range tests {
+		got := duplicateReason(normalizeEntityText(tt.a), normalizeEntityText(tt.b)) != ""
+		if got != tt.want {
+			t.Errorf("duplicateReason(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFindAndMergeDuplicateEntities(t *testing.T) {
+	path := t.TempDir() + "/test_duplicates.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddObservation("Bob Smith", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddObservation("bob smith", "Likes coffee"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddObservation("Bob Smith", "Lives in Oslo"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	for _, rel := range [][2]string{{"Alice", "Bob Smith"}, {"Alice", "bob smith"}, {"bob smith", "Bob Smith"}} {
+		if _, err := db.AddRelationship(rel[0], rel[1], "knows"); err != nil {
+			t.Fatalf("AddRelationship failed: %v", err)
+		}
+	}
+
+	candidates, err := db.FindDuplicateEntities()
+	if err != nil {
+		t.Fatalf("FindDuplicateEntities failed: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].Keep.Text != "Bob Smith" || candidates[0].Duplicate.Text != "bob smith" {
+		t.Fatalf("FindDuplicateEntities() = %+v, want to keep Bob Smith over bob smith", candidates)
+	}
+
+	if err := db.Locked(func() error { return db.MergeEntities(candidates[0].Keep.ID, candidates[0].Duplicate.ID) }); err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+
+	if entities, _ := db.SearchEntities(nil, false); len(entities) != 2 {
+		t.Errorf("After merge got entities %+v, want Alice and Bob Smith", entities)
+	}
+	if observations, _ := db.SearchObservations("Bob Smith", nil, false); len(observations) != 3 {
+		t.Errorf("After merge Bob Smith has %d observations, want 3", len(observations))
+	}
+	// The duplicate knows relationship is collapsed, and the one between the two is dropped
+	if relationships, _ := db.SearchRelationships("", "", "", nil, false); len(relationships) != 1 {
+		t.Errorf("After merge got relationships %+v, want only Alice knows Bob Smith", relationships)
+	}
+	if candidates, _ := db.FindDuplicateEntities(); len(candidates) != 0 {
+		t.Errorf("After merge FindDuplicateEntities() = %+v, want none", candidates)
+	}
+
+	// A merge is one change to undo
+	if err := db.Locked(func() error { _, err := db.Undo(); return err }); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if entities, _ := db.SearchEntities(nil, false); len(entities) != 3 {
+		t.Errorf("After undoing the merge got entities %+v, want all three back", entities)
+	}
+
+	if err := db.MergeEntities(candidates[0].Keep.ID, candidates[0].Keep.ID); err == nil {
+		t.Error("MergeEntities of an entity into itself should fail")
+	}
+}
+
+func TestMergeEntityInto(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_merge_into.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	_, _ = db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Alice Smith", "Lives in Oslo")
+	if err := db.SetNote("Alice", "# Alice"); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+
+	if err := db.MergeEntityInto("Alice", "Nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound merging into a missing entity, got %v", err)
+	}
+	if err := db.Locked(func() error { return db.MergeEntityInto("Alice", "Alice Smith") }); err != nil {
+		t.Fatalf("MergeEntityInto failed: %v", err)
+	}
+
+	if observations, _ := db.SearchObservations("Alice Smith", nil, false); len(observations) != 2 {
+		t.Errorf("After merge Alice Smith has %d observations, want 2", len(observations))
+	}
+	if exists, _ := db.EntityExists("Alice"); exists {
+		t.Error("Expected Alice merged away")
+	}
+	// The note moves over, since Alice Smith had none
+	if note, err := db.Note("Alice Smith"); err != nil || note != "# Alice" {
+		t.Errorf("Note = %q, %v; want Alice's note", note, err)
+	}
+}
+
+func TestPreviewMerge(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_preview_merge.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	obsID, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Alice Smith", "Lives in Oslo")
+	knowsID, _ := db.AddRelationship("Alice", "Bob", "knows")
+	duplicateID, _ := db.AddRelationship("Alice Smith", "Bob", "knows")
+	managesID, _ := db.AddRelationship("Alice", "Carol", "manages")
+	selfID, _ := db.AddRelationship("Alice Smith", "Alice", "is")
+	_ = db.SetNote("Alice", "Goes by Ali")
+
+	preview, err := db.PreviewMergeInto("Alice", "Alice Smith")
+	if err != nil {
+		t.Fatalf("PreviewMergeInto failed: %v", err)
+	}
+	if len(preview.Observations) != 1 || preview.Observations[0].ID != obsID {
+		t.Errorf("Expected observation %d re-pointed, got %+v", obsID, preview.Observations)
+	}
+	if len(preview.Repointed) != 2 || preview.Repointed[0].ID != knowsID || preview.Repointed[1].ID != managesID {
+		t.Errorf("Expected relationships %d and %d re-pointed, got %+v", knowsID, managesID, preview.Repointed)
+	}
+	if len(preview.Dropped) != 1 || preview.Dropped[0].ID != selfID {
+		t.Errorf("Expected relationship %d dropped, got %+v", selfID, preview.Dropped)
+	}
+	if len(preview.Collapsed) != 1 || preview.Collapsed[0].ID != duplicateID || preview.Collapsed[0].Into != knowsID {
+		t.Errorf("Expected relationship %d collapsed into %d, got %+v", duplicateID, knowsID, preview.Collapsed)
+	}
+	if !preview.NoteMoved {
+		t.Error("Expected Alice's note to move")
+	}
+
+	// The preview matches what the merge does
+	if err := db.Locked(func() error { return db.MergeEntityInto("Alice", "Alice Smith") }); err != nil {
+		t.Fatalf("MergeEntityInto failed: %v", err)
+	}
+	rels, _ := db.SearchRelationships("", "", "", nil, false)
+	var ids []int64
+	for _, r := range rels {
+		ids = append(ids, r.ID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []int64{knowsID, managesID}) {
+		t.Errorf("After merge relationships are %v, want %v", ids, []int64{knowsID, managesID})
+	}
+
+	if _, err := db.PreviewMergeInto("Alice Smith", "Alice Smith"); err == nil {
+		t.Error("Expected error previewing a merge of an entity into itself")
+	}
+}
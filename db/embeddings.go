@@ -0,0 +1,280 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+)
+
+// PendingEmbedding is an observation that needs embedding.
+type PendingEmbedding struct {
+	ID   int64
+	Text string
+}
+
+// Chunk is the vector of part of an observation's text, text[Start:End] in bytes.
+// A short observation has one chunk covering all of it.
+type Chunk struct {
+	ObservationID int64
+	Start         int
+	End           int
+	Vector        []float32
+}
+
+// SimilarObservation is an observation found by vector search, with the chunk of it that
+// matched best and how closely, as cosine similarity from -1 to 1.
+type SimilarObservation struct {
+	Observation
+	Score float32 `json:"score"`
+	Start int     `json:"chunk_start"`
+	End   int     `json:"chunk_end"`
+}
+
+// SimilarEntity is an entity about observations found by vector search, scored by its best one.
+type SimilarEntity struct {
+	Entity string  `json:"entity"`
+	Score  float32 `json:"score"`
+}
+
+// Related is the neighbourhood of an observation, found by RelatedTo.
+type Related struct {
+	Observations []SimilarObservation `json:"observations"`
+	Entities     []SimilarEntity      `json:"entities"`
+}
+
+// pendingEmbeddingsClause matches observations (aliased o) with no vectors from the model
+// given as its only argument, or only vectors older than their text
+const pendingEmbeddingsClause = `NOT EXISTS (SELECT 1 FROM embeddings e
+	WHERE e.observation_id = o.id AND e.model = ? AND e.embedded_at >= o.updated_at)`
+
+// EmbeddingModels returns the models the stored vectors were made with, in order.
+func (db *DB) EmbeddingModels() ([]string, error) {
+	return collect(scanRows(db, "SELECT DISTINCT model FROM embeddings ORDER BY model", nil, nil, "embedding models", func(rows *sql.Rows, model *string) error {
+		return rows.Scan(model)
+	}))
+}
+
+// CountPendingEmbeddings returns how many observations need embedding with model.
+func (db *DB) CountPendingEmbeddings(model string) (int, error) {
+	var count int
+	err := db.queryRow("SELECT COUNT(*) FROM observations o WHERE "+pendingEmbeddingsClause, model).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count observations to embed: %w", err)
+	}
+	return count, nil
+}
+
+// PendingEmbeddings returns up to limit observations after afterID, in ID order, that need
+// embedding with model, because they have no vectors, ones from another model, or stale ones.
+func (db *DB) PendingEmbeddings(model string, afterID int64, limit int) ([]PendingEmbedding, error) {
+	query := "SELECT o.id, o.text FROM observations o WHERE o.id > ? AND " + pendingEmbeddingsClause + " ORDER BY o.id LIMIT ?"
+	return collect(scanRows(db, query, []interface{}{afterID, model, limit}, nil, "observations to embed", func(rows *sql.Rows, p *PendingEmbedding) error {
+		return rows.Scan(&p.ID, &p.Text)
+	}))
+}
+
+// SaveEmbeddings stores chunks, made by model, replacing every vector their observations had.
+// Each observation's chunks must be given together, in text order.
+func (db *DB) SaveEmbeddings(model string, chunks []Chunk) error {
+	return db.Transaction(func(tx *DB) error {
+		next := map[int64]int{}
+		for _, c := range chunks {
+			n, seen := next[c.ObservationID]
+			if !seen {
+				if _, err := tx.exec("DELETE FROM embeddings WHERE observation_id = ?", c.ObservationID); err != nil {
+					return fmt.Errorf("failed to replace embeddings: %w", err)
+				}
+			}
+			_, err := tx.exec(`INSERT INTO embeddings (observation_id, chunk, start_offset, end_offset, model, vector)
+				VALUES (?, ?, ?, ?, ?, ?)`, c.ObservationID, n, c.Start, c.End, model, encodeVector(c.Vector))
+			if err != nil {
+				return fmt.Errorf("failed to save embedding: %w", err)
+			}
+			next[c.ObservationID] = n + 1
+		}
+		return nil
+	})
+}
+
+// DropEmbeddings deletes every stored vector, returning how many there were.
+func (db *DB) DropEmbeddings() (int, error) {
+	result, err := db.exec("DELETE FROM embeddings")
+	if err != nil {
+		return 0, fmt.Errorf("failed to drop embeddings: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n), nil
+}
+
+// Embedding returns the model and chunks stored for the observation with the given ID.
+// Returns an error wrapping ErrNotFound if it hasn't been embedded.
+func (db *DB) Embedding(id int64) (string, []Chunk, error) {
+	var model string
+	chunks, err := collect(scanRows(db, "SELECT model, start_offset, end_offset, vector FROM embeddings WHERE observation_id = ? ORDER BY chunk", []interface{}{id}, nil, "embeddings", func(rows *sql.Rows, c *Chunk) error {
+		var data []byte
+		c.ObservationID = id
+		if err := rows.Scan(&model, &c.Start, &c.End, &data); err != nil {
+			return err
+		}
+		c.Vector = decodeVector(data)
+		return nil
+	}))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(chunks) == 0 {
+		return "", nil, fmt.Errorf("embedding of observation %d %w", id, ErrNotFound)
+	}
+	return model, chunks, nil
+}
+
+// SimilarObservations returns up to limit observations whose vectors from model are most
+// similar to vector, best first, each with its best-matching chunk. Archived observations
+// are left out unless searches include them.
+func (db *DB) SimilarObservations(model string, vector []float32, limit int) ([]SimilarObservation, error) {
+	query := "SELECT e.observation_id, e.start_offset, e.end_offset, e.vector FROM embeddings e JOIN observations o ON o.id = e.observation_id WHERE e.model = ?"
+	if clause := db.archivedClause(); clause != "" {
+		query += " AND " + clause
+	}
+	rows, err := db.query(query, model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	best := map[int64]*SimilarObservation{}
+	for rows.Next() {
+		var id int64
+		var start, end int
+		var data []byte
+		if err := rows.Scan(&id, &start, &end, &data); err != nil {
+			return nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		score := Cosine(vector, decodeVector(data))
+		if b, ok := best[id]; !ok || score > b.Score {
+			best[id] = &SimilarObservation{Observation: Observation{ID: id}, Score: score, Start: start, End: end}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search embeddings: %w", err)
+	}
+
+	results := make([]SimilarObservation, 0, len(best))
+	for _, b := range best {
+		results = append(results, *b)
+	}
+	slices.SortFunc(results, func(a, b SimilarObservation) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.ID, b.ID))
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	ids := make([]int64, len(results))
+	for i, r := range results {
+		ids[i] = r.ID
+	}
+	placeholders, args := idList(ids)
+	observations, err := collect(scanRows(db, observationsSelect+" WHERE o.id IN ("+placeholders+")", args, nil, "observations", scanObservation))
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[int64]Observation, len(observations))
+	for _, o := range observations {
+		byID[o.ID] = o
+	}
+	for i := range results {
+		results[i].Observation = byID[results[i].ID]
+	}
+	return results, nil
+}
+
+// RelatedTo returns up to limit observations most similar to the one with the given ID,
+// best first, and the other entities they're about. Only vectors from the model that embedded
+// the observation are compared, so it works without the provider.
+// Returns an error wrapping ErrNotFound if the observation hasn't been embedded.
+func (db *DB) RelatedTo(id int64, limit int) (*Related, error) {
+	source, err := db.GetObservation(id)
+	if err != nil {
+		return nil, err
+	}
+	model, chunks, err := db.Embedding(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// A long observation is compared by the average of its chunks
+	mean := make([]float32, len(chunks[0].Vector))
+	for _, c := range chunks {
+		for i := range min(len(mean), len(c.Vector)) {
+			mean[i] += c.Vector[i] / float32(len(chunks))
+		}
+	}
+
+	similar, err := db.SimilarObservations(model, mean, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	related := &Related{
+		Observations: slices.DeleteFunc(similar, func(s SimilarObservation) bool { return s.ID == id }),
+		Entities:     []SimilarEntity{},
+	}
+	if len(related.Observations) > limit {
+		related.Observations = related.Observations[:limit]
+	}
+
+	seen := map[string]bool{source.EntityText: true}
+	for _, s := range related.Observations {
+		// Observations are best first, so an entity's first is its best
+		if !seen[s.EntityText] {
+			seen[s.EntityText] = true
+			related.Entities = append(related.Entities, SimilarEntity{Entity: s.EntityText, Score: s.Score})
+		}
+	}
+	return related, nil
+}
+
+// Cosine returns the cosine similarity of a and b, or 0 if either is all zeros or their
+// lengths differ.
+func Cosine(a, b []float32) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(normA*normB))
+}
+
+// encodeVector packs a vector as little-endian float32s
+func encodeVector(vector []float32) []byte {
+	data := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+// decodeVector unpacks a vector packed by encodeVector
+func decodeVector(data []byte) []float32 {
+	vector := make([]float32, len(data)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return vector
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestEmbeddings(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_embeddings.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	first, _ := db.AddObservation("Alice", "Likes tea")
+	second, _ := db.AddObservation("Alice", "Lives in Oslo")
+
+	if n, _ := db.CountPendingEmbeddings("local/hash-4"); n != 2 {
+		t.Errorf("Expected 2 observations to embed, got %d", n)
+	}
+	if err := db.SaveEmbeddings("local/hash-4", []Chunk{{ObservationID: first, End: 9, Vector: []float32{1, 0, -0.5, 0}}}); err != nil {
+		t.Fatalf("SaveEmbeddings failed: %v", err)
+	}
+	pending, err := db.PendingEmbeddings("local/hash-4", 0, 10)
+	if err != nil || len(pending) != 1 || pending[0].ID != second {
+		t.Errorf("PendingEmbeddings = %+v, %v; want only observation %d", pending, err, second)
+	}
+	if pending, _ := db.PendingEmbeddings("local/hash-4", second, 10); len(pending) != 0 {
+		t.Errorf("Expected nothing pending after ID %d, got %+v", second, pending)
+	}
+
+	model, chunks, err := db.Embedding(first)
+	if err != nil || model != "local/hash-4" || len(chunks) != 1 || !slices.Equal(chunks[0].Vector, []float32{1, 0, -0.5, 0}) {
+		t.Errorf("Embedding = %q, %+v, %v", model, chunks, err)
+	}
+	if _, _, err := db.Embedding(second); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an observation without a vector, got %v", err)
+	}
+
+	// Another model's vectors don't count
+	if n, _ := db.CountPendingEmbeddings("ollama/all-minilm"); n != 2 {
+		t.Errorf("Expected 2 observations to embed with a new model, got %d", n)
+	}
+	if models, _ := db.EmbeddingModels(); !slices.Equal(models, []string{"local/hash-4"}) {
+		t.Errorf("EmbeddingModels = %v", models)
+	}
+
+	if n, err := db.DropEmbeddings(); err != nil || n != 1 {
+		t.Errorf("DropEmbeddings = %d, %v; want 1", n, err)
+	}
+	if err := db.SaveEmbeddings("local/hash-4", []Chunk{{ObservationID: first, End: 9, Vector: []float32{1, 0, 0, 0}}}); err != nil {
+		t.Fatalf("SaveEmbeddings failed: %v", err)
+	}
+	if err := db.DeleteObservation(first); err != nil {
+		t.Fatalf("DeleteObservation failed: %v", err)
+	}
+	if models, _ := db.EmbeddingModels(); len(models) != 0 {
+		t.Errorf("Expected deleting the observation to delete its vector, got models %v", models)
+	}
+}
+
+func TestSimilarObservations(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_similar.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tea, _ := db.AddObservation("Alice", "Likes tea. Drives a truck")
+	oslo, _ := db.AddObservation("Alice", "Lives in Oslo")
+	err = db.SaveEmbeddings("test", []Chunk{
+		{ObservationID: tea, Start: 0, End: 10, Vector: []float32{1, 0}},
+		{ObservationID: tea, Start: 11, End: 25, Vector: []float32{0, 1}},
+		{ObservationID: oslo, Start: 0, End: 13, Vector: []float32{0.6, 0.8}},
+	})
+	if err != nil {
+		t.Fatalf("SaveEmbeddings failed: %v", err)
+	}
+
+	similar, err := db.SimilarObservations("test", []float32{0, 1}, 10)
+	if err != nil {
+		t.Fatalf("SimilarObservations failed: %v", err)
+	}
+	if len(similar) != 2 || similar[0].ID != tea || similar[0].Start != 11 || similar[1].ID != oslo {
+		t.Fatalf("Expected the truck chunk first, then Oslo, got %+v", similar)
+	}
+	if similar[0].Text != "Likes tea. Drives a truck" || similar[0].Score < 0.999 {
+		t.Errorf("Expected the full observation with a perfect score, got %+v", similar[0])
+	}
+
+	if similar, _ := db.SimilarObservations("test", []float32{0, 1}, 1); len(similar) != 1 {
+		t.Errorf("Expected the limit to apply, got %+v", similar)
+	}
+	if similar, _ := db.SimilarObservations("other", []float32{0, 1}, 10); len(similar) != 0 {
+		t.Errorf("Expected no results for another model, got %+v", similar)
+	}
+
+	if _, err := db.Archive([]int64{tea}); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	if similar, _ := db.SimilarObservations("test", []float32{0, 1}, 10); len(similar) != 1 || similar[0].ID != oslo {
+		t.Errorf("Expected archived observations left out, got %+v", similar)
+	}
+}
+
+func TestRelatedTo(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_related.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tea, _ := db.AddObservation("Alice", "Likes tea")
+	coffee, _ := db.AddObservation("Bob", "Likes coffee")
+	oslo, _ := db.AddObservation("Carol", "Lives in Oslo")
+	err = db.SaveEmbeddings("test", []Chunk{
+		{ObservationID: tea, End: 9, Vector: []float32{1, 0.1}},
+		{ObservationID: coffee, End: 12, Vector: []float32{1, 0.2}},
+		{ObservationID: oslo, End: 13, Vector: []float32{0, 1}},
+	})
+	if err != nil {
+		t.Fatalf("SaveEmbeddings failed: %v", err)
+	}
+
+	related, err := db.RelatedTo(tea, 1)
+	if err != nil {
+		t.Fatalf("RelatedTo failed: %v", err)
+	}
+	if len(related.Observations) != 1 || related.Observations[0].ID != coffee {
+		t.Errorf("Expected only the coffee observation, got %+v", related.Observations)
+	}
+	if len(related.Entities) != 1 || related.Entities[0].Entity != "Bob" {
+		t.Errorf("Expected only Bob among the entities, got %+v", related.Entities)
+	}
+
+	noVector, _ := db.AddObservation("Alice", "Not embedded")
+	if _, err := db.RelatedTo(noVector, 5); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for an observation without a vector, got %v", err)
+	}
+}
@@ -0,0 +1,23 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// EngineVersions returns the versions of SQLite and SQLCipher compiled into the driver.
+func EngineVersions() (sqlite, sqlcipher string, err error) {
+	conn, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open SQLite: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	if err := conn.QueryRow("SELECT sqlite_version()").Scan(&sqlite); err != nil {
+		return "", "", fmt.Errorf("failed to read SQLite version: %w", err)
+	}
+	if err := conn.QueryRow("PRAGMA cipher_version").Scan(&sqlcipher); err != nil {
+		return "", "", fmt.Errorf("failed to read SQLCipher version: %w", err)
+	}
+	return sqlite, sqlcipher, nil
+}
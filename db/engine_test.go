@@ -0,0 +1,13 @@
+package db
+
+import "testing"
+
+func TestEngineVersions(t *testing.T) {
+	sqlite, sqlcipher, err := EngineVersions()
+	if err != nil {
+		t.Fatalf("EngineVersions failed: %v", err)
+	}
+	if sqlite == "" || sqlcipher == "" {
+		t.Errorf("Expected both versions, got %q and %q", sqlite, sqlcipher)
+	}
+}
@@ -0,0 +1,37 @@
+package db
+
+import "fmt"
+
+// EntityExists reports whether there's an entity named text.
+func (db *DB) EntityExists(text string) (bool, error) {
+	return db.exists("entity", "SELECT 1 FROM entities WHERE text = ?", text)
+}
+
+// ObservationExists reports whether there's an observation with the given ID.
+func (db *DB) ObservationExists(id int64) (bool, error) {
+	return db.exists("observation", "SELECT 1 FROM observations WHERE id = ?", id)
+}
+
+// RelationshipExists reports whether there's a relationship from the entity named fromText
+// to the one named toText, of type relType unless it's empty.
+func (db *DB) RelationshipExists(fromText, toText, relType string) (bool, error) {
+	query := `SELECT 1 FROM relationships r
+		JOIN entities e1 ON r.from_id = e1.id
+		JOIN entities e2 ON r.to_id = e2.id
+		WHERE e1.text = ? AND e2.text = ?`
+	args := []interface{}{fromText, toText}
+	if relType != "" {
+		query += " AND r.type = ?"
+		args = append(args, relType)
+	}
+	return db.exists("relationship", query, args...)
+}
+
+// exists reports whether query returns any rows.
+func (db *DB) exists(what, query string, args ...interface{}) (bool, error) {
+	var found bool
+	if err := db.queryRow("SELECT EXISTS ("+query+")", args...).Scan(&found); err != nil {
+		return false, fmt.Errorf("failed to look for %s: %w", what, err)
+	}
+	return found, nil
+}
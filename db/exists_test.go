@@ -0,0 +1,35 @@
+package db
+
+import "testing"
+
+func TestExists(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_exists.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	id, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddRelationship("Alice", "Bob", "knows")
+
+	checks := []struct {
+		name  string
+		found func() (bool, error)
+		want  bool
+	}{
+		{"entity", func() (bool, error) { return db.EntityExists("Alice") }, true},
+		{"missing entity", func() (bool, error) { return db.EntityExists("Ali") }, false},
+		{"observation", func() (bool, error) { return db.ObservationExists(id) }, true},
+		{"missing observation", func() (bool, error) { return db.ObservationExists(id + 1) }, false},
+		{"relationship", func() (bool, error) { return db.RelationshipExists("Alice", "Bob", "") }, true},
+		{"relationship of type", func() (bool, error) { return db.RelationshipExists("Alice", "Bob", "knows") }, true},
+		{"relationship of another type", func() (bool, error) { return db.RelationshipExists("Alice", "Bob", "manages") }, false},
+		{"reversed relationship", func() (bool, error) { return db.RelationshipExists("Bob", "Alice", "") }, false},
+	}
+	for _, c := range checks {
+		found, err := c.found()
+		if err != nil || found != c.want {
+			t.Errorf("%s: exists = %v, %v; want %v", c.name, found, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SetExplain makes searches write their SQL, SQLite's plan for it, and how long
+// it took to w, to show whether indices are used. A nil w turns this off.
+func (db *DB) SetExplain(w io.Writer) {
+	db.explain = w
+}
+
+// explainQuery writes query and SQLite's plan for it to db.explain, labelled with what.
+// Failing to get the plan isn't an error, since the query itself may still run.
+func (db *DB) explainQuery(what, query string, args []interface{}) {
+	w := db.explain
+	_, _ = fmt.Fprintf(w, "%s query: %s\n", what, strings.Join(strings.Fields(query), " "))
+
+	rows, err := db.query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		_, _ = fmt.Fprintf(w, "%s plan unavailable: %v\n", what, err)
+		return
+	}
+	defer func() { _ = rows.Close() }()
+
+	// Each step's parent is an earlier step, or 0 at the top, so depths are known by the time they're needed
+	depths := map[int]int{0: 0}
+	_, _ = fmt.Fprintf(w, "%s plan:\n", what)
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			_, _ = fmt.Fprintf(w, "%s plan unavailable: %v\n", what, err)
+			return
+		}
+		depths[id] = depths[parent] + 1
+		_, _ = fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depths[id]), detail)
+	}
+}
+
+// explainTime writes how long what has taken since start to db.explain.
+func (db *DB) explainTime(what string, start time.Time) {
+	_, _ = fmt.Fprintf(db.explain, "%s took %s\n", what, time.Since(start).Round(time.Microsecond))
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	path := t.TempDir() + "/test_explain.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	db.SetExplain(&buf)
+	if _, err := db.CountSearchRelationships("Alice", "", "", nil, false); err != nil {
+		t.Fatalf("CountSearchRelationships failed: %v", err)
+	}
+	if _, err := db.SearchRelationships("Alice", "", "", nil, false); err != nil {
+		t.Fatalf("SearchRelationships failed: %v", err)
+	}
+
+	got := buf.String()
+	for _, want := range []string{
+		"counting relationships query: SELECT COUNT(*) FROM (",
+		"counting relationships plan:\n",
+		"relationships plan:\n  SCAN TABLE relationships",
+		"reading 1 relationships took ",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("explain output missing %q:\n%s", want, got)
+		}
+	}
+
+	buf.Reset()
+	db.SetExplain(nil)
+	if _, err := db.SearchRelationships("Alice", "", "", nil, false); err != nil {
+		t.Fatalf("SearchRelationships failed: %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected no explain output once turned off, got:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,208 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrFeatureDisabled is returned when something needs a feature the database hasn't enabled.
+var ErrFeatureDisabled = errors.New("is not enabled")
+
+// Feature is an optional subsystem whose tables a database only carries once it's enabled
+// with EnableFeature, like a migration applied on demand.
+type Feature struct {
+	Name  string
+	Usage string
+	Up    string
+	Down  string
+	// Applied is a query returning a nonzero count if the feature's tables are the ones
+	// Up creates. Enabled features whose tables an older amem created are rebuilt on open.
+	Applied string
+}
+
+// FeatureFTS is full-text search of observations, with SQLite's FTS4.
+const FeatureFTS = "fts"
+
+// features contains every feature a database can enable
+var features = []Feature{
+	{
+		Name:  FeatureFTS,
+		Usage: "Full-text search of observations by word, prefix (tea*), and phrase",
+		// External content tables index observations' text without storing a copy. A table's
+		// tokenizer is fixed, so observations tagged English, the only language SQLite has a
+		// stemmer for, get a table of their own where "running" matches "run"
+		Up: `
+CREATE VIRTUAL TABLE observations_fts USING fts4(content="observations", text, tokenize=unicode61);
+CREATE VIRTUAL TABLE observations_fts_en USING fts4(content="observations", text, tokenize=porter);
+INSERT INTO observations_fts (docid, text) SELECT id, text FROM observations WHERE lang IS NOT 'en';
+INSERT INTO observations_fts_en (docid, text) SELECT id, text FROM observations WHERE lang IS 'en';
+CREATE TRIGGER observations_fts_insert AFTER INSERT ON observations BEGIN
+	INSERT INTO observations_fts (docid, text) SELECT new.id, new.text WHERE new.lang IS NOT 'en';
+	INSERT INTO observations_fts_en (docid, text) SELECT new.id, new.text WHERE new.lang IS 'en';
+END;
+CREATE TRIGGER observations_fts_before_update BEFORE UPDATE OF text, lang ON observations BEGIN
+	DELETE FROM observations_fts WHERE docid = old.id AND old.lang IS NOT 'en';
+	DELETE FROM observations_fts_en WHERE docid = old.id AND old.lang IS 'en';
+END;
+CREATE TRIGGER observations_fts_after_update AFTER UPDATE OF text, lang ON observations BEGIN
+	INSERT INTO observations_fts (docid, text) SELECT new.id, new.text WHERE new.lang IS NOT 'en';
+	INSERT INTO observations_fts_en (docid, text) SELECT new.id, new.text WHERE new.lang IS 'en';
+END;
+CREATE TRIGGER observations_fts_delete BEFORE DELETE ON observations BEGIN
+	DELETE FROM observations_fts WHERE docid = old.id AND old.lang IS NOT 'en';
+	DELETE FROM observations_fts_en WHERE docid = old.id AND old.lang IS 'en';
+END;
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'observations_fts_en'`,
+		Down: `
+DROP TRIGGER IF EXISTS observations_fts_insert;
+DROP TRIGGER IF EXISTS observations_fts_before_update;
+DROP TRIGGER IF EXISTS observations_fts_after_update;
+DROP TRIGGER IF EXISTS observations_fts_delete;
+DROP TABLE IF EXISTS observations_fts_en;
+DROP TABLE IF EXISTS observations_fts;
+`,
+	},
+}
+
+// Features returns the features a database can enable.
+func Features() []Feature {
+	return features
+}
+
+// lookupFeature returns the feature called name.
+func lookupFeature(name string) (Feature, error) {
+	names := make([]string, len(features))
+	for i, f := range features {
+		if f.Name == name {
+			return f, nil
+		}
+		names[i] = f.Name
+	}
+	return Feature{}, fmt.Errorf("unknown feature %q: use %s", name, strings.Join(names, ", "))
+}
+
+// HasFeature reports whether the feature called name is enabled on this database.
+func (db *DB) HasFeature(name string) (bool, error) {
+	var count int
+	if err := db.queryRow("SELECT COUNT(*) FROM features WHERE name = ?", name).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to check feature: %w", err)
+	}
+	return count > 0, nil
+}
+
+// EnableFeature creates the tables of the feature called name and records that it's
+// enabled. Enabling a feature that's already enabled does nothing.
+func (db *DB) EnableFeature(name string) error {
+	f, err := lookupFeature(name)
+	if err != nil {
+		return err
+	}
+	if enabled, err := db.HasFeature(name); err != nil || enabled {
+		return err
+	}
+	return db.changeFeature(f.Up, "INSERT INTO features (name) VALUES (?)", name)
+}
+
+// DisableFeature drops the tables of the feature called name, and the data in them.
+// Returns an error wrapping ErrFeatureDisabled if it isn't enabled.
+func (db *DB) DisableFeature(name string) error {
+	f, err := lookupFeature(name)
+	if err != nil {
+		return err
+	}
+	enabled, err := db.HasFeature(name)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return fmt.Errorf("feature %q %w", name, ErrFeatureDisabled)
+	}
+	return db.changeFeature(f.Down, "DELETE FROM features WHERE name = ?", name)
+}
+
+// refreshFeatures rebuilds the enabled features on conn whose tables an older version of
+// amem created, so they work the way this version expects.
+func refreshFeatures(conn *sql.DB) error {
+	for _, f := range features {
+		var enabled, applied int
+		if err := conn.QueryRow("SELECT COUNT(*) FROM features WHERE name = ?", f.Name).Scan(&enabled); err != nil {
+			return fmt.Errorf("failed to check feature: %w", err)
+		}
+		if enabled == 0 {
+			continue
+		}
+		if err := conn.QueryRow(f.Applied).Scan(&applied); err != nil {
+			return fmt.Errorf("failed to check feature %s: %w", f.Name, err)
+		}
+		if applied > 0 {
+			continue
+		}
+
+		tx, err := conn.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		if _, err := tx.Exec(f.Down + f.Up); err != nil {
+			_ = tx.Rollback()
+			return fmt.Errorf("failed to rebuild feature %s: %w", f.Name, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit feature %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// changeFeature runs a feature's schema change and records it with record, all or nothing.
+func (db *DB) changeFeature(schema, record, name string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return fmt.Errorf("failed to change feature %s: %w", name, err)
+	}
+	if _, err := tx.Exec(record, name); err != nil {
+		return fmt.Errorf("failed to record feature %s: %w", name, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit feature %s: %w", name, err)
+	}
+	return nil
+}
+
+// SearchFullText returns the observations matching the FTS4 query match, such as
+// "tea OR coffee" or "green tea*", optionally only those about entities matching entityText.
+// Words in observations tagged English also match other forms of them, like "runs" for "run".
+// Returns an error wrapping ErrFeatureDisabled unless FeatureFTS is enabled.
+func (db *DB) SearchFullText(entityText, match string) ([]Observation, error) {
+	enabled, err := db.HasFeature(FeatureFTS)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, fmt.Errorf("full-text search %w: run 'amem enable %s' first", ErrFeatureDisabled, FeatureFTS)
+	}
+
+	where := []string{`o.id IN (SELECT docid FROM observations_fts WHERE observations_fts MATCH ?
+		UNION ALL SELECT docid FROM observations_fts_en WHERE observations_fts_en MATCH ?)`}
+	args := []interface{}{match, match}
+	if clause := db.archivedClause(); clause != "" {
+		where = append(where, clause)
+	}
+	if db.lang != "" {
+		where = append(where, "o.lang = ?")
+		args = append(args, db.lang)
+	}
+	if entityText != "" {
+		where = append(where, "e.text LIKE ?")
+		args = append(args, "%"+entityText+"%")
+	}
+	query := observationsSelect + " WHERE " + strings.Join(where, " AND ") + observationsOrder
+	return collect(scanRows(db, query, args, nil, "observations", scanObservation))
+}
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFeatures(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_features.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	teaID, _ := db.AddObservation("Alice", "Likes green tea")
+	_, _ = db.AddObservation("Bob", "Drinks coffee")
+
+	if enabled, err := db.HasFeature(FeatureFTS); err != nil || enabled {
+		t.Fatalf("Expected fts to start disabled, got %v (err %v)", enabled, err)
+	}
+	if _, err := db.SearchFullText("", "tea"); !errors.Is(err, ErrFeatureDisabled) {
+		t.Errorf("Expected ErrFeatureDisabled searching before enabling, got %v", err)
+	}
+	if err := db.EnableFeature("namespaces"); err == nil {
+		t.Error("Expected an error enabling an unknown feature")
+	}
+
+	if err := db.EnableFeature(FeatureFTS); err != nil {
+		t.Fatalf("EnableFeature failed: %v", err)
+	}
+	if err := db.EnableFeature(FeatureFTS); err != nil {
+		t.Errorf("Expected enabling twice to do nothing, got %v", err)
+	}
+	if enabled, _ := db.HasFeature(FeatureFTS); !enabled {
+		t.Error("Expected fts to be enabled")
+	}
+
+	search := func(entityText, match string) []string {
+		t.Helper()
+		observations, err := db.SearchFullText(entityText, match)
+		if err != nil {
+			t.Fatalf("SearchFullText(%q) failed: %v", match, err)
+		}
+		var texts []string
+		for _, o := range observations {
+			texts = append(texts, o.Text)
+		}
+		return texts
+	}
+	if got := search("", "tea"); len(got) != 1 || got[0] != "Likes green tea" {
+		t.Errorf("Expected existing observations to be indexed, got %v", got)
+	}
+	if got := search("", "te*"); len(got) != 1 {
+		t.Errorf("Expected a prefix match, got %v", got)
+	}
+
+	_, _ = db.AddObservation("Carol", "Brews tea at home")
+	if err := db.UpdateObservation(teaID, "Likes green juice"); err != nil {
+		t.Fatalf("UpdateObservation failed: %v", err)
+	}
+	if got := search("", "tea"); len(got) != 1 || got[0] != "Brews tea at home" {
+		t.Errorf("Expected the index to follow adds and edits, got %v", got)
+	}
+	if got := search("Bob", "tea OR coffee"); len(got) != 1 || got[0] != "Drinks coffee" {
+		t.Errorf("Expected only Bob's observation, got %v", got)
+	}
+
+	if err := db.DisableFeature(FeatureFTS); err != nil {
+		t.Fatalf("DisableFeature failed: %v", err)
+	}
+	if err := db.DisableFeature(FeatureFTS); !errors.Is(err, ErrFeatureDisabled) {
+		t.Errorf("Expected ErrFeatureDisabled disabling twice, got %v", err)
+	}
+	if _, err := db.AddObservation("Dave", "Likes tea"); err != nil {
+		t.Errorf("Expected adds to work after disabling, got %v", err)
+	}
+}
+
+func TestFeatureFTSLang(t *testing.T) {
+	path := t.TempDir() + "/test_features_lang.db"
+	key := "testkey123456789012"
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	_, _ = db.AddObservationWithOptions("Alice", "Goes running every morning", ObservationOptions{Lang: "en"})
+	_, _ = db.AddObservationWithOptions("Bob", "Goes running at night", ObservationOptions{})
+	_, _ = db.AddObservationWithOptions("Carol", "Geht gern laufen", ObservationOptions{Lang: "de"})
+	if err := db.EnableFeature(FeatureFTS); err != nil {
+		t.Fatalf("EnableFeature failed: %v", err)
+	}
+
+	search := func(db *DB, match string) []string {
+		t.Helper()
+		observations, err := db.SearchFullText("", match)
+		if err != nil {
+			t.Fatalf("SearchFullText(%q) failed: %v", match, err)
+		}
+		var entities []string
+		for _, o := range observations {
+			entities = append(entities, o.EntityText)
+		}
+		return entities
+	}
+	// Only English observations are stemmed
+	if got := search(db, "run"); len(got) != 1 || got[0] != "Alice" {
+		t.Errorf("Expected run to match Alice's English observation, got %v", got)
+	}
+	if got := search(db, "running"); len(got) != 2 {
+		t.Errorf("Expected running to match both observations, got %v", got)
+	}
+	if err := db.SetLang("de"); err != nil {
+		t.Fatalf("SetLang failed: %v", err)
+	}
+	if got := search(db, "laufen OR running"); len(got) != 1 || got[0] != "Carol" {
+		t.Errorf("Expected only Carol's German observation, got %v", got)
+	}
+	_ = db.SetLang("")
+
+	// Tables an older amem created are rebuilt when the database is opened
+	if _, err := db.conn.Exec("DROP TABLE observations_fts_en"); err != nil {
+		t.Fatal(err)
+	}
+	_ = db.Close()
+	db, err = Open(path, key)
+	if err != nil {
+		t.Fatalf("Failed to reopen database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if got := search(db, "run"); len(got) != 1 || got[0] != "Alice" {
+		t.Errorf("Expected the rebuilt index to find Alice, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Feedback a reader can give on an observation with Mark.
+const (
+	FeedbackHelpful = "helpful"
+	FeedbackStale   = "stale"
+)
+
+// feedbackRank orders observations (aliased o) marked more helpful than stale before
+// unmarked ones, and those marked more stale than helpful after.
+const feedbackRank = `COALESCE((SELECT CASE WHEN f.helpful > f.stale THEN 1 WHEN f.stale > f.helpful THEN -1 ELSE 0 END
+	FROM feedback f WHERE f.observation_id = o.id), 0)`
+
+// Mark records feedback on an observation: FeedbackHelpful or FeedbackStale.
+// Marks accumulate; an observation's rank follows whichever it has more of.
+func (db *DB) Mark(id int64, feedback string) error {
+	if feedback != FeedbackHelpful && feedback != FeedbackStale {
+		return fmt.Errorf("invalid feedback %q (use %s or %s)", feedback, FeedbackHelpful, FeedbackStale)
+	}
+	if _, err := db.GetObservation(id); err != nil {
+		return err
+	}
+
+	// feedback is one of two column names, checked above
+	_, err := db.exec(`INSERT INTO feedback (observation_id, `+feedback+`) VALUES (?, 1)
+		ON CONFLICT (observation_id) DO UPDATE SET `+feedback+` = `+feedback+` + 1, updated_at = CURRENT_TIMESTAMP`, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark observation: %w", err)
+	}
+	return nil
+}
+
+// ClearMarks removes all feedback on an observation.
+func (db *DB) ClearMarks(id int64) error {
+	if _, err := db.GetObservation(id); err != nil {
+		return err
+	}
+	if _, err := db.exec("DELETE FROM feedback WHERE observation_id = ?", id); err != nil {
+		return fmt.Errorf("failed to clear marks: %w", err)
+	}
+	return nil
+}
+
+// MarkedObservation is an observation with the feedback it's been given.
+type MarkedObservation struct {
+	Observation
+	Helpful int `json:"helpful"`
+	Stale   int `json:"stale"`
+}
+
+// FindStale returns observations marked stale more often than helpful, candidates for
+// pruning, most stale first.
+func (db *DB) FindStale() ([]MarkedObservation, error) {
+	query, args, err := db.observationsQuery("", nil, false)
+	if err != nil {
+		return nil, err
+	}
+	query = `SELECT q.*, f.helpful, f.stale FROM (` + query + `) q
+		JOIN feedback f ON f.observation_id = q.id
+		WHERE f.stale > f.helpful
+		ORDER BY f.stale - f.helpful DESC, q.id`
+	return collect(scanRows(db, query, args, nil, "observations", func(rows *sql.Rows, o *MarkedObservation) error {
+		return rows.Scan(&o.ID, &o.EntityID, &o.EntityText, &o.Text, &o.Timestamp, &o.UpdatedAt, &o.Importance, &o.Lang, &o.Helpful, &o.Stale)
+	}))
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMarkFeedback(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_feedback.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	old, _ := db.AddObservation("Alice", "Likes tea")
+	middle, _ := db.AddObservation("Alice", "Likes cake")
+	newest, _ := db.AddObservation("Alice", "Likes chess")
+
+	if err := db.Mark(old, FeedbackHelpful); err != nil {
+		t.Fatalf("Mark failed: %v", err)
+	}
+	if err := db.Mark(newest, FeedbackStale); err != nil {
+		t.Fatalf("Mark failed: %v", err)
+	}
+	if err := db.Mark(999, FeedbackStale); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound marking a missing observation, got %v", err)
+	}
+	if err := db.Mark(old, "great"); err == nil {
+		t.Error("Expected an error for unknown feedback")
+	}
+
+	// Helpful first and stale last, otherwise newest first
+	observations, err := db.SearchObservations("Alice", nil, true)
+	if err != nil {
+		t.Fatalf("SearchObservations failed: %v", err)
+	}
+	if len(observations) != 3 || observations[0].ID != old || observations[1].ID != middle || observations[2].ID != newest {
+		t.Errorf("Expected helpful, unmarked, then stale, got %+v", observations)
+	}
+
+	stale, err := db.FindStale()
+	if err != nil {
+		t.Fatalf("FindStale failed: %v", err)
+	}
+	if len(stale) != 1 || stale[0].ID != newest || stale[0].Text != "Likes chess" || stale[0].EntityText != "Alice" || stale[0].Stale != 1 {
+		t.Errorf("Expected the stale observation, got %+v", stale)
+	}
+
+	// Marked helpful as often as stale, it's no longer stale
+	if err := db.Mark(newest, FeedbackHelpful); err != nil {
+		t.Fatalf("Mark failed: %v", err)
+	}
+	if stale, _ := db.FindStale(); len(stale) != 0 {
+		t.Errorf("Expected no stale observations, got %+v", stale)
+	}
+
+	if err := db.ClearMarks(old); err != nil {
+		t.Fatalf("ClearMarks failed: %v", err)
+	}
+	observations, _ = db.SearchObservations("Alice", nil, true)
+	if observations[0].ID != newest {
+		t.Errorf("Expected newest first after clearing marks, got %+v", observations)
+	}
+}
@@ -0,0 +1,38 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// ErrNotFound is returned when no record has the requested ID.
+var ErrNotFound = errors.New("not found")
+
+// GetEntity returns the entity with the given ID.
+func (db *DB) GetEntity(id int64) (*Entity, error) {
+	query, args := entitiesQuery(nil, nil, false, EntityFilter{})
+	return getOne(scanRows(db, query+" WHERE id = ?", append(args, id), nil, "entities", scanEntity), "entity", id)
+}
+
+// GetObservation returns the observation with the given ID.
+func (db *DB) GetObservation(id int64) (*Observation, error) {
+	return getOne(scanRows(db, observationsSelect+" WHERE o.id = ?", []interface{}{id}, nil, "observations", scanObservation), "observation", id)
+}
+
+// GetRelationship returns the relationship with the given ID.
+func (db *DB) GetRelationship(id int64) (*Relationship, error) {
+	query, args := relationshipsQuery("", "", "", "", nil, nil, false)
+	return getOne(scanRows(db, query+" WHERE r.id = ?", append(args, id), nil, "relationships", scanRelationship), "relationship", id)
+}
+
+// getOne returns the first value of seq, or ErrNotFound naming what and id if it is empty.
+func getOne[T any](seq iter.Seq2[T, error], what string, id int64) (*T, error) {
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		return &v, nil
+	}
+	return nil, fmt.Errorf("%s with ID %d %w", what, id, ErrNotFound)
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	path := t.TempDir() + "/test_get.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	obsID, err := db.AddObservationWithOptions("Alice", "Likes tea", ObservationOptions{Importance: 4})
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	relID, err := db.AddRelationship("Alice", "Bob", "knows")
+	if err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	obs, err := db.GetObservation(obsID)
+	if err != nil {
+		t.Fatalf("GetObservation failed: %v", err)
+	}
+	if obs.Text != "Likes tea" || obs.EntityText != "Alice" || obs.Importance != 4 {
+		t.Errorf("GetObservation() = %+v", obs)
+	}
+
+	entity, err := db.GetEntity(obs.EntityID)
+	if err != nil {
+		t.Fatalf("GetEntity failed: %v", err)
+	}
+	if entity.Text != "Alice" || entity.CreatedAt == "" {
+		t.Errorf("GetEntity() = %+v", entity)
+	}
+
+	rel, err := db.GetRelationship(relID)
+	if err != nil {
+		t.Fatalf("GetRelationship failed: %v", err)
+	}
+	if rel.FromText != "Alice" || rel.ToText != "Bob" || rel.Type != "knows" {
+		t.Errorf("GetRelationship() = %+v", rel)
+	}
+
+	if _, err := db.GetEntity(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetEntity(999) error = %v, want ErrNotFound", err)
+	}
+	if _, err := db.GetObservation(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetObservation(999) error = %v, want ErrNotFound", err)
+	}
+	if _, err := db.GetRelationship(999); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetRelationship(999) error = %v, want ErrNotFound", err)
+	}
+}
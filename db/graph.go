@@ -0,0 +1,475 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// maxIDsPerQuery keeps IN lists well under SQLite's limit on bound parameters.
+const maxIDsPerQuery = 500
+
+// Subgraph is the part of the graph within some number of hops of a root entity.
+type Subgraph struct {
+	Root          string         `json:"root"`
+	Depth         int            `json:"depth"`
+	Entities      []Entity       `json:"entities"`
+	Observations  []Observation  `json:"observations"`
+	Relationships []Relationship `json:"relationships"`
+}
+
+// Subgraph returns the entities reachable from the entity named root by following at most
+// depth relationships in either direction, with their observations and the relationships between them.
+func (db *DB) Subgraph(root string, depth int) (*Subgraph, error) {
+	if depth < 0 {
+		return nil, fmt.Errorf("depth cannot be negative")
+	}
+
+	var rootID int64
+	err := db.queryRow("SELECT id FROM entities WHERE text = ?", root).Scan(&rootID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("entity '%s' %w", root, ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find entity: %w", err)
+	}
+
+	// Walk outward one hop at a time, so each entity is visited once
+	reached := map[int64]bool{rootID: true}
+	frontier := []int64{rootID}
+	for hop := 0; hop < depth && len(frontier) > 0; hop++ {
+		var next []int64
+		err := inChunks(frontier, func(in string, args []interface{}) error {
+			rows, err := db.query("SELECT from_id, to_id FROM relationships WHERE from_id IN "+in+" OR to_id IN "+in, append(args, args...)...)
+			if err != nil {
+				return fmt.Errorf("failed to search relationships: %w", err)
+			}
+			defer func() { _ = rows.Close() }()
+
+			for rows.Next() {
+				var fromID, toID int64
+				if err := rows.Scan(&fromID, &toID); err != nil {
+					return fmt.Errorf("failed to scan relationships: %w", err)
+				}
+				for _, id := range []int64{fromID, toID} {
+					if !reached[id] {
+						reached[id] = true
+						next = append(next, id)
+					}
+				}
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			return nil, err
+		}
+		frontier = next
+	}
+
+	ids := make([]int64, 0, len(reached))
+	for id := range reached {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	graph := &Subgraph{Root: root, Depth: depth}
+	err = inChunks(ids, func(in string, args []interface{}) error {
+		query, _ := entitiesQuery(nil, nil, false, EntityFilter{})
+		entities, err := collect(scanRows(db, query+" WHERE id IN "+in, args, nil, "entities", scanEntity))
+		if err != nil {
+			return err
+		}
+		graph.Entities = append(graph.Entities, entities...)
+
+		observations, err := collect(scanRows(db, observationsSelect+" WHERE o.entity_id IN "+in, args, nil, "observations", scanObservation))
+		if err != nil {
+			return err
+		}
+		graph.Observations = append(graph.Observations, observations...)
+
+		// Every relationship between reached entities has its source in some chunk
+		query, _ = relationshipsQuery("", "", "", "", nil, nil, false)
+		for r, err := range scanRows(db, query+" WHERE r.from_id IN "+in, args, nil, "relationships", scanRelationship) {
+			if err != nil {
+				return err
+			}
+			if reached[r.ToID] {
+				graph.Relationships = append(graph.Relationships, r)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(graph.Entities, func(a, b Entity) int { return cmp.Compare(a.Text, b.Text) })
+	slices.SortFunc(graph.Observations, func(a, b Observation) int { return cmp.Compare(a.ID, b.ID) })
+	slices.SortFunc(graph.Relationships, func(a, b Relationship) int { return cmp.Compare(a.ID, b.ID) })
+	return graph, nil
+}
+
+// inChunks calls fn with a parenthesized placeholder list and its arguments
+// for each run of at most maxIDsPerQuery ids.
+func inChunks(ids []int64, fn func(in string, args []interface{}) error) error {
+	for chunk := range slices.Chunk(ids, maxIDsPerQuery) {
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		in := "(" + strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",") + ")"
+		if err := fn(in, args); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ways RankEntities can score entities.
+const (
+	// RankByDegree scores an entity by how many relationships it has
+	RankByDegree = "degree"
+	// RankByPageRank scores an entity by PageRank, so links from well-connected entities count for more
+	RankByPageRank = "pagerank"
+)
+
+// pageRank tuning: the usual damping factor, and when to stop iterating.
+const (
+	pageRankDamping       = 0.85
+	pageRankMaxIterations = 100
+	pageRankTolerance     = 1e-9
+)
+
+// RankedEntity is an entity with its centrality score.
+type RankedEntity struct {
+	Entity
+	Score  float64 `json:"score"`
+	Degree int     `json:"degree"`
+}
+
+// RankEntities scores every entity by method (RankByDegree or RankByPageRank) and
+// returns the limit highest scoring, most central first. A limit of 0 returns all.
+// PageRank follows relationships from source to target, or both ways if undirected.
+func (db *DB) RankEntities(method string, limit int, undirected bool) ([]RankedEntity, error) {
+	if method != RankByDegree && method != RankByPageRank {
+		return nil, fmt.Errorf("unknown ranking %q: use %s or %s", method, RankByDegree, RankByPageRank)
+	}
+
+	nodes, edges, err := db.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	degree := map[int64]int{}
+	for _, e := range edges {
+		degree[e[0]]++
+		degree[e[1]]++
+	}
+
+	scores := map[int64]float64{}
+	if method == RankByPageRank {
+		if undirected {
+			for _, e := range edges {
+				edges = append(edges, [2]int64{e[1], e[0]})
+			}
+		}
+		scores = pageRank(nodes, edges)
+	} else {
+		for _, id := range nodes {
+			scores[id] = float64(degree[id])
+		}
+	}
+
+	ranked := make([]RankedEntity, len(nodes))
+	for i, id := range nodes {
+		ranked[i] = RankedEntity{Entity: Entity{ID: id}, Score: scores[id], Degree: degree[id]}
+	}
+	slices.SortFunc(ranked, func(a, b RankedEntity) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(b.Degree, a.Degree), cmp.Compare(a.ID, b.ID))
+	})
+	if limit > 0 && len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	// Fill in the details of just the entities being returned
+	ids := make([]int64, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.ID
+	}
+	details, err := db.entitiesByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range ranked {
+		ranked[i].Entity = details[ranked[i].ID]
+	}
+	return ranked, nil
+}
+
+// graph returns the ID of every entity, and every relationship as a (from, to) pair of entity IDs.
+func (db *DB) graph() ([]int64, [][2]int64, error) {
+	var nodes []int64
+	for id, err := range scanRows(db, "SELECT id FROM entities ORDER BY id", nil, nil, "entities", func(rows *sql.Rows, id *int64) error {
+		return rows.Scan(id)
+	}) {
+		if err != nil {
+			return nil, nil, err
+		}
+		nodes = append(nodes, id)
+	}
+
+	var edges [][2]int64
+	for e, err := range scanRows(db, "SELECT from_id, to_id FROM relationships ORDER BY id", nil, nil, "relationships", func(rows *sql.Rows, e *[2]int64) error {
+		return rows.Scan(&e[0], &e[1])
+	}) {
+		if err != nil {
+			return nil, nil, err
+		}
+		edges = append(edges, e)
+	}
+	return nodes, edges, nil
+}
+
+// entitiesByID returns the entities with the given IDs, keyed by ID.
+func (db *DB) entitiesByID(ids []int64) (map[int64]Entity, error) {
+	entities := make(map[int64]Entity, len(ids))
+	err := inChunks(ids, func(in string, args []interface{}) error {
+		query, _ := entitiesQuery(nil, nil, false, EntityFilter{})
+		for e, err := range scanRows(db, query+" WHERE id IN "+in, args, nil, "entities", scanEntity) {
+			if err != nil {
+				return err
+			}
+			entities[e.ID] = e
+		}
+		return nil
+	})
+	return entities, err
+}
+
+// pageRank computes the PageRank of each node, following edges from their source to their target.
+// Nodes without outgoing edges share their rank with every node, so scores always sum to 1.
+func pageRank(nodes []int64, edges [][2]int64) map[int64]float64 {
+	n := float64(len(nodes))
+	rank := make(map[int64]float64, len(nodes))
+	if len(nodes) == 0 {
+		return rank
+	}
+	for _, id := range nodes {
+		rank[id] = 1 / n
+	}
+
+	outDegree := map[int64]int{}
+	for _, e := range edges {
+		outDegree[e[0]]++
+	}
+
+	for range pageRankMaxIterations {
+		var dangling float64
+		for _, id := range nodes {
+			if outDegree[id] == 0 {
+				dangling += rank[id]
+			}
+		}
+
+		base := (1-pageRankDamping)/n + pageRankDamping*dangling/n
+		next := make(map[int64]float64, len(nodes))
+		for _, id := range nodes {
+			next[id] = base
+		}
+		for _, e := range edges {
+			next[e[1]] += pageRankDamping * rank[e[0]] / float64(outDegree[e[0]])
+		}
+
+		var change float64
+		for _, id := range nodes {
+			change += math.Abs(next[id] - rank[id])
+		}
+		rank = next
+		if change < pageRankTolerance {
+			break
+		}
+	}
+	return rank
+}
+
+// Ways Clusters can group entities.
+const (
+	// ClusterByComponents groups entities connected by any chain of relationships
+	ClusterByComponents = "components"
+	// ClusterByCommunities splits components into densely related communities by label propagation
+	ClusterByCommunities = "communities"
+)
+
+// Label propagation tuning: how long one run may take to settle, and how many
+// runs to choose the best from, since each run depends on the order nodes are visited.
+const (
+	labelPropagationMaxIterations = 100
+	labelPropagationRuns          = 10
+)
+
+// Clusters groups every entity by method (ClusterByComponents or ClusterByCommunities),
+// ignoring the direction of relationships. Clusters are returned largest first, each sorted by text;
+// entities without relationships are clusters of one.
+func (db *DB) Clusters(method string) ([][]Entity, error) {
+	if method != ClusterByComponents && method != ClusterByCommunities {
+		return nil, fmt.Errorf("unknown clustering %q: use %s or %s", method, ClusterByComponents, ClusterByCommunities)
+	}
+
+	nodes, edges, err := db.graph()
+	if err != nil {
+		return nil, err
+	}
+
+	var labels map[int64]int64
+	if method == ClusterByCommunities {
+		labels = communities(nodes, edges)
+	} else {
+		labels = components(nodes, edges)
+	}
+
+	details, err := db.entitiesByID(nodes)
+	if err != nil {
+		return nil, err
+	}
+	groups := map[int64][]Entity{}
+	for _, id := range nodes {
+		groups[labels[id]] = append(groups[labels[id]], details[id])
+	}
+
+	clusters := make([][]Entity, 0, len(groups))
+	for _, group := range groups {
+		slices.SortFunc(group, func(a, b Entity) int { return cmp.Compare(a.Text, b.Text) })
+		clusters = append(clusters, group)
+	}
+	slices.SortFunc(clusters, func(a, b []Entity) int {
+		return cmp.Or(cmp.Compare(len(b), len(a)), cmp.Compare(a[0].Text, b[0].Text))
+	})
+	return clusters, nil
+}
+
+// components labels each node with the smallest ID in its connected component.
+func components(nodes []int64, edges [][2]int64) map[int64]int64 {
+	parent := make(map[int64]int64, len(nodes))
+	for _, id := range nodes {
+		parent[id] = id
+	}
+	var find func(int64) int64
+	find = func(id int64) int64 {
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, e := range edges {
+		a, b := find(e[0]), find(e[1])
+		if a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	labels := make(map[int64]int64, len(nodes))
+	for _, id := range nodes {
+		labels[id] = find(id)
+	}
+	return labels
+}
+
+// communities runs label propagation several times and keeps the result with the highest modularity.
+// Runs are seeded, so the result is the same every time for the same graph.
+func communities(nodes []int64, edges [][2]int64) map[int64]int64 {
+	var best map[int64]int64
+	bestScore := math.Inf(-1)
+	for seed := range uint64(labelPropagationRuns) {
+		labels := labelPropagation(nodes, edges, rand.New(rand.NewPCG(seed, seed)))
+		if score := modularity(labels, edges); score > bestScore {
+			best, bestScore = labels, score
+		}
+	}
+	return best
+}
+
+// labelPropagation finds communities by repeatedly giving each node the label most common
+// among its neighbors, visiting nodes in a random order, until no label changes.
+func labelPropagation(nodes []int64, edges [][2]int64, rng *rand.Rand) map[int64]int64 {
+	neighbors := map[int64][]int64{}
+	for _, e := range edges {
+		if e[0] != e[1] {
+			neighbors[e[0]] = append(neighbors[e[0]], e[1])
+			neighbors[e[1]] = append(neighbors[e[1]], e[0])
+		}
+	}
+
+	labels := make(map[int64]int64, len(nodes))
+	for _, id := range nodes {
+		labels[id] = id
+	}
+
+	order := slices.Clone(nodes)
+	for range labelPropagationMaxIterations {
+		rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+
+		changed := false
+		for _, id := range order {
+			if len(neighbors[id]) == 0 {
+				continue
+			}
+			counts := map[int64]int{}
+			for _, n := range neighbors[id] {
+				counts[labels[n]]++
+			}
+
+			// Keep the current label if it's among the most common, or else pick one at random
+			most := 0
+			for _, count := range counts {
+				most = max(most, count)
+			}
+			if counts[labels[id]] == most {
+				continue
+			}
+			var candidates []int64
+			for label, count := range counts {
+				if count == most {
+					candidates = append(candidates, label)
+				}
+			}
+			slices.Sort(candidates)
+			labels[id] = candidates[rng.IntN(len(candidates))]
+			changed = true
+		}
+		if !changed {
+			break
+		}
+	}
+	return labels
+}
+
+// modularity measures how much more densely connected nodes with the same label are than chance would
+// suggest, from about -0.5 to 1. Higher is a better division into communities.
+func modularity(labels map[int64]int64, edges [][2]int64) float64 {
+	if len(edges) == 0 {
+		return 0
+	}
+	m := float64(len(edges))
+
+	inside := map[int64]float64{}
+	degree := map[int64]float64{}
+	for _, e := range edges {
+		a, b := labels[e[0]], labels[e[1]]
+		if a == b {
+			inside[a]++
+		}
+		degree[a]++
+		degree[b]++
+	}
+
+	var q float64
+	for label, d := range degree {
+		q += inside[label]/m - (d/(2*m))*(d/(2*m))
+	}
+	return q
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestSubgraph(t *testing.T) {
+	path := t.TempDir() + "/test_subgraph.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Project X <- Alice -> Bob -> Carol, Dave -> Project X, and Erin off on her own
+	for _, rel := range [][3]string{
+		{"Alice", "Project X", "works on"},
+		{"Alice", "Bob", "knows"},
+		{"Bob", "Carol", "knows"},
+		{"Dave", "Project X", "works on"},
+		{"Erin", "Frank", "knows"},
+	} {
+		if _, err := db.AddRelationship(rel[0], rel[1], rel[2]); err != nil {
+			t.Fatalf("AddRelationship failed: %v", err)
+		}
+	}
+	if _, err := db.AddObservation("Bob", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddObservation("Carol", "Likes coffee"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	tests := []struct {
+		depth         int
+		entities      []string
+		relationships int
+		observations  int
+	}{
+		{0, []string{"Project X"}, 0, 0},
+		{1, []string{"Alice", "Dave", "Project X"}, 2, 0},
+		{2, []string{"Alice", "Bob", "Dave", "Project X"}, 3, 1},
+		{5, []string{"Alice", "Bob", "Carol", "Dave", "Project X"}, 4, 2},
+	}
+	for _, tt := range tests {
+		graph, err := db.Subgraph("Project X", tt.depth)
+		if err != nil {
+			t.Fatalf("Subgraph(depth %d) failed: %v", tt.depth, err)
+		}
+		var names []string
+		for _, e := range graph.Entities {
+			names = append(names, e.Text)
+		}
+		if !slices.Equal(names, tt.entities) {
+			t.Errorf("Subgraph(depth %d) entities = %v, want %v", tt.depth, names, tt.entities)
+		}
+		if len(graph.Relationships) != tt.relationships {
+			t.Errorf("Subgraph(depth %d) has %d relationships, want %d", tt.depth, len(graph.Relationships), tt.relationships)
+		}
+		if len(graph.Observations) != tt.observations {
+			t.Errorf("Subgraph(depth %d) has %d observations, want %d", tt.depth, len(graph.Observations), tt.observations)
+		}
+	}
+
+	if _, err := db.Subgraph("Nobody", 1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Subgraph of a missing entity error = %v, want ErrNotFound", err)
+	}
+	if _, err := db.Subgraph("Alice", -1); err == nil {
+		t.Error("Subgraph with a negative depth should fail")
+	}
+}
+
+func TestInChunks(t *testing.T) {
+	ids := make([]int64, maxIDsPerQuery*2+1)
+	for i := range ids {
+		ids[i] = int64(i)
+	}
+
+	var sizes []int
+	err := inChunks(ids, func(in string, args []interface{}) error {
+		sizes = append(sizes, len(args))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("inChunks failed: %v", err)
+	}
+	if !slices.Equal(sizes, []int{maxIDsPerQuery, maxIDsPerQuery, 1}) {
+		t.Errorf("inChunks chunk sizes = %v", sizes)
+	}
+}
+
+func TestRankEntities(t *testing.T) {
+	path := t.TempDir() + "/test_rank.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Hub has the most relationships; Carol has fewer, but they all come from Hub
+	for _, rel := range [][2]string{
+		{"Alice", "Hub"},
+		{"Bob", "Hub"},
+		{"Dave", "Hub"},
+		{"Erin", "Hub"},
+		{"Hub", "Carol"},
+	} {
+		if _, err := db.AddRelationship(rel[0], rel[1], "knows"); err != nil {
+			t.Fatalf("AddRelationship failed: %v", err)
+		}
+	}
+	if _, err := db.AddEntity("Loner"); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	ranked, err := db.RankEntities(RankByDegree, 0, false)
+	if err != nil {
+		t.Fatalf("RankEntities failed: %v", err)
+	}
+	if len(ranked) != 7 || ranked[0].Text != "Hub" || ranked[0].Degree != 5 || ranked[6].Text != "Loner" {
+		t.Errorf("RankEntities(degree) = %+v, want Hub first and Loner last", ranked)
+	}
+
+	ranked, err = db.RankEntities(RankByPageRank, 2, false)
+	if err != nil {
+		t.Fatalf("RankEntities failed: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].Text != "Carol" || ranked[1].Text != "Hub" {
+		t.Errorf("RankEntities(pagerank, 2) = %+v, want Carol, then Hub", ranked)
+	}
+
+	// Ignoring direction, Carol's one relationship counts for no more than anyone else's
+	ranked, err = db.RankEntities(RankByPageRank, 2, true)
+	if err != nil {
+		t.Fatalf("RankEntities failed: %v", err)
+	}
+	if len(ranked) != 2 || ranked[0].Text != "Hub" || ranked[1].Score >= ranked[0].Score {
+		t.Errorf("RankEntities(pagerank, 2, undirected) = %+v, want Hub first", ranked)
+	}
+
+	if _, err := db.RankEntities("popularity", 0, false); err == nil {
+		t.Error("RankEntities with an unknown method should fail")
+	}
+}
+
+func TestPageRank(t *testing.T) {
+	// A cycle ranks everyone equally
+	rank := pageRank([]int64{1, 2, 3}, [][2]int64{{1, 2}, {2, 3}, {3, 1}})
+	for id, score := range rank {
+		if math.Abs(score-1.0/3) > 1e-6 {
+			t.Errorf("pageRank of %d in a cycle = %f, want 1/3", id, score)
+		}
+	}
+
+	// Scores sum to 1, even with dangling nodes
+	rank = pageRank([]int64{1, 2, 3, 4}, [][2]int64{{1, 2}, {3, 2}})
+	var total float64
+	for _, score := range rank {
+		total += score
+	}
+	if math.Abs(total-1) > 1e-6 || rank[2] <= rank[1] {
+		t.Errorf("pageRank = %v (total %f), want 2 ranked highest and a total of 1", rank, total)
+	}
+
+	if rank := pageRank(nil, nil); len(rank) != 0 {
+		t.Errorf("pageRank of nothing = %v", rank)
+	}
+}
+
+func TestClusters(t *testing.T) {
+	path := t.TempDir() + "/test_clusters.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Two tight triangles joined by one relationship, a separate pair, and a loner
+	for _, rel := range [][2]string{
+		{"Alice", "Bob"},
+		{"Bob", "Carol"},
+		{"Carol", "Alice"},
+		{"Dave", "Erin"},
+		{"Erin", "Frank"},
+		{"Frank", "Dave"},
+		{"Carol", "Dave"},
+		{"Xavier", "Yolanda"},
+	} {
+		if _, err := db.AddRelationship(rel[0], rel[1], "knows"); err != nil {
+			t.Fatalf("AddRelationship failed: %v", err)
+		}
+	}
+	if _, err := db.AddEntity("Zed"); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	texts := func(clusters [][]Entity) [][]string {
+		var result [][]string
+		for _, c := range clusters {
+			var names []string
+			for _, e := range c {
+				names = append(names, e.Text)
+			}
+			result = append(result, names)
+		}
+		return result
+	}
+
+	tests := []struct {
+		method string
+		want   [][]string
+	}{
+		{ClusterByComponents, [][]string{
+			{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank"},
+			{"Xavier", "Yolanda"},
+			{"Zed"},
+		}},
+		{ClusterByCommunities, [][]string{
+			{"Alice", "Bob", "Carol"},
+			{"Dave", "Erin", "Frank"},
+			{"Xavier", "Yolanda"},
+			{"Zed"},
+		}},
+	}
+	for _, tt := range tests {
+		clusters, err := db.Clusters(tt.method)
+		if err != nil {
+			t.Fatalf("Clusters(%s) failed: %v", tt.method, err)
+		}
+		if got := texts(clusters); !slices.EqualFunc(got, tt.want, slices.Equal) {
+			t.Errorf("Clusters(%s) = %v, want %v", tt.method, got, tt.want)
+		}
+	}
+
+	if _, err := db.Clusters("kmeans"); err == nil {
+		t.Error("Clusters with an unknown method should fail")
+	}
+}
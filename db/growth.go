@@ -0,0 +1,40 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// growthFormats are the strftime formats naming each period Growth can group by.
+var growthFormats = map[string]string{
+	"day":   "%Y-%m-%d",
+	"week":  "%Y-W%W",
+	"month": "%Y-%m",
+	"year":  "%Y",
+}
+
+// GrowthPeriod counts the records added in one period.
+type GrowthPeriod struct {
+	Period        string `json:"period"`
+	Observations  int    `json:"observations"`
+	Relationships int    `json:"relationships"`
+}
+
+// Growth counts the observations and relationships added in each day, week, month, or
+// year, as by says, oldest first. Counts come from the records' timestamps, so deleted
+// records aren't counted, and periods when nothing still stored was added are left out.
+func (db *DB) Growth(by string) ([]GrowthPeriod, error) {
+	format, ok := growthFormats[by]
+	if !ok {
+		return nil, fmt.Errorf("unknown period %q: use day, week, month, or year", by)
+	}
+
+	query := `SELECT period, SUM(observations), SUM(relationships) FROM (
+			SELECT strftime(?1, timestamp) AS period, 1 AS observations, 0 AS relationships FROM observations
+			UNION ALL
+			SELECT strftime(?1, timestamp), 0, 1 FROM relationships
+		) GROUP BY period ORDER BY period`
+	return collect(scanRows(db, query, []interface{}{format}, nil, "growth", func(rows *sql.Rows, p *GrowthPeriod) error {
+		return rows.Scan(&p.Period, &p.Observations, &p.Relationships)
+	}))
+}
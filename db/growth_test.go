@@ -0,0 +1,46 @@
+package db
+
+import "testing"
+
+func TestGrowth(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_growth.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	first, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Alice", "Lives in Oslo")
+	rel, _ := db.AddRelationship("Alice", "Bob", "knows")
+	_, _ = db.AddRelationship("Alice", "Carol", "knows")
+	if _, err := db.exec("UPDATE observations SET timestamp = '2025-01-15 10:00:00' WHERE id = ?", first); err != nil {
+		t.Fatalf("Failed to age observation: %v", err)
+	}
+	if _, err := db.exec("UPDATE relationships SET timestamp = '2025-03-01 09:00:00' WHERE id = ?", rel); err != nil {
+		t.Fatalf("Failed to age relationship: %v", err)
+	}
+
+	periods, err := db.Growth("month")
+	if err != nil {
+		t.Fatalf("Growth failed: %v", err)
+	}
+	if len(periods) != 3 {
+		t.Fatalf("Expected 3 months, got %+v", periods)
+	}
+	if periods[0] != (GrowthPeriod{Period: "2025-01", Observations: 1}) {
+		t.Errorf("Unexpected first month: %+v", periods[0])
+	}
+	if periods[1] != (GrowthPeriod{Period: "2025-03", Relationships: 1}) {
+		t.Errorf("Unexpected second month: %+v", periods[1])
+	}
+	if periods[2].Observations != 1 || periods[2].Relationships != 1 {
+		t.Errorf("Expected this month's observation and relationship, got %+v", periods[2])
+	}
+
+	if years, _ := db.Growth("year"); len(years) == 0 || years[0].Period != "2025" {
+		t.Errorf("Unexpected years: %+v", years)
+	}
+	if _, err := db.Growth("decade"); err == nil {
+		t.Error("Expected error for an unknown period")
+	}
+}
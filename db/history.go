@@ -0,0 +1,162 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// maxHistoryBatches is how many writes can be undone; older history is pruned.
+const maxHistoryBatches = 100
+
+// ErrNothingToUndo is returned by Undo when there's no recorded change left to revert.
+var ErrNothingToUndo = errors.New("nothing to undo")
+
+// Change is one row change recorded in the history.
+type Change struct {
+	Table       string `json:"table"`
+	RowID       int64  `json:"row_id"`
+	Description string `json:"description"`
+}
+
+// Batch is the set of changes made by one write.
+type Batch struct {
+	ID        int64    `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Changes   []Change `json:"changes"`
+}
+
+// startHistoryBatch begins a new history batch, so the changes made by the
+// next write can be undone together. Called by Locked before each write.
+func (db *DB) startHistoryBatch() error {
+	if !db.history {
+		return nil
+	}
+	result, err := db.exec("INSERT INTO history_batches DEFAULT VALUES")
+	if err != nil {
+		return fmt.Errorf("failed to start history batch: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to start history batch: %w", err)
+	}
+
+	if id%10 == 0 {
+		cutoff := id - maxHistoryBatches
+		if _, err := db.exec("DELETE FROM history WHERE batch <= ?", cutoff); err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+		if _, err := db.exec("DELETE FROM history_batches WHERE id <= ?", cutoff); err != nil {
+			return fmt.Errorf("failed to prune history: %w", err)
+		}
+	}
+	return nil
+}
+
+// LastBatch returns the most recent write that Undo would revert.
+func (db *DB) LastBatch() (*Batch, error) {
+	var batchID int64
+	if err := db.queryRow("SELECT COALESCE(MAX(batch), 0) FROM history").Scan(&batchID); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	if batchID == 0 {
+		return nil, ErrNothingToUndo
+	}
+
+	batch := &Batch{ID: batchID}
+	err := db.queryRow("SELECT COALESCE((SELECT timestamp FROM history_batches WHERE id = ?), '')", batchID).Scan(&batch.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	rows, err := db.query("SELECT tbl, row_id, description FROM history WHERE batch = ? ORDER BY id", batchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var c Change
+		if err := rows.Scan(&c.Table, &c.RowID, &c.Description); err != nil {
+			return nil, fmt.Errorf("failed to scan history: %w", err)
+		}
+		batch.Changes = append(batch.Changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	return batch, nil
+}
+
+// Undo reverts the most recent write by running its recorded inverse
+// statements, newest first, in one transaction. An undo can't itself be undone.
+// Should be run under Locked.
+func (db *DB) Undo() (*Batch, error) {
+	batch, err := db.LastBatch()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	// Reinserted rows may be restored before the entities they reference
+	if _, err := tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+		return nil, fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT undo_sql FROM history WHERE batch = ? ORDER BY id DESC", batch.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+	var statements []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan history: %w", err)
+		}
+		statements = append(statements, stmt)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("failed to undo change: %w", err)
+		}
+	}
+
+	// Forget the undone batch and the changes the undo itself just recorded
+	if _, err := tx.Exec("DELETE FROM history WHERE batch >= ?", batch.ID); err != nil {
+		return nil, fmt.Errorf("failed to update history: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit undo: %w", err)
+	}
+
+	// Restored and reverted observations need their trigrams back
+	for _, c := range batch.Changes {
+		if c.Table != "observations" {
+			continue
+		}
+		var text string
+		err := db.queryRow("SELECT text FROM observations WHERE id = ?", c.RowID).Scan(&text)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			return batch, fmt.Errorf("failed to read observation %d: %w", c.RowID, err)
+		}
+		if err := db.indexObservation(c.RowID, text); err != nil {
+			return batch, err
+		}
+	}
+
+	return batch, nil
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUndo(t *testing.T) {
+	path := t.TempDir() + "/test_undo.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("Undo on a new database error = %v, want ErrNothingToUndo", err)
+	}
+
+	write := func(fn func() error) {
+		t.Helper()
+		if err := db.Locked(fn); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	undo := func() *Batch {
+		t.Helper()
+		var batch *Batch
+		write(func() error {
+			var err error
+			batch, err = db.Undo()
+			return err
+		})
+		return batch
+	}
+
+	var obsID int64
+	write(func() error {
+		var err error
+		obsID, err = db.AddObservation("Alice", "Likes tea")
+		return err
+	})
+	write(func() error {
+		_, err := db.AddRelationship("Alice", "Bob", "knows")
+		return err
+	})
+	write(func() error { return db.UpdateObservation(obsID, "Likes coffee") })
+	original, err := db.SearchObservations("Alice", nil, false)
+	if err != nil || len(original) != 1 {
+		t.Fatalf("SearchObservations = %v, %v", original, err)
+	}
+	write(func() error { return db.DeleteEntityByText("Alice") })
+
+	// Undoing the delete restores the entity and everything that cascaded
+	batch := undo()
+	if len(batch.Changes) != 3 {
+		t.Errorf("Undo reverted %d changes, want 3 (entity, observation, relationship): %+v", len(batch.Changes), batch.Changes)
+	}
+	restored, err := db.SearchObservations("Alice", nil, false)
+	if err != nil || len(restored) != 1 || restored[0] != original[0] {
+		t.Errorf("After undoing delete got %+v, want %+v (err %v)", restored, original, err)
+	}
+	if rels, _ := db.SearchRelationships("Alice", "Bob", "knows", nil, false); len(rels) != 1 {
+		t.Errorf("After undoing delete got %d relationships, want 1", len(rels))
+	}
+
+	// Then the edit
+	undo()
+	restored, _ = db.SearchObservations("Alice", nil, false)
+	if len(restored) != 1 || restored[0].Text != "Likes tea" {
+		t.Errorf("After undoing edit got %+v, want text %q", restored, "Likes tea")
+	}
+
+	// Then the relationship, including the entity it created
+	undo()
+	if entities, _ := db.SearchEntities([]string{"Bob"}, false); len(entities) != 0 {
+		t.Errorf("After undoing relationship, Bob should be gone, got %+v", entities)
+	}
+
+	// Then the first add
+	undo()
+	if count, _ := db.CountEntities(); count != 0 {
+		t.Errorf("After undoing everything got %d entities, want 0", count)
+	}
+
+	if _, err := db.Undo(); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Undo with no history left error = %v, want ErrNothingToUndo", err)
+	}
+}
+
+func TestUndoReindexesTrigrams(t *testing.T) {
+	path := t.TempDir() + "/test_undo_trigram.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var id int64
+	if err := db.Locked(func() error {
+		id, err = db.AddObservation("Alice", "Enjoys mountaineering")
+		return err
+	}); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.BuildTrigramIndex(); err != nil {
+		t.Fatalf("BuildTrigramIndex failed: %v", err)
+	}
+	if err := db.Locked(func() error { return db.DeleteObservation(id) }); err != nil {
+		t.Fatalf("DeleteObservation failed: %v", err)
+	}
+	if err := db.Locked(func() error { _, err := db.Undo(); return err }); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+
+	observations, err := db.SearchObservations("", []string{"mountain"}, false)
+	if err != nil || len(observations) != 1 {
+		t.Errorf("Search after undoing delete = %+v, %v; want the restored observation", observations, err)
+	}
+}
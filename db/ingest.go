@@ -0,0 +1,31 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// IngestedHash returns the SHA-256 the file at path had when memories were last extracted
+// from it, recorded with SetIngested, or "" if they never were.
+func (db *DB) IngestedHash(path string) (string, error) {
+	var hash string
+	err := db.queryRow("SELECT sha256 FROM ingested_files WHERE path = ?", path).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get ingested file: %w", err)
+	}
+	return hash, nil
+}
+
+// SetIngested records that memories were extracted from the file at path when its SHA-256 was hash.
+func (db *DB) SetIngested(path, hash string) error {
+	_, err := db.exec(`INSERT INTO ingested_files (path, sha256) VALUES (?, ?)
+		ON CONFLICT (path) DO UPDATE SET sha256 = excluded.sha256, ingested_at = CURRENT_TIMESTAMP`, path, hash)
+	if err != nil {
+		return fmt.Errorf("failed to record ingested file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package db
+
+import "testing"
+
+func TestIngested(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_ingested.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if hash, err := db.IngestedHash("/logs/a.jsonl"); err != nil || hash != "" {
+		t.Errorf("IngestedHash = %q, %v; want none", hash, err)
+	}
+	if err := db.SetIngested("/logs/a.jsonl", "abc"); err != nil {
+		t.Fatalf("SetIngested failed: %v", err)
+	}
+	if err := db.SetIngested("/logs/a.jsonl", "def"); err != nil {
+		t.Fatalf("SetIngested failed to replace: %v", err)
+	}
+	if hash, _ := db.IngestedHash("/logs/a.jsonl"); hash != "def" {
+		t.Errorf("IngestedHash = %q, want def", hash)
+	}
+}
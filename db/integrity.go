@@ -0,0 +1,47 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCorrupt is returned when Options.CheckIntegrity finds pages of the database damaged.
+var ErrCorrupt = errors.New("database is damaged")
+
+// maxIntegrityProblems limits how many of the problems an integrity check finds are reported.
+const maxIntegrityProblems = 3
+
+// checkIntegrity verifies every page of the database on conn against its HMAC with
+// SQLCipher's cipher_integrity_check, returning an error wrapping ErrCorrupt if any fail.
+// Damage otherwise goes unnoticed until a query happens to read a bad page, by which
+// time the snapshots from before it may have been pruned.
+func checkIntegrity(conn *sql.DB, path string) error {
+	rows, err := conn.Query("PRAGMA cipher_integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var problems []string
+	for rows.Next() {
+		var problem string
+		if err := rows.Scan(&problem); err != nil {
+			return fmt.Errorf("failed to check integrity: %w", err)
+		}
+		problems = append(problems, problem)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check integrity: %w", err)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+
+	found := strings.Join(problems[:min(len(problems), maxIntegrityProblems)], "; ")
+	if len(problems) > maxIntegrityProblems {
+		found += fmt.Sprintf("; and %d more", len(problems)-maxIntegrityProblems)
+	}
+	return fmt.Errorf("%w: %s (%s)", ErrCorrupt, path, found)
+}
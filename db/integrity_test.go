@@ -0,0 +1,51 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckIntegrity(t *testing.T) {
+	path := t.TempDir() + "/test_integrity.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if _, err := db.AddObservation("Alice", strings.Repeat("Likes tea. ", 20)); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	_ = db.Close()
+
+	opts := DefaultOptions()
+	opts.CheckIntegrity = true
+	healthy, err := OpenWithOptions(path, key, opts)
+	if err != nil {
+		t.Fatalf("Expected a healthy database to pass its integrity check, got %v", err)
+	}
+	_ = healthy.Close()
+
+	// Damage a page past the header, which opening alone never reads
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[3*4096+100] ^= 0xff
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := OpenWithOptions(path, key, opts); !errors.Is(err, ErrCorrupt) || !strings.Contains(err.Error(), "page 4") {
+		t.Errorf("Expected ErrCorrupt naming page 4, got %v", err)
+	}
+	damaged, err := Open(path, key)
+	if err != nil {
+		t.Fatalf("Expected the damaged database to open without the check, got %v", err)
+	}
+	_ = damaged.Close()
+}
@@ -0,0 +1,83 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"iter"
+	"time"
+)
+
+// scanRows returns an iterator over the rows of query, scanning each with scan.
+// The query runs when iteration starts, and rows are read one at a time, so
+// large result sets are never held in memory. A non-nil buildErr (from building
+// the query) is yielded instead of running it. what names the rows in errors.
+func scanRows[T any](db *DB, query string, args []interface{}, buildErr error, what string, scan func(*sql.Rows, *T) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var zero T
+		if buildErr != nil {
+			yield(zero, buildErr)
+			return
+		}
+
+		count := 0
+		if db.explain != nil {
+			db.explainQuery(what, query, args)
+			start := time.Now()
+			defer func() { db.explainTime(fmt.Sprintf("reading %d %s", count, what), start) }()
+		}
+
+		rows, err := db.query(query, args...)
+		if err != nil {
+			yield(zero, fmt.Errorf("failed to search %s: %w", what, err))
+			return
+		}
+		defer func() { _ = rows.Close() }()
+
+		for rows.Next() {
+			var v T
+			if err := scan(rows, &v); err != nil {
+				yield(zero, fmt.Errorf("failed to scan %s: %w", what, err))
+				return
+			}
+			count++
+			if !yield(v, nil) {
+				return
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			yield(zero, fmt.Errorf("failed to read %s: %w", what, err))
+		}
+	}
+}
+
+// countQuery returns how many rows query would return.
+func (db *DB) countQuery(query string, args []interface{}, buildErr error, what string) (int, error) {
+	if buildErr != nil {
+		return 0, buildErr
+	}
+
+	query = "SELECT COUNT(*) FROM (" + query + ")"
+	if db.explain != nil {
+		db.explainQuery("counting "+what, query, args)
+		defer db.explainTime("counting "+what, time.Now())
+	}
+
+	var count int
+	if err := db.queryRow(query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s: %w", what, err)
+	}
+	return count, nil
+}
+
+// collect reads every value from seq, stopping at the first error.
+func collect[T any](seq iter.Seq2[T, error]) ([]T, error) {
+	var results []T
+	for v, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, v)
+	}
+	return results, nil
+}
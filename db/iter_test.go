@@ -0,0 +1,67 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestSearchIter(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_iter.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, text := range []string{"Likes tea", "Likes coffee", "Reads books"} {
+		if _, err := db.AddObservation("Alice", text); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	_, _ = db.AddRelationship("Alice", "Bob", "knows")
+
+	count, err := db.CountSearchObservations("Alice", []string{"likes"}, true)
+	if err != nil {
+		t.Fatalf("CountSearchObservations failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+
+	var texts []string
+	for o, err := range db.SearchObservationsIter("Alice", []string{"likes"}, true) {
+		if err != nil {
+			t.Fatalf("SearchObservationsIter failed: %v", err)
+		}
+		texts = append(texts, o.Text)
+	}
+	if len(texts) != count {
+		t.Errorf("Expected %d observations from iterator, got %v", count, texts)
+	}
+
+	// Stopping early closes the query
+	seen := 0
+	for _, err := range db.SearchObservationsIter("", nil, true) {
+		if err != nil {
+			t.Fatalf("SearchObservationsIter failed: %v", err)
+		}
+		seen++
+		break
+	}
+	if seen != 1 {
+		t.Errorf("Expected to stop after 1 observation, got %d", seen)
+	}
+	if _, err := db.AddObservation("Alice", "Writes code"); err != nil {
+		t.Errorf("Write after stopping iteration failed: %v", err)
+	}
+
+	if count, _ := db.CountSearchEntities([]string{"ali", "bob"}, true); count != 2 {
+		t.Errorf("Expected 2 entities, got %d", count)
+	}
+	if count, _ := db.CountSearchRelationships("", "", "knows", nil, true); count != 1 {
+		t.Errorf("Expected 1 relationship, got %d", count)
+	}
+	for r, err := range db.SearchRelationshipsIter("", "", "knows", nil, true) {
+		if err != nil || r.FromText != "Alice" || r.ToText != "Bob" {
+			t.Errorf("Unexpected relationship %+v, err %v", r, err)
+		}
+	}
+}
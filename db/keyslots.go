@@ -0,0 +1,323 @@
+package db
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultKeySlot names the slot holding a database's original key when key slots are enabled.
+const DefaultKeySlot = "default"
+
+// keySlotIterations is the PBKDF2 work factor for new slots (lowered in tests).
+var keySlotIterations = 600_000
+
+// ErrNoKeySlot is returned when a key doesn't unlock any of a database's key slots.
+var ErrNoKeySlot = errors.New("key does not match any key slot")
+
+// keySlot holds the database's data key encrypted with a key derived from one passphrase.
+type keySlot struct {
+	Name       string `json:"name"`
+	Salt       []byte `json:"salt"`
+	Iterations int    `json:"iterations"`
+	Nonce      []byte `json:"nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// keySlotFile is the key slots file stored next to a database.
+type keySlotFile struct {
+	Slots []keySlot `json:"slots"`
+}
+
+// KeySlotsPath returns the key slots file for a database.
+// It only exists once key slots are enabled, and must be kept (and backed up) with the database.
+func KeySlotsPath(dbPath string) string {
+	return dbPath + ".keys"
+}
+
+// readKeySlots reads a database's key slots, returning nil if it doesn't use them.
+func readKeySlots(dbPath string) (*keySlotFile, error) {
+	data, err := os.ReadFile(KeySlotsPath(dbPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read key slots: %w", err)
+	}
+
+	var f keySlotFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse key slots: %w", err)
+	}
+	return &f, nil
+}
+
+// writeKeySlotsTemp writes slots to a temporary file beside the key slots file
+// and returns its path, to be renamed into place once it's safe to.
+func writeKeySlotsTemp(dbPath string, f *keySlotFile) (string, error) {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal key slots: %w", err)
+	}
+
+	path := KeySlotsPath(dbPath)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create key slots file: %w", err)
+	}
+	_, werr := tmp.Write(data)
+	cerr := tmp.Close()
+	if werr != nil || cerr != nil {
+		_ = os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write key slots: %w", errors.Join(werr, cerr))
+	}
+	return tmp.Name(), nil
+}
+
+// writeKeySlots atomically replaces a database's key slots.
+func writeKeySlots(dbPath string, f *keySlotFile) error {
+	tmp, err := writeKeySlotsTemp(dbPath, f)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, KeySlotsPath(dbPath)); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to save key slots: %w", err)
+	}
+	return nil
+}
+
+// copyKeySlots copies the key slots of the database at dbPath, if it has them, to go with
+// its copy at path.
+func copyKeySlots(dbPath, path string) error {
+	data, err := os.ReadFile(KeySlotsPath(dbPath))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("failed to read key slots: %w", err)
+	}
+	if err := os.WriteFile(KeySlotsPath(path), data, 0o600); err != nil {
+		return fmt.Errorf("failed to copy key slots: %w", err)
+	}
+	return nil
+}
+
+// removeKeySlots deletes the key slots of the database copy at path, if it has them.
+func removeKeySlots(path string) error {
+	err := os.Remove(KeySlotsPath(path))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove key slots: %w", err)
+	}
+	return nil
+}
+
+// slotCipher derives the AEAD that wraps the data key for passphrase.
+func slotCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	kek, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// wrapKey creates a slot named name that unlocks dataKey with passphrase.
+func wrapKey(name, passphrase, dataKey string) (keySlot, error) {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+
+	aead, err := slotCipher(passphrase, salt, keySlotIterations)
+	if err != nil {
+		return keySlot{}, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+
+	return keySlot{
+		Name:       name,
+		Salt:       salt,
+		Iterations: keySlotIterations,
+		Nonce:      nonce,
+		// The name is authenticated so a slot can't be passed off as another
+		WrappedKey: aead.Seal(nil, nonce, []byte(dataKey), []byte(name)),
+	}, nil
+}
+
+// unwrap returns the data key if passphrase opens the slot.
+func (s keySlot) unwrap(passphrase string) (string, bool) {
+	aead, err := slotCipher(passphrase, s.Salt, s.Iterations)
+	if err != nil || len(s.Nonce) != aead.NonceSize() {
+		return "", false
+	}
+	dataKey, err := aead.Open(nil, s.Nonce, s.WrappedKey, []byte(s.Name))
+	if err != nil {
+		return "", false
+	}
+	return string(dataKey), true
+}
+
+// unlockKey returns the key that decrypts the database at path given the user's key,
+// and the slot it unlocked. Databases without key slots are decrypted by key itself.
+func unlockKey(path, key string) (string, string, error) {
+	f, err := readKeySlots(path)
+	if err != nil || f == nil {
+		return key, "", err
+	}
+
+	for _, s := range f.Slots {
+		if dataKey, ok := s.unwrap(key); ok {
+			return dataKey, s.Name, nil
+		}
+	}
+	return "", "", ErrNoKeySlot
+}
+
+// KeySlots returns the names of the database's key slots, or nil if it doesn't use them.
+func (db *DB) KeySlots() ([]string, error) {
+	f, err := readKeySlots(db.path)
+	if err != nil || f == nil {
+		return nil, err
+	}
+
+	names := make([]string, len(f.Slots))
+	for i, s := range f.Slots {
+		names[i] = s.Name
+	}
+	return names, nil
+}
+
+// KeySlot returns the name of the slot the database was unlocked with, or "" without key slots.
+func (db *DB) KeySlot() string {
+	return db.slot
+}
+
+// AddKeySlot lets passphrase unlock the database as slot name.
+// The first slot added enables key slots: the database is re-encrypted once with
+// a random data key, and the key it was opened with becomes the DefaultKeySlot slot.
+// Should be run under Locked.
+func (db *DB) AddKeySlot(name, passphrase string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("key slot name cannot be empty")
+	}
+	if passphrase == "" {
+		return fmt.Errorf("key cannot be empty")
+	}
+
+	f, err := readKeySlots(db.path)
+	if err != nil {
+		return err
+	}
+	if f != nil {
+		if slices.ContainsFunc(f.Slots, func(s keySlot) bool { return s.Name == name }) {
+			return fmt.Errorf("key slot %q already exists", name)
+		}
+		slot, err := wrapKey(name, passphrase, db.key)
+		if err != nil {
+			return err
+		}
+		f.Slots = append(f.Slots, slot)
+		return writeKeySlots(db.path, f)
+	}
+
+	if name == DefaultKeySlot {
+		return fmt.Errorf("key slot name %q is reserved for the current key", DefaultKeySlot)
+	}
+	return db.enableKeySlots(name, passphrase)
+}
+
+// enableKeySlots moves the database to a random data key wrapped by its current
+// key and passphrase.
+func (db *DB) enableKeySlots(name, passphrase string) error {
+	raw := make([]byte, 32)
+	_, _ = rand.Read(raw)
+	dataKey := hex.EncodeToString(raw)
+
+	current, err := wrapKey(DefaultKeySlot, db.key, dataKey)
+	if err != nil {
+		return err
+	}
+	added, err := wrapKey(name, passphrase, dataKey)
+	if err != nil {
+		return err
+	}
+
+	// Stage the slots before re-encrypting so the data key can't be lost
+	tmp, err := writeKeySlotsTemp(db.path, &keySlotFile{Slots: []keySlot{current, added}})
+	if err != nil {
+		return err
+	}
+	oldKey := db.key
+	if err := db.Rekey(dataKey); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, KeySlotsPath(db.path)); err != nil {
+		_ = os.Remove(tmp)
+		if rerr := db.Rekey(oldKey); rerr != nil {
+			return fmt.Errorf("failed to save key slots (%v) and to restore the original key; the database key is now %s: %w", err, dataKey, rerr)
+		}
+		return fmt.Errorf("failed to save key slots: %w", err)
+	}
+	db.slot = DefaultKeySlot
+	return nil
+}
+
+// RemoveKeySlot revokes slot name's key. The last slot can't be removed.
+// Should be run under Locked.
+func (db *DB) RemoveKeySlot(name string) error {
+	f, err := readKeySlots(db.path)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return fmt.Errorf("database has no key slots")
+	}
+
+	i := slices.IndexFunc(f.Slots, func(s keySlot) bool { return s.Name == name })
+	if i < 0 {
+		return fmt.Errorf("key slot %q not found", name)
+	}
+	if len(f.Slots) == 1 {
+		return fmt.Errorf("cannot remove the last key slot")
+	}
+	f.Slots = slices.Delete(f.Slots, i, i+1)
+	return writeKeySlots(db.path, f)
+}
+
+// changeSlotKey rewraps the data key in the slot the database was unlocked
+// with, so newKey replaces that slot's key without re-encrypting the database.
+func (db *DB) changeSlotKey(newKey string) error {
+	f, err := readKeySlots(db.path)
+	if err != nil {
+		return err
+	}
+	if f == nil {
+		return fmt.Errorf("database has no key slots")
+	}
+
+	i := slices.IndexFunc(f.Slots, func(s keySlot) bool { return s.Name == db.slot })
+	if i < 0 {
+		return fmt.Errorf("key slot %q not found", db.slot)
+	}
+	slot, err := wrapKey(db.slot, newKey, db.key)
+	if err != nil {
+		return err
+	}
+	f.Slots[i] = slot
+	return writeKeySlots(db.path, f)
+}
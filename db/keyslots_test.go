@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"testing"
+)
+
+// fastKeySlots lowers the key derivation work factor for the test
+func fastKeySlots(t *testing.T) {
+	t.Helper()
+	old := keySlotIterations
+	keySlotIterations = 1000
+	t.Cleanup(func() { keySlotIterations = old })
+}
+
+func TestKeySlots(t *testing.T) {
+	fastKeySlots(t)
+	path := t.TempDir() + "/test_keyslots.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	if _, err := db.AddEntity("Alice"); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	if slots, err := db.KeySlots(); err != nil || slots != nil {
+		t.Fatalf("KeySlots() = %v, %v; want none before enabling", slots, err)
+	}
+	if err := db.AddKeySlot(DefaultKeySlot, "bobs-key"); err == nil {
+		t.Error("AddKeySlot(DefaultKeySlot) should fail when enabling key slots")
+	}
+	if err := db.AddKeySlot("bob", "bobs-key"); err != nil {
+		t.Fatalf("AddKeySlot failed: %v", err)
+	}
+	if err := db.AddKeySlot("carol", "carols-key"); err != nil {
+		t.Fatalf("AddKeySlot failed: %v", err)
+	}
+	if err := db.AddKeySlot("bob", "other"); err == nil {
+		t.Error("AddKeySlot with an existing name should fail")
+	}
+
+	// Still usable after re-encrypting with the data key
+	if _, err := db.AddEntity("Bob"); err != nil {
+		t.Fatalf("AddEntity after enabling key slots failed: %v", err)
+	}
+	_ = db.Close()
+
+	// Each key opens the database
+	for _, k := range []string{key, "bobs-key", "carols-key"} {
+		db, err := Open(path, k)
+		if err != nil {
+			t.Fatalf("Open with %q failed: %v", k, err)
+		}
+		entities, err := db.SearchEntities(nil, false)
+		if err != nil || len(entities) != 2 {
+			t.Errorf("SearchEntities with %q = %d entities, %v; want 2", k, len(entities), err)
+		}
+		_ = db.Close()
+	}
+
+	if _, err := Open(path, "wrong-key"); !errors.Is(err, ErrNoKeySlot) {
+		t.Errorf("Open with an unknown key error = %v, want ErrNoKeySlot", err)
+	}
+
+	// Revoking bob's slot locks bob out without affecting the others
+	db, err = Open(path, "carols-key")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if db.KeySlot() != "carol" {
+		t.Errorf("KeySlot() = %q, want %q", db.KeySlot(), "carol")
+	}
+	if err := db.RemoveKeySlot("bob"); err != nil {
+		t.Fatalf("RemoveKeySlot failed: %v", err)
+	}
+	if err := db.RemoveKeySlot("bob"); err == nil {
+		t.Error("RemoveKeySlot of a missing slot should fail")
+	}
+	slots, err := db.KeySlots()
+	if err != nil || !slices.Equal(slots, []string{DefaultKeySlot, "carol"}) {
+		t.Errorf("KeySlots() = %v, %v; want [default carol]", slots, err)
+	}
+	_ = db.Close()
+
+	if _, err := Open(path, "bobs-key"); !errors.Is(err, ErrNoKeySlot) {
+		t.Errorf("Open with a revoked key error = %v, want ErrNoKeySlot", err)
+	}
+	if db, err := Open(path, key); err != nil {
+		t.Errorf("Open with the default key after revoking bob failed: %v", err)
+	} else {
+		_ = db.Close()
+	}
+}
+
+func TestKeySlotsRekey(t *testing.T) {
+	fastKeySlots(t)
+	path := t.TempDir() + "/test_keyslots_rekey.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	if err := db.AddKeySlot("bob", "bobs-key"); err != nil {
+		t.Fatalf("AddKeySlot failed: %v", err)
+	}
+	_ = db.Close()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Rekeying bob's slot changes only bob's key, without re-encrypting
+	db, err = Open(path, "bobs-key")
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if err := db.Rekey("bobs-new-key"); err != nil {
+		t.Fatalf("Rekey failed: %v", err)
+	}
+	_ = db.Close()
+
+	after, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(info.ModTime()) {
+		t.Error("Rekey with key slots should not rewrite the database")
+	}
+
+	if _, err := Open(path, "bobs-key"); !errors.Is(err, ErrNoKeySlot) {
+		t.Errorf("Open with bob's old key error = %v, want ErrNoKeySlot", err)
+	}
+	for _, k := range []string{key, "bobs-new-key"} {
+		db, err := Open(path, k)
+		if err != nil {
+			t.Fatalf("Open with %q failed: %v", k, err)
+		}
+		_ = db.Close()
+	}
+}
+
+func TestRemoveLastKeySlot(t *testing.T) {
+	fastKeySlots(t)
+	path := t.TempDir() + "/test_keyslots_last.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.RemoveKeySlot(DefaultKeySlot); err == nil {
+		t.Error("RemoveKeySlot without key slots should fail")
+	}
+	if err := db.AddKeySlot("bob", "bobs-key"); err != nil {
+		t.Fatalf("AddKeySlot failed: %v", err)
+	}
+	if err := db.RemoveKeySlot(DefaultKeySlot); err != nil {
+		t.Fatalf("RemoveKeySlot failed: %v", err)
+	}
+	if err := db.RemoveKeySlot("bob"); err == nil {
+		t.Error("RemoveKeySlot of the last slot should fail")
+	}
+}
@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"amem/lang"
+)
+
+// LangAuto as an observation's language detects it from the observation's text.
+const LangAuto = "auto"
+
+// validLang matches ISO 639-1 codes, and the three-letter ISO 639-3 codes of languages without one.
+var validLang = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// normalizeLang returns the language code code in lowercase, or an error if it isn't one.
+// The empty code, for an unknown language, is returned as it is.
+func normalizeLang(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code != "" && !validLang.MatchString(code) {
+		return "", fmt.Errorf("invalid language %q: use an ISO 639-1 code like en or de", code)
+	}
+	return code, nil
+}
+
+// observationLang returns the language code for an observation with text: code itself, or
+// the language detected in text if code is LangAuto.
+func observationLang(code, text string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(code), LangAuto) {
+		return lang.Detect(text), nil
+	}
+	return normalizeLang(code)
+}
+
+// langArg returns code as a lang column value, or nil for an unknown language.
+func langArg(code string) interface{} {
+	if code == "" {
+		return nil
+	}
+	return code
+}
+
+// SetLang has observation searches return only observations in the language code, like
+// "de". The empty code returns observations in every language, and those without one.
+func (db *DB) SetLang(code string) error {
+	code, err := normalizeLang(code)
+	if err != nil {
+		return err
+	}
+	db.lang = code
+	return nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import "testing"
+
+func TestObservationLang(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_lang.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	add := func(text, lang string) int64 {
+		t.Helper()
+		id, err := db.AddObservationWithOptions("Alice", text, ObservationOptions{Lang: lang})
+		if err != nil {
+			t.Fatalf("AddObservationWithOptions(%q, %q) failed: %v", text, lang, err)
+		}
+		return id
+	}
+	tagged := add("Trinkt gern Tee", "DE")
+	detected := add("Sie ist nach Berlin gezogen und sucht eine Wohnung", LangAuto)
+	untagged := add("Likes tea", "")
+	unsure := add("Likes tea", LangAuto)
+
+	for id, want := range map[int64]string{tagged: "de", detected: "de", untagged: "", unsure: ""} {
+		o, err := db.GetObservation(id)
+		if err != nil {
+			t.Fatalf("GetObservation failed: %v", err)
+		}
+		if o.Lang != want {
+			t.Errorf("Observation %q has language %q, want %q", o.Text, o.Lang, want)
+		}
+	}
+
+	if _, err := db.AddObservationWithOptions("Alice", "Likes tea", ObservationOptions{Lang: "english"}); err == nil {
+		t.Error("Expected an error adding an observation with an invalid language")
+	}
+
+	if err := db.SetLang("de"); err != nil {
+		t.Fatalf("SetLang failed: %v", err)
+	}
+	observations, err := db.SearchObservations("Alice", nil, false)
+	if err != nil || len(observations) != 2 {
+		t.Errorf("Expected the 2 German observations, got %+v (err %v)", observations, err)
+	}
+	if err := db.SetLang("Deutsch!"); err == nil {
+		t.Error("Expected an error filtering by an invalid language")
+	}
+	if err := db.SetLang(""); err != nil {
+		t.Fatalf("SetLang failed: %v", err)
+	}
+	if count, _ := db.CountSearchObservations("Alice", nil, false); count != 4 {
+		t.Errorf("Expected every observation without a language filter, got %d", count)
+	}
+
+	// Undoing a delete restores the language
+	if err := db.Locked(func() error { return db.DeleteObservation(tagged) }); err != nil {
+		t.Fatalf("DeleteObservation failed: %v", err)
+	}
+	if err := db.Locked(func() error { _, err := db.Undo(); return err }); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if o, err := db.GetObservation(tagged); err != nil || o.Lang != "de" {
+		t.Errorf("Expected the restored observation to keep its language, got %+v (err %v)", o, err)
+	}
+}
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLockTimeout is how long writers wait for another process's write lock.
+const DefaultLockTimeout = 5 * time.Second
+
+// staleEmptyLock is how old an empty lock file must be before it's treated as
+// abandoned by a process that crashed before writing its PID.
+const staleEmptyLock = 10 * time.Second
+
+// LockedError is returned when another process holds the write lock.
+type LockedError struct {
+	Path string
+	PID  int
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("database is locked by another process (lock file %s)", e.Path)
+	}
+	return fmt.Sprintf("database is locked by PID %d (lock file %s)", e.PID, e.Path)
+}
+
+// LockPath returns the advisory write lock file for a database.
+func LockPath(dbPath string) string {
+	return dbPath + ".lock"
+}
+
+// Locked runs fn while holding the database's write lock, so writes from other
+// goroutines and other amem processes cannot interleave with it.
+// Retries with backoff until the lock timeout before returning a *LockedError.
+// While a write queue is running, fn is run by the queue instead.
+func (db *DB) Locked(fn func() error) error {
+	job := writeJob{fn: fn, done: make(chan error, 1)}
+
+	db.queueMu.RLock()
+	if q := db.queue; q != nil {
+		q.jobs <- job
+		db.queueMu.RUnlock()
+		return <-job.done
+	}
+	db.queueMu.RUnlock()
+
+	db.runLocked([]writeJob{job})
+	return <-job.done
+}
+
+// runLocked runs jobs in order under a single acquisition of the write lock,
+// reporting each job's result on its done channel.
+func (db *DB) runLocked(jobs []writeJob) {
+	db.lockMu.Lock()
+	defer db.lockMu.Unlock()
+
+	path := LockPath(db.path)
+	if err := acquireLock(path, db.lockTimeout); err != nil {
+		for _, job := range jobs {
+			job.done <- err
+		}
+		return
+	}
+	defer func() { _ = os.Remove(path) }()
+
+	for _, job := range jobs {
+		if err := db.startHistoryBatch(); err != nil {
+			job.done <- err
+			continue
+		}
+		job.done <- job.fn()
+	}
+}
+
+// acquireLock creates the lock file containing this process's PID, waiting
+// for the current holder to release it and removing it if the holder died.
+func acquireLock(path string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	backoff := 10 * time.Millisecond
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			_, werr := f.WriteString(strconv.Itoa(os.Getpid()))
+			cerr := f.Close()
+			if werr != nil || cerr != nil {
+				_ = os.Remove(path)
+				return fmt.Errorf("failed to write lock file: %w", errors.Join(werr, cerr))
+			}
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		pid, stale := lockHolder(path)
+		if stale {
+			if err := reclaimLock(path); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return &LockedError{Path: path, PID: pid}
+		}
+
+		// Jitter keeps competing writers from retrying in lockstep
+		time.Sleep(backoff/2 + rand.N(backoff/2+1))
+		backoff = min(backoff*2, 250*time.Millisecond)
+	}
+}
+
+// reclaimLock removes the stale lock file at path. Between finding it stale and removing
+// it, another writer may have reclaimed it and taken the lock, so writers reclaim one at a
+// time under an OS lock on a guard file, which is released even if its holder dies, and
+// check the lock is still stale first.
+func reclaimLock(path string) error {
+	guard, err := os.OpenFile(path+".reclaim", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open lock guard file: %w", err)
+	}
+	defer func() { _ = guard.Close() }()
+	if err := lockFile(guard); err != nil {
+		return fmt.Errorf("failed to lock guard file: %w", err)
+	}
+	// Closing the guard unlocks it
+
+	if _, stale := lockHolder(path); !stale {
+		return nil
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale lock file: %w", err)
+	}
+	return nil
+}
+
+// lockHolder returns the PID recorded in a lock file and whether the lock is
+// stale (its process no longer exists).
+func lockHolder(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// Released between our create and read, or unreadable; retrying will tell
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		info, statErr := os.Stat(path)
+		return 0, statErr == nil && time.Since(info.ModTime()) > staleEmptyLock
+	}
+
+	return pid, !processAlive(pid)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newLockTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := Init(t.TempDir()+"/test_lock.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	return db
+}
+
+func TestLockedRemovesLockFile(t *testing.T) {
+	db := newLockTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	err := db.Locked(func() error {
+		data, err := os.ReadFile(LockPath(db.Path()))
+		if err != nil {
+			t.Fatalf("Lock file should exist while locked: %v", err)
+		}
+		if string(data) != strconv.Itoa(os.Getpid()) {
+			t.Errorf("Expected lock file to contain PID %d, got %q", os.Getpid(), data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Locked failed: %v", err)
+	}
+
+	if _, err := os.Stat(LockPath(db.Path())); !os.IsNotExist(err) {
+		t.Errorf("Lock file should be removed after unlocking, got %v", err)
+	}
+}
+
+func TestLockedByAnotherProcess(t *testing.T) {
+	db := newLockTestDB(t)
+	defer func() { _ = db.Close() }()
+	db.lockTimeout = 50 * time.Millisecond
+
+	// Our parent process is alive and isn't us, so it stands in for another writer
+	holder := os.Getppid()
+	if err := os.WriteFile(LockPath(db.Path()), []byte(strconv.Itoa(holder)), 0o600); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	defer func() { _ = os.Remove(LockPath(db.Path())) }()
+
+	err := db.Locked(func() error { return nil })
+	var locked *LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Expected LockedError, got %v", err)
+	}
+	if locked.PID != holder {
+		t.Errorf("Expected PID %d, got %d", holder, locked.PID)
+	}
+}
+
+func TestLockedRemovesStaleLock(t *testing.T) {
+	db := newLockTestDB(t)
+	defer func() { _ = db.Close() }()
+	db.lockTimeout = 50 * time.Millisecond
+
+	// PIDs this large aren't handed out, so the holder is certainly gone
+	if err := os.WriteFile(LockPath(db.Path()), []byte("2147483646"), 0o600); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+
+	if err := db.Locked(func() error { return nil }); err != nil {
+		t.Errorf("Stale lock should be taken over, got %v", err)
+	}
+}
+
+func TestLockedConcurrentWriters(t *testing.T) {
+	db := newLockTestDB(t)
+	defer func() { _ = db.Close() }()
+
+	var wg sync.WaitGroup
+	active := 0
+	var mu sync.Mutex
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Locked(func() error {
+				mu.Lock()
+				active++
+				if active > 1 {
+					t.Error("Writers overlapped")
+				}
+				mu.Unlock()
+
+				_, err := db.AddEntity("Alice")
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return err
+			})
+			if err != nil {
+				t.Errorf("Locked failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestLockedReclaimStaleLockConcurrently(t *testing.T) {
+	path := t.TempDir() + "/test_lock_reclaim.db"
+	first, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	// Separate handles lock like separate processes, without the in-process mutex
+	handles := []*DB{first}
+	for len(handles) < 8 {
+		db, err := Open(path, "testkey123456789012")
+		if err != nil {
+			t.Fatalf("Failed to open database: %v", err)
+		}
+		handles = append(handles, db)
+	}
+	defer func() {
+		for _, db := range handles {
+			_ = db.Close()
+		}
+	}()
+
+	var mu sync.Mutex
+	active := 0
+	for round := 0; round < 20; round++ {
+		// Every writer finds the same stale lock, and only one may take it over
+		if err := os.WriteFile(LockPath(path), []byte("2147483646"), 0o600); err != nil {
+			t.Fatalf("Failed to write lock file: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		for _, db := range handles {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				err := db.Locked(func() error {
+					mu.Lock()
+					active++
+					if active > 1 {
+						t.Error("Writers overlapped after reclaiming a stale lock")
+					}
+					mu.Unlock()
+
+					time.Sleep(time.Millisecond)
+
+					mu.Lock()
+					active--
+					mu.Unlock()
+					return nil
+				})
+				if err != nil {
+					t.Errorf("Locked failed: %v", err)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultMaintenanceInterval is how often long-running processes maintain the database
+// when the config doesn't say.
+const DefaultMaintenanceInterval = 24 * time.Hour
+
+// autoVacuumIncremental is PRAGMA auto_vacuum's value for databases that free pages on request.
+const autoVacuumIncremental = 2
+
+// Maintenance schedules Maintain in long-running processes like 'amem serve'.
+type Maintenance struct {
+	// Interval is how often to maintain the database, as a duration like "6h".
+	// "0" turns scheduled maintenance off; empty means DefaultMaintenanceInterval.
+	Interval string `json:"interval,omitempty"`
+	// Backup also takes a backup after each run, pruned as the config's backup retention allows.
+	Backup bool `json:"backup,omitempty"`
+
+	every time.Duration
+}
+
+// Validate checks that the interval is a usable duration.
+func (m *Maintenance) Validate() error {
+	if m.Interval == "" {
+		m.every = DefaultMaintenanceInterval
+		return nil
+	}
+	every, err := time.ParseDuration(m.Interval)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q: %w", m.Interval, err)
+	}
+	if every < 0 {
+		return fmt.Errorf("interval cannot be negative")
+	}
+	m.every = every
+	return nil
+}
+
+// Every returns how often to maintain the database, or 0 if never. Call Validate first.
+func (m *Maintenance) Every() time.Duration {
+	return m.every
+}
+
+// MaintenanceReport says what Maintain did.
+type MaintenanceReport struct {
+	// FreedPages is how many unused pages incremental vacuuming returned to the filesystem
+	FreedPages int64
+	// Evicted is how many observations were evicted to bring the database within its quota
+	Evicted int
+}
+
+// Maintain keeps a long-running database healthy: it refreshes the statistics SQLite's
+// query planner uses with ANALYZE, returns unused pages to the filesystem if the database
+// vacuums incrementally, and evicts observations over the quota. Should be run under Locked.
+func (db *DB) Maintain() (*MaintenanceReport, error) {
+	report := &MaintenanceReport{}
+
+	if _, err := db.exec("ANALYZE"); err != nil {
+		return report, fmt.Errorf("failed to analyze: %w", err)
+	}
+
+	// Databases created before incremental vacuuming was the default free pages only on a full VACUUM
+	var autoVacuum int
+	if err := db.queryRow("PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil {
+		return report, fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if autoVacuum == autoVacuumIncremental {
+		var before, after int64
+		if err := db.queryRow("PRAGMA freelist_count").Scan(&before); err != nil {
+			return report, fmt.Errorf("failed to count free pages: %w", err)
+		}
+		if _, err := db.exec("PRAGMA incremental_vacuum"); err != nil {
+			return report, fmt.Errorf("failed to vacuum: %w", err)
+		}
+		if err := db.queryRow("PRAGMA freelist_count").Scan(&after); err != nil {
+			return report, fmt.Errorf("failed to count free pages: %w", err)
+		}
+		report.FreedPages = before - after
+	}
+
+	evicted, err := db.EnforceQuota()
+	report.Evicted = evicted
+	if err != nil {
+		return report, fmt.Errorf("failed to enforce quota: %w", err)
+	}
+	return report, nil
+}
+
+// StartMaintenance runs Maintain under Locked every interval until the returned stop
+// function is called, passing each result to done. For long-running processes like 'amem serve'.
+func (db *DB) StartMaintenance(every time.Duration, done func(*MaintenanceReport, error)) (stop func()) {
+	quit := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+				var report *MaintenanceReport
+				err := db.Locked(func() error {
+					var err error
+					report, err = db.Maintain()
+					return err
+				})
+				done(report, err)
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-stopped
+	}
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintain(t *testing.T) {
+	path := t.TempDir() + "/test_maintain.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	var autoVacuum int
+	if err := db.queryRow("PRAGMA auto_vacuum").Scan(&autoVacuum); err != nil || autoVacuum != autoVacuumIncremental {
+		t.Fatalf("auto_vacuum = %d, %v; want new databases to vacuum incrementally", autoVacuum, err)
+	}
+
+	// Fill some pages, then free them. Deleted records stay in the history, so use a table of our own
+	if _, err := db.exec("CREATE TABLE scratch (x)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.exec("INSERT INTO scratch SELECT zeroblob(4000) FROM (WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50) SELECT i FROM n)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.exec("DROP TABLE scratch"); err != nil {
+		t.Fatal(err)
+	}
+	for i := range 3 {
+		if _, err := db.AddObservation("Bob", string(rune('a'+i))); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	if err := db.SetQuota(&Quota{MaxRecords: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := db.Maintain()
+	if err != nil {
+		t.Fatalf("Maintain failed: %v", err)
+	}
+	if report.FreedPages == 0 {
+		t.Error("Expected Maintain to free the deleted observations' pages")
+	}
+	if report.Evicted != 1 {
+		t.Errorf("Evicted = %d, want 1 to bring Bob and his observations within the quota", report.Evicted)
+	}
+
+	var stats int
+	if err := db.queryRow("SELECT COUNT(*) FROM sqlite_master WHERE name = 'sqlite_stat1'").Scan(&stats); err != nil || stats != 1 {
+		t.Errorf("Expected ANALYZE to create sqlite_stat1, got %d, %v", stats, err)
+	}
+}
+
+func TestStartMaintenance(t *testing.T) {
+	path := t.TempDir() + "/test_start_maintenance.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	ran := make(chan error, 10)
+	stop := db.StartMaintenance(10*time.Millisecond, func(_ *MaintenanceReport, err error) {
+		ran <- err
+	})
+
+	select {
+	case err := <-ran:
+		if err != nil {
+			t.Errorf("scheduled maintenance failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("maintenance never ran")
+	}
+	stop()
+}
+
+func TestMaintenanceValidate(t *testing.T) {
+	tests := map[string]time.Duration{"": DefaultMaintenanceInterval, "6h": 6 * time.Hour, "0": 0}
+	for interval, want := range tests {
+		m := &Maintenance{Interval: interval}
+		if err := m.Validate(); err != nil || m.Every() != want {
+			t.Errorf("Maintenance{%q}.Every() = %v, %v; want %v", interval, m.Every(), err, want)
+		}
+	}
+	for _, interval := range []string{"soon", "-1h"} {
+		if err := (&Maintenance{Interval: interval}).Validate(); err == nil {
+			t.Errorf("Maintenance{%q}.Validate() should fail", interval)
+		}
+	}
+}
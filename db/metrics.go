@@ -0,0 +1,46 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Metrics are gauges describing the memory store, for monitoring.
+type Metrics struct {
+	Entities             int
+	Observations         int
+	ArchivedObservations int
+	Relationships        int
+	// UsedBytes is the size of the database excluding free pages
+	UsedBytes int64
+	// LastChange is when a record was last created or updated, or zero if there are none
+	LastChange time.Time
+}
+
+// Metrics returns the current Metrics.
+func (db *DB) Metrics() (*Metrics, error) {
+	m := &Metrics{}
+	var lastChange sql.NullInt64
+	err := db.queryRow(`SELECT
+			(SELECT COUNT(*) FROM entities),
+			(SELECT COUNT(*) FROM observations),
+			(SELECT COUNT(*) FROM archived),
+			(SELECT COUNT(*) FROM relationships),
+			(SELECT CAST(strftime('%s', MAX(t)) AS INTEGER) FROM (
+				SELECT MAX(updated_at) AS t FROM entities
+				UNION ALL SELECT MAX(updated_at) FROM observations
+				UNION ALL SELECT MAX(updated_at) FROM relationships
+			))`).Scan(&m.Entities, &m.Observations, &m.ArchivedObservations, &m.Relationships, &lastChange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics: %w", err)
+	}
+	if lastChange.Valid {
+		m.LastChange = time.Unix(lastChange.Int64, 0).UTC()
+	}
+
+	if m.UsedBytes, err = db.usedBytes(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
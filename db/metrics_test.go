@@ -0,0 +1,40 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_metrics.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	m, err := db.Metrics()
+	if err != nil {
+		t.Fatalf("Metrics failed: %v", err)
+	}
+	if m.Entities != 0 || !m.LastChange.IsZero() || m.UsedBytes <= 0 {
+		t.Errorf("Unexpected metrics for an empty database: %+v", m)
+	}
+
+	id, _ := db.AddObservation("Alice", "Likes tea")
+	_, _ = db.AddObservation("Alice", "Lives in Oslo")
+	_, _ = db.AddRelationship("Alice", "Bob", "knows")
+	if _, err := db.Archive([]int64{id}); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+
+	m, err = db.Metrics()
+	if err != nil {
+		t.Fatalf("Metrics failed: %v", err)
+	}
+	if m.Entities != 2 || m.Observations != 2 || m.ArchivedObservations != 1 || m.Relationships != 1 {
+		t.Errorf("Unexpected counts: %+v", m)
+	}
+	if time.Since(m.LastChange) > time.Minute {
+		t.Errorf("Expected a recent last change, got %v", m.LastChange)
+	}
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"sort"
 )
@@ -11,6 +12,9 @@ type Migration struct {
 	Version int
 	Up      string
 	Down    string
+	// Applied is a query returning a nonzero count if the migration's changes
+	// are present, used by Repair when schema_migrations can't be trusted
+	Applied string
 }
 
 // migrations contains all schema migrations in order
@@ -46,6 +50,7 @@ CREATE INDEX idx_relationships_from ON relationships(from_id);
 CREATE INDEX idx_relationships_to ON relationships(to_id);
 CREATE INDEX idx_relationships_type ON relationships(type);
 `,
+		Applied: `SELECT COUNT(*) = 3 FROM sqlite_master WHERE type='table' AND name IN ('entities', 'observations', 'relationships')`,
 		Down: `
 DROP INDEX IF EXISTS idx_relationships_type;
 DROP INDEX IF EXISTS idx_relationships_to;
@@ -54,6 +59,442 @@ DROP INDEX IF EXISTS idx_observations_entity;
 DROP TABLE IF EXISTS relationships;
 DROP TABLE IF EXISTS observations;
 DROP TABLE IF EXISTS entities;
+`,
+	},
+	{
+		Version: 2,
+		Up: `
+ALTER TABLE observations ADD COLUMN importance INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE observations ADD COLUMN access_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE observations ADD COLUMN last_accessed DATETIME;
+`,
+		Applied: `SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name = 'importance'`,
+		Down: `
+CREATE TABLE observations_old (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	entity_id INTEGER NOT NULL,
+	text TEXT NOT NULL,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+	FOREIGN KEY (entity_id) REFERENCES entities(id) ON DELETE CASCADE
+);
+INSERT INTO observations_old (id, entity_id, text, timestamp)
+	SELECT id, entity_id, text, timestamp FROM observations;
+DROP TABLE observations;
+ALTER TABLE observations_old RENAME TO observations;
+CREATE INDEX idx_observations_entity ON observations(entity_id);
+`,
+	},
+	{
+		// History for undo: triggers record the SQL that reverses each change,
+		// grouped into one batch per write (see startHistoryBatch).
+		// Migrations that add columns to these tables must recreate their triggers.
+		Version: 3,
+		Up: `
+CREATE TABLE history_batches (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	batch INTEGER NOT NULL,
+	tbl TEXT NOT NULL,
+	row_id INTEGER NOT NULL,
+	description TEXT NOT NULL,
+	undo_sql TEXT NOT NULL
+);
+
+CREATE INDEX idx_history_batch ON history(batch);
+
+CREATE TRIGGER history_entities_insert AFTER INSERT ON entities BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'entities', NEW.id,
+		printf('added entity %s', NEW.text),
+		printf('DELETE FROM entities WHERE id = %d', NEW.id));
+END;
+
+CREATE TRIGGER history_entities_update AFTER UPDATE OF text ON entities BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'entities', OLD.id,
+		printf('renamed entity %s to %s', OLD.text, NEW.text),
+		printf('UPDATE entities SET text = %s WHERE id = %d', quote(OLD.text), OLD.id));
+END;
+
+CREATE TRIGGER history_entities_delete AFTER DELETE ON entities BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'entities', OLD.id,
+		printf('deleted entity %s', OLD.text),
+		printf('INSERT INTO entities (id, text) VALUES (%d, %s)', OLD.id, quote(OLD.text)));
+END;
+
+CREATE TRIGGER history_observations_insert AFTER INSERT ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', NEW.id,
+		printf('added observation %d: %s', NEW.id, NEW.text),
+		printf('DELETE FROM observations WHERE id = %d', NEW.id));
+END;
+
+CREATE TRIGGER history_observations_update AFTER UPDATE OF entity_id, text, importance ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', OLD.id,
+		printf('edited observation %d', OLD.id),
+		printf('UPDATE observations SET entity_id = %d, text = %s, importance = %d WHERE id = %d',
+			OLD.entity_id, quote(OLD.text), OLD.importance, OLD.id));
+END;
+
+CREATE TRIGGER history_observations_delete AFTER DELETE ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', OLD.id,
+		printf('deleted observation %d: %s', OLD.id, OLD.text),
+		printf('INSERT INTO observations (id, entity_id, text, timestamp, importance, access_count, last_accessed) VALUES (%d, %d, %s, %s, %d, %d, %s)',
+			OLD.id, OLD.entity_id, quote(OLD.text), quote(OLD.timestamp), OLD.importance, OLD.access_count, quote(OLD.last_accessed)));
+END;
+
+CREATE TRIGGER history_relationships_insert AFTER INSERT ON relationships BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'relationships', NEW.id,
+		printf('added relationship %d (%s)', NEW.id, NEW.type),
+		printf('DELETE FROM relationships WHERE id = %d', NEW.id));
+END;
+
+CREATE TRIGGER history_relationships_update AFTER UPDATE OF from_id, to_id, type ON relationships BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'relationships', OLD.id,
+		printf('edited relationship %d', OLD.id),
+		printf('UPDATE relationships SET from_id = %d, to_id = %d, type = %s WHERE id = %d',
+			OLD.from_id, OLD.to_id, quote(OLD.type), OLD.id));
+END;
+
+CREATE TRIGGER history_relationships_delete AFTER DELETE ON relationships BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'relationships', OLD.id,
+		printf('deleted relationship %d (%s)', OLD.id, OLD.type),
+		printf('INSERT INTO relationships (id, from_id, to_id, type, timestamp) VALUES (%d, %d, %d, %s, %s)',
+			OLD.id, OLD.from_id, OLD.to_id, quote(OLD.type), quote(OLD.timestamp)));
+END;
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'history'`,
+		Down: `
+DROP TRIGGER IF EXISTS history_relationships_delete;
+DROP TRIGGER IF EXISTS history_relationships_update;
+DROP TRIGGER IF EXISTS history_relationships_insert;
+DROP TRIGGER IF EXISTS history_observations_delete;
+DROP TRIGGER IF EXISTS history_observations_update;
+DROP TRIGGER IF EXISTS history_observations_insert;
+DROP TRIGGER IF EXISTS history_entities_delete;
+DROP TRIGGER IF EXISTS history_entities_update;
+DROP TRIGGER IF EXISTS history_entities_insert;
+DROP TABLE IF EXISTS history;
+DROP TABLE IF EXISTS history_batches;
+`,
+	},
+	{
+		// created_at/updated_at, maintained by triggers. Existing entities are
+		// dated by their earliest observation or relationship.
+		Version: 4,
+		Up: `
+ALTER TABLE entities ADD COLUMN created_at DATETIME;
+ALTER TABLE entities ADD COLUMN updated_at DATETIME;
+ALTER TABLE observations ADD COLUMN updated_at DATETIME;
+ALTER TABLE relationships ADD COLUMN updated_at DATETIME;
+
+UPDATE entities SET created_at = COALESCE((
+	SELECT MIN(timestamp) FROM (
+		SELECT timestamp FROM observations WHERE entity_id = entities.id
+		UNION ALL
+		SELECT timestamp FROM relationships WHERE from_id = entities.id OR to_id = entities.id
+	)
+), CURRENT_TIMESTAMP);
+UPDATE entities SET updated_at = created_at;
+UPDATE observations SET updated_at = timestamp;
+UPDATE relationships SET updated_at = timestamp;
+
+CREATE INDEX idx_entities_updated_at ON entities(updated_at);
+CREATE INDEX idx_observations_updated_at ON observations(updated_at);
+CREATE INDEX idx_relationships_updated_at ON relationships(updated_at);
+
+CREATE TRIGGER entities_created_at AFTER INSERT ON entities WHEN NEW.created_at IS NULL BEGIN
+	UPDATE entities SET created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+CREATE TRIGGER entities_updated_at AFTER UPDATE OF text ON entities BEGIN
+	UPDATE entities SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER observations_created_at AFTER INSERT ON observations WHEN NEW.updated_at IS NULL BEGIN
+	UPDATE observations SET updated_at = NEW.timestamp WHERE id = NEW.id;
+END;
+CREATE TRIGGER observations_updated_at AFTER UPDATE OF entity_id, text, importance ON observations BEGIN
+	UPDATE observations SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+CREATE TRIGGER relationships_created_at AFTER INSERT ON relationships WHEN NEW.updated_at IS NULL BEGIN
+	UPDATE relationships SET updated_at = NEW.timestamp WHERE id = NEW.id;
+END;
+CREATE TRIGGER relationships_updated_at AFTER UPDATE OF from_id, to_id, type ON relationships BEGIN
+	UPDATE relationships SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END;
+
+-- Undoing a delete restores the timestamps too
+DROP TRIGGER history_entities_delete;
+CREATE TRIGGER history_entities_delete AFTER DELETE ON entities BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'entities', OLD.id,
+		printf('deleted entity %s', OLD.text),
+		printf('INSERT INTO entities (id, text, created_at, updated_at) VALUES (%d, %s, %s, %s)',
+			OLD.id, quote(OLD.text), quote(OLD.created_at), quote(OLD.updated_at)));
+END;
+
+DROP TRIGGER history_observations_delete;
+CREATE TRIGGER history_observations_delete AFTER DELETE ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', OLD.id,
+		printf('deleted observation %d: %s', OLD.id, OLD.text),
+		printf('INSERT INTO observations (id, entity_id, text, timestamp, importance, access_count, last_accessed, updated_at) VALUES (%d, %d, %s, %s, %d, %d, %s, %s)',
+			OLD.id, OLD.entity_id, quote(OLD.text), quote(OLD.timestamp), OLD.importance, OLD.access_count, quote(OLD.last_accessed), quote(OLD.updated_at)));
+END;
+
+DROP TRIGGER history_relationships_delete;
+CREATE TRIGGER history_relationships_delete AFTER DELETE ON relationships BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'relationships', OLD.id,
+		printf('deleted relationship %d (%s)', OLD.id, OLD.type),
+		printf('INSERT INTO relationships (id, from_id, to_id, type, timestamp, updated_at) VALUES (%d, %d, %d, %s, %s, %s)',
+			OLD.id, OLD.from_id, OLD.to_id, quote(OLD.type), quote(OLD.timestamp), quote(OLD.updated_at)));
+END;
+`,
+		Applied: `SELECT COUNT(*) FROM pragma_table_info('entities') WHERE name = 'created_at'`,
+		Down: `
+DROP TRIGGER IF EXISTS relationships_updated_at;
+DROP TRIGGER IF EXISTS relationships_created_at;
+DROP TRIGGER IF EXISTS observations_updated_at;
+DROP TRIGGER IF EXISTS observations_created_at;
+DROP TRIGGER IF EXISTS entities_updated_at;
+DROP TRIGGER IF EXISTS entities_created_at;
+DROP INDEX IF EXISTS idx_relationships_updated_at;
+DROP INDEX IF EXISTS idx_observations_updated_at;
+DROP INDEX IF EXISTS idx_entities_updated_at;
+`,
+	},
+	{
+		// Opt-in log of searches and the observations they returned
+		Version: 5,
+		Up: `
+CREATE TABLE retrievals (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	timestamp DATETIME DEFAULT CURRENT_TIMESTAMP,
+	about TEXT NOT NULL DEFAULT '',
+	query TEXT NOT NULL,
+	results INTEGER NOT NULL
+);
+CREATE TABLE retrieved_observations (
+	retrieval_id INTEGER NOT NULL REFERENCES retrievals(id) ON DELETE CASCADE,
+	observation_id INTEGER NOT NULL
+);
+CREATE INDEX idx_retrieved_observations ON retrieved_observations(observation_id);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'retrievals'`,
+		Down: `
+DROP INDEX IF EXISTS idx_retrieved_observations;
+DROP TABLE IF EXISTS retrieved_observations;
+DROP TABLE IF EXISTS retrievals;
+`,
+	},
+	{
+		// Feedback on observations from 'amem mark'. A table of its own, so the
+		// history triggers on observations don't have to change.
+		Version: 6,
+		Up: `
+CREATE TABLE feedback (
+	observation_id INTEGER PRIMARY KEY REFERENCES observations(id) ON DELETE CASCADE,
+	helpful INTEGER NOT NULL DEFAULT 0,
+	stale INTEGER NOT NULL DEFAULT 0,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'feedback'`,
+		Down: `
+DROP TABLE IF EXISTS feedback;
+`,
+	},
+	{
+		// Archived observations, left out of searches by default
+		Version: 7,
+		Up: `
+CREATE TABLE archived (
+	observation_id INTEGER PRIMARY KEY REFERENCES observations(id) ON DELETE CASCADE,
+	archived_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'archived'`,
+		Down: `
+DROP TABLE IF EXISTS archived;
+`,
+	},
+	{
+		// Long-form notes on entities, edited as a whole rather than appended to
+		Version: 8,
+		Up: `
+CREATE TABLE entity_notes (
+	entity_id INTEGER PRIMARY KEY REFERENCES entities(id) ON DELETE CASCADE,
+	note TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'entity_notes'`,
+		Down: `
+DROP TABLE IF EXISTS entity_notes;
+`,
+	},
+	{
+		// Sensitive settings from 'amem config set --secret', kept encrypted in the
+		// database instead of in plaintext config files
+		Version: 9,
+		Up: `
+CREATE TABLE config_secrets (
+	name TEXT PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'config_secrets'`,
+		Down: `
+DROP TABLE IF EXISTS config_secrets;
+`,
+	},
+	{
+		// Vectors for semantic search, made by 'amem index'. Each is tagged with the
+		// model that made it, since vectors from different models can't be compared
+		Version: 10,
+		Up: `
+CREATE TABLE embeddings (
+	observation_id INTEGER PRIMARY KEY REFERENCES observations(id) ON DELETE CASCADE,
+	model TEXT NOT NULL,
+	vector BLOB NOT NULL,
+	embedded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'embeddings'`,
+		Down: `
+DROP TABLE IF EXISTS embeddings;
+`,
+	},
+	{
+		// Long observations are embedded in chunks, so a vector can point at the part
+		// of the text it matched. Existing vectors become each observation's only chunk
+		Version: 11,
+		Up: `
+CREATE TABLE embeddings_chunked (
+	observation_id INTEGER NOT NULL REFERENCES observations(id) ON DELETE CASCADE,
+	chunk INTEGER NOT NULL,
+	start_offset INTEGER NOT NULL,
+	end_offset INTEGER NOT NULL,
+	model TEXT NOT NULL,
+	vector BLOB NOT NULL,
+	embedded_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (observation_id, chunk)
+);
+INSERT INTO embeddings_chunked (observation_id, chunk, start_offset, end_offset, model, vector, embedded_at)
+	SELECT e.observation_id, 0, 0, length(CAST(o.text AS BLOB)), e.model, e.vector, e.embedded_at
+	FROM embeddings e JOIN observations o ON o.id = e.observation_id;
+DROP TABLE embeddings;
+ALTER TABLE embeddings_chunked RENAME TO embeddings;
+`,
+		Applied: `SELECT COUNT(*) FROM pragma_table_info('embeddings') WHERE name = 'chunk'`,
+		Down: `
+CREATE TABLE embeddings_whole (
+	observation_id INTEGER PRIMARY KEY REFERENCES observations(id) ON DELETE CASCADE,
+	model TEXT NOT NULL,
+	vector BLOB NOT NULL,
+	embedded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO embeddings_whole (observation_id, model, vector, embedded_at)
+	SELECT observation_id, model, vector, embedded_at FROM embeddings WHERE chunk = 0;
+DROP TABLE embeddings;
+ALTER TABLE embeddings_whole RENAME TO embeddings;
+`,
+	},
+	{
+		// Files 'amem watch' has extracted memories from, so unchanged ones are skipped
+		Version: 12,
+		Up: `
+CREATE TABLE ingested_files (
+	path TEXT PRIMARY KEY,
+	sha256 TEXT NOT NULL,
+	ingested_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'ingested_files'`,
+		Down: `
+DROP TABLE IF EXISTS ingested_files;
+`,
+	},
+	{
+		// Optional features enabled on this database, whose tables only exist once they are
+		Version: 13,
+		Up: `
+CREATE TABLE features (
+	name TEXT PRIMARY KEY,
+	enabled_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name = 'features'`,
+		Down: `
+DROP TABLE IF EXISTS features;
+`,
+	},
+	{
+		// The amem version that applied each migration, so older versions can say which they need
+		Version: 14,
+		Up: `
+ALTER TABLE schema_migrations ADD COLUMN amem_version TEXT;
+`,
+		Applied: `SELECT COUNT(*) FROM pragma_table_info('schema_migrations') WHERE name = 'amem_version'`,
+		Down: `
+CREATE TABLE schema_migrations_old (
+	version INTEGER PRIMARY KEY,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO schema_migrations_old (version, applied_at) SELECT version, applied_at FROM schema_migrations;
+DROP TABLE schema_migrations;
+ALTER TABLE schema_migrations_old RENAME TO schema_migrations;
+`,
+	},
+	{
+		// Observations' language, as an ISO 639-1 code, for filtering searches and choosing FTS
+		// tokenizers. Like version 4, rolling back leaves the column in place
+		Version: 15,
+		Up: `
+ALTER TABLE observations ADD COLUMN lang TEXT;
+CREATE INDEX idx_observations_lang ON observations(lang);
+`,
+		Applied: `SELECT COUNT(*) FROM pragma_table_info('observations') WHERE name = 'lang'`,
+		Down: `
+DROP INDEX IF EXISTS idx_observations_lang;
+`,
+	},
+	{
+		// Undoing a delete restores the observation's language too
+		Version: 16,
+		Up: `
+DROP TRIGGER history_observations_delete;
+CREATE TRIGGER history_observations_delete AFTER DELETE ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', OLD.id,
+		printf('deleted observation %d: %s', OLD.id, OLD.text),
+		printf('INSERT INTO observations (id, entity_id, text, timestamp, importance, access_count, last_accessed, updated_at, lang) VALUES (%d, %d, %s, %s, %d, %d, %s, %s, %s)',
+			OLD.id, OLD.entity_id, quote(OLD.text), quote(OLD.timestamp), OLD.importance, OLD.access_count, quote(OLD.last_accessed), quote(OLD.updated_at), quote(OLD.lang)));
+END;
+`,
+		Applied: `SELECT COUNT(*) FROM sqlite_master WHERE type='trigger' AND name = 'history_observations_delete' AND sql LIKE '%lang%'`,
+		Down: `
+DROP TRIGGER IF EXISTS history_observations_delete;
+CREATE TRIGGER history_observations_delete AFTER DELETE ON observations BEGIN
+	INSERT INTO history (batch, tbl, row_id, description, undo_sql) VALUES (
+		(SELECT COALESCE(MAX(id), 0) FROM history_batches), 'observations', OLD.id,
+		printf('deleted observation %d: %s', OLD.id, OLD.text),
+		printf('INSERT INTO observations (id, entity_id, text, timestamp, importance, access_count, last_accessed, updated_at) VALUES (%d, %d, %s, %s, %d, %d, %s, %s)',
+			OLD.id, OLD.entity_id, quote(OLD.text), quote(OLD.timestamp), OLD.importance, OLD.access_count, quote(OLD.last_accessed), quote(OLD.updated_at)));
+END;
 `,
 	},
 }
@@ -75,6 +516,68 @@ func getCurrentVersion(conn *sql.DB) (int, error) {
 	return version, nil
 }
 
+// LatestSchemaVersion returns the schema version this build migrates databases to
+func LatestSchemaVersion() int {
+	latest := 0
+	for _, m := range migrations {
+		if m.Version > latest {
+			latest = m.Version
+		}
+	}
+	return latest
+}
+
+// appVersion is the version of the program using this package, recorded with the
+// migrations it applies. Set with SetAppVersion.
+var appVersion string
+
+// SetAppVersion sets the program version recorded with the migrations Open and Init apply,
+// which older versions report as the one needed to open the database. Development builds
+// with no release version, passed as "" or "dev", aren't recorded.
+func SetAppVersion(version string) {
+	appVersion = version
+	if version == "dev" {
+		appVersion = ""
+	}
+}
+
+// ErrSchemaTooNew is returned when opening a database migrated by a newer version of amem.
+var ErrSchemaTooNew = errors.New("database was upgraded by a newer version of amem")
+
+// checkSchemaVersion returns an error wrapping ErrSchemaTooNew if the database's schema
+// is newer than this build knows, naming the version that upgraded it when it was recorded.
+// Opening it anyway would fail later with obscure SQL errors, or worse, write data the
+// newer schema doesn't expect.
+func checkSchemaVersion(conn *sql.DB) error {
+	current, err := getCurrentVersion(conn)
+	if err != nil {
+		return fmt.Errorf("failed to get schema version: %w", err)
+	}
+	latest := LatestSchemaVersion()
+	if current <= latest {
+		return nil
+	}
+
+	// Databases without the amem_version column are too old to get here, but check anyway
+	var needed sql.NullString
+	_ = conn.QueryRow("SELECT amem_version FROM schema_migrations WHERE version = ?", current).Scan(&needed)
+	if needed.String != "" {
+		return fmt.Errorf("%w (schema version %d, this version supports up to %d): upgrade amem to %s or later to open it",
+			ErrSchemaTooNew, current, latest, needed.String)
+	}
+	return fmt.Errorf("%w (schema version %d, this version supports up to %d): upgrade amem to open it", ErrSchemaTooNew, current, latest)
+}
+
+// isInitialized reports whether the database has been set up by Init
+func isInitialized(conn *sql.DB) (bool, error) {
+	var count int
+	err := conn.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='schema_migrations'").Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // migrate applies all pending migrations
 func migrate(conn *sql.DB) error {
 	// Create schema_migrations table
@@ -121,5 +624,12 @@ func migrate(conn *sql.DB) error {
 		}
 	}
 
+	// Record which version applied them, now that the column for it exists
+	if appVersion != "" {
+		if _, err := conn.Exec("UPDATE schema_migrations SET amem_version = ? WHERE version > ?", appVersion, currentVersion); err != nil {
+			return fmt.Errorf("failed to record amem version: %w", err)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Note returns the note on the entity named text, or "" if it has none.
+// Unlike observations, an entity has at most one note, a Markdown document
+// that's edited as a whole.
+func (db *DB) Note(text string) (string, error) {
+	id, err := db.entityID(text)
+	if err != nil {
+		return "", err
+	}
+
+	var note string
+	err = db.queryRow("SELECT note FROM entity_notes WHERE entity_id = ?", id).Scan(&note)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get note: %w", err)
+	}
+	return note, nil
+}
+
+// SetNote replaces the note on the entity named text. An empty note removes it.
+func (db *DB) SetNote(text, note string) error {
+	id, err := db.entityID(text)
+	if err != nil {
+		return err
+	}
+
+	note = db.trimInput(note)
+	if strings.TrimSpace(note) == "" {
+		if _, err := db.exec("DELETE FROM entity_notes WHERE entity_id = ?", id); err != nil {
+			return fmt.Errorf("failed to remove note: %w", err)
+		}
+		return nil
+	}
+	if err := db.checkSecrets(note); err != nil {
+		return err
+	}
+
+	_, err = db.exec(`INSERT INTO entity_notes (entity_id, note) VALUES (?, ?)
+		ON CONFLICT (entity_id) DO UPDATE SET note = excluded.note, updated_at = CURRENT_TIMESTAMP`, id, note)
+	if err != nil {
+		return fmt.Errorf("failed to set note: %w", err)
+	}
+	return nil
+}
+
+// entityID returns the ID of the entity named text, without creating it.
+func (db *DB) entityID(text string) (int64, error) {
+	var id int64
+	err := db.queryRow("SELECT id FROM entities WHERE text = ?", text).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("entity '%s' %w", text, ErrNotFound)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find entity: %w", err)
+	}
+	return id, nil
+}
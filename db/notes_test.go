@@ -0,0 +1,59 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNotes(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_notes.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddEntity("Alice"); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	if note, err := db.Note("Alice"); err != nil || note != "" {
+		t.Errorf("Note = %q, %v; want no note", note, err)
+	}
+	if err := db.SetNote("Nobody", "# Nobody"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound setting a note on a missing entity, got %v", err)
+	}
+
+	if err := db.SetNote("Alice", "# Alice\n\nLeads the platform team."); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if err := db.SetNote("Alice", "# Alice\n\nLeads the data team."); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if note, err := db.Note("Alice"); err != nil || note != "# Alice\n\nLeads the data team." {
+		t.Errorf("Note = %q, %v; want the replaced note", note, err)
+	}
+
+	// Notes aren't observations
+	if count, _ := db.CountObservations(); count != 0 {
+		t.Errorf("Expected no observations, got %d", count)
+	}
+
+	if err := db.SetNote("Alice", ""); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if note, err := db.Note("Alice"); err != nil || note != "" {
+		t.Errorf("Note = %q, %v; want the note removed", note, err)
+	}
+
+	// Deleting the entity deletes its note
+	if err := db.SetNote("Alice", "Gone soon"); err != nil {
+		t.Fatalf("SetNote failed: %v", err)
+	}
+	if err := db.DeleteEntityByText("Alice"); err != nil {
+		t.Fatalf("DeleteEntityByText failed: %v", err)
+	}
+	var notes int
+	if err := db.queryRow("SELECT COUNT(*) FROM entity_notes").Scan(&notes); err != nil || notes != 0 {
+		t.Errorf("Expected the note deleted with its entity, got %d (%v)", notes, err)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+// Page selects a window of search results in descending ID order, newest first.
+// Paging by ID rather than offset keeps pages stable as new records are added.
+type Page struct {
+	// Limit is the maximum number of results
+	Limit int
+	// BeforeID, when set, selects only results with smaller IDs
+	BeforeID int64
+}
+
+// pageQuery restricts a search query, which must select an id column, to page.
+func pageQuery(query string, args []interface{}, page Page) (string, []interface{}) {
+	paged := "SELECT * FROM (" + query + ")"
+	pagedArgs := append([]interface{}{}, args...)
+
+	if page.BeforeID > 0 {
+		paged += " WHERE id < ?"
+		pagedArgs = append(pagedArgs, page.BeforeID)
+	}
+
+	paged += " ORDER BY id DESC LIMIT ?"
+	pagedArgs = append(pagedArgs, page.Limit)
+
+	return paged, pagedArgs
+}
+
+// SearchEntitiesPage is SearchEntities, returning one page of results.
+func (db *DB) SearchEntitiesPage(keywords []string, useUnion bool, page Page) ([]Entity, error) {
+	query, args := entitiesQuery(keywords, db.required, useUnion, EntityFilter{})
+	query, args = pageQuery(query, args, page)
+	return collect(scanRows(db, query, args, nil, "entities", scanEntity))
+}
+
+// SearchObservationsPage is SearchObservations, returning one page of results.
+func (db *DB) SearchObservationsPage(entityText string, keywords []string, useUnion bool, page Page) ([]Observation, error) {
+	query, args, err := db.observationsQuery(entityText, keywords, useUnion)
+	query, args = pageQuery(query, args, page)
+	return collect(scanRows(db, query, args, err, "observations", scanObservation))
+}
+
+// SearchRelationshipsPage is SearchRelationships, returning one page of results.
+func (db *DB) SearchRelationshipsPage(fromText, toText, relType string, keywords []string, useUnion bool, page Page) ([]Relationship, error) {
+	query, args := relationshipsQuery(fromText, toText, "", relType, keywords, db.required, useUnion)
+	query, args = pageQuery(query, args, page)
+	return collect(scanRows(db, query, args, nil, "relationships", scanRelationship))
+}
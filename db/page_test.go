@@ -0,0 +1,57 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestSearchObservationsPage(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_page.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, text := range []string{"one", "two", "three", "four", "five"} {
+		if _, err := db.AddObservation("Alice", text); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+
+	first, err := db.SearchObservationsPage("Alice", nil, true, Page{Limit: 2})
+	if err != nil {
+		t.Fatalf("SearchObservationsPage failed: %v", err)
+	}
+	if len(first) != 2 || first[0].Text != "five" || first[1].Text != "four" {
+		t.Fatalf("Expected newest two observations, got %+v", first)
+	}
+
+	// Records added between pages don't shift later pages
+	if _, err := db.AddObservation("Alice", "six"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	second, err := db.SearchObservationsPage("Alice", nil, true, Page{Limit: 2, BeforeID: first[1].ID})
+	if err != nil {
+		t.Fatalf("SearchObservationsPage failed: %v", err)
+	}
+	if len(second) != 2 || second[0].Text != "three" || second[1].Text != "two" {
+		t.Errorf("Expected next two observations, got %+v", second)
+	}
+
+	last, err := db.SearchObservationsPage("Alice", nil, true, Page{Limit: 2, BeforeID: second[1].ID})
+	if err != nil {
+		t.Fatalf("SearchObservationsPage failed: %v", err)
+	}
+	if len(last) != 1 || last[0].Text != "one" {
+		t.Errorf("Expected last observation, got %+v", last)
+	}
+
+	entities, err := db.SearchEntitiesPage(nil, true, Page{Limit: 10})
+	if err != nil || len(entities) != 1 {
+		t.Errorf("Expected 1 entity, got %v (err %v)", entities, err)
+	}
+	relationships, err := db.SearchRelationshipsPage("", "", "", nil, true, Page{Limit: 10})
+	if err != nil || len(relationships) != 0 {
+		t.Errorf("Expected no relationships, got %v (err %v)", relationships, err)
+	}
+}
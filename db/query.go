@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"amem/query"
+)
+
+// queryTarget says how a parsed query applies to one kind of record.
+type queryTarget struct {
+	// terms builds the condition for a keyword
+	terms func(keyword string) (string, []interface{}, error)
+	// fields maps query fields to the columns they match; other fields match nothing
+	fields map[string][]string
+	// created is the column recording when the record was created
+	created string
+}
+
+// RunQuery returns the entities, observations, and relationships matching a parsed query.
+// Fields that don't apply to a kind of record match none of them, so type:knows
+// returns only relationships.
+func (db *DB) RunQuery(q *query.Query) ([]Entity, []Observation, []Relationship, error) {
+	entityQuery, _ := entitiesQuery(nil, nil, false, EntityFilter{})
+	where, args, err := queryWhere(q, queryTarget{
+		terms: func(keyword string) (string, []interface{}, error) {
+			clause, args := buildWhereClause([]string{keyword}, []string{"text"}, false)
+			return clause, args, nil
+		},
+		fields:  map[string][]string{query.FieldEntity: {"text"}},
+		created: "created_at",
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	entities, err := collect(scanRows(db, entityQuery+where+entitiesOrder, args, nil, "entities", scanEntity))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	where, args, err = queryWhere(q, queryTarget{
+		terms: func(keyword string) (string, []interface{}, error) {
+			return db.observationKeywordClause([]string{keyword}, false)
+		},
+		fields:  map[string][]string{query.FieldEntity: {"e.text"}},
+		created: "o.timestamp",
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if clause := db.archivedClause(); clause != "" && where == "" {
+		where = " WHERE " + clause
+	} else if clause != "" {
+		where += " AND " + clause
+	}
+	observations, err := collect(scanRows(db, observationsSelect+where+observationsOrder, args, nil, "observations", scanObservation))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	relationshipQuery, _ := relationshipsQuery("", "", "", "", nil, nil, false)
+	where, args, err = queryWhere(q, queryTarget{
+		terms: func(keyword string) (string, []interface{}, error) {
+			clause, args := buildWhereClause([]string{keyword}, []string{"e1.text", "e2.text", "r.type"}, false)
+			return clause, args, nil
+		},
+		fields: map[string][]string{
+			query.FieldEntity: {"e1.text", "e2.text"},
+			query.FieldFrom:   {"e1.text"},
+			query.FieldTo:     {"e2.text"},
+			query.FieldType:   {"r.type"},
+		},
+		created: "r.timestamp",
+	})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	relationships, err := collect(scanRows(db, relationshipQuery+where+relationshipsOrder, args, nil, "relationships", scanRelationship))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return entities, observations, relationships, nil
+}
+
+// queryWhere builds the WHERE clause, with its leading space, selecting records of target that match q.
+func queryWhere(q *query.Query, target queryTarget) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	if q.Expr != nil {
+		clause, exprArgs, err := compileExpr(q.Expr, target)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, clause)
+		args = append(args, exprArgs...)
+	}
+	// Timestamps are stored in UTC as 'YYYY-MM-DD HH:MM:SS', so strings compare in time order
+	if !q.Since.IsZero() {
+		conditions = append(conditions, target.created+" >= ?")
+		args = append(args, q.Since.UTC().Format(time.DateTime))
+	}
+	if !q.Before.IsZero() {
+		conditions = append(conditions, target.created+" < ?")
+		args = append(args, q.Before.UTC().Format(time.DateTime))
+	}
+
+	if len(conditions) == 0 {
+		return "", nil, nil
+	}
+	return " WHERE (" + strings.Join(conditions, ") AND (") + ")", args, nil
+}
+
+// compileExpr turns a query expression into an SQL condition on target's columns.
+func compileExpr(expr query.Expr, target queryTarget) (string, []interface{}, error) {
+	switch e := expr.(type) {
+	case query.And:
+		return compileBinary(e.Left, " AND ", e.Right, target)
+	case query.Or:
+		return compileBinary(e.Left, " OR ", e.Right, target)
+	case query.Not:
+		clause, args, err := compileExpr(e.Expr, target)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + clause, args, nil
+	case query.Term:
+		clause, args, err := target.terms(e.Text)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + clause + ")", args, nil
+	case query.Field:
+		columns, ok := target.fields[e.Name]
+		if !ok {
+			return "0", nil, nil
+		}
+		clause, args := buildWhereClause([]string{e.Value}, columns, false)
+		return clause, args, nil
+	}
+	return "", nil, fmt.Errorf("unknown query expression %T", expr)
+}
+
+// compileBinary compiles left and right and joins them with op.
+func compileBinary(left query.Expr, op string, right query.Expr, target queryTarget) (string, []interface{}, error) {
+	l, lArgs, err := compileExpr(left, target)
+	if err != nil {
+		return "", nil, err
+	}
+	r, rArgs, err := compileExpr(right, target)
+	if err != nil {
+		return "", nil, err
+	}
+	return "(" + l + op + r + ")", append(lArgs, rArgs...), nil
+}
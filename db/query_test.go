@@ -0,0 +1,104 @@
+package db
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"amem/query"
+)
+
+func TestRunQuery(t *testing.T) {
+	path := t.TempDir() + "/test_query.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, o := range [][2]string{
+		{"Alice", "Drinks coffee every morning"},
+		{"Alice", "Prefers tea after lunch"},
+		{"Alice", "Plays chess"},
+		{"Bob", "Drinks coffee black"},
+	} {
+		if _, err := db.AddObservation(o[0], o[1]); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	for _, r := range [][3]string{
+		{"Alice", "Bob", "knows"},
+		{"Bob", "Carol", "manages"},
+	} {
+		if _, err := db.AddRelationship(r[0], r[1], r[2]); err != nil {
+			t.Fatalf("AddRelationship failed: %v", err)
+		}
+	}
+
+	now := time.Now()
+	tests := []struct {
+		input         string
+		entities      []string
+		observations  []string
+		relationships []string
+	}{
+		{
+			input:        "entity:Alice AND (coffee OR tea)",
+			observations: []string{"Drinks coffee every morning", "Prefers tea after lunch"},
+		},
+		{
+			input:        "coffee -entity:Bob",
+			observations: []string{"Drinks coffee every morning"},
+		},
+		{
+			input:         "type:knows",
+			relationships: []string{"Alice knows Bob"},
+		},
+		{
+			input:         "entity:Carol",
+			entities:      []string{"Carol"},
+			relationships: []string{"Bob manages Carol"},
+		},
+		{
+			input:         "(from:Bob OR to:Bob) since:1h",
+			relationships: []string{"Alice knows Bob", "Bob manages Carol"},
+		},
+		{
+			input: "chess before:1h",
+		},
+	}
+	for _, tt := range tests {
+		q, err := query.Parse(tt.input, now)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+		}
+		entities, observations, relationships, err := db.RunQuery(q)
+		if err != nil {
+			t.Fatalf("RunQuery(%q) failed: %v", tt.input, err)
+		}
+
+		var gotEntities, gotObservations, gotRelationships []string
+		for _, e := range entities {
+			gotEntities = append(gotEntities, e.Text)
+		}
+		for _, o := range observations {
+			gotObservations = append(gotObservations, o.Text)
+		}
+		for _, r := range relationships {
+			gotRelationships = append(gotRelationships, r.FromText+" "+r.Type+" "+r.ToText)
+		}
+		slices.Sort(gotObservations)
+		slices.Sort(gotRelationships)
+
+		if !slices.Equal(gotEntities, tt.entities) {
+			t.Errorf("RunQuery(%q) entities = %v, want %v", tt.input, gotEntities, tt.entities)
+		}
+		if !slices.Equal(gotObservations, tt.observations) {
+			t.Errorf("RunQuery(%q) observations = %v, want %v", tt.input, gotObservations, tt.observations)
+		}
+		if !slices.Equal(gotRelationships, tt.relationships) {
+			t.Errorf("RunQuery(%q) relationships = %v, want %v", tt.input, gotRelationships, tt.relationships)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+// maxWriteBatch bounds how many queued writes run under one lock acquisition.
+const maxWriteBatch = 64
+
+// writeJob is a write waiting for the lock; its result is sent on done.
+type writeJob struct {
+	fn   func() error
+	done chan error
+}
+
+// writeQueue feeds writes to the single writer goroutine.
+type writeQueue struct {
+	jobs    chan writeJob
+	stopped chan struct{}
+}
+
+// StartWriteQueue routes all Locked writes through a single writer goroutine
+// until the returned stop function is called. Writes run in the order they
+// were submitted, and bursts of writes are batched under one lock acquisition.
+// Reads are unaffected and run concurrently. For long-running processes like
+// 'amem serve'.
+func (db *DB) StartWriteQueue() (stop func()) {
+	q := &writeQueue{
+		jobs:    make(chan writeJob, maxWriteBatch),
+		stopped: make(chan struct{}),
+	}
+
+	db.queueMu.Lock()
+	db.queue = q
+	db.queueMu.Unlock()
+
+	go db.runWriteQueue(q)
+
+	return func() {
+		// Once no sender holds queueMu, nothing else can be queued
+		db.queueMu.Lock()
+		db.queue = nil
+		db.queueMu.Unlock()
+
+		close(q.jobs)
+		<-q.stopped
+	}
+}
+
+// runWriteQueue runs queued writes until the queue is closed, batching any
+// writes that arrived while the previous batch was running.
+func (db *DB) runWriteQueue(q *writeQueue) {
+	defer close(q.stopped)
+
+	for job := range q.jobs {
+		batch := []writeJob{job}
+	drain:
+		for len(batch) < maxWriteBatch {
+			select {
+			case next, ok := <-q.jobs:
+				if !ok {
+					break drain
+				}
+				batch = append(batch, next)
+			default:
+				break drain
+			}
+		}
+
+		db.runLocked(batch)
+	}
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestWriteQueue(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_queue.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	stop := db.StartWriteQueue()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := db.Locked(func() error {
+				_, err := db.AddObservation("Alice", "Likes tea")
+				return err
+			})
+			if err != nil {
+				t.Errorf("Queued write failed: %v", err)
+			}
+		}()
+	}
+
+	// Reads don't wait for the queue
+	if _, err := db.SearchObservations("Alice", nil, true); err != nil {
+		t.Errorf("Read during queued writes failed: %v", err)
+	}
+
+	wg.Wait()
+	stop()
+
+	count, err := db.CountObservations()
+	if err != nil {
+		t.Fatalf("CountObservations failed: %v", err)
+	}
+	if count != 20 {
+		t.Errorf("Expected 20 observations, got %d", count)
+	}
+
+	// Writes still work directly once the queue is stopped
+	if err := db.Locked(func() error { return nil }); err != nil {
+		t.Errorf("Locked after stopping queue failed: %v", err)
+	}
+}
+
+func TestWriteQueueOrder(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_queue_order.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	stop := db.StartWriteQueue()
+	defer stop()
+
+	// Writes submitted one after another run in submission order
+	var order []int
+	for i := 0; i < 5; i++ {
+		if err := db.Locked(func() error {
+			order = append(order, i)
+			return nil
+		}); err != nil {
+			t.Fatalf("Locked failed: %v", err)
+		}
+	}
+	for i, got := range order {
+		if got != i {
+			t.Errorf("Expected write %d at position %d, got %d", i, i, got)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Eviction policies for Quota.
+const (
+	PolicyOldest           = "oldest"
+	PolicyLeastAccessed    = "least-accessed"
+	PolicyLowestImportance = "lowest-importance"
+)
+
+// ErrQuotaExceeded is returned when a quota cannot be met by evicting observations.
+var ErrQuotaExceeded = errors.New("quota exceeded and no observations left to evict")
+
+// Quota caps how much a database may hold.
+// A zero limit means no limit. Only observations are evicted; entities and
+// relationships count toward MaxRecords but are never removed.
+type Quota struct {
+	MaxRecords int    `json:"max_records,omitempty"`
+	MaxDBBytes int64  `json:"max_db_bytes,omitempty"`
+	Policy     string `json:"policy,omitempty"`
+}
+
+// Validate checks that the quota's policy is known.
+func (q *Quota) Validate() error {
+	switch q.Policy {
+	case "", PolicyOldest, PolicyLeastAccessed, PolicyLowestImportance:
+	default:
+		return fmt.Errorf("unknown quota policy '%s' (use %s, %s, or %s)", q.Policy, PolicyOldest, PolicyLeastAccessed, PolicyLowestImportance)
+	}
+	if q.MaxRecords < 0 || q.MaxDBBytes < 0 {
+		return fmt.Errorf("quota limits cannot be negative")
+	}
+	return nil
+}
+
+// evictionOrder returns the ORDER BY clause selecting observations to evict first.
+func (q *Quota) evictionOrder() string {
+	switch q.Policy {
+	case PolicyLeastAccessed:
+		return "access_count ASC, COALESCE(last_accessed, timestamp) ASC, id ASC"
+	case PolicyLowestImportance:
+		return "importance ASC, timestamp ASC, id ASC"
+	default:
+		return "timestamp ASC, id ASC"
+	}
+}
+
+// SetQuota sets the quota enforced after every add. A nil quota disables enforcement.
+func (db *DB) SetQuota(q *Quota) error {
+	if q != nil {
+		if err := q.Validate(); err != nil {
+			return err
+		}
+	}
+	db.quota = q
+	return nil
+}
+
+// Quota returns the quota enforced after every add, or nil if there is none.
+func (db *DB) Quota() *Quota {
+	return db.quota
+}
+
+// EnforceQuota evicts observations until the database is within its quota.
+// Returns the number of evicted observations.
+func (db *DB) EnforceQuota() (int, error) {
+	return db.enforceQuota(0)
+}
+
+// enforceQuotaOnAdd enforces the quota after an insert, never evicting keepID.
+// A quota that can't be met is not an error here; the record was still added.
+func (db *DB) enforceQuotaOnAdd(keepID int64) error {
+	// Meeting a byte quota needs a VACUUM, which can't run in a transaction, so
+	// Transaction enforces it after committing
+	if db.tx != nil {
+		db.quotaPending = true
+		if keepID != 0 {
+			db.quotaKeepID = keepID
+		}
+		return nil
+	}
+	if _, err := db.enforceQuota(keepID); err != nil && !errors.Is(err, ErrQuotaExceeded) {
+		return fmt.Errorf("failed to enforce quota: %w", err)
+	}
+	return nil
+}
+
+func (db *DB) enforceQuota(keepID int64) (int, error) {
+	if db.quota == nil {
+		return 0, nil
+	}
+
+	evicted := 0
+
+	if db.quota.MaxRecords > 0 {
+		total, err := db.countRecords()
+		if err != nil {
+			return evicted, err
+		}
+		if excess := total - db.quota.MaxRecords; excess > 0 {
+			n, err := db.evictObservations(keepID, excess)
+			evicted += n
+			if err != nil {
+				return evicted, err
+			}
+		}
+	}
+
+	if db.quota.MaxDBBytes > 0 {
+		for {
+			size, err := db.usedBytes()
+			if err != nil {
+				return evicted, err
+			}
+			excess := size - db.quota.MaxDBBytes
+			if excess <= 0 {
+				break
+			}
+			// Every observation carries index and full-text pages besides its text, so the
+			// average an observation takes up says how many to evict. It counts the schema's
+			// own pages too, so it errs on evicting too few, and the loop evicts more.
+			var observations int64
+			if err := db.queryRow("SELECT COUNT(*) FROM observations").Scan(&observations); err != nil {
+				return evicted, fmt.Errorf("failed to count observations: %w", err)
+			}
+			perRow := size / max(observations, 1)
+			n, err := db.evictObservations(keepID, int(max((excess+perRow-1)/perRow, 1)))
+			evicted += n
+			if err != nil {
+				return evicted, err
+			}
+			// Deleted rows only free whole pages after a vacuum
+			if _, err := db.exec("VACUUM"); err != nil {
+				return evicted, fmt.Errorf("failed to vacuum: %w", err)
+			}
+		}
+	}
+
+	return evicted, nil
+}
+
+// evictObservations deletes up to count observations in eviction order.
+func (db *DB) evictObservations(keepID int64, count int) (int, error) {
+	query := "SELECT id FROM observations WHERE id != ? ORDER BY " + db.quota.evictionOrder() + " LIMIT ?"
+	rows, err := db.query(query, keepID, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to select observations to evict: %w", err)
+	}
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan observation: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	_ = rows.Close()
+
+	if len(ids) == 0 {
+		return 0, ErrQuotaExceeded
+	}
+
+	placeholders, args := idList(ids)
+	if _, err := db.exec("DELETE FROM observations WHERE id IN ("+placeholders+")", args...); err != nil {
+		return 0, fmt.Errorf("failed to evict observations: %w", err)
+	}
+	// Undo history would keep a copy of each evicted observation, so evicting could never
+	// shrink the database; evictions aren't undone
+	if db.history {
+		if _, err := db.exec("DELETE FROM history WHERE tbl = 'observations' AND row_id IN ("+placeholders+")", args...); err != nil {
+			return 0, fmt.Errorf("failed to evict observations: %w", err)
+		}
+	}
+
+	if len(ids) < count {
+		return len(ids), ErrQuotaExceeded
+	}
+	return len(ids), nil
+}
+
+// countRecords returns the total number of entities, observations, and relationships.
+func (db *DB) countRecords() (int, error) {
+	var total int
+	err := db.queryRow(`
+		SELECT (SELECT COUNT(*) FROM entities)
+			+ (SELECT COUNT(*) FROM observations)
+			+ (SELECT COUNT(*) FROM relationships)
+	`).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count records: %w", err)
+	}
+	return total, nil
+}
+
+// usedBytes returns the size of the database excluding free pages.
+func (db *DB) usedBytes() (int64, error) {
+	var pageCount, freeCount, pageSize int64
+	if err := db.queryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := db.queryRow("PRAGMA freelist_count").Scan(&freeCount); err != nil {
+		return 0, fmt.Errorf("failed to read freelist count: %w", err)
+	}
+	if err := db.queryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return (pageCount - freeCount) * pageSize, nil
+}
+
+// MarkObservationsAccessed records that observations were returned to a reader.
+// Used by the least-accessed eviction policy. Should be run under Locked.
+func (db *DB) MarkObservationsAccessed(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders, args := idList(ids)
+	query := "UPDATE observations SET access_count = access_count + 1, last_accessed = CURRENT_TIMESTAMP WHERE id IN (" + placeholders + ")"
+	if _, err := db.exec(query, args...); err != nil {
+		return fmt.Errorf("failed to mark observations accessed: %w", err)
+	}
+	return nil
+}
+
+// idList returns a placeholder list and matching arguments for an IN clause.
+func idList(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return strings.TrimSuffix(strings.Repeat("?,", len(ids)), ","), args
+}
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestQuotaMaxRecordsOldest(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_quota.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// 1 entity + 2 observations fills a quota of 3
+	if err := db.SetQuota(&Quota{MaxRecords: 3, Policy: PolicyOldest}); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	first, _ := db.AddObservation("Alice", "first")
+	if _, err := db.AddObservation("Alice", "second"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	third, err := db.AddObservation("Alice", "third")
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	results, err := db.SearchObservations("Alice", nil, true)
+	if err != nil {
+		t.Fatalf("SearchObservations failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 observations after eviction, got %d", len(results))
+	}
+	for _, o := range results {
+		if o.ID == first {
+			t.Error("Expected oldest observation to be evicted")
+		}
+	}
+	found := false
+	for _, o := range results {
+		if o.ID == third {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected newly added observation to be kept")
+	}
+}
+
+func TestQuotaLowestImportance(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_quota_importance.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	important, _ := db.AddObservationWithOptions("Alice", "important", ObservationOptions{Importance: 5})
+	trivial, _ := db.AddObservationWithOptions("Alice", "trivial", ObservationOptions{Importance: 0})
+	_, _ = db.AddObservationWithOptions("Alice", "useful", ObservationOptions{Importance: 3})
+
+	if err := db.SetQuota(&Quota{MaxRecords: 3, Policy: PolicyLowestImportance}); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	evicted, err := db.EnforceQuota()
+	if err != nil {
+		t.Fatalf("EnforceQuota failed: %v", err)
+	}
+	if evicted != 1 {
+		t.Errorf("Expected 1 eviction, got %d", evicted)
+	}
+
+	results, _ := db.SearchObservations("Alice", nil, true)
+	for _, o := range results {
+		if o.ID == trivial {
+			t.Error("Expected lowest importance observation to be evicted")
+		}
+	}
+	if len(results) != 2 || (results[0].ID != important && results[1].ID != important) {
+		t.Errorf("Expected important observation to be kept, got %v", results)
+	}
+}
+
+func TestQuotaLeastAccessed(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_quota_accessed.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	popular, _ := db.AddObservation("Alice", "popular")
+	ignored, _ := db.AddObservation("Alice", "ignored")
+	if err := db.MarkObservationsAccessed([]int64{popular}); err != nil {
+		t.Fatalf("MarkObservationsAccessed failed: %v", err)
+	}
+
+	if err := db.SetQuota(&Quota{MaxRecords: 2, Policy: PolicyLeastAccessed}); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	if _, err := db.EnforceQuota(); err != nil {
+		t.Fatalf("EnforceQuota failed: %v", err)
+	}
+
+	results, _ := db.SearchObservations("Alice", nil, true)
+	if len(results) != 1 || results[0].ID != popular {
+		t.Errorf("Expected only popular observation to remain (ignored ID %d), got %v", ignored, results)
+	}
+}
+
+func TestQuotaUnsatisfiable(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_quota_unsatisfiable.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	_, _ = db.AddEntity("Alice")
+	_, _ = db.AddEntity("Bob")
+
+	if err := db.SetQuota(&Quota{MaxRecords: 1}); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	if _, err := db.EnforceQuota(); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+
+	// Adds still succeed when the quota can't be met
+	if _, err := db.AddEntity("Charlie"); err != nil {
+		t.Errorf("Expected add to succeed, got %v", err)
+	}
+}
+
+func TestQuotaInvalidPolicy(t *testing.T) {
+	q := &Quota{Policy: "random"}
+	if err := q.Validate(); err == nil {
+		t.Error("Expected error for unknown policy")
+	}
+}
+
+func TestQuotaMaxDBBytes(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_quota_bytes.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for i := 0; i < 20; i++ {
+		if _, err := db.AddObservation("Alice", fmt.Sprintf("Note %d: %s", i, strings.Repeat("Likes tea. ", 100))); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+	size, err := db.usedBytes()
+	if err != nil {
+		t.Fatalf("usedBytes failed: %v", err)
+	}
+
+	// Adds run in a transaction, where the VACUUM that frees evicted pages can't
+	if err := db.SetQuota(&Quota{MaxDBBytes: size - 8192}); err != nil {
+		t.Fatalf("SetQuota failed: %v", err)
+	}
+	if _, err := db.AddEntity("Bob"); err != nil {
+		t.Fatalf("AddEntity over a byte quota failed: %v", err)
+	}
+	id, err := db.AddObservation("Carol", "hi")
+	if err != nil {
+		t.Fatalf("AddObservation over a byte quota failed: %v", err)
+	}
+
+	var left int
+	if err := db.queryRow("SELECT COUNT(*) FROM observations WHERE text != 'hi'").Scan(&left); err != nil {
+		t.Fatalf("Failed to count observations: %v", err)
+	}
+	// Each observation is over 1 KB, so an 8 KB overage needs only a few evicted
+	if left >= 20 || left < 10 {
+		t.Errorf("Expected a few observations evicted to meet the byte quota, %d of 20 left", left)
+	}
+	if size, _ := db.usedBytes(); size > db.quota.MaxDBBytes {
+		t.Errorf("Expected the database within its quota, using %d of %d bytes", size, db.quota.MaxDBBytes)
+	}
+	if _, err := db.GetObservation(id); err != nil {
+		t.Errorf("Expected the new observation to be kept: %v", err)
+	}
+}
@@ -0,0 +1,158 @@
+package db
+
+import (
+	"fmt"
+	"sort"
+)
+
+// indexes lists every index the schema should have, for Repair to recreate.
+var indexes = map[string]string{
+	"idx_observations_entity":      "CREATE INDEX IF NOT EXISTS idx_observations_entity ON observations(entity_id)",
+	"idx_relationships_from":       "CREATE INDEX IF NOT EXISTS idx_relationships_from ON relationships(from_id)",
+	"idx_relationships_to":         "CREATE INDEX IF NOT EXISTS idx_relationships_to ON relationships(to_id)",
+	"idx_relationships_type":       "CREATE INDEX IF NOT EXISTS idx_relationships_type ON relationships(type)",
+	"idx_history_batch":            "CREATE INDEX IF NOT EXISTS idx_history_batch ON history(batch)",
+	"idx_entities_updated_at":      "CREATE INDEX IF NOT EXISTS idx_entities_updated_at ON entities(updated_at)",
+	"idx_observations_updated_at":  "CREATE INDEX IF NOT EXISTS idx_observations_updated_at ON observations(updated_at)",
+	"idx_relationships_updated_at": "CREATE INDEX IF NOT EXISTS idx_relationships_updated_at ON relationships(updated_at)",
+}
+
+// RepairReport describes what Repair found and fixed.
+type RepairReport struct {
+	// MigrationsRerun is true if schema_migrations didn't match the schema and was rebuilt
+	MigrationsRerun bool
+	// SchemaVersion is the schema version after repair
+	SchemaVersion int
+	// ForeignKeysEnabled is true if foreign key enforcement had been off
+	ForeignKeysEnabled bool
+	// RecreatedIndexes names the missing indexes that were rebuilt
+	RecreatedIndexes []string
+	// Rows deleted for referencing entities that no longer exist
+	OrphanedObservations  int64
+	OrphanedRelationships int64
+}
+
+// Repair fixes a damaged database: it rebuilds schema_migrations from the actual
+// schema and applies missing migrations, re-enables foreign keys, recreates
+// missing indexes, and removes rows that reference deleted entities.
+func (db *DB) Repair() (*RepairReport, error) {
+	report := &RepairReport{}
+
+	rerun, err := db.repairMigrations()
+	if err != nil {
+		return report, err
+	}
+	report.MigrationsRerun = rerun
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		return report, err
+	}
+	report.SchemaVersion = version
+
+	var foreignKeys int
+	if err := db.queryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil {
+		return report, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	if foreignKeys == 0 {
+		if _, err := db.exec("PRAGMA foreign_keys = ON"); err != nil {
+			return report, fmt.Errorf("failed to enable foreign keys: %w", err)
+		}
+		report.ForeignKeysEnabled = true
+	}
+
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		var count int
+		if err := db.queryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='index' AND name = ?", name).Scan(&count); err != nil {
+			return report, fmt.Errorf("failed to check index %s: %w", name, err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := db.exec(indexes[name]); err != nil {
+			return report, fmt.Errorf("failed to recreate index %s: %w", name, err)
+		}
+		report.RecreatedIndexes = append(report.RecreatedIndexes, name)
+	}
+
+	result, err := db.exec(`
+		DELETE FROM relationships
+		WHERE from_id NOT IN (SELECT id FROM entities) OR to_id NOT IN (SELECT id FROM entities)
+	`)
+	if err != nil {
+		return report, fmt.Errorf("failed to remove orphaned relationships: %w", err)
+	}
+	report.OrphanedRelationships, _ = result.RowsAffected()
+
+	result, err = db.exec("DELETE FROM observations WHERE entity_id NOT IN (SELECT id FROM entities)")
+	if err != nil {
+		return report, fmt.Errorf("failed to remove orphaned observations: %w", err)
+	}
+	report.OrphanedObservations, _ = result.RowsAffected()
+
+	return report, nil
+}
+
+// repairMigrations checks schema_migrations against the schema itself. If they
+// disagree, it records the migrations that are actually applied and applies the
+// rest. Returns whether schema_migrations had to be rebuilt.
+func (db *DB) repairMigrations() (bool, error) {
+	if _, err := db.exec(schemaVersionsTable); err != nil {
+		return false, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	recorded := map[int]bool{}
+	rows, err := db.query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return false, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			_ = rows.Close()
+			return false, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		recorded[version] = true
+	}
+	_ = rows.Close()
+
+	// Migrations are applied in order, so everything up to the first missing one is applied
+	var applied []int
+	for _, m := range migrations {
+		var present bool
+		if err := db.queryRow(m.Applied).Scan(&present); err != nil {
+			return false, fmt.Errorf("failed to check migration %d: %w", m.Version, err)
+		}
+		if !present {
+			break
+		}
+		applied = append(applied, m.Version)
+	}
+
+	consistent := len(recorded) == len(applied)
+	for _, version := range applied {
+		consistent = consistent && recorded[version]
+	}
+
+	if !consistent {
+		if _, err := db.exec("DELETE FROM schema_migrations"); err != nil {
+			return false, fmt.Errorf("failed to reset schema_migrations: %w", err)
+		}
+		for _, version := range applied {
+			if _, err := db.exec("INSERT INTO schema_migrations (version) VALUES (?)", version); err != nil {
+				return false, fmt.Errorf("failed to record migration %d: %w", version, err)
+			}
+		}
+	}
+
+	if err := migrate(db.conn); err != nil {
+		return false, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return !consistent, nil
+}
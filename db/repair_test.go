@@ -0,0 +1,93 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	path := t.TempDir() + "/test_repair.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	aliceID, _ := db.AddEntity("Alice")
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	// Damage the database: orphan rows, drop an index, and forget a migration
+	conn := db.conn
+	conn.SetMaxOpenConns(1)
+	damage := []string{
+		"PRAGMA foreign_keys = OFF",
+		"DELETE FROM entities WHERE text = 'Bob'",
+		"DROP INDEX idx_relationships_to",
+		"DELETE FROM schema_migrations WHERE version >= 2",
+	}
+	for _, stmt := range damage {
+		if _, err := conn.Exec(stmt); err != nil {
+			t.Fatalf("Failed to damage database (%s): %v", stmt, err)
+		}
+	}
+	_ = db.Close()
+
+	// Migration 2 onward is recorded as missing but its columns exist, so migrating fails
+	if _, err := Open(path, key); err == nil {
+		t.Fatal("Expected Open to fail on inconsistent schema_migrations")
+	}
+
+	db, err = OpenWithOptions(path, key, Options{LockTimeout: DefaultLockTimeout, SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("OpenWithOptions failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	report, err := db.Repair()
+	if err != nil {
+		t.Fatalf("Repair failed: %v", err)
+	}
+
+	if !report.MigrationsRerun {
+		t.Error("Expected schema_migrations to be rebuilt")
+	}
+	if report.SchemaVersion != LatestSchemaVersion() {
+		t.Errorf("Expected schema version %d, got %d", LatestSchemaVersion(), report.SchemaVersion)
+	}
+	if len(report.RecreatedIndexes) != 1 || report.RecreatedIndexes[0] != "idx_relationships_to" {
+		t.Errorf("Expected idx_relationships_to to be recreated, got %v", report.RecreatedIndexes)
+	}
+	if report.OrphanedRelationships != 1 {
+		t.Errorf("Expected 1 orphaned relationship, got %d", report.OrphanedRelationships)
+	}
+	if report.OrphanedObservations != 0 {
+		t.Errorf("Expected no orphaned observations, got %d", report.OrphanedObservations)
+	}
+
+	observations, err := db.SearchObservations("Alice", nil, true)
+	if err != nil || len(observations) != 1 || observations[0].EntityID != aliceID {
+		t.Errorf("Expected Alice's observation to survive, got %v (err %v)", observations, err)
+	}
+
+	// A healthy database needs no repairs
+	report, err = db.Repair()
+	if err != nil {
+		t.Fatalf("Second Repair failed: %v", err)
+	}
+	if report.MigrationsRerun || len(report.RecreatedIndexes) > 0 || report.OrphanedRelationships > 0 {
+		t.Errorf("Expected nothing to repair, got %+v", report)
+	}
+}
+
+func TestMigrationsHaveAppliedCheck(t *testing.T) {
+	for _, m := range migrations {
+		if m.Applied == "" {
+			t.Errorf("Migration %d has no Applied query", m.Version)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+)
+
+// BackupSnapshotName names the snapshots 'amem backup' takes. Only these are pruned,
+// so snapshots taken by hand are never removed by a retention policy.
+const BackupSnapshotName = "backup"
+
+// MigrationSnapshotName names the snapshots Open takes before upgrading a database's schema,
+// so a migration that goes wrong can be undone with 'amem snapshot restore pre-migrate'.
+const MigrationSnapshotName = "pre-migrate"
+
+// Retention says which backups to keep. A backup is kept if it's the newest of one of
+// the last KeepDaily days or KeepWeekly weeks that have backups; the newest backup is
+// always kept. Days and weeks are in local time.
+type Retention struct {
+	KeepDaily  int `json:"keep_daily,omitempty"`
+	KeepWeekly int `json:"keep_weekly,omitempty"`
+}
+
+// Validate checks that the retention keeps something.
+func (r *Retention) Validate() error {
+	if r.KeepDaily < 0 || r.KeepWeekly < 0 {
+		return fmt.Errorf("keep_daily and keep_weekly cannot be negative")
+	}
+	if r.KeepDaily == 0 && r.KeepWeekly == 0 {
+		return fmt.Errorf("set keep_daily or keep_weekly, or remove the backup setting to keep every backup")
+	}
+	return nil
+}
+
+// PruneBackups deletes the backups, snapshots named BackupSnapshotName, that r doesn't
+// keep, and returns them.
+func (db *DB) PruneBackups(r *Retention) ([]Snapshot, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	backups := slices.DeleteFunc(snapshots, func(s Snapshot) bool { return s.Name != BackupSnapshotName })
+
+	prune := r.expired(backups, time.Local)
+	for i, s := range prune {
+		if err := os.Remove(s.Path); err != nil {
+			return prune[:i], fmt.Errorf("failed to remove backup %s: %w", s.Path, err)
+		}
+		if err := removeManifest(s.Path); err != nil {
+			return prune[:i+1], err
+		}
+		if err := removeKeySlots(s.Path); err != nil {
+			return prune[:i+1], err
+		}
+	}
+	return prune, nil
+}
+
+// expired returns the backups, given oldest first, that r doesn't keep, oldest first.
+func (r *Retention) expired(backups []Snapshot, loc *time.Location) []Snapshot {
+	keep := map[string]bool{}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+
+	for i := len(backups) - 1; i >= 0; i-- {
+		b := backups[i]
+		created := b.Created.In(loc)
+		day := created.Format(time.DateOnly)
+		year, week := created.ISOWeek()
+		weekKey := fmt.Sprintf("%d-W%02d", year, week)
+
+		if i == len(backups)-1 {
+			keep[b.Path] = true
+		}
+		if !days[day] && len(days) < r.KeepDaily {
+			keep[b.Path] = true
+		}
+		if !weeks[weekKey] && len(weeks) < r.KeepWeekly {
+			keep[b.Path] = true
+		}
+		days[day] = true
+		weeks[weekKey] = true
+	}
+
+	var expired []Snapshot
+	for _, b := range backups {
+		if !keep[b.Path] {
+			expired = append(expired, b)
+		}
+	}
+	return expired
+}
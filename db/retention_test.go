@@ -0,0 +1,111 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRetentionExpired(t *testing.T) {
+	at := func(date string) Snapshot {
+		created, err := time.Parse("2006-01-02 15:04", date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return Snapshot{Name: BackupSnapshotName, Created: created, Path: date}
+	}
+	// Mondays start ISO weeks: 2025-03-03, 03-10, and 03-17
+	backups := []Snapshot{
+		at("2025-02-24 09:00"),
+		at("2025-03-03 09:00"),
+		at("2025-03-05 09:00"),
+		at("2025-03-10 09:00"),
+		at("2025-03-12 09:00"),
+		at("2025-03-12 18:00"),
+		at("2025-03-13 09:00"),
+		at("2025-03-17 09:00"),
+	}
+
+	tests := []struct {
+		retention Retention
+		want      []string
+	}{
+		{
+			// The newest of each of the last three days with backups
+			retention: Retention{KeepDaily: 3},
+			want:      []string{"2025-02-24 09:00", "2025-03-03 09:00", "2025-03-05 09:00", "2025-03-10 09:00", "2025-03-12 09:00"},
+		},
+		{
+			// The newest of each of the last three weeks
+			retention: Retention{KeepWeekly: 3},
+			want:      []string{"2025-02-24 09:00", "2025-03-03 09:00", "2025-03-10 09:00", "2025-03-12 09:00", "2025-03-12 18:00"},
+		},
+		{
+			retention: Retention{KeepDaily: 2, KeepWeekly: 4},
+			want:      []string{"2025-03-03 09:00", "2025-03-10 09:00", "2025-03-12 09:00", "2025-03-12 18:00"},
+		},
+		{
+			retention: Retention{KeepDaily: 100},
+			want:      []string{"2025-03-12 09:00"},
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, s := range tt.retention.expired(backups, time.UTC) {
+			got = append(got, s.Path)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%+v expired %v, want %v", tt.retention, got, tt.want)
+		}
+	}
+}
+
+func TestPruneBackups(t *testing.T) {
+	path := t.TempDir() + "/test_prune.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Two backups from earlier days and one hand-made snapshot, then today's backup
+	dir := SnapshotsDir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"20250101T000000.000Z_backup.db", "20250102T000000.000Z_backup.db", "20250101T000000.000Z_keep-me.db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.CreateSnapshot(BackupSnapshotName); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+
+	pruned, err := db.PruneBackups(&Retention{KeepDaily: 2})
+	if err != nil {
+		t.Fatalf("PruneBackups failed: %v", err)
+	}
+	if len(pruned) != 1 || filepath.Base(pruned[0].Path) != "20250101T000000.000Z_backup.db" {
+		t.Errorf("PruneBackups() pruned %+v, want the oldest backup", pruned)
+	}
+
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	var names []string
+	for _, s := range snapshots {
+		names = append(names, s.Name)
+	}
+	if want := []string{"keep-me", "backup", "backup"}; !slices.Equal(names, want) {
+		t.Errorf("Snapshots after pruning = %v, want %v", names, want)
+	}
+
+	if _, err := db.PruneBackups(&Retention{}); err == nil {
+		t.Error("PruneBackups with nothing to keep should fail")
+	}
+}
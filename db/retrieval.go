@@ -0,0 +1,158 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SetRecordRetrievals turns the retrieval log read by RetrievalStats on or off.
+func (db *DB) SetRecordRetrievals(on bool) {
+	db.recordRetrievals = on
+}
+
+// Retrieved records that a search returned observations to a reader: it marks them
+// accessed for the least-accessed quota policy and, if retrievals are recorded, logs
+// the search and its results. about is the entity the search was limited to, if any.
+// Should be run under Locked.
+func (db *DB) Retrieved(about string, keywords []string, ids []int64) error {
+	if err := db.MarkObservationsAccessed(ids); err != nil {
+		return err
+	}
+	if !db.recordRetrievals {
+		return nil
+	}
+
+	return db.Transaction(func(tx *DB) error {
+		result, err := tx.exec("INSERT INTO retrievals (about, query, results) VALUES (?, ?, ?)",
+			about, strings.Join(keywords, " "), len(ids))
+		if err != nil {
+			return fmt.Errorf("failed to record retrieval: %w", err)
+		}
+		retrieval, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to record retrieval: %w", err)
+		}
+		for _, id := range ids {
+			if _, err := tx.exec("INSERT INTO retrieved_observations (retrieval_id, observation_id) VALUES (?, ?)", retrieval, id); err != nil {
+				return fmt.Errorf("failed to record retrieved observation: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// RetrievedObservation is an observation and how many recorded searches returned it.
+type RetrievedObservation struct {
+	ID        int64  `json:"id"`
+	Entity    string `json:"entity"`
+	Text      string `json:"text"`
+	Retrieved int    `json:"retrieved"`
+}
+
+// QueryCount is a search and how many times it was made.
+type QueryCount struct {
+	About string `json:"about,omitempty"`
+	Query string `json:"query"`
+	Count int    `json:"count"`
+}
+
+// RetrievalStats summarizes the retrieval log.
+type RetrievalStats struct {
+	Searches int `json:"searches"`
+	// ZeroResults counts searches that returned no observations
+	ZeroResults int `json:"zero_results"`
+	// Most and Least are the observations returned by the most and fewest searches;
+	// Least includes observations no search returned
+	Most  []RetrievedObservation `json:"most"`
+	Least []RetrievedObservation `json:"least"`
+	// ZeroResultQueries are the most frequent searches that returned nothing
+	ZeroResultQueries []QueryCount `json:"zero_result_queries"`
+}
+
+// ZeroResultRate returns the fraction of searches that returned no observations.
+func (s *RetrievalStats) ZeroResultRate() float64 {
+	if s.Searches == 0 {
+		return 0
+	}
+	return float64(s.ZeroResults) / float64(s.Searches)
+}
+
+// RetrievalStats summarizes the retrieval log, listing up to limit observations and queries in each ranking.
+func (db *DB) RetrievalStats(limit int) (*RetrievalStats, error) {
+	stats := &RetrievalStats{}
+	err := db.queryRow("SELECT COUNT(*), COUNT(CASE WHEN results = 0 THEN 1 END) FROM retrievals").Scan(&stats.Searches, &stats.ZeroResults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count retrievals: %w", err)
+	}
+
+	ranked := `SELECT o.id, e.text, o.text, COUNT(r.observation_id) AS retrieved
+		FROM observations o
+		JOIN entities e ON e.id = o.entity_id
+		LEFT JOIN retrieved_observations r ON r.observation_id = o.id
+		GROUP BY o.id
+		ORDER BY retrieved %s, o.id
+		LIMIT ?`
+	if stats.Most, err = db.retrievedObservations(fmt.Sprintf(ranked, "DESC"), limit); err != nil {
+		return nil, err
+	}
+	// Observations never retrieved would fill Most with zeros
+	for i, o := range stats.Most {
+		if o.Retrieved == 0 {
+			stats.Most = stats.Most[:i]
+			break
+		}
+	}
+	if stats.Least, err = db.retrievedObservations(fmt.Sprintf(ranked, "ASC"), limit); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.query(`SELECT about, query, COUNT(*) AS n FROM retrievals WHERE results = 0
+		GROUP BY about, query ORDER BY n DESC, MAX(id) DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zero-result queries: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var q QueryCount
+		if err := rows.Scan(&q.About, &q.Query, &q.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan zero-result query: %w", err)
+		}
+		stats.ZeroResultQueries = append(stats.ZeroResultQueries, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read zero-result queries: %w", err)
+	}
+	return stats, nil
+}
+
+func (db *DB) retrievedObservations(query string, limit int) ([]RetrievedObservation, error) {
+	rows, err := db.query(query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank observations: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var observations []RetrievedObservation
+	for rows.Next() {
+		var o RetrievedObservation
+		if err := rows.Scan(&o.ID, &o.Entity, &o.Text, &o.Retrieved); err != nil {
+			return nil, fmt.Errorf("failed to scan observation: %w", err)
+		}
+		observations = append(observations, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to rank observations: %w", err)
+	}
+	return observations, nil
+}
+
+// ClearRetrievals deletes the retrieval log. Should be run under Locked.
+func (db *DB) ClearRetrievals() error {
+	if _, err := db.exec("DELETE FROM retrieved_observations"); err != nil {
+		return fmt.Errorf("failed to clear retrievals: %w", err)
+	}
+	if _, err := db.exec("DELETE FROM retrievals"); err != nil {
+		return fmt.Errorf("failed to clear retrievals: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package db
+
+import "testing"
+
+func TestRetrievalStats(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_retrieval.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	tea, _ := db.AddObservation("Alice", "Likes tea")
+	cake, _ := db.AddObservation("Alice", "Likes cake")
+	unseen, _ := db.AddObservation("Bob", "Likes chess")
+
+	// Nothing is logged until recording is turned on
+	if err := db.Retrieved("", []string{"tea"}, []int64{tea}); err != nil {
+		t.Fatalf("Retrieved failed: %v", err)
+	}
+	db.SetRecordRetrievals(true)
+	for _, search := range []struct {
+		keywords []string
+		ids      []int64
+	}{
+		{[]string{"tea"}, []int64{tea}},
+		{[]string{"likes"}, []int64{tea, cake}},
+		{[]string{"coffee"}, nil},
+		{[]string{"coffee"}, nil},
+	} {
+		if err := db.Retrieved("", search.keywords, search.ids); err != nil {
+			t.Fatalf("Retrieved failed: %v", err)
+		}
+	}
+
+	stats, err := db.RetrievalStats(10)
+	if err != nil {
+		t.Fatalf("RetrievalStats failed: %v", err)
+	}
+	if stats.Searches != 4 || stats.ZeroResults != 2 || stats.ZeroResultRate() != 0.5 {
+		t.Errorf("Expected 4 searches, 2 with no results, got %+v", stats)
+	}
+	if len(stats.Most) != 2 || stats.Most[0].ID != tea || stats.Most[0].Retrieved != 2 {
+		t.Errorf("Expected tea most retrieved and never-retrieved observations left out, got %+v", stats.Most)
+	}
+	if len(stats.Least) != 3 || stats.Least[0].ID != unseen || stats.Least[0].Retrieved != 0 {
+		t.Errorf("Expected the unretrieved observation least retrieved, got %+v", stats.Least)
+	}
+	if len(stats.ZeroResultQueries) != 1 || stats.ZeroResultQueries[0] != (QueryCount{Query: "coffee", Count: 2}) {
+		t.Errorf("Expected 'coffee' as the zero-result query, got %+v", stats.ZeroResultQueries)
+	}
+
+	if err := db.ClearRetrievals(); err != nil {
+		t.Fatalf("ClearRetrievals failed: %v", err)
+	}
+	if stats, _ := db.RetrievalStats(10); stats.Searches != 0 || len(stats.Most) != 0 {
+		t.Errorf("Expected no stats after clearing, got %+v", stats)
+	}
+}
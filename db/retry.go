@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// busyTimeoutMillis is how long SQLite itself waits on a lock before returning
+// SQLITE_BUSY; retry handles longer waits so the lock timeout is honored.
+const busyTimeoutMillis = 100
+
+// isBusy reports whether err means another connection holds a conflicting lock.
+func isBusy(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.Code == sqlite3.ErrBusy || sqliteErr.Code == sqlite3.ErrLocked
+}
+
+// retry runs fn, retrying with jittered backoff while it fails because the
+// database is busy, until the lock timeout passes.
+func (db *DB) retry(fn func() error) error {
+	deadline := time.Now().Add(db.lockTimeout)
+	backoff := 10 * time.Millisecond
+
+	for {
+		err := fn()
+		if err == nil || !isBusy(err) || time.Now().After(deadline) {
+			return err
+		}
+
+		time.Sleep(backoff/2 + rand.N(backoff/2+1))
+		backoff = min(backoff*2, 250*time.Millisecond)
+	}
+}
+
+// executor is what statements run on: the connection pool, or the transaction
+// of a DB passed to a Transaction callback.
+type executor interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// executor returns where the DB's statements run.
+func (db *DB) executor() executor {
+	if db.tx != nil {
+		return db.tx
+	}
+	return db.conn
+}
+
+// exec is conn.Exec with busy retries.
+func (db *DB) exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := db.retry(func() error {
+		var err error
+		result, err = db.executor().Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// query is conn.Query with busy retries. Errors while iterating rows are not retried.
+func (db *DB) query(query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := db.retry(func() error {
+		var err error
+		rows, err = db.executor().Query(query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// row is a single-row query that runs, with busy retries, when scanned.
+type row struct {
+	db    *DB
+	query string
+	args  []interface{}
+}
+
+// queryRow is conn.QueryRow with busy retries.
+func (db *DB) queryRow(query string, args ...interface{}) row {
+	return row{db: db, query: query, args: args}
+}
+
+func (r row) Scan(dest ...interface{}) error {
+	return r.db.retry(func() error {
+		return r.db.executor().QueryRow(r.query, r.args...).Scan(dest...)
+	})
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBusy(t *testing.T) {
+	path := t.TempDir() + "/test_retry.db"
+	key := "testkey123456789012"
+
+	holder, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = holder.Close() }()
+
+	writer, err := OpenWithOptions(path, key, Options{LockTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = writer.Close() }()
+
+	// An open write transaction on another connection keeps the database busy
+	tx, err := holder.conn.Begin()
+	if err != nil {
+		t.Fatalf("Failed to begin transaction: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO entities (text) VALUES ('Alice')"); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+
+	if _, err := writer.AddEntity("Bob"); !isBusy(err) {
+		t.Fatalf("Expected busy error after lock timeout, got %v", err)
+	}
+
+	// Once the lock is released within the timeout, the write goes through
+	writer.lockTimeout = 5 * time.Second
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		_ = tx.Commit()
+	}()
+	if _, err := writer.AddEntity("Bob"); err != nil {
+		t.Errorf("Expected write to succeed after retrying, got %v", err)
+	}
+}
+
+func TestOpenWithOptionsRejectsNegativeTimeout(t *testing.T) {
+	if _, err := OpenWithOptions(t.TempDir()+"/test.db", "testkey123456789012", Options{LockTimeout: -time.Second}); err == nil {
+		t.Error("Expected error for negative lock timeout")
+	}
+}
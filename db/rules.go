@@ -0,0 +1,165 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrRuleViolation is returned when a write breaks one of the database's rules.
+var ErrRuleViolation = errors.New("rule violation")
+
+// Rules constrain what may be written, to keep a shared memory graph consistent.
+// Zero values impose no constraint.
+type Rules struct {
+	// MaxEntityLength caps entity text, in characters
+	MaxEntityLength int `json:"max_entity_length,omitempty"`
+	// MaxObservationLength caps observation text, in characters
+	MaxObservationLength int `json:"max_observation_length,omitempty"`
+	// MaxRelationshipTypeLength caps relationship types, in characters
+	MaxRelationshipTypeLength int `json:"max_relationship_type_length,omitempty"`
+	// BannedCharacters may not appear in entity text, observation text, or relationship types
+	BannedCharacters string `json:"banned_characters,omitempty"`
+	// RelationshipTypePattern is a regular expression each whole relationship type must match
+	RelationshipTypePattern string `json:"relationship_type_pattern,omitempty"`
+	// TrimWhitespace trims surrounding whitespace from entities, observations, and relationship
+	// types as they're written, and refuses empty entities and observations
+	TrimWhitespace bool `json:"trim_whitespace,omitempty"`
+	// Secrets is what to do with observations that look like they contain credentials,
+	// like AWS keys or private keys: SecretsWarn, SecretsRefuse, or nothing
+	Secrets string `json:"secrets,omitempty"`
+
+	relationshipType *regexp.Regexp
+}
+
+// Validate checks that the rules are usable, compiling the relationship type pattern.
+func (r *Rules) Validate() error {
+	if r.MaxEntityLength < 0 || r.MaxObservationLength < 0 || r.MaxRelationshipTypeLength < 0 {
+		return fmt.Errorf("max_entity_length, max_observation_length, and max_relationship_type_length cannot be negative")
+	}
+	switch r.Secrets {
+	case "", SecretsWarn, SecretsRefuse:
+	default:
+		return fmt.Errorf("invalid secrets %q (use %s or %s)", r.Secrets, SecretsWarn, SecretsRefuse)
+	}
+	if r.RelationshipTypePattern != "" {
+		re, err := regexp.Compile(`^(?:` + r.RelationshipTypePattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("invalid relationship_type_pattern: %w", err)
+		}
+		r.relationshipType = re
+	}
+	return nil
+}
+
+// SetRules sets the rules checked before every add and edit. Nil rules allow anything.
+func (db *DB) SetRules(r *Rules) error {
+	if r != nil {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+	db.rules = r
+	return nil
+}
+
+// Rules returns the rules checked before every add and edit, or nil if there are none.
+func (db *DB) Rules() *Rules {
+	return db.rules
+}
+
+// checkEntity checks entity text against the database's rules.
+func (db *DB) checkEntity(text string) error {
+	if db.rules == nil {
+		return nil
+	}
+	if err := db.checkNotEmpty("entity", text); err != nil {
+		return err
+	}
+	if err := checkLength("entity", text, db.rules.MaxEntityLength); err != nil {
+		return err
+	}
+	return db.rules.checkCharacters("entity", text)
+}
+
+// checkObservation checks observation text against the database's rules.
+func (db *DB) checkObservation(text string) error {
+	if db.rules == nil {
+		return nil
+	}
+	if err := db.checkNotEmpty("observation", text); err != nil {
+		return err
+	}
+	if err := checkLength("observation", text, db.rules.MaxObservationLength); err != nil {
+		return err
+	}
+	return db.rules.checkCharacters("observation", text)
+}
+
+// checkRelationshipType checks a relationship type against the database's rules.
+func (db *DB) checkRelationshipType(relType string) error {
+	if db.rules == nil {
+		return nil
+	}
+	if db.rules.relationshipType != nil && !db.rules.relationshipType.MatchString(relType) {
+		return fmt.Errorf("%w: relationship type '%s' doesn't match the pattern %s", ErrRuleViolation, relType, db.rules.RelationshipTypePattern)
+	}
+	if err := checkLength("relationship type", relType, db.rules.MaxRelationshipTypeLength); err != nil {
+		return err
+	}
+	return db.rules.checkCharacters("relationship type", relType)
+}
+
+// checkLength returns an error naming what if text is longer than limit characters. A zero limit allows any length.
+func checkLength(what, text string, limit int) error {
+	if limit <= 0 {
+		return nil
+	}
+	if n := utf8.RuneCountInString(text); n > limit {
+		return fmt.Errorf("%w: %s is %d characters long; the limit is %d", ErrRuleViolation, what, n, limit)
+	}
+	return nil
+}
+
+// TruncateEntity cuts entity text to the rules' length limit, if it has one.
+func (r *Rules) TruncateEntity(text string) string {
+	if r == nil {
+		return text
+	}
+	return truncate(text, r.MaxEntityLength)
+}
+
+// TruncateObservation cuts observation text to the rules' length limit, if it has one.
+func (r *Rules) TruncateObservation(text string) string {
+	if r == nil {
+		return text
+	}
+	return truncate(text, r.MaxObservationLength)
+}
+
+// TruncateRelationshipType cuts a relationship type to the rules' length limit, if it has one.
+func (r *Rules) TruncateRelationshipType(relType string) string {
+	if r == nil {
+		return relType
+	}
+	return truncate(relType, r.MaxRelationshipTypeLength)
+}
+
+// truncate cuts text to limit characters. A zero limit leaves it whole.
+func truncate(text string, limit int) string {
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	return string([]rune(text)[:limit])
+}
+
+// checkCharacters returns an error naming what if text contains a banned character.
+func (r *Rules) checkCharacters(what, text string) error {
+	if i := strings.IndexAny(text, r.BannedCharacters); i >= 0 {
+		c, _ := utf8.DecodeRuneInString(text[i:])
+		return fmt.Errorf("%w: %s contains the banned character %q", ErrRuleViolation, what, c)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package db
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	path := t.TempDir() + "/test_rules.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Added before the rules, so it can still be referenced after
+	if _, err := db.AddEntity("Legacy|Entity"); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	if err := db.SetRules(&Rules{
+		MaxObservationLength:    10,
+		BannedCharacters:        "|;",
+		RelationshipTypePattern: "[a-z_]+",
+	}); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+
+	violations := map[string]func() error{
+		"long observation": func() error {
+			_, err := db.AddObservation("Alice", "Likes tea a lot")
+			return err
+		},
+		"banned in entity": func() error {
+			_, err := db.AddObservation("Alice;Bob", "Likes tea")
+			return err
+		},
+		"banned in observation": func() error {
+			_, err := db.AddObservation("Alice", "tea|coffee")
+			return err
+		},
+		"relationship type pattern": func() error {
+			_, err := db.AddRelationship("Alice", "Bob", "Knows")
+			return err
+		},
+		"edited entity": func() error {
+			return db.UpdateEntity("Legacy|Entity", "Still|Banned")
+		},
+	}
+	for name, write := range violations {
+		if err := write(); !errors.Is(err, ErrRuleViolation) {
+			t.Errorf("%s: error = %v, want ErrRuleViolation", name, err)
+		}
+	}
+
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Errorf("AddObservation within the rules failed: %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "works_with"); err != nil {
+		t.Errorf("AddRelationship within the rules failed: %v", err)
+	}
+	if _, err := db.AddObservation("Legacy|Entity", "Still here"); err != nil {
+		t.Errorf("AddObservation about an entity from before the rules failed: %v", err)
+	}
+
+	if err := db.SetRules(&Rules{RelationshipTypePattern: "("}); err == nil || !strings.Contains(err.Error(), "relationship_type_pattern") {
+		t.Errorf("SetRules with an invalid pattern error = %v", err)
+	}
+	if err := db.SetRules(nil); err != nil {
+		t.Errorf("SetRules(nil) failed: %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "Knows"); err != nil {
+		t.Errorf("AddRelationship without rules failed: %v", err)
+	}
+}
+
+func TestLengthRules(t *testing.T) {
+	path := t.TempDir() + "/test_lengths.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	rules := &Rules{MaxEntityLength: 5, MaxObservationLength: 8, MaxRelationshipTypeLength: 5}
+	if err := db.SetRules(rules); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+
+	if _, err := db.AddEntity("Alexander"); !errors.Is(err, ErrRuleViolation) || !strings.Contains(err.Error(), "entity is 9 characters long; the limit is 5") {
+		t.Errorf("AddEntity over the limit error = %v", err)
+	}
+	if _, err := db.AddObservation("Alice", "Likes tea a lot"); !errors.Is(err, ErrRuleViolation) {
+		t.Errorf("AddObservation over the limit error = %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "works with"); !errors.Is(err, ErrRuleViolation) || !strings.Contains(err.Error(), "relationship type") {
+		t.Errorf("AddRelationship over the limit error = %v", err)
+	}
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Errorf("AddRelationship within the limits failed: %v", err)
+	}
+
+	// Limits count characters, not bytes
+	if got := rules.TruncateEntity("Zoë Smith"); got != "Zoë S" {
+		t.Errorf("TruncateEntity() = %q, want %q", got, "Zoë S")
+	}
+	if got := rules.TruncateObservation("Likes tea"); got != "Likes te" {
+		t.Errorf("TruncateObservation() = %q", got)
+	}
+	if got := rules.TruncateRelationshipType("knows"); got != "knows" {
+		t.Errorf("TruncateRelationshipType() = %q, want it unchanged", got)
+	}
+	if got := (*Rules)(nil).TruncateObservation("Likes tea"); got != "Likes tea" {
+		t.Errorf("TruncateObservation() without rules = %q, want it unchanged", got)
+	}
+
+	if err := db.SetRules(&Rules{MaxEntityLength: -1}); err == nil {
+		t.Error("SetRules with a negative limit should fail")
+	}
+}
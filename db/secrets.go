@@ -0,0 +1,52 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// ErrSecret is returned when an observation looks like it contains a credential
+// and the database's rules refuse secrets.
+var ErrSecret = fmt.Errorf("%w: possible secret", ErrRuleViolation)
+
+// What the secrets rule does with an observation that looks like it contains a credential.
+const (
+	SecretsWarn   = "warn"
+	SecretsRefuse = "refuse"
+)
+
+// secretPatterns match credentials that are obvious from their shape alone.
+var secretPatterns = []struct {
+	kind string
+	re   *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abpr]-[A-Za-z0-9-]{10,}`)},
+	{"API key", regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_-]{20,}`)},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+}
+
+// FindSecrets returns the kinds of credential text appears to contain, in a fixed order.
+func FindSecrets(text string) []string {
+	var kinds []string
+	for _, p := range secretPatterns {
+		if p.re.MatchString(text) {
+			kinds = append(kinds, p.kind)
+		}
+	}
+	return kinds
+}
+
+// checkSecrets refuses observation text that looks like it contains a credential,
+// if the database's rules say to.
+func (db *DB) checkSecrets(text string) error {
+	if db.rules == nil || db.rules.Secrets != SecretsRefuse {
+		return nil
+	}
+	if kinds := FindSecrets(text); len(kinds) > 0 {
+		return fmt.Errorf("%w: observation looks like it contains a credential (%s)", ErrSecret, kinds[0])
+	}
+	return nil
+}
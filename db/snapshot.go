@@ -0,0 +1,189 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// snapshotTimeFormat timestamps snapshot file names so they sort by age.
+const snapshotTimeFormat = "20060102T150405.000Z"
+
+// validSnapshotName keeps snapshot names safe to use in file names.
+var validSnapshotName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Snapshot is a named copy of the database taken at a point in time.
+type Snapshot struct {
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	Size    int64     `json:"size"`
+	Path    string    `json:"path"`
+}
+
+// SnapshotsDir returns the directory holding a database's snapshots.
+func SnapshotsDir(dbPath string) string {
+	return dbPath + ".snapshots"
+}
+
+// CreateSnapshot copies the database, encrypted with the same key, into the snapshots directory.
+// Names needn't be unique; restoring a name uses its newest snapshot.
+func (db *DB) CreateSnapshot(name string) (*Snapshot, error) {
+	if !validSnapshotName.MatchString(name) {
+		return nil, fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_', and '-'", name)
+	}
+
+	return writeSnapshot(db.exec, db.path, name)
+}
+
+// writeSnapshot copies the database at dbPath, using exec to run statements on it, into
+// its snapshots directory as a snapshot called name.
+func writeSnapshot(exec func(string, ...interface{}) (sql.Result, error), dbPath, name string) (*Snapshot, error) {
+	dir := SnapshotsDir(dbPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+
+	created := time.Now().UTC()
+	path := filepath.Join(dir, created.Format(snapshotTimeFormat)+"_"+name+".db")
+
+	if err := backupTo(exec, dbPath, path); err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return &Snapshot{Name: name, Created: created, Size: info.Size(), Path: path}, nil
+}
+
+// Snapshots returns the database's snapshots, oldest first.
+func (db *DB) Snapshots() ([]Snapshot, error) {
+	entries, err := os.ReadDir(SnapshotsDir(db.path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read snapshots directory: %w", err)
+	}
+
+	var snapshots []Snapshot
+	for _, entry := range entries {
+		stamp, name, ok := strings.Cut(strings.TrimSuffix(entry.Name(), ".db"), "_")
+		if entry.IsDir() || !ok || !strings.HasSuffix(entry.Name(), ".db") {
+			continue
+		}
+		created, err := time.Parse(snapshotTimeFormat, stamp)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read snapshot: %w", err)
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:    name,
+			Created: created,
+			Size:    info.Size(),
+			Path:    filepath.Join(SnapshotsDir(db.path), entry.Name()),
+		})
+	}
+
+	slices.SortFunc(snapshots, func(a, b Snapshot) int { return a.Created.Compare(b.Created) })
+	return snapshots, nil
+}
+
+// FindSnapshot returns the newest snapshot named name.
+func (db *DB) FindSnapshot(name string) (*Snapshot, error) {
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Name == name {
+			return &snapshots[i], nil
+		}
+	}
+	return nil, fmt.Errorf("snapshot %q not found", name)
+}
+
+// RestoreSnapshot replaces the database's contents with snap's.
+// Should be run under Locked.
+func (db *DB) RestoreSnapshot(snap *Snapshot) error {
+	if err := db.restoreFrom(snap.Path); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+
+	// Snapshots taken by older versions may need migrating
+	if err := migrate(db.conn); err != nil {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	// The snapshot's history predates the restore, so undoing it would be surprising
+	if _, err := db.exec("DELETE FROM history"); err != nil {
+		return fmt.Errorf("failed to clear history: %w", err)
+	}
+	return nil
+}
+
+// restoreFrom copies the database at path over this one with SQLite's backup API,
+// which other connections see as an ordinary write.
+func (db *DB) restoreFrom(path string) error {
+	src, err := sql.Open("sqlite3", dsn(path, db.key))
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = src.Close() }()
+	if err := src.Ping(); err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+
+	ctx := context.Background()
+	srcConn, err := src.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer func() { _ = srcConn.Close() }()
+
+	destConn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { _ = destConn.Close() }()
+
+	return destConn.Raw(func(dest any) error {
+		return srcConn.Raw(func(source any) error {
+			backup, err := dest.(*sqlite3.SQLiteConn).Backup("main", source.(*sqlite3.SQLiteConn), "main")
+			if err != nil {
+				return err
+			}
+
+			// Step reports busy as not done, so retry until the lock timeout
+			deadline := time.Now().Add(db.lockTimeout)
+			for {
+				done, err := backup.Step(-1)
+				if err != nil {
+					_ = backup.Close()
+					return err
+				}
+				if done {
+					return backup.Close()
+				}
+				if time.Now().After(deadline) {
+					_ = backup.Close()
+					return fmt.Errorf("database stayed busy for %s", db.lockTimeout)
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		})
+	})
+}
@@ -0,0 +1,195 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSnapshots(t *testing.T) {
+	path := t.TempDir() + "/test_snapshot.db"
+	key := "testkey123456789012"
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if snapshots, err := db.Snapshots(); err != nil || len(snapshots) != 0 {
+		t.Fatalf("Snapshots() = %v, %v; want none", snapshots, err)
+	}
+
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	first, err := db.CreateSnapshot("before-import")
+	if err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if filepath.Dir(first.Path) != SnapshotsDir(path) || first.Size == 0 {
+		t.Errorf("CreateSnapshot() = %+v, want a non-empty file in %s", first, SnapshotsDir(path))
+	}
+
+	// Snapshots are encrypted with the database's key
+	if snap, err := Open(first.Path, "wrong-key"); err == nil {
+		_ = snap.Close()
+		t.Error("Open of a snapshot with the wrong key should fail")
+	}
+
+	if _, err := db.AddObservation("Alice", "Likes coffee"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.CreateSnapshot("before-import"); err != nil {
+		t.Fatalf("CreateSnapshot failed: %v", err)
+	}
+	if _, err := db.AddObservation("Bob", "Likes juice"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	snapshots, err := db.Snapshots()
+	if err != nil {
+		t.Fatalf("Snapshots failed: %v", err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Path != first.Path || snapshots[1].Name != "before-import" {
+		t.Fatalf("Snapshots() = %+v, want both snapshots oldest first", snapshots)
+	}
+
+	// Restoring a name uses its newest snapshot
+	restored, err := db.FindSnapshot("before-import")
+	if err != nil {
+		t.Fatalf("FindSnapshot failed: %v", err)
+	}
+	if restored.Path != snapshots[1].Path {
+		t.Errorf("FindSnapshot() = %s, want %s", restored.Path, snapshots[1].Path)
+	}
+	if err := db.RestoreSnapshot(restored); err != nil {
+		t.Fatalf("RestoreSnapshot failed: %v", err)
+	}
+	observations, err := db.SearchObservations("", nil, false)
+	if err != nil {
+		t.Fatalf("SearchObservations failed: %v", err)
+	}
+	if len(observations) != 2 {
+		t.Errorf("After restore got %d observations, want 2", len(observations))
+	}
+	if entities, _ := db.SearchEntities([]string{"Bob"}, false); len(entities) != 0 {
+		t.Error("After restore, Bob should be gone")
+	}
+
+	// The restore is visible to other connections
+	other, err := Open(path, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = other.Close() }()
+	if observations, _ := other.SearchObservations("", nil, false); len(observations) != 2 {
+		t.Errorf("Another connection sees %d observations after restore, want 2", len(observations))
+	}
+
+	if _, err := db.FindSnapshot("missing"); err == nil {
+		t.Error("FindSnapshot of a missing snapshot should fail")
+	}
+}
+
+func TestCreateSnapshotInvalidName(t *testing.T) {
+	path := t.TempDir() + "/test_snapshot_name.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	for _, name := range []string{"", "../escape", "a/b", ".hidden", "with space"} {
+		if _, err := db.CreateSnapshot(name); err == nil {
+			t.Errorf("CreateSnapshot(%q) should fail", name)
+		}
+	}
+	if _, err := os.Stat(SnapshotsDir(path)); err == nil {
+		entries, _ := os.ReadDir(SnapshotsDir(path))
+		if len(entries) != 0 {
+			t.Errorf("Invalid names created %d snapshot files", len(entries))
+		}
+	}
+}
+
+func TestBackupBeforeMigrating(t *testing.T) {
+	path := t.TempDir() + "/test_migration_backup.db"
+	key := "testkey123456789012"
+
+	// unmigrate rolls back the latest migration, leaving it pending for the next Open
+	unmigrate := func() {
+		t.Helper()
+		db, err := Open(path, key)
+		if err != nil {
+			t.Fatalf("Open failed: %v", err)
+		}
+		defer func() { _ = db.Close() }()
+		latest := migrations[len(migrations)-1]
+		if _, err := db.conn.Exec(latest.Down); err != nil {
+			t.Fatalf("Failed to roll back migration %d: %v", latest.Version, err)
+		}
+		if _, err := db.conn.Exec("DELETE FROM schema_migrations WHERE version = ?", latest.Version); err != nil {
+			t.Fatalf("Failed to unrecord migration %d: %v", latest.Version, err)
+		}
+	}
+
+	db, err := Init(path, key)
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	_, _ = db.AddObservation("Alice", "Likes tea")
+	if db.MigrationBackup() != nil {
+		t.Error("Expected no backup for a new database")
+	}
+	_ = db.Close()
+	unmigrate()
+
+	db, err = Open(path, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	snap := db.MigrationBackup()
+	if snap == nil || snap.Name != MigrationSnapshotName {
+		t.Fatalf("Expected a %s snapshot, got %+v", MigrationSnapshotName, snap)
+	}
+	if version, _ := db.SchemaVersion(); version != LatestSchemaVersion() {
+		t.Errorf("Expected the database to be migrated after the backup, got version %d", version)
+	}
+	_ = db.Close()
+
+	backup, err := OpenWithOptions(snap.Path, key, Options{SkipMigrations: true})
+	if err != nil {
+		t.Fatalf("Failed to open the backup: %v", err)
+	}
+	if version, _ := backup.SchemaVersion(); version != LatestSchemaVersion()-1 {
+		t.Errorf("Expected the backup to have the old schema, got version %d", version)
+	}
+	if observations, _ := backup.SearchObservations("Alice", nil, true); len(observations) != 1 {
+		t.Errorf("Expected the backup to have the data, got %v", observations)
+	}
+	_ = backup.Close()
+
+	db, err = Open(path, key)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	if db.MigrationBackup() != nil {
+		t.Error("Expected no backup when nothing is pending")
+	}
+	_ = db.Close()
+
+	unmigrate()
+	db, err = OpenWithOptions(path, key, Options{SkipMigrationBackup: true})
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	if db.MigrationBackup() != nil {
+		t.Error("Expected no backup with SkipMigrationBackup")
+	}
+	if snapshots, _ := db.Snapshots(); len(snapshots) != 1 {
+		t.Errorf("Expected only the first backup, got %v", snapshots)
+	}
+}
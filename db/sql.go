@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sqlite3 "github.com/mutecomm/go-sqlcipher/v4"
+)
+
+// ErrNotReadOnly is returned when a statement given to ReadOnlyQuery would do more than read.
+var ErrNotReadOnly = errors.New("only read-only statements are allowed")
+
+// sqliteRecursive is SQLITE_RECURSIVE, which the driver doesn't export, authorizing WITH RECURSIVE.
+const sqliteRecursive = 33
+
+// QueryResult holds the rows a statement returned.
+type QueryResult struct {
+	Columns []string `json:"columns"`
+	// Rows hold int64, float64, string, or nil values; blobs are read as strings and times
+	// as RFC 3339 strings, matching how records are shown elsewhere
+	Rows [][]any `json:"rows"`
+}
+
+// ReadOnlyQuery runs a statement that may only read the database, like SELECT or WITH,
+// and returns all its rows. SQLite's authorizer rejects any statement that would write,
+// including each statement of a list like "SELECT 1; DELETE FROM entities", and PRAGMAs.
+func (db *DB) ReadOnlyQuery(ctx context.Context, statement string) (*QueryResult, error) {
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	setAuthorizer := func(authorize func(int, string, string, string) int) error {
+		return conn.Raw(func(driverConn any) error {
+			c, ok := driverConn.(*sqlite3.SQLiteConn)
+			if !ok {
+				return fmt.Errorf("unexpected driver connection %T", driverConn)
+			}
+			c.RegisterAuthorizer(authorize)
+			return nil
+		})
+	}
+
+	denied := false
+	if err := setAuthorizer(func(op int, _, _, _ string) int {
+		switch op {
+		case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqlite3.SQLITE_FUNCTION, sqliteRecursive:
+			return sqlite3.SQLITE_OK
+		}
+		denied = true
+		return sqlite3.SQLITE_DENY
+	}); err != nil {
+		return nil, err
+	}
+	// The connection goes back to the pool, so it mustn't keep refusing writes
+	defer func() { _ = setAuthorizer(nil) }()
+
+	rows, err := conn.QueryContext(ctx, statement)
+	if err != nil {
+		if denied {
+			return nil, fmt.Errorf("%w: %w", ErrNotReadOnly, err)
+		}
+		return nil, fmt.Errorf("failed to run query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	result := &QueryResult{Rows: [][]any{}}
+	if result.Columns, err = rows.Columns(); err != nil {
+		return nil, fmt.Errorf("failed to read columns: %w", err)
+	}
+	for rows.Next() {
+		values := make([]any, len(result.Columns))
+		ptrs := make([]any, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		for i, v := range values {
+			switch v := v.(type) {
+			case []byte:
+				values[i] = string(v)
+			case time.Time:
+				values[i] = v.UTC().Format(time.RFC3339)
+			}
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read rows: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	path := t.TempDir() + "/test_sql.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if _, err := db.AddRelationship("Alice", "Bob", "knows"); err != nil {
+		t.Fatalf("AddRelationship failed: %v", err)
+	}
+
+	ctx := context.Background()
+	result, err := db.ReadOnlyQuery(ctx, "SELECT text, id * 1.5 AS half, NULL FROM entities ORDER BY text")
+	if err != nil {
+		t.Fatalf("ReadOnlyQuery failed: %v", err)
+	}
+	if want := []string{"text", "half", "NULL"}; !reflect.DeepEqual(result.Columns, want) {
+		t.Errorf("Columns = %v, want %v", result.Columns, want)
+	}
+	if want := [][]any{{"Alice", 1.5, nil}, {"Bob", 3.0, nil}}; !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("Rows = %v, want %v", result.Rows, want)
+	}
+
+	result, err = db.ReadOnlyQuery(ctx, "WITH RECURSIVE n(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM n WHERE x < 3) SELECT COUNT(*) FROM n")
+	if err != nil {
+		t.Fatalf("ReadOnlyQuery with a recursive CTE failed: %v", err)
+	}
+	if want := [][]any{{int64(3)}}; !reflect.DeepEqual(result.Rows, want) {
+		t.Errorf("Rows = %v, want %v", result.Rows, want)
+	}
+
+	for _, statement := range []string{
+		"DELETE FROM entities",
+		"UPDATE entities SET text = 'Carol'",
+		"SELECT 1; DELETE FROM entities",
+		"CREATE TABLE t (x)",
+		"PRAGMA key = 'other'",
+		"ATTACH DATABASE 'other.db' AS other",
+	} {
+		if _, err := db.ReadOnlyQuery(ctx, statement); !errors.Is(err, ErrNotReadOnly) {
+			t.Errorf("ReadOnlyQuery(%q) error = %v, want ErrNotReadOnly", statement, err)
+		}
+	}
+
+	// The connection must accept writes again afterwards
+	if _, err := db.AddEntity("Carol"); err != nil {
+		t.Fatalf("AddEntity after a denied query failed: %v", err)
+	}
+	count, err := db.CountSearchEntities(nil, false)
+	if err != nil || count != 3 {
+		t.Errorf("CountSearchEntities() = %d, %v; want 3", count, err)
+	}
+}
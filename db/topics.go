@@ -0,0 +1,211 @@
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Topic is a group of observations with similar vectors, found by Topics.
+type Topic struct {
+	Size    int           `json:"size"`
+	Samples []Observation `json:"samples"`
+}
+
+// kMeans tuning: how long clustering may take to settle, and the most topics chosen
+// when none are asked for.
+const (
+	kMeansMaxIterations = 50
+	maxDefaultTopics    = 20
+)
+
+// Topics groups the observations embedded with model into k topics by k-means clustering
+// of their vectors, largest first, each with up to samples of the observations closest to
+// its centre, closest first. If k is 0, it's chosen from the number of observations.
+// Clustering is seeded, so the result is the same every time for the same vectors.
+// Archived observations are left out unless searches include them.
+func (db *DB) Topics(model string, k, samples int) ([]Topic, error) {
+	ids, vectors, err := db.observationVectors(model)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return []Topic{}, nil
+	}
+	if k <= 0 {
+		k = min(maxDefaultTopics, max(1, int(math.Round(math.Sqrt(float64(len(ids))/2)))))
+	}
+	k = min(k, len(ids))
+
+	assignments, centroids := kMeans(vectors, k, rand.New(rand.NewPCG(1, 1)))
+
+	members := make([][]int, k)
+	for i, c := range assignments {
+		members[c] = append(members[c], i)
+	}
+	var topics []Topic
+	var sampleIDs []int64
+	for c, m := range members {
+		if len(m) == 0 {
+			continue
+		}
+		slices.SortFunc(m, func(a, b int) int {
+			return cmp.Compare(Cosine(vectors[b], centroids[c]), Cosine(vectors[a], centroids[c]))
+		})
+		topic := Topic{Size: len(m)}
+		for _, i := range m[:min(samples, len(m))] {
+			topic.Samples = append(topic.Samples, Observation{ID: ids[i]})
+			sampleIDs = append(sampleIDs, ids[i])
+		}
+		topics = append(topics, topic)
+	}
+	slices.SortStableFunc(topics, func(a, b Topic) int { return cmp.Compare(b.Size, a.Size) })
+
+	observations := map[int64]Observation{}
+	err = inChunks(sampleIDs, func(in string, args []interface{}) error {
+		found, err := collect(scanRows(db, observationsSelect+" WHERE o.id IN "+in, args, nil, "observations", scanObservation))
+		for _, o := range found {
+			observations[o.ID] = o
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range topics {
+		for i, o := range t.Samples {
+			t.Samples[i] = observations[o.ID]
+		}
+	}
+	return topics, nil
+}
+
+// observationVectors returns the IDs of the observations embedded with model, in order,
+// and for each the unit-length average of its chunks' vectors
+func (db *DB) observationVectors(model string) ([]int64, [][]float32, error) {
+	query := "SELECT e.observation_id, e.vector FROM embeddings e JOIN observations o ON o.id = e.observation_id WHERE e.model = ?"
+	if clause := db.archivedClause(); clause != "" {
+		query += " AND " + clause
+	}
+	rows, err := db.query(query+" ORDER BY e.observation_id, e.chunk", model)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get embeddings: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var ids []int64
+	var vectors [][]float32
+	for rows.Next() {
+		var id int64
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		vector := decodeVector(data)
+		if n := len(ids); n > 0 && ids[n-1] == id {
+			// Another chunk of the same observation: sum them, and normalize below
+			for i := range min(len(vector), len(vectors[n-1])) {
+				vectors[n-1][i] += vector[i]
+			}
+			continue
+		}
+		ids = append(ids, id)
+		vectors = append(vectors, vector)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to get embeddings: %w", err)
+	}
+
+	for _, v := range vectors {
+		normalize(v)
+	}
+	return ids, vectors, nil
+}
+
+// kMeans clusters unit-length vectors into k groups by cosine similarity, starting from
+// centres chosen by k-means++, and returns each vector's group and each group's centre.
+func kMeans(vectors [][]float32, k int, rng *rand.Rand) ([]int, [][]float32) {
+	// k-means++: each centre after the first is picked with probability proportional to
+	// the squared distance from the nearest centre so far, spreading them out
+	centroids := [][]float32{slices.Clone(vectors[rng.IntN(len(vectors))])}
+	distances := make([]float64, len(vectors))
+	for len(centroids) < k {
+		var total float64
+		for i, v := range vectors {
+			nearest := math.Inf(1)
+			for _, c := range centroids {
+				nearest = min(nearest, 1-float64(Cosine(v, c)))
+			}
+			distances[i] = nearest * nearest
+			total += distances[i]
+		}
+		next := rng.IntN(len(vectors))
+		if total > 0 {
+			target := rng.Float64() * total
+			for i, d := range distances {
+				if target -= d; target <= 0 {
+					next = i
+					break
+				}
+			}
+		}
+		centroids = append(centroids, slices.Clone(vectors[next]))
+	}
+
+	assignments := make([]int, len(vectors))
+	for i := range assignments {
+		assignments[i] = -1
+	}
+	for range kMeansMaxIterations {
+		changed := false
+		for i, v := range vectors {
+			best, bestScore := 0, float32(math.Inf(-1))
+			for c, centroid := range centroids {
+				if score := Cosine(v, centroid); score > bestScore {
+					best, bestScore = c, score
+				}
+			}
+			if assignments[i] != best {
+				assignments[i], changed = best, true
+			}
+		}
+		if !changed {
+			break
+		}
+
+		// Move each centre to the average of its group. An empty group keeps its centre
+		sums := make([][]float32, k)
+		for i, c := range assignments {
+			if sums[c] == nil {
+				sums[c] = make([]float32, len(vectors[i]))
+			}
+			for j := range min(len(sums[c]), len(vectors[i])) {
+				sums[c][j] += vectors[i][j]
+			}
+		}
+		for c, sum := range sums {
+			if sum != nil {
+				normalize(sum)
+				centroids[c] = sum
+			}
+		}
+	}
+	return assignments, centroids
+}
+
+// normalize scales v to unit length, unless it's all zeros
+func normalize(v []float32) {
+	var norm float64
+	for _, x := range v {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 {
+		return
+	}
+	norm = math.Sqrt(norm)
+	for i := range v {
+		v[i] = float32(float64(v[i]) / norm)
+	}
+}
@@ -0,0 +1,52 @@
+package db
+
+import "testing"
+
+func TestTopics(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_topics.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if topics, err := db.Topics("test", 0, 3); err != nil || len(topics) != 0 {
+		t.Errorf("Expected no topics without vectors, got %+v, %v", topics, err)
+	}
+
+	// Three observations about drinks, two about places
+	var chunks []Chunk
+	for i, v := range [][]float32{{1, 0.1}, {1, 0}, {0.9, 0.1}, {0, 1}, {0.1, 1}} {
+		id, _ := db.AddObservation("Alice", string(rune('a'+i)))
+		chunks = append(chunks, Chunk{ObservationID: id, End: 1, Vector: v})
+	}
+	if err := db.SaveEmbeddings("test", chunks); err != nil {
+		t.Fatalf("SaveEmbeddings failed: %v", err)
+	}
+
+	topics, err := db.Topics("test", 2, 2)
+	if err != nil {
+		t.Fatalf("Topics failed: %v", err)
+	}
+	if len(topics) != 2 || topics[0].Size != 3 || topics[1].Size != 2 {
+		t.Fatalf("Expected topics of 3 and 2, got %+v", topics)
+	}
+	if len(topics[0].Samples) != 2 || topics[0].Samples[0].Text == "" {
+		t.Errorf("Expected 2 loaded samples of the first topic, got %+v", topics[0].Samples)
+	}
+	for _, o := range topics[1].Samples {
+		if o.Text != "d" && o.Text != "e" {
+			t.Errorf("Expected only places in the second topic, got %q", o.Text)
+		}
+	}
+
+	again, _ := db.Topics("test", 2, 2)
+	if again[0].Samples[0].ID != topics[0].Samples[0].ID {
+		t.Error("Expected the same topics every time")
+	}
+	if topics, _ := db.Topics("test", 10, 1); len(topics) > 5 {
+		t.Errorf("Expected no more topics than observations, got %d", len(topics))
+	}
+	if topics, _ := db.Topics("test", 0, 1); len(topics) != 2 {
+		t.Errorf("Expected 2 topics chosen for 5 observations, got %d", len(topics))
+	}
+}
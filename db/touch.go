@@ -0,0 +1,26 @@
+package db
+
+import "fmt"
+
+// Touch marks the observations with the given IDs as updated and accessed now, without
+// changing them, so a still-true old fact ranks as recent again and isn't evicted or
+// archived as stale. Returns how many were touched.
+func (db *DB) Touch(ids []int64) (int, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := db.checkObservationsExist(ids); err != nil {
+		return 0, err
+	}
+
+	placeholders, args := idList(ids)
+	result, err := db.exec("UPDATE observations SET updated_at = CURRENT_TIMESTAMP, last_accessed = CURRENT_TIMESTAMP WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to touch observations: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n), nil
+}
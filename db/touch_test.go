@@ -0,0 +1,46 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTouch(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_touch.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	old, _ := db.AddObservation("Alice", "Likes tea")
+	recent, _ := db.AddObservation("Alice", "Likes cake")
+	if _, err := db.exec("UPDATE observations SET updated_at = CASE id WHEN ? THEN '2020-01-01 00:00:00' ELSE '2021-01-01 00:00:00' END", old); err != nil {
+		t.Fatalf("Failed to age observations: %v", err)
+	}
+
+	observations, _ := db.SearchObservations("Alice", nil, true)
+	if len(observations) != 2 || observations[0].ID != recent {
+		t.Fatalf("Expected the recent observation first, got %+v", observations)
+	}
+
+	if _, err := db.Touch([]int64{old, 999}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound touching a missing observation, got %v", err)
+	}
+	var before int
+	_ = db.queryRow("SELECT COUNT(*) FROM history").Scan(&before)
+	if n, err := db.Touch([]int64{old}); err != nil || n != 1 {
+		t.Fatalf("Touch = %d, %v; want 1", n, err)
+	}
+
+	observations, _ = db.SearchObservations("Alice", nil, true)
+	if len(observations) != 2 || observations[0].ID != old || observations[0].Text != "Likes tea" {
+		t.Errorf("Expected the touched observation first and unchanged, got %+v", observations)
+	}
+
+	// Touching isn't a change that can be undone
+	var after int
+	_ = db.queryRow("SELECT COUNT(*) FROM history").Scan(&after)
+	if after != before {
+		t.Errorf("Expected touching to leave history alone, got %d rows, was %d", after, before)
+	}
+}
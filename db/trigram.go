@@ -0,0 +1,191 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The trigram index is optional: it exists only after BuildTrigramIndex, and
+// while it exists, adds and edits keep it current and searches use it to find
+// candidate observations instead of scanning every observation's text.
+const trigramSchema = `
+CREATE TABLE IF NOT EXISTS observation_trigrams (
+	trigram TEXT NOT NULL,
+	observation_id INTEGER NOT NULL,
+	PRIMARY KEY (trigram, observation_id)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS idx_observation_trigrams_observation ON observation_trigrams(observation_id);
+
+CREATE TRIGGER IF NOT EXISTS observation_trigrams_delete AFTER DELETE ON observations BEGIN
+	DELETE FROM observation_trigrams WHERE observation_id = old.id;
+END;
+`
+
+// trigrams returns the distinct three-character substrings of text, lowercased.
+func trigrams(text string) []string {
+	runes := []rune(strings.ToLower(text))
+	seen := map[string]bool{}
+	var result []string
+	for i := 0; i+3 <= len(runes); i++ {
+		t := string(runes[i : i+3])
+		if !seen[t] {
+			seen[t] = true
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// HasTrigramIndex reports whether the trigram index has been built.
+func (db *DB) HasTrigramIndex() (bool, error) {
+	var count int
+	err := db.queryRow("SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='observation_trigrams'").Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("failed to check trigram index: %w", err)
+	}
+	return count > 0, nil
+}
+
+// BuildTrigramIndex creates the trigram index if needed and (re)indexes every observation.
+// Returns the number of observations indexed.
+func (db *DB) BuildTrigramIndex() (int, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(trigramSchema); err != nil {
+		return 0, fmt.Errorf("failed to create trigram index: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM observation_trigrams"); err != nil {
+		return 0, fmt.Errorf("failed to clear trigram index: %w", err)
+	}
+
+	rows, err := tx.Query("SELECT id, text FROM observations")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read observations: %w", err)
+	}
+	texts := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var text string
+		if err := rows.Scan(&id, &text); err != nil {
+			_ = rows.Close()
+			return 0, fmt.Errorf("failed to scan observation: %w", err)
+		}
+		texts[id] = text
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read observations: %w", err)
+	}
+
+	stmt, err := tx.Prepare("INSERT OR IGNORE INTO observation_trigrams (trigram, observation_id) VALUES (?, ?)")
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare trigram insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	for id, text := range texts {
+		for _, t := range trigrams(text) {
+			if _, err := stmt.Exec(t, id); err != nil {
+				return 0, fmt.Errorf("failed to index observation %d: %w", id, err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit trigram index: %w", err)
+	}
+
+	return len(texts), nil
+}
+
+// DropTrigramIndex removes the trigram index. Searches go back to scanning.
+func (db *DB) DropTrigramIndex() error {
+	_, err := db.exec(`
+		DROP TRIGGER IF EXISTS observation_trigrams_delete;
+		DROP TABLE IF EXISTS observation_trigrams;
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to drop trigram index: %w", err)
+	}
+	return nil
+}
+
+// indexObservation replaces an observation's trigrams, if the trigram index exists.
+func (db *DB) indexObservation(id int64, text string) error {
+	enabled, err := db.HasTrigramIndex()
+	if err != nil || !enabled {
+		return err
+	}
+
+	if _, err := db.exec("DELETE FROM observation_trigrams WHERE observation_id = ?", id); err != nil {
+		return fmt.Errorf("failed to update trigram index: %w", err)
+	}
+
+	ts := trigrams(text)
+	if len(ts) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ts))
+	args := make([]interface{}, 0, len(ts)*2)
+	for i, t := range ts {
+		placeholders[i] = "(?, ?)"
+		args = append(args, t, id)
+	}
+	query := "INSERT OR IGNORE INTO observation_trigrams (trigram, observation_id) VALUES " + strings.Join(placeholders, ", ")
+	if _, err := db.exec(query, args...); err != nil {
+		return fmt.Errorf("failed to update trigram index: %w", err)
+	}
+	return nil
+}
+
+// observationKeywordClause builds the keyword condition for observation searches,
+// matching each keyword against the observation or its entity like buildWhereClause.
+// With the trigram index, observation text is only compared for candidates that
+// contain all of a keyword's trigrams.
+func (db *DB) observationKeywordClause(keywords []string, useUnion bool) (string, []interface{}, error) {
+	enabled, err := db.HasTrigramIndex()
+	if err != nil {
+		return "", nil, err
+	}
+	if !enabled {
+		clause, args := buildWhereClause(keywords, []string{"o.text", "e.text"}, useUnion)
+		return clause, args, nil
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	for _, keyword := range keywords {
+		ts := trigrams(keyword)
+		// Short keywords have no trigrams, and LIKE wildcards can't be matched by them
+		if len(ts) == 0 || strings.ContainsAny(keyword, "%_") {
+			conditions = append(conditions, "(o.text LIKE ? OR e.text LIKE ?)")
+			args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+			continue
+		}
+
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ts)), ", ")
+		conditions = append(conditions, fmt.Sprintf(`((o.id IN (
+			SELECT observation_id FROM observation_trigrams
+			WHERE trigram IN (%s)
+			GROUP BY observation_id HAVING COUNT(*) = %d
+		) AND o.text LIKE ?) OR o.entity_id IN (SELECT id FROM entities WHERE text LIKE ?))`, placeholders, len(ts)))
+		for _, t := range ts {
+			args = append(args, t)
+		}
+		args = append(args, "%"+keyword+"%", "%"+keyword+"%")
+	}
+
+	joiner := " AND "
+	if useUnion {
+		joiner = " OR "
+	}
+
+	return strings.Join(conditions, joiner), args, nil
+}
@@ -0,0 +1,100 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestTrigrams(t *testing.T) {
+	got := trigrams("Ababab")
+	want := []string{"aba", "bab"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, got)
+		}
+	}
+
+	if got := trigrams("ab"); len(got) != 0 {
+		t.Errorf("Expected no trigrams for short text, got %v", got)
+	}
+}
+
+func TestTrigramIndexSearch(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_trigram.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	_, _ = db.AddObservation("Alice", "Likes green tea")
+	_, _ = db.AddObservation("Bob", "Drinks coffee")
+	_, _ = db.AddObservation("Coffee shop", "Opens early")
+
+	searches := [][]string{{"tea"}, {"COFFEE"}, {"ee"}, {"tea", "coffee"}, {"g_een"}, {"missing"}}
+	before := map[int]int{}
+	for i, keywords := range searches {
+		results, err := db.SearchObservations("", keywords, true)
+		if err != nil {
+			t.Fatalf("SearchObservations failed: %v", err)
+		}
+		before[i] = len(results)
+	}
+
+	indexed, err := db.BuildTrigramIndex()
+	if err != nil {
+		t.Fatalf("BuildTrigramIndex failed: %v", err)
+	}
+	if indexed != 3 {
+		t.Errorf("Expected 3 observations indexed, got %d", indexed)
+	}
+
+	// The index must not change results, including entity matches and short keywords
+	for i, keywords := range searches {
+		results, err := db.SearchObservations("", keywords, true)
+		if err != nil {
+			t.Fatalf("SearchObservations failed: %v", err)
+		}
+		if len(results) != before[i] {
+			t.Errorf("Search %v: expected %d results with index, got %d", keywords, before[i], len(results))
+		}
+	}
+
+	// New and edited observations are indexed
+	id, err := db.AddObservation("Alice", "Reads novels")
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if results, _ := db.SearchObservations("", []string{"novel"}, true); len(results) != 1 {
+		t.Errorf("Expected new observation to be found, got %d results", len(results))
+	}
+	if err := db.UpdateObservation(id, "Reads poetry"); err != nil {
+		t.Fatalf("UpdateObservation failed: %v", err)
+	}
+	if results, _ := db.SearchObservations("", []string{"novel"}, true); len(results) != 0 {
+		t.Errorf("Expected old text not to match, got %d results", len(results))
+	}
+	if results, _ := db.SearchObservations("", []string{"poetry"}, true); len(results) != 1 {
+		t.Errorf("Expected edited observation to be found, got %d results", len(results))
+	}
+
+	// Deleted observations leave no trigrams behind
+	if err := db.DeleteObservation(id); err != nil {
+		t.Fatalf("DeleteObservation failed: %v", err)
+	}
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM observation_trigrams WHERE observation_id = ?", id).Scan(&count); err != nil {
+		t.Fatalf("Failed to count trigrams: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("Expected trigrams of deleted observation to be removed, got %d", count)
+	}
+
+	if err := db.DropTrigramIndex(); err != nil {
+		t.Fatalf("DropTrigramIndex failed: %v", err)
+	}
+	if enabled, _ := db.HasTrigramIndex(); enabled {
+		t.Error("Expected trigram index to be dropped")
+	}
+}
@@ -0,0 +1,32 @@
+package db
+
+import "fmt"
+
+// Transaction runs fn with a DB whose statements all run in one transaction, committed
+// if fn returns nil and rolled back otherwise, so its writes land together or not at all.
+// Called on a DB that's already in a transaction, fn joins it. fn must not call Locked.
+// Should be run under Locked.
+func (db *DB) Transaction(fn func(tx *DB) error) error {
+	if db.tx != nil {
+		return fn(db)
+	}
+
+	sqlTx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = sqlTx.Rollback() }()
+
+	tx := &DB{conn: db.conn, tx: sqlTx, settings: db.settings}
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	if tx.quotaPending {
+		return db.enforceQuotaOnAdd(tx.quotaKeepID)
+	}
+	return nil
+}
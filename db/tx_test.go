@@ -0,0 +1,94 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTransaction(t *testing.T) {
+	path := t.TempDir() + "/test_tx.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	failed := errors.New("failed")
+	err = db.Locked(func() error {
+		return db.Transaction(func(tx *DB) error {
+			if _, err := tx.AddObservation("Alice", "Likes tea"); err != nil {
+				return err
+			}
+			return failed
+		})
+	})
+	if !errors.Is(err, failed) {
+		t.Fatalf("Transaction() = %v, want the callback's error", err)
+	}
+	if count, _ := db.CountEntities(); count != 0 {
+		t.Errorf("Expected a failed transaction to add nothing, got %d entities", count)
+	}
+
+	err = db.Locked(func() error {
+		return db.Transaction(func(tx *DB) error {
+			if _, err := tx.AddObservation("Alice", "Likes tea"); err != nil {
+				return err
+			}
+			// Nested transactions join the outer one
+			return tx.Transaction(func(inner *DB) error {
+				_, err := inner.AddRelationship("Alice", "Bob", "knows")
+				return err
+			})
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if count, _ := db.CountObservations(); count != 1 {
+		t.Errorf("Expected the observation committed, got %d observations", count)
+	}
+	if count, _ := db.CountRelationships(); count != 1 {
+		t.Errorf("Expected the relationship committed, got %d relationships", count)
+	}
+
+	// The transaction's writes are one change to undo
+	if err := db.Locked(func() error { _, err := db.Undo(); return err }); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if count, _ := db.CountEntities(); count != 0 {
+		t.Errorf("Expected undo to revert the whole transaction, got %d entities", count)
+	}
+}
+
+func TestTransactionSettings(t *testing.T) {
+	db, err := Init(t.TempDir()+"/test_tx_settings.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.SetLang("de"); err != nil {
+		t.Fatalf("SetLang failed: %v", err)
+	}
+	var found []Observation
+	err = db.Locked(func() error {
+		return db.Transaction(func(tx *DB) error {
+			if _, err := tx.AddObservationWithOptions("Alice", "Trinkt gern Tee", ObservationOptions{Lang: "de"}); err != nil {
+				return err
+			}
+			if _, err := tx.AddObservationWithOptions("Alice", "Likes tea", ObservationOptions{Lang: "en"}); err != nil {
+				return err
+			}
+			// The transaction searches with the language set on db
+			found, err = tx.SearchObservations("Alice", nil, false)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("Transaction failed: %v", err)
+	}
+	if len(found) != 1 || found[0].Lang != "de" {
+		t.Errorf("Expected only the German observation, got %+v", found)
+	}
+}
@@ -0,0 +1,97 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+)
+
+// trimInput trims surrounding whitespace from text if the rules say to.
+func (db *DB) trimInput(text string) string {
+	if db.rules == nil || !db.rules.TrimWhitespace {
+		return text
+	}
+	return strings.TrimSpace(text)
+}
+
+// checkNotEmpty returns an error naming what if text is empty and the rules trim whitespace,
+// since an empty entity or observation is junk left by a mistaken write.
+func (db *DB) checkNotEmpty(what, text string) error {
+	if db.rules == nil || !db.rules.TrimWhitespace || text != "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %s is empty", ErrRuleViolation, what)
+}
+
+// WhitespaceIssue is an entity or observation whose text is empty or has surrounding whitespace.
+type WhitespaceIssue struct {
+	// Kind is "entity" or "observation"
+	Kind string `json:"kind"`
+	ID   int64  `json:"id"`
+	Text string `json:"text"`
+}
+
+// Empty reports whether the record's text is nothing but whitespace.
+func (i WhitespaceIssue) Empty() bool {
+	return strings.TrimSpace(i.Text) == ""
+}
+
+// FindWhitespaceIssues returns the entities, then observations, whose text is empty or
+// starts or ends with whitespace, by ID.
+func (db *DB) FindWhitespaceIssues() ([]WhitespaceIssue, error) {
+	var issues []WhitespaceIssue
+	for _, table := range []struct{ kind, name string }{{"entity", "entities"}, {"observation", "observations"}} {
+		rows, err := db.query("SELECT id, text FROM " + table.name + " ORDER BY id")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+		for rows.Next() {
+			issue := WhitespaceIssue{Kind: table.kind}
+			if err := rows.Scan(&issue.ID, &issue.Text); err != nil {
+				_ = rows.Close()
+				return nil, fmt.Errorf("failed to scan %s: %w", table.kind, err)
+			}
+			if issue.Text != strings.TrimSpace(issue.Text) || issue.Text == "" {
+				issues = append(issues, issue)
+			}
+		}
+		_ = rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table.name, err)
+		}
+	}
+	return issues, nil
+}
+
+// FixWhitespaceIssue trims the record's text and returns what it did. An entity whose trimmed
+// text is another entity's is merged into that one. Empty observations are deleted, as are
+// empty entities with nothing about them; empty entities with observations or relationships
+// are left to be renamed. Should be run under Locked.
+func (db *DB) FixWhitespaceIssue(issue WhitespaceIssue) (string, error) {
+	trimmed := strings.TrimSpace(issue.Text)
+
+	if issue.Kind == "observation" {
+		if trimmed == "" {
+			return "deleted", db.DeleteObservation(issue.ID)
+		}
+		return "trimmed", db.UpdateObservation(issue.ID, trimmed)
+	}
+
+	if trimmed == "" {
+		var links int
+		err := db.queryRow(`SELECT (SELECT COUNT(*) FROM observations WHERE entity_id = ?1) +
+			(SELECT COUNT(*) FROM relationships WHERE from_id = ?1 OR to_id = ?1)`, issue.ID).Scan(&links)
+		if err != nil {
+			return "", fmt.Errorf("failed to count entity links: %w", err)
+		}
+		if links > 0 {
+			return "skipped: it has observations or relationships, so rename it with 'amem edit entity'", nil
+		}
+		return "deleted", db.DeleteEntity(issue.ID)
+	}
+
+	var existing int64
+	if db.queryRow("SELECT id FROM entities WHERE text = ?", trimmed).Scan(&existing) == nil {
+		return fmt.Sprintf("merged into [%d]", existing), db.MergeEntities(existing, issue.ID)
+	}
+	return "trimmed", db.UpdateEntity(issue.Text, trimmed)
+}
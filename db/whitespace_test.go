@@ -0,0 +1,123 @@
+package db
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTrimWhitespaceRule(t *testing.T) {
+	path := t.TempDir() + "/test_trim.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if err := db.SetRules(&Rules{TrimWhitespace: true}); err != nil {
+		t.Fatalf("SetRules failed: %v", err)
+	}
+
+	id1, err := db.AddEntity("Alice")
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+	id2, err := db.AddEntity("  Alice\n")
+	if err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+	if id1 != id2 {
+		t.Errorf("Expected %q to be trimmed to the existing entity, got IDs %d and %d", "  Alice\n", id1, id2)
+	}
+
+	obsID, err := db.AddObservation(" Alice ", " Likes tea ")
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	obs, err := db.GetObservation(obsID)
+	if err != nil {
+		t.Fatalf("GetObservation failed: %v", err)
+	}
+	if obs.Text != "Likes tea" || obs.EntityText != "Alice" {
+		t.Errorf("Expected a trimmed observation about Alice, got %+v", obs)
+	}
+
+	if _, err := db.AddEntity("   "); !errors.Is(err, ErrRuleViolation) {
+		t.Errorf("AddEntity of only whitespace error = %v, want a rule violation", err)
+	}
+	if _, err := db.AddObservation("Alice", "\t"); !errors.Is(err, ErrRuleViolation) {
+		t.Errorf("AddObservation of only whitespace error = %v, want a rule violation", err)
+	}
+	if err := db.UpdateObservation(obsID, " "); !errors.Is(err, ErrRuleViolation) {
+		t.Errorf("UpdateObservation to only whitespace error = %v, want a rule violation", err)
+	}
+}
+
+func TestFixWhitespaceIssues(t *testing.T) {
+	path := t.TempDir() + "/test_fix_whitespace.db"
+
+	db, err := Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Written without the rule, as older databases were
+	if _, err := db.AddObservation("Alice", "Likes tea"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddObservation("Alice ", "Likes coffee "); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddObservation(" Bob", ""); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	if _, err := db.AddEntity(""); err != nil {
+		t.Fatalf("AddEntity failed: %v", err)
+	}
+
+	issues, err := db.FindWhitespaceIssues()
+	if err != nil {
+		t.Fatalf("FindWhitespaceIssues failed: %v", err)
+	}
+	var found []string
+	for _, issue := range issues {
+		found = append(found, issue.Kind+":"+issue.Text)
+	}
+	want := []string{"entity:Alice ", "entity: Bob", "entity:", "observation:Likes coffee ", "observation:"}
+	if len(found) != len(want) {
+		t.Fatalf("FindWhitespaceIssues() = %q, want %q", found, want)
+	}
+	for i := range want {
+		if found[i] != want[i] {
+			t.Errorf("FindWhitespaceIssues()[%d] = %q, want %q", i, found[i], want[i])
+		}
+	}
+
+	for _, issue := range issues {
+		if _, err := db.FixWhitespaceIssue(issue); err != nil {
+			t.Fatalf("FixWhitespaceIssue(%+v) failed: %v", issue, err)
+		}
+	}
+
+	if issues, err := db.FindWhitespaceIssues(); err != nil || len(issues) != 0 {
+		t.Errorf("FindWhitespaceIssues() after fixing = %+v, %v; want none", issues, err)
+	}
+	entities, err := db.SearchEntities(nil, false)
+	if err != nil {
+		t.Fatalf("SearchEntities failed: %v", err)
+	}
+	if len(entities) != 2 || entities[0].Text != "Alice" || entities[1].Text != "Bob" {
+		t.Errorf("Expected Alice merged, Bob trimmed, and the empty entity deleted, got %+v", entities)
+	}
+	observations, err := db.SearchObservations("Alice", nil, false)
+	if err != nil {
+		t.Fatalf("SearchObservations failed: %v", err)
+	}
+	if len(observations) != 2 {
+		t.Errorf("Expected both of Alice's observations on the kept entity, got %+v", observations)
+	}
+	if count, _ := db.CountObservations(); count != 2 {
+		t.Errorf("Expected the empty observation deleted, got %d observations", count)
+	}
+}
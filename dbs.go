@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"amem/config"
+	"github.com/urfave/cli/v3"
+)
+
+// dbsCommand builds the 'dbs' command, which manages the registry of databases 'amem init' has created
+func dbsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "dbs",
+		Usage: "List and forget the memory databases created on this machine",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List every database 'amem init' has created, oldest first",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					reg, err := config.ReadRegistry()
+					if err != nil {
+						return err
+					}
+					if len(reg.Databases) == 0 {
+						fmt.Println("No databases registered")
+						return nil
+					}
+
+					for _, d := range reg.Databases {
+						created := "unknown date"
+						if !d.Created.IsZero() {
+							created = d.Created.Local().Format("2006-01-02 15:04:05")
+						}
+						scope := d.Scope
+						if scope == "" {
+							scope = "?"
+						}
+						missing := ""
+						if _, err := os.Stat(d.DBPath); errors.Is(err, os.ErrNotExist) {
+							missing = " (missing)"
+						}
+						fmt.Printf("%s  %-6s  %s%s\n", created, scope, d.DBPath, missing)
+					}
+					return nil
+				},
+			},
+			{
+				Name:      "forget",
+				Usage:     "Remove a database from the list, leaving its files alone",
+				ArgsUsage: "<database path, config path, or project directory>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path := cmd.Args().First()
+					if path == "" {
+						return fmt.Errorf("a database path, config path, or project directory is required")
+					}
+					abs, err := filepath.Abs(path)
+					if err != nil {
+						return fmt.Errorf("failed to resolve absolute path: %w", err)
+					}
+
+					forgotten, err := config.Forget(abs)
+					if err != nil {
+						return err
+					}
+					for _, d := range forgotten {
+						fmt.Printf("✓ Forgot %s\n", d.DBPath)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// doctorCommand builds the 'doctor' command, which looks for problems in what's been remembered
+func doctorCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "doctor",
+		Usage: "Look for problems in the memory graph, like duplicate entities",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "duplicates",
+				Usage: "Look for entities that are probably the same thing under different text",
+			},
+			&cli.BoolFlag{
+				Name:  "whitespace",
+				Usage: "Look for entities and observations that are empty or have surrounding whitespace",
+			},
+			&cli.BoolFlag{
+				Name:  "stale",
+				Usage: "Look for observations marked stale with 'amem mark'",
+			},
+			&cli.BoolFlag{
+				Name:  "apply",
+				Usage: "Ask whether to merge each pair of duplicates and delete each stale observation, and trim whitespace",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			// Without a check named, run them all
+			all := !cmd.Bool("duplicates") && !cmd.Bool("whitespace") && !cmd.Bool("stale")
+			apply := cmd.Bool("apply")
+
+			return withDB(func(database *db.DB) error {
+				if all || cmd.Bool("whitespace") {
+					if err := checkWhitespace(database, apply); err != nil {
+						return err
+					}
+				}
+				if all || cmd.Bool("duplicates") {
+					if err := checkDuplicates(database, apply); err != nil {
+						return err
+					}
+				}
+				if all || cmd.Bool("stale") {
+					return checkStale(database, apply)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// checkDuplicates reports entities that look like the same thing, merging those confirmed if apply is set
+func checkDuplicates(database *db.DB, apply bool) error {
+	candidates, err := database.FindDuplicateEntities()
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		fmt.Println("✓ No duplicate entities found")
+		return nil
+	}
+
+	fmt.Printf("Found %d possible duplicate entities:\n", len(candidates))
+	if !apply {
+		for _, c := range candidates {
+			fmt.Printf("  %q [%d] → %q [%d] (%s)\n", c.Duplicate.Text, c.Duplicate.ID, c.Keep.Text, c.Keep.ID, c.Reason)
+		}
+		fmt.Println("Run 'amem doctor --duplicates --apply' to merge them.")
+		return nil
+	}
+
+	return mergeDuplicates(database, candidates)
+}
+
+// checkWhitespace reports entities and observations that are empty or have surrounding whitespace,
+// fixing them if apply is set
+func checkWhitespace(database *db.DB, apply bool) error {
+	issues, err := database.FindWhitespaceIssues()
+	if err != nil {
+		return err
+	}
+	if len(issues) == 0 {
+		fmt.Println("✓ No empty or untrimmed entities or observations found")
+		return nil
+	}
+
+	fmt.Printf("Found %d empty or untrimmed entities and observations:\n", len(issues))
+	for _, issue := range issues {
+		if !apply {
+			fmt.Printf("  %s [%d] %q\n", issue.Kind, issue.ID, issue.Text)
+			continue
+		}
+		var done string
+		err := database.Locked(func() error {
+			var err error
+			done, err = database.FixWhitespaceIssue(issue)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fix %s [%d]: %w", issue.Kind, issue.ID, err)
+		}
+		fmt.Printf("  %s [%d] %q: %s\n", issue.Kind, issue.ID, issue.Text, done)
+	}
+	if !apply {
+		fmt.Println("Run 'amem doctor --whitespace --apply' to trim them, and delete the empty ones.")
+	}
+	return nil
+}
+
+// checkStale reports observations marked stale more often than helpful, asking whether
+// to delete each if apply is set
+func checkStale(database *db.DB, apply bool) error {
+	stale, err := database.FindStale()
+	if err != nil {
+		return err
+	}
+	if len(stale) == 0 {
+		fmt.Println("✓ No stale observations found")
+		return nil
+	}
+
+	fmt.Printf("Found %d stale observations:\n", len(stale))
+	if !apply {
+		for _, o := range stale {
+			fmt.Printf("  [%d] %s: %s (stale %d, helpful %d)\n", o.ID, o.EntityText, o.Text, o.Stale, o.Helpful)
+		}
+		fmt.Println("Run 'amem doctor --stale --apply' to delete them.")
+		return nil
+	}
+
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+	count := 0
+	for _, o := range stale {
+		fmt.Printf("Delete [%d] %s: %s? (stale %d, helpful %d) [y/N/q]: ", o.ID, o.EntityText, o.Text, o.Stale, o.Helpful)
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Println()
+			break
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "q" {
+			break
+		}
+		if answer != "y" && answer != "yes" {
+			continue
+		}
+
+		if err := database.Locked(func() error { return database.DeleteObservation(o.ID) }); err != nil {
+			return err
+		}
+		count++
+	}
+
+	fmt.Printf("✓ Deleted %d observations\n", count)
+	return nil
+}
+
+// mergeDuplicates asks about each duplicate in turn, merging those confirmed.
+// Each merge is its own change, so 'amem undo' reverts them one at a time.
+func mergeDuplicates(database *db.DB, candidates []db.DuplicateCandidate) error {
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+
+	merged := map[int64]bool{}
+	count := 0
+	for _, c := range candidates {
+		// An earlier merge may have removed one of this pair
+		if merged[c.Keep.ID] || merged[c.Duplicate.ID] {
+			continue
+		}
+
+		fmt.Printf("Merge %q [%d] into %q [%d]? (%s) [y/N/q]: ", c.Duplicate.Text, c.Duplicate.ID, c.Keep.Text, c.Keep.ID, c.Reason)
+		line, err := stdinReader.ReadString('\n')
+		if err != nil && (err != io.EOF || line == "") {
+			fmt.Println()
+			break
+		}
+		answer := strings.ToLower(strings.TrimSpace(line))
+		if answer == "q" {
+			break
+		}
+		if answer != "y" && answer != "yes" {
+			continue
+		}
+
+		if err := database.Locked(func() error { return database.MergeEntities(c.Keep.ID, c.Duplicate.ID) }); err != nil {
+			return err
+		}
+		merged[c.Duplicate.ID] = true
+		count++
+	}
+
+	fmt.Printf("✓ Merged %d entities\n", count)
+	return nil
+}
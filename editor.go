@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// editorComment starts the lines of an editor template that are dropped from the saved text
+const editorComment = "#"
+
+// noteComment is editorComment for notes, which are Markdown and so keep lines starting
+// with # as headings. %% is the comment marker of Markdown editors like Obsidian.
+const noteComment = "%%"
+
+// editorCommand returns the user's editor from $VISUAL or $EDITOR, split into a program and its arguments
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editText opens the user's editor on initial followed by help as comment lines, and returns
+// what was saved without the comment lines or surrounding whitespace
+func editText(initial, help string) (string, error) {
+	return editTextWith(editorComment, initial, help)
+}
+
+// editTextWith is editText with comment lines starting with comment
+func editTextWith(comment, initial, help string) (string, error) {
+	f, err := os.CreateTemp("", "amem-*.md")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file to edit: %w", err)
+	}
+	path := f.Name()
+	defer func() { _ = os.Remove(path) }()
+
+	var template strings.Builder
+	template.WriteString(initial)
+	template.WriteString("\n\n")
+	for _, line := range strings.Split(help, "\n") {
+		fmt.Fprintf(&template, "%s %s\n", comment, line)
+	}
+	_, err = f.WriteString(template.String())
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to write file to edit: %w", err)
+	}
+
+	editor := editorCommand()
+	cmd := exec.Command(editor[0], append(editor[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed: %w", editor[0], err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read edited file: %w", err)
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, comment) {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
@@ -0,0 +1,69 @@
+package embed
+
+import (
+	"regexp"
+	"unicode/utf8"
+)
+
+// Span is a chunk of a text, as byte offsets.
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// runesPerToken estimates how many characters a token covers. Common tokenizers average
+// about four for English, so three errs on the side of smaller chunks.
+const runesPerToken = 3
+
+var wordPattern = regexp.MustCompile(`\S+`)
+
+// EstimateTokens estimates how many tokens text is.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + runesPerToken - 1) / runesPerToken
+}
+
+// Chunk splits text into spans of at most maxTokens estimated tokens, breaking between
+// words, so no part of a long text is lost to a model's input limit. Words too long for
+// a chunk of their own are cut. Text within the limit is one span, and so is all text if
+// maxTokens is 0.
+func Chunk(text string, maxTokens int) []Span {
+	if maxTokens <= 0 || EstimateTokens(text) <= maxTokens {
+		return []Span{{Start: 0, End: len(text)}}
+	}
+
+	var spans []Span
+	start, end := -1, -1
+	for _, word := range wordPattern.FindAllStringIndex(text, -1) {
+		for _, piece := range cutWord(text, word[0], word[1], maxTokens) {
+			if start >= 0 && EstimateTokens(text[start:piece.End]) > maxTokens {
+				spans = append(spans, Span{Start: start, End: end})
+				start = -1
+			}
+			if start < 0 {
+				start = piece.Start
+			}
+			end = piece.End
+		}
+	}
+	if start < 0 {
+		// Nothing but whitespace
+		return []Span{{Start: 0, End: len(text)}}
+	}
+	return append(spans, Span{Start: start, End: end})
+}
+
+// cutWord splits the word text[start:end] into pieces of at most maxTokens estimated tokens
+func cutWord(text string, start, end, maxTokens int) []Span {
+	var pieces []Span
+	for EstimateTokens(text[start:end]) > maxTokens {
+		cut, runes := start, 0
+		for runes < maxTokens*runesPerToken {
+			_, size := utf8.DecodeRuneInString(text[cut:])
+			cut += size
+			runes++
+		}
+		pieces = append(pieces, Span{Start: start, End: cut})
+		start = cut
+	}
+	return append(pieces, Span{Start: start, End: end})
+}
@@ -0,0 +1,41 @@
+package embed
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunk(t *testing.T) {
+	if spans := Chunk("Likes tea", 3); len(spans) != 1 || spans[0] != (Span{0, 9}) {
+		t.Errorf("Expected short text in one span, got %v", spans)
+	}
+	if spans := Chunk("Likes green tea", 0); len(spans) != 1 {
+		t.Errorf("Expected no limit with 0 max tokens, got %v", spans)
+	}
+
+	text := "one two three four five six seven eight nine ten"
+	spans := Chunk(text, 5)
+	if len(spans) < 2 {
+		t.Fatalf("Expected several spans, got %v", spans)
+	}
+	var words []string
+	for i, span := range spans {
+		chunk := text[span.Start:span.End]
+		if EstimateTokens(chunk) > 5 {
+			t.Errorf("Span %d is %d tokens: %q", i, EstimateTokens(chunk), chunk)
+		}
+		if chunk != strings.TrimSpace(chunk) {
+			t.Errorf("Expected span %d to break between words: %q", i, chunk)
+		}
+		words = append(words, strings.Fields(chunk)...)
+	}
+	if strings.Join(words, " ") != text {
+		t.Errorf("Expected the spans to cover every word, got %v", words)
+	}
+
+	long := strings.Repeat("é", 20)
+	spans = Chunk(long, 2)
+	if len(spans) != 4 || spans[3].End != len(long) {
+		t.Errorf("Expected a long word cut into 4 pieces, got %v", spans)
+	}
+}
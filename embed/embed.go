@@ -0,0 +1,117 @@
+// Package embed turns text into vectors for semantic features, using an OpenAI-compatible
+// API, Ollama, or a local hashing model that needs no network, chosen by the config.
+package embed
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Providers that can be named in the config
+const (
+	ProviderLocal  = "local"
+	ProviderOpenAI = "openai"
+	ProviderOllama = "ollama"
+)
+
+// Embedder turns texts into vectors, one per text, in order.
+type Embedder interface {
+	// Model names the provider and model, like "ollama/nomic-embed-text".
+	// Vectors from different models can't be compared.
+	Model() string
+	// MaxTokens is the most tokens the model takes per text, or 0 if there's no limit.
+	// Longer texts should be split with Chunk first.
+	MaxTokens() int
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// Config is the "embeddings" section of the config file.
+type Config struct {
+	// Provider is "local" (the default), "openai" for any OpenAI-compatible API, or "ollama"
+	Provider string `json:"provider,omitempty"`
+	// Model is the provider's model name. The local model has only one
+	Model string `json:"model,omitempty"`
+	// URL is the provider's base URL, e.g. "https://api.openai.com/v1" or "http://localhost:11434"
+	URL string `json:"url,omitempty"`
+	// APIKeySecret names the secret, set with 'amem config set --secret', holding the API key
+	APIKeySecret string `json:"api_key_secret,omitempty"`
+	// Dimensions sizes the local model's vectors, 256 by default
+	Dimensions int `json:"dimensions,omitempty"`
+	// MaxTokens is the most tokens the model takes per text. Longer observations are
+	// embedded in chunks. By default 8191 for openai, 2048 for ollama, and 512 for local
+	MaxTokens int `json:"max_tokens,omitempty"`
+}
+
+// Validate checks that the provider is known and the settings fit it.
+func (c *Config) Validate() error {
+	switch c.Provider {
+	case "", ProviderLocal:
+		if c.Model != "" || c.URL != "" || c.APIKeySecret != "" {
+			return fmt.Errorf("the local provider takes no model, url, or api_key_secret")
+		}
+	case ProviderOpenAI, ProviderOllama:
+		if c.Dimensions != 0 {
+			return fmt.Errorf("dimensions only applies to the local provider")
+		}
+	default:
+		return fmt.Errorf("unknown provider '%s' (use %s, %s, or %s)", c.Provider, ProviderLocal, ProviderOpenAI, ProviderOllama)
+	}
+	if c.Dimensions < 0 {
+		return fmt.Errorf("dimensions must be positive")
+	}
+	if c.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	return nil
+}
+
+// New returns the embedder cfg selects, which is the local model if cfg is nil.
+// apiKey is sent to OpenAI-compatible APIs, and may be empty for ones that need none.
+func New(cfg *Config, apiKey string) (Embedder, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	switch cfg.Provider {
+	case ProviderOpenAI:
+		return &openAI{
+			url:       strings.TrimSuffix(orDefault(cfg.URL, "https://api.openai.com/v1"), "/"),
+			model:     orDefault(cfg.Model, "text-embedding-3-small"),
+			maxTokens: orDefaultInt(cfg.MaxTokens, 8191),
+			apiKey:    apiKey,
+			client:    client,
+		}, nil
+	case ProviderOllama:
+		return &ollama{
+			url:       strings.TrimSuffix(orDefault(cfg.URL, "http://localhost:11434"), "/"),
+			model:     orDefault(cfg.Model, "nomic-embed-text"),
+			maxTokens: orDefaultInt(cfg.MaxTokens, 2048),
+			client:    client,
+		}, nil
+	default:
+		local := NewLocal(cfg.Dimensions)
+		local.maxTokens = orDefaultInt(cfg.MaxTokens, local.maxTokens)
+		return local, nil
+	}
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
+
+func orDefaultInt(value, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
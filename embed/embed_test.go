@@ -0,0 +1,122 @@
+package embed
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestLocal(t *testing.T) {
+	e, err := New(nil, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if e.Model() != "local/hash-256" {
+		t.Errorf("Model = %q", e.Model())
+	}
+
+	vectors, err := e.Embed(context.Background(), []string{"Likes green tea", "likes tea, green", "Drives a truck", ""})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if len(vectors) != 4 || len(vectors[0]) != 256 {
+		t.Fatalf("Unexpected vectors: %d of %d", len(vectors), len(vectors[0]))
+	}
+	if same := dot(vectors[0], vectors[1]); same < 0.999 {
+		t.Errorf("Expected the same words to embed the same, got similarity %v", same)
+	}
+	if different := dot(vectors[0], vectors[2]); different > 0.5 {
+		t.Errorf("Expected different words to embed differently, got similarity %v", different)
+	}
+	if dot(vectors[3], vectors[3]) != 0 {
+		t.Error("Expected a zero vector for text without words")
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer sk-test" {
+			http.Error(w, "bad request", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		// Out of order, as the API allows
+		_, _ = w.Write([]byte(`{"data": [{"index": 1, "embedding": [0, 1]}, {"index": 0, "embedding": [1, 0]}]}`))
+	}))
+	defer srv.Close()
+
+	e, err := New(&Config{Provider: ProviderOpenAI, URL: srv.URL + "/v1/"}, "sk-test")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if e.Model() != "openai/text-embedding-3-small" {
+		t.Errorf("Model = %q", e.Model())
+	}
+	vectors, err := e.Embed(context.Background(), []string{"a", "b"})
+	if err != nil {
+		t.Fatalf("Embed failed: %v", err)
+	}
+	if vectors[0][0] != 1 || vectors[1][1] != 1 {
+		t.Errorf("Expected vectors in input order, got %v", vectors)
+	}
+
+	e, _ = New(&Config{Provider: ProviderOpenAI, URL: srv.URL + "/v1"}, "wrong")
+	if _, err := e.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Error("Expected an error for a rejected request")
+	}
+}
+
+func TestOllama(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/embed" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(`{"embeddings": [[0.5, 0.5]]}`))
+	}))
+	defer srv.Close()
+
+	e, err := New(&Config{Provider: ProviderOllama, URL: srv.URL, Model: "all-minilm"}, "")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	if e.Model() != "ollama/all-minilm" {
+		t.Errorf("Model = %q", e.Model())
+	}
+	if vectors, err := e.Embed(context.Background(), []string{"a"}); err != nil || len(vectors) != 1 {
+		t.Errorf("Embed = %v, %v", vectors, err)
+	}
+	if _, err := e.Embed(context.Background(), []string{"a", "b"}); err == nil {
+		t.Error("Expected an error when the provider returns too few embeddings")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, cfg := range []Config{
+		{Provider: "cohere"},
+		{Model: "text-embedding-3-small"},
+		{Provider: ProviderOllama, Dimensions: 64},
+		{Dimensions: -1},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", cfg)
+		}
+	}
+	if err := (&Config{Provider: ProviderLocal, Dimensions: 64}).Validate(); err != nil {
+		t.Errorf("Expected a local config to be valid: %v", err)
+	}
+}
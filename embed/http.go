@@ -0,0 +1,121 @@
+package embed
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// openAI embeds text with an OpenAI-compatible /embeddings endpoint
+type openAI struct {
+	url       string
+	model     string
+	maxTokens int
+	apiKey    string
+	client    *http.Client
+}
+
+func (o *openAI) Model() string {
+	return ProviderOpenAI + "/" + o.model
+}
+
+func (o *openAI) MaxTokens() int {
+	return o.maxTokens
+}
+
+func (o *openAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	body := map[string]any{"model": o.model, "input": texts}
+	if err := post(ctx, o.client, o.url+"/embeddings", o.apiKey, body, &response); err != nil {
+		return nil, err
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range response.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding provider returned an embedding for input %d of %d", d.Index, len(texts))
+		}
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, checkVectors(vectors)
+}
+
+// ollama embeds text with a local Ollama server's /api/embed endpoint
+type ollama struct {
+	url       string
+	model     string
+	maxTokens int
+	client    *http.Client
+}
+
+func (o *ollama) Model() string {
+	return ProviderOllama + "/" + o.model
+}
+
+func (o *ollama) MaxTokens() int {
+	return o.maxTokens
+}
+
+func (o *ollama) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	var response struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	body := map[string]any{"model": o.model, "input": texts}
+	if err := post(ctx, o.client, o.url+"/api/embed", "", body, &response); err != nil {
+		return nil, err
+	}
+	if len(response.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("embedding provider returned %d embeddings for %d inputs", len(response.Embeddings), len(texts))
+	}
+	return response.Embeddings, checkVectors(response.Embeddings)
+}
+
+// post sends body as JSON to url and decodes the JSON response into out
+func post(ctx context.Context, client *http.Client, url, apiKey string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal embedding request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create embedding request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach embedding provider: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("embedding provider returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid embedding response: %w", err)
+	}
+	return nil
+}
+
+// checkVectors returns an error if any input went without a vector
+func checkVectors(vectors [][]float32) error {
+	for i, v := range vectors {
+		if len(v) == 0 {
+			return fmt.Errorf("embedding provider returned no embedding for input %d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package embed
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Local embeds text by hashing its words into a fixed number of buckets, so texts that
+// share words get similar vectors. It knows nothing about meaning, but works offline.
+type Local struct {
+	dimensions int
+	maxTokens  int
+}
+
+// NewLocal returns a local embedder making vectors of the given size, 256 if it's 0.
+func NewLocal(dimensions int) *Local {
+	if dimensions <= 0 {
+		dimensions = 256
+	}
+	return &Local{dimensions: dimensions, maxTokens: 512}
+}
+
+// Model names the local model and its vector size.
+func (l *Local) Model() string {
+	return fmt.Sprintf("local/hash-%d", l.dimensions)
+}
+
+// MaxTokens is the longest text the local model embeds well: longer texts share so many
+// words that their vectors blur together.
+func (l *Local) MaxTokens() int {
+	return l.maxTokens
+}
+
+// Embed returns a unit-length vector for each text. Texts without words get a zero vector.
+func (l *Local) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vectors[i] = l.embed(text)
+	}
+	return vectors, nil
+}
+
+func (l *Local) embed(text string) []float32 {
+	vector := make([]float32, l.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	for _, word := range words {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(word))
+		sum := h.Sum32()
+		// The top bit picks a sign, so unrelated words sharing a bucket tend to cancel out
+		if sum&(1<<31) != 0 {
+			vector[sum%uint32(l.dimensions)]--
+		} else {
+			vector[sum%uint32(l.dimensions)]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vector {
+			vector[i] = float32(float64(vector[i]) / norm)
+		}
+	}
+	return vector
+}
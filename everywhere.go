@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"amem/config"
+	"amem/db"
+	"amem/view"
+)
+
+// searchEverywhere searches every database amem knows about, printing merged results
+// labelled with the database each came from. Databases that can't be opened are skipped
+// with a warning, so one missing key doesn't hide the rest. Observations are cut to
+// snippets of about snippet characters when it's positive.
+func searchEverywhere(keywords, required []string, useUnion, withIDs bool, snippet int) error {
+	var sources []config.Source
+	var err error
+	if keyOverride != "" {
+		sources, err = config.LoadAllWithKey(keyOverride)
+	} else {
+		sources, err = config.LoadAll()
+	}
+	if err != nil {
+		return err
+	}
+
+	var entities []view.Sourced[db.Entity]
+	var observations []view.Sourced[db.Observation]
+	var relationships []view.Sourced[db.Relationship]
+	searched := 0
+	for _, source := range sources {
+		if source.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", source.Name, source.Err)
+			continue
+		}
+
+		e, o, r, err := searchSource(source, keywords, required, useUnion)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: skipping %s: %v\n", source.Name, err)
+			continue
+		}
+		for i := range o {
+			o[i].Text = view.Snippet(o[i].Text, keywords, snippet)
+		}
+		entities = append(entities, labelled(source.Name, e)...)
+		observations = append(observations, labelled(source.Name, o)...)
+		relationships = append(relationships, labelled(source.Name, r)...)
+		searched++
+	}
+	if searched == 0 {
+		return fmt.Errorf("no database could be searched")
+	}
+
+	view.FormatAllSourced(entities, observations, relationships, withIDs)
+	return nil
+}
+
+// searchSource searches one database for keywords in entities, observations, and relationships,
+// keeping only results that contain every required keyword.
+func searchSource(source config.Source, keywords, required []string, useUnion bool) ([]db.Entity, []db.Observation, []db.Relationship, error) {
+	database, err := db.OpenWithOptions(source.Config.DBPath, source.Config.EncryptionKey, openOptions(&source.Config.Config))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
+		}
+	}()
+
+	database.SetRequired(required)
+
+	entities, err := database.SearchEntities(keywords, useUnion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	observations, err := database.SearchObservations("", keywords, useUnion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	relationships, err := database.SearchRelationships("", "", "", keywords, useUnion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	ids := make([]int64, len(observations))
+	for i, o := range observations {
+		ids[i] = o.ID
+	}
+	if err := database.Locked(func() error { return database.MarkObservationsAccessed(ids) }); err != nil {
+		return nil, nil, nil, err
+	}
+	return entities, observations, relationships, nil
+}
+
+// labelled pairs each result with the name of the database it came from.
+func labelled[T interface{ Format(bool) string }](source string, results []T) []view.Sourced[T] {
+	sourced := make([]view.Sourced[T], len(results))
+	for i, r := range results {
+		sourced[i] = view.Sourced[T]{Source: source, Result: r}
+	}
+	return sourced
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// exitNotExist is the exit code for 'exists' not finding the record, distinct from
+// the 1 used for failures so scripts can tell them apart.
+const exitNotExist = 3
+
+// errNotExist is returned by 'exists' when the record doesn't exist
+var errNotExist = errors.New("record does not exist")
+
+// existsCommand builds the 'exists' command, which reports whether a record exists through its exit code
+func existsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "exists",
+		Usage: fmt.Sprintf("Check whether a record exists: exit with status 0 if it does, %d if it doesn't", exitNotExist),
+		Commands: []*cli.Command{
+			{
+				Name:      "entity",
+				Usage:     "Check whether an entity exists",
+				ArgsUsage: "<entity name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" || cmd.Args().Len() > 1 {
+						return fmt.Errorf("usage: amem exists entity <entity name>")
+					}
+					return reportExists(func(database *db.DB) (bool, error) {
+						return database.EntityExists(name)
+					}, fmt.Sprintf("Entity '%s'", name))
+				},
+			},
+			{
+				Name:  "observation",
+				Usage: "Check whether an observation exists",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:     "id",
+						Usage:    "Observation ID",
+						Required: true,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					id := int64(cmd.Int("id"))
+					return reportExists(func(database *db.DB) (bool, error) {
+						return database.ObservationExists(id)
+					}, fmt.Sprintf("Observation ID %d", id))
+				},
+			},
+			{
+				Name:  "relationship",
+				Usage: "Check whether a relationship exists",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "from",
+						Usage:    "Entity the relationship is from",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:     "to",
+						Usage:    "Entity the relationship is to",
+						Required: true,
+					},
+					&cli.StringFlag{
+						Name:  "type",
+						Usage: "Type of relationship (default: any)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					from, to, relType := cmd.String("from"), cmd.String("to"), cmd.String("type")
+					what := fmt.Sprintf("Relationship %s -> %s", from, to)
+					if relType != "" {
+						what = fmt.Sprintf("Relationship %s -[%s]-> %s", from, relType, to)
+					}
+					return reportExists(func(database *db.DB) (bool, error) {
+						return database.RelationshipExists(from, to, relType)
+					}, what)
+				},
+			},
+		},
+	}
+}
+
+// reportExists runs find, printing whether what exists and returning errNotExist if it doesn't
+func reportExists(find func(*db.DB) (bool, error), what string) error {
+	return withDB(func(database *db.DB) error {
+		found, err := find(database)
+		if err != nil {
+			return err
+		}
+		if !found {
+			fmt.Printf("%s does not exist\n", what)
+			return errNotExist
+		}
+		fmt.Printf("%s exists\n", what)
+		return nil
+	})
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"amem/config"
+	"amem/db"
+	"amem/redact"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// exportCommand builds the 'export' command, which prints memories in a form to use elsewhere
+func exportCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "export",
+		Usage: "Print memories as a digest to paste into a prompt or a wiki",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "format",
+				Usage: "Output format (markdown)",
+				Value: "markdown",
+			},
+			&cli.StringFlag{
+				Name:  "about",
+				Usage: "Only export memories about a topic: entities named for it, their observations, and anything else mentioning it",
+			},
+			&cli.StringFlag{
+				Name:  "entity",
+				Usage: "Only export entities whose text contains this, their observations, and their relationships",
+			},
+			&cli.StringFlag{
+				Name:  "type",
+				Usage: "Only export relationships whose type contains this",
+			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "Only export memories added at or after this duration ago (e.g. 2h) or date/time (e.g. 2025-01-31 14:00)",
+			},
+			&cli.BoolFlag{
+				Name:  "no-redact",
+				Usage: "Don't apply the config's redact rules",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			format := cmd.String("format")
+			if format != "markdown" {
+				return fmt.Errorf("unknown format %q (use markdown)", format)
+			}
+			topic := cmd.String("about")
+			filter := exportFilter{entity: cmd.String("entity"), relType: cmd.String("type")}
+			if value := cmd.String("since"); value != "" {
+				since, err := parseTimeFlag("since", value, time.Now())
+				if err != nil {
+					return err
+				}
+				filter.since = since
+			}
+
+			return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+				// An export is a copy of everything, not a recall
+				database.SetIncludeArchived(true)
+				entities, observations, relationships, err := memoriesAbout(database, topic)
+				if err != nil {
+					return err
+				}
+				entities, observations, relationships = filter.apply(entities, observations, relationships)
+				if !cmd.Bool("no-redact") {
+					redactMemories(cfg.Redact, entities, observations, relationships)
+				}
+
+				title := "Memories"
+				switch {
+				case topic != "":
+					title = "Memories about " + topic
+				case filter.entity != "":
+					title = "Memories about " + filter.entity
+				}
+				fmt.Print(view.Markdown(title, entities, observations, relationships))
+				return nil
+			})
+		},
+	}
+}
+
+// memoriesAbout returns the entities named for topic, their observations, and the observations
+// and relationships mentioning it. With no topic, it returns everything.
+func memoriesAbout(database *db.DB, topic string) ([]db.Entity, []db.Observation, []db.Relationship, error) {
+	if topic == "" {
+		return database.SearchAll(nil, false)
+	}
+
+	entities, err := database.SearchEntities([]string{topic}, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	about, err := database.SearchObservations(topic, nil, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	mentioning, err := database.SearchObservations("", []string{topic}, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	relationships, err := database.SearchRelationships("", "", "", []string{topic}, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// An observation about the topic can also mention it
+	observations := about
+	seen := map[int64]bool{}
+	for _, o := range about {
+		seen[o.ID] = true
+	}
+	for _, o := range mentioning {
+		if !seen[o.ID] {
+			observations = append(observations, o)
+		}
+	}
+	return entities, observations, relationships, nil
+}
+
+// redactMemories applies rules to the text of every record, in place.
+func redactMemories(rules redact.Rules, entities []db.Entity, observations []db.Observation, relationships []db.Relationship) {
+	if len(rules) == 0 {
+		return
+	}
+	for i := range entities {
+		entities[i].Text = rules.Apply(entities[i].Text)
+	}
+	for i := range observations {
+		observations[i].EntityText = rules.Apply(observations[i].EntityText)
+		observations[i].Text = rules.Apply(observations[i].Text)
+	}
+	for i := range relationships {
+		r := &relationships[i]
+		r.FromText, r.ToText, r.Type = rules.Apply(r.FromText), rules.Apply(r.ToText), rules.Apply(r.Type)
+	}
+}
+
+// exportFilter narrows an export to a slice of memory. Zero fields don't filter.
+type exportFilter struct {
+	// entity keeps entities whose text contains it, their observations, and relationships involving them
+	entity string
+	// relType keeps relationships whose type contains it
+	relType string
+	// since keeps records created at or after it
+	since time.Time
+}
+
+// apply returns the records that pass the filter. Like the search flags, text matches ignore case.
+func (f exportFilter) apply(entities []db.Entity, observations []db.Observation, relationships []db.Relationship) ([]db.Entity, []db.Observation, []db.Relationship) {
+	entities = slices.DeleteFunc(entities, func(e db.Entity) bool {
+		return !f.matchesEntity(e.Text) || !f.matchesTime(e.CreatedAt)
+	})
+	observations = slices.DeleteFunc(observations, func(o db.Observation) bool {
+		return !f.matchesEntity(o.EntityText) || !f.matchesTime(o.Timestamp)
+	})
+	relationships = slices.DeleteFunc(relationships, func(r db.Relationship) bool {
+		return !(f.matchesEntity(r.FromText) || f.matchesEntity(r.ToText)) || !containsFold(r.Type, f.relType) || !f.matchesTime(r.Timestamp)
+	})
+	return entities, observations, relationships
+}
+
+// matchesEntity reports whether an entity's text passes the filter.
+func (f exportFilter) matchesEntity(text string) bool {
+	return containsFold(text, f.entity)
+}
+
+// matchesTime reports whether a record created at timestamp, an RFC 3339 time, passes the filter.
+// Records whose timestamp can't be read are kept rather than silently dropped.
+func (f exportFilter) matchesTime(timestamp string) bool {
+	if f.since.IsZero() {
+		return true
+	}
+	t, err := time.Parse(time.RFC3339, timestamp)
+	return err != nil || !t.Before(f.since)
+}
+
+// containsFold reports whether s contains substr, ignoring case.
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
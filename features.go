@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// enableCommand builds the 'enable' command, which adds an optional feature's tables to the database
+func enableCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "enable",
+		Usage:     "Enable an optional feature on this database, or list the features with none given",
+		ArgsUsage: "[feature]",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			name := cmd.Args().First()
+			if name == "" {
+				return withDB(printFeatures)
+			}
+			return withWriteDB(func(database *db.DB) error {
+				enabled, err := database.HasFeature(name)
+				if err != nil {
+					return err
+				}
+				if enabled {
+					fmt.Printf("%s is already enabled\n", name)
+					return nil
+				}
+				if err := database.EnableFeature(name); err != nil {
+					return err
+				}
+				fmt.Printf("✓ Enabled %s\n", name)
+				return nil
+			})
+		},
+	}
+}
+
+// disableCommand builds the 'disable' command, which drops an optional feature's tables
+func disableCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "disable",
+		Usage:     "Disable an optional feature, dropping its tables (the memories themselves are kept)",
+		ArgsUsage: "<feature>",
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			name := cmd.Args().First()
+			if name == "" {
+				return fmt.Errorf("feature name is required")
+			}
+			return withWriteDB(func(database *db.DB) error {
+				err := database.DisableFeature(name)
+				if errors.Is(err, db.ErrFeatureDisabled) {
+					fmt.Printf("%s is not enabled\n", name)
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				fmt.Printf("✓ Disabled %s\n", name)
+				return nil
+			})
+		},
+	}
+}
+
+// printFeatures lists the optional features and whether each is enabled on database
+func printFeatures(database *db.DB) error {
+	for _, f := range db.Features() {
+		enabled, err := database.HasFeature(f.Name)
+		if err != nil {
+			return err
+		}
+		state := "disabled"
+		if enabled {
+			state = "enabled"
+		}
+		fmt.Printf("%-8s %-9s %s\n", f.Name, state, f.Usage)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// record is one entity, observation, or relationship looked up by ID.
+type record struct {
+	Kind  string
+	Value any
+	// Fields are the record's details in display order
+	Fields [][2]string
+}
+
+// MarshalJSON writes the record's own fields with its kind alongside. It's
+// "kind" rather than "type" because relationships already have a type.
+func (r record) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	fields["kind"], _ = json.Marshal(r.Kind)
+	return json.Marshal(fields)
+}
+
+// notedEntity is an entity with its note, if it has one
+type notedEntity struct {
+	db.Entity
+	Note string `json:"note,omitempty"`
+}
+
+// getCommand builds the 'get' command, which prints one record by ID
+func getCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "get",
+		Usage:     "Show one entity, observation, or relationship by ID",
+		ArgsUsage: "[entity|observation|relationship] <id>",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the record as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			args := cmd.Args().Slice()
+			var kind, idArg string
+			switch len(args) {
+			case 1:
+				idArg = args[0]
+			case 2:
+				kind, idArg = args[0], args[1]
+			default:
+				return fmt.Errorf("usage: amem get [entity|observation|relationship] <id>")
+			}
+			id, err := strconv.ParseInt(idArg, 10, 64)
+			if err != nil || id <= 0 {
+				return fmt.Errorf("invalid ID %q", idArg)
+			}
+
+			return withDB(func(database *db.DB) error {
+				rec, err := getRecord(database, kind, id)
+				if err != nil {
+					return err
+				}
+
+				if cmd.Bool("json") {
+					data, err := json.MarshalIndent(rec, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal %s: %w", rec.Kind, err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+
+				fmt.Printf("%s %d\n", rec.Kind, id)
+				for _, field := range rec.Fields {
+					fmt.Printf("  %-11s %s\n", field[0]+":", field[1])
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// singularTypes accepts record types as they're named in 'add' (singular) or 'search' (plural).
+var singularTypes = map[string]string{
+	"entity":        "entity",
+	"entities":      "entity",
+	"observation":   "observation",
+	"observations":  "observation",
+	"relationship":  "relationship",
+	"relationships": "relationship",
+}
+
+// getRecord looks up id as kind, or, if kind is empty, as whichever type has a record with that ID.
+// IDs are only unique within a type, so an ID found in several types must be given one.
+func getRecord(database *db.DB, kind string, id int64) (*record, error) {
+	lookups := map[string]func() (*record, error){
+		"entity": func() (*record, error) {
+			e, err := database.GetEntity(id)
+			if err != nil {
+				return nil, err
+			}
+			note, err := database.Note(e.Text)
+			if err != nil {
+				return nil, err
+			}
+			rec := &record{Kind: "entity", Value: notedEntity{*e, note}, Fields: [][2]string{
+				{"text", e.Text},
+				{"created_at", view.Time(e.CreatedAt)},
+				{"updated_at", view.Time(e.UpdatedAt)},
+			}}
+			if note != "" {
+				rec.Fields = append(rec.Fields, [2]string{"note", note})
+			}
+			return rec, nil
+		},
+		"observation": func() (*record, error) {
+			o, err := database.GetObservation(id)
+			if err != nil {
+				return nil, err
+			}
+			rec := &record{Kind: "observation", Value: o, Fields: [][2]string{
+				{"entity", fmt.Sprintf("%s (ID %d)", o.EntityText, o.EntityID)},
+				{"text", o.Text},
+				{"importance", strconv.Itoa(o.Importance)},
+				{"timestamp", view.Time(o.Timestamp)},
+				{"updated_at", view.Time(o.UpdatedAt)},
+			}}
+			if o.Lang != "" {
+				rec.Fields = append(rec.Fields, [2]string{"lang", o.Lang})
+			}
+			return rec, nil
+		},
+		"relationship": func() (*record, error) {
+			r, err := database.GetRelationship(id)
+			if err != nil {
+				return nil, err
+			}
+			return &record{Kind: "relationship", Value: r, Fields: [][2]string{
+				{"from", fmt.Sprintf("%s (ID %d)", r.FromText, r.FromID)},
+				{"type", r.Type},
+				{"to", fmt.Sprintf("%s (ID %d)", r.ToText, r.ToID)},
+				{"timestamp", view.Time(r.Timestamp)},
+				{"updated_at", view.Time(r.UpdatedAt)},
+			}}, nil
+		},
+	}
+
+	if kind != "" {
+		lookup, ok := lookups[singularTypes[kind]]
+		if !ok {
+			return nil, fmt.Errorf("unknown record type %q: use entity, observation, or relationship", kind)
+		}
+		return lookup()
+	}
+
+	var found []*record
+	for _, kind := range []string{"entity", "observation", "relationship"} {
+		rec, err := lookups[kind]()
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		found = append(found, rec)
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("no record with ID %d", id)
+	case 1:
+		return found[0], nil
+	default:
+		types := make([]string, len(found))
+		for i, rec := range found {
+			types[i] = rec.Kind
+		}
+		return nil, fmt.Errorf("ID %d matches more than one type (%s): use 'amem get <type> %d'", id, strings.Join(types, ", "), id)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"amem/gitrepo"
+)
+
+// offerGitignore checks whether a new database lies inside a git repository
+// without being ignored. If so, it offers to add the database (and the local
+// .amem directory, if given) to the repository's .gitignore, and warns loudly
+// if the user declines, so encrypted blobs don't get committed by accident.
+func offerGitignore(dbPath, amemDir string) error {
+	root, err := gitrepo.FindRoot(filepath.Dir(dbPath))
+	if err != nil {
+		// Not in a repository, nothing to protect against
+		return nil
+	}
+
+	ignored, err := gitrepo.IsIgnored(root, dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not check whether git ignores %s: %v\n", dbPath, err)
+		return nil
+	}
+	if ignored {
+		return nil
+	}
+
+	dbEntry, err := gitrepo.IgnoreEntry(root, dbPath, false)
+	if err != nil {
+		return err
+	}
+	entries := []string{dbEntry}
+	if amemDir != "" {
+		if entry, err := gitrepo.IgnoreEntry(root, amemDir, true); err == nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	fmt.Printf("\nThe database is inside the git repository at %s and is not ignored.\n", root)
+	answer, err := prompt(fmt.Sprintf("Add %s to %s? [y/n]", strings.Join(entries, " and "), filepath.Join(root, ".gitignore")), "y")
+	if err != nil {
+		return fmt.Errorf("failed to read answer: %w", err)
+	}
+
+	if answer != "y" && answer != "yes" {
+		fmt.Fprintf(os.Stderr, "\nWARNING: %s is not ignored by git.\n", dbPath)
+		fmt.Fprintf(os.Stderr, "WARNING: Committing it would publish your encrypted memory database. Add it to .gitignore before committing.\n\n")
+		return nil
+	}
+
+	added, err := gitrepo.AddIgnores(root, entries...)
+	if err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		fmt.Printf("Added %s to %s\n", strings.Join(added, ", "), filepath.Join(root, ".gitignore"))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package gitrepo
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// FindRoot walks up the directory tree from startDir looking for a .git entry.
+// Returns the repository root, or an error wrapping os.ErrNotExist if startDir isn't in a repository.
+func FindRoot(startDir string) (string, error) {
+	current := startDir
+
+	for {
+		// .git is a directory in normal checkouts and a file in worktrees and submodules
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			return current, nil
+		}
+
+		parent := filepath.Dir(current)
+		// Reached filesystem root
+		if parent == current {
+			return "", fmt.Errorf("not in a git repository: %w", os.ErrNotExist)
+		}
+		current = parent
+	}
+}
+
+// IsIgnored reports whether git ignores path in the repository at root.
+// Requires the git command.
+func IsIgnored(root, path string) (bool, error) {
+	cmd := exec.Command("git", "-C", root, "check-ignore", "--quiet", "--no-index", path)
+	err := cmd.Run()
+	if err == nil {
+		return true, nil
+	}
+
+	// Exit status 1 means not ignored; anything else is a failure
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to run git check-ignore: %w", err)
+}
+
+// IgnoreEntry returns the .gitignore line matching path in the repository at root,
+// anchored to the repository root. Directories end in a slash.
+func IgnoreEntry(root, path string, isDir bool) (string, error) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is not inside %s", path, root)
+	}
+
+	entry := "/" + filepath.ToSlash(rel)
+	if isDir {
+		entry += "/"
+	}
+	return entry, nil
+}
+
+// AddIgnores appends entries to root's .gitignore, skipping any already listed.
+// Entries match existing lines with or without a leading or trailing slash.
+// Returns the entries that were added.
+func AddIgnores(root string, entries ...string) ([]string, error) {
+	path := filepath.Join(root, ".gitignore")
+
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read .gitignore: %w", err)
+	}
+
+	normalize := func(line string) string {
+		return strings.Trim(strings.TrimSpace(line), "/")
+	}
+	listed := map[string]bool{}
+	for _, line := range strings.Split(string(existing), "\n") {
+		listed[normalize(line)] = true
+	}
+
+	var added []string
+	var content strings.Builder
+	for _, entry := range entries {
+		if listed[normalize(entry)] {
+			continue
+		}
+		listed[normalize(entry)] = true
+		added = append(added, entry)
+		content.WriteString(entry + "\n")
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open .gitignore: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// Don't join our first entry onto an unterminated last line
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		if _, err := f.WriteString("\n"); err != nil {
+			return nil, fmt.Errorf("failed to write .gitignore: %w", err)
+		}
+	}
+	if _, err := f.WriteString(content.String()); err != nil {
+		return nil, fmt.Errorf("failed to write .gitignore: %w", err)
+	}
+
+	return added, nil
+}
@@ -0,0 +1,114 @@
+package gitrepo
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindRoot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("failed to create .git: %v", err)
+	}
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatalf("failed to create nested dir: %v", err)
+	}
+
+	found, err := FindRoot(nested)
+	if err != nil {
+		t.Fatalf("FindRoot failed: %v", err)
+	}
+	if found != root {
+		t.Errorf("expected %s, got %s", root, found)
+	}
+}
+
+func TestFindRootWorktreeFile(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, ".git"), []byte("gitdir: /elsewhere\n"), 0o644); err != nil {
+		t.Fatalf("failed to write .git file: %v", err)
+	}
+
+	found, err := FindRoot(root)
+	if err != nil {
+		t.Fatalf("FindRoot failed: %v", err)
+	}
+	if found != root {
+		t.Errorf("expected %s, got %s", root, found)
+	}
+}
+
+func TestFindRootNotFound(t *testing.T) {
+	_, err := FindRoot(t.TempDir())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestAddIgnores(t *testing.T) {
+	root := t.TempDir()
+	path := filepath.Join(root, ".gitignore")
+	if err := os.WriteFile(path, []byte("node_modules\n.amem"), 0o644); err != nil {
+		t.Fatalf("failed to write .gitignore: %v", err)
+	}
+
+	added, err := AddIgnores(root, "/.amem/", "/amem.db")
+	if err != nil {
+		t.Fatalf("AddIgnores failed: %v", err)
+	}
+	if len(added) != 1 || added[0] != "/amem.db" {
+		t.Errorf("expected only /amem.db to be added, got %v", added)
+	}
+
+	data, _ := os.ReadFile(path)
+	if string(data) != "node_modules\n.amem\n/amem.db\n" {
+		t.Errorf("unexpected .gitignore contents: %q", data)
+	}
+
+	// Running again adds nothing
+	added, err = AddIgnores(root, "/.amem/", "/amem.db")
+	if err != nil || len(added) != 0 {
+		t.Errorf("expected nothing to be added, got %v (err %v)", added, err)
+	}
+}
+
+func TestIgnoreEntry(t *testing.T) {
+	root := t.TempDir()
+
+	entry, err := IgnoreEntry(root, filepath.Join(root, "sub", ".amem"), true)
+	if err != nil || entry != "/sub/.amem/" {
+		t.Errorf("expected /sub/.amem/, got %q (err %v)", entry, err)
+	}
+
+	if _, err := IgnoreEntry(root, filepath.Dir(root), false); err == nil {
+		t.Error("expected error for path outside the repository")
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	if err := exec.Command("git", "init", "--quiet", root).Run(); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("/amem.db\n"), 0o644); err != nil {
+		t.Fatalf("failed to write .gitignore: %v", err)
+	}
+
+	ignored, err := IsIgnored(root, filepath.Join(root, "amem.db"))
+	if err != nil || !ignored {
+		t.Errorf("expected amem.db to be ignored, got %v (err %v)", ignored, err)
+	}
+
+	ignored, err = IsIgnored(root, filepath.Join(root, "other.db"))
+	if err != nil || ignored {
+		t.Errorf("expected other.db not to be ignored, got %v (err %v)", ignored, err)
+	}
+}
@@ -2,13 +2,17 @@ module amem
 
 go 1.25.3
 
+require (
+	github.com/mutecomm/go-sqlcipher/v4 v4.4.2
+	github.com/urfave/cli/v3 v3.5.0
+	github.com/zalando/go-keyring v0.2.6
+	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/sys v0.37.0
+	golang.org/x/term v0.36.0
+)
+
 require (
 	al.essio.dev/pkg/shellescape v1.5.1 // indirect
 	github.com/danieljoos/wincred v1.2.2 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
-	github.com/mutecomm/go-sqlcipher/v4 v4.4.2 // indirect
-	github.com/urfave/cli/v3 v3.5.0 // indirect
-	github.com/zalando/go-keyring v0.2.6 // indirect
-	golang.org/x/sys v0.37.0 // indirect
-	golang.org/x/term v0.36.0 // indirect
 )
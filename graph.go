@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// graphCommand builds the 'graph' command, which works with the graph of entities and relationships
+func graphCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "graph",
+		Usage: "Explore the graph of entities and relationships",
+		Commands: []*cli.Command{
+			{
+				Name:  "export",
+				Usage: "Print the part of the graph within a few hops of an entity",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "root",
+						Usage:    "Entity to start from",
+						Required: true,
+					},
+					&cli.IntFlag{
+						Name:  "depth",
+						Usage: "How many relationships away from the root to include",
+						Value: 1,
+					},
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Output format (text, json, dot, mermaid)",
+						Value: "text",
+					},
+					&cli.BoolFlag{
+						Name:  "with-ids",
+						Usage: "Show database IDs with text results",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					format := cmd.String("format")
+					switch format {
+					case "text", "json", "dot", "mermaid":
+					default:
+						return fmt.Errorf("unknown format %q: use text, json, dot, or mermaid", format)
+					}
+
+					return withDB(func(database *db.DB) error {
+						graph, err := database.Subgraph(cmd.String("root"), cmd.Int("depth"))
+						if err != nil {
+							return err
+						}
+
+						switch format {
+						case "json":
+							data, err := json.MarshalIndent(graph, "", "  ")
+							if err != nil {
+								return fmt.Errorf("failed to marshal graph: %w", err)
+							}
+							fmt.Println(string(data))
+						case "dot":
+							fmt.Print(graphDOT(graph))
+						case "mermaid":
+							fmt.Print(graphMermaid(graph))
+						default:
+							view.FormatAll(graph.Entities, graph.Observations, graph.Relationships, cmd.Bool("with-ids"))
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "rank",
+				Usage: "List the most connected entities",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "by",
+						Usage: "How to score entities (degree: count relationships; pagerank: also weigh who they're related to)",
+						Value: db.RankByDegree,
+					},
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "How many entities to list (0 for all)",
+						Value: 10,
+					},
+					&cli.BoolFlag{
+						Name:  "undirected",
+						Usage: "Score pagerank as if relationships pointed both ways",
+					},
+					&cli.BoolFlag{
+						Name:  "with-ids",
+						Usage: "Show database IDs with results",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Int("limit") < 0 {
+						return fmt.Errorf("--limit cannot be negative")
+					}
+
+					return withDB(func(database *db.DB) error {
+						ranked, err := database.RankEntities(cmd.String("by"), cmd.Int("limit"), cmd.Bool("undirected"))
+						if err != nil {
+							return err
+						}
+						if len(ranked) == 0 {
+							fmt.Println("No entities found")
+							return nil
+						}
+
+						for i, r := range ranked {
+							name := r.Text
+							if cmd.Bool("with-ids") {
+								name = fmt.Sprintf("[%d] %s", r.ID, r.Text)
+							}
+							if cmd.String("by") == db.RankByPageRank {
+								fmt.Printf("%3d. %s (score %.4f, %d relationships)\n", i+1, name, r.Score, r.Degree)
+							} else {
+								fmt.Printf("%3d. %s (%d relationships)\n", i+1, name, r.Degree)
+							}
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "clusters",
+				Usage: "Group entities into clusters of related entities",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "by",
+						Usage: "How to group entities (components: connected at all; communities: densely connected)",
+						Value: db.ClusterByComponents,
+					},
+					&cli.BoolFlag{
+						Name:  "with-ids",
+						Usage: "Show database IDs with results",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return withDB(func(database *db.DB) error {
+						clusters, err := database.Clusters(cmd.String("by"))
+						if err != nil {
+							return err
+						}
+						if len(clusters) == 0 {
+							fmt.Println("No entities found")
+							return nil
+						}
+
+						// Clusters are largest first, so any entities on their own come last
+						var alone []db.Entity
+						for len(clusters) > 0 && len(clusters[len(clusters)-1]) == 1 {
+							alone = append(alone, clusters[len(clusters)-1][0])
+							clusters = clusters[:len(clusters)-1]
+						}
+						slices.Reverse(alone)
+
+						if len(clusters) > 0 {
+							fmt.Printf("Found %d clusters:\n", len(clusters))
+						}
+						for i, cluster := range clusters {
+							fmt.Printf("%d. %d entities: %s\n", i+1, len(cluster), entityList(cluster, cmd.Bool("with-ids")))
+						}
+						if len(alone) > 0 {
+							if len(clusters) > 0 {
+								fmt.Println()
+							}
+							fmt.Printf("%d entities related to no others: %s\n", len(alone), entityList(alone, cmd.Bool("with-ids")))
+						}
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// entityList joins entities' text with commas.
+func entityList(entities []db.Entity, withIDs bool) string {
+	names := make([]string, len(entities))
+	for i, e := range entities {
+		names[i] = e.Text
+		if withIDs {
+			names[i] = fmt.Sprintf("[%d] %s", e.ID, e.Text)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// graphDOT renders a subgraph in Graphviz's DOT language, highlighting the root.
+func graphDOT(graph *db.Subgraph) string {
+	var b strings.Builder
+	b.WriteString("digraph amem {\n")
+	for _, e := range graph.Entities {
+		attrs := fmt.Sprintf("label=%s", dotQuote(e.Text))
+		if e.Text == graph.Root {
+			attrs += ", style=bold"
+		}
+		fmt.Fprintf(&b, "  n%d [%s];\n", e.ID, attrs)
+	}
+	for _, r := range graph.Relationships {
+		fmt.Fprintf(&b, "  n%d -> n%d [label=%s];\n", r.FromID, r.ToID, dotQuote(r.Type))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote quotes s as a DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// graphMermaid renders a subgraph as a Mermaid flowchart.
+func graphMermaid(graph *db.Subgraph) string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	for _, e := range graph.Entities {
+		fmt.Fprintf(&b, "  n%d[%s]\n", e.ID, mermaidQuote(e.Text))
+	}
+	for _, r := range graph.Relationships {
+		fmt.Fprintf(&b, "  n%d -->|%s| n%d\n", r.FromID, mermaidQuote(r.Type), r.ToID)
+	}
+	return b.String()
+}
+
+// mermaidQuote quotes s as a Mermaid label, escaping characters that would end it.
+func mermaidQuote(s string) string {
+	return `"` + strings.NewReplacer(`"`, "#quot;", "|", "#124;", "\n", " ").Replace(s) + `"`
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"amem/config"
+	"amem/db"
+	"amem/embed"
+	"github.com/urfave/cli/v3"
+)
+
+// indexEmbeddings is 'amem index --embeddings', which embeds observations that have no
+// vector from the configured model, or a stale one. Vectors from another model are only
+// replaced with --rebuild, since that may mean re-embedding every observation.
+func indexEmbeddings(ctx context.Context, cmd *cli.Command) error {
+	batchSize := int(cmd.Int("batch-size"))
+	if batchSize < 1 {
+		return fmt.Errorf("--batch-size must be at least 1")
+	}
+
+	return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+		if cmd.Bool("drop") {
+			return database.Locked(func() error {
+				n, err := database.DropEmbeddings()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Dropped %d embeddings\n", n)
+				return nil
+			})
+		}
+
+		embedder, err := newEmbedder(cfg, database)
+		if err != nil {
+			return err
+		}
+		model := embedder.Model()
+
+		models, err := database.EmbeddingModels()
+		if err != nil {
+			return err
+		}
+		if others := slices.DeleteFunc(models, func(m string) bool { return m == model }); len(others) > 0 && !cmd.Bool("rebuild") {
+			return fmt.Errorf("observations were embedded with %s, but the config uses %s: run 'amem index --rebuild' to re-embed them", others[0], model)
+		}
+
+		total, err := database.CountPendingEmbeddings(model)
+		if err != nil {
+			return err
+		}
+		if total == 0 {
+			fmt.Printf("All observations are embedded with %s\n", model)
+			return nil
+		}
+
+		done, err := embedPending(ctx, database, embedder, batchSize, func(done int) {
+			fmt.Fprintf(os.Stderr, "\rEmbedded %d of %d observations", done, total)
+		})
+		if done > 0 {
+			fmt.Fprintln(os.Stderr)
+		}
+		if err != nil {
+			return fmt.Errorf("%w (embedded %d of %d; run the same command again to resume)", err, done, total)
+		}
+		fmt.Printf("✓ Embedded %d observations with %s\n", done, model)
+		return nil
+	})
+}
+
+// embedPending embeds every observation the database says needs it, batchSize at a time,
+// saving each batch as it goes so an interrupted run loses at most one batch.
+// Reports the running total to progress after each batch, and returns it.
+func embedPending(ctx context.Context, database *db.DB, embedder embed.Embedder, batchSize int, progress func(done int)) (int, error) {
+	done := 0
+	var afterID int64
+	for {
+		batch, err := database.PendingEmbeddings(embedder.Model(), afterID, batchSize)
+		if err != nil || len(batch) == 0 {
+			return done, err
+		}
+
+		// Observations too long for the model are embedded in chunks
+		var chunks []db.Chunk
+		var texts []string
+		for _, p := range batch {
+			for _, span := range embed.Chunk(p.Text, embedder.MaxTokens()) {
+				chunks = append(chunks, db.Chunk{ObservationID: p.ID, Start: span.Start, End: span.End})
+				texts = append(texts, p.Text[span.Start:span.End])
+			}
+		}
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return done, err
+		}
+		for i := range chunks {
+			chunks[i].Vector = vectors[i]
+		}
+		if err := database.Locked(func() error {
+			return database.SaveEmbeddings(embedder.Model(), chunks)
+		}); err != nil {
+			return done, err
+		}
+
+		done += len(batch)
+		afterID = batch[len(batch)-1].ID
+		progress(done)
+	}
+}
+
+// newEmbedder returns the embedder the config selects, with its API key from the database's secrets
+func newEmbedder(cfg *config.LoadedConfig, database *db.DB) (embed.Embedder, error) {
+	var apiKey string
+	if cfg.Embeddings != nil && cfg.Embeddings.APIKeySecret != "" {
+		var err error
+		if apiKey, err = database.ConfigSecret(cfg.Embeddings.APIKeySecret); err != nil {
+			return nil, fmt.Errorf("failed to get the embeddings API key: %w (set it with 'amem config set --secret %s')", err, cfg.Embeddings.APIKeySecret)
+		}
+	}
+	return embed.New(cfg.Embeddings, apiKey)
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// claudeCodeHooks maps Claude Code hook events to the amem command run for each.
+// SessionStart recalls recent memories; UserPromptSubmit recalls memories related to each prompt;
+// Stop adds the memories written in the transcript once the agent has replied.
+var claudeCodeHooks = []struct {
+	event   string
+	command string
+}{
+	{"SessionStart", "amem context --hook"},
+	{"UserPromptSubmit", "amem context --hook"},
+	{"Stop", "amem watch --hook"},
+}
+
+// claudeCodeSettingsPath returns the Claude Code settings file to write hooks to.
+// Project settings live in <root>/.claude/settings.json, user settings in ~/.claude/settings.json.
+func claudeCodeSettingsPath(root string, global bool) (string, error) {
+	if global {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		root = home
+	}
+	return filepath.Join(root, ".claude", "settings.json"), nil
+}
+
+// addClaudeCodeHooks merges the amem hooks into Claude Code settings JSON.
+// Other settings and hooks are preserved. Returns the updated JSON and the events that were added.
+func addClaudeCodeHooks(existing []byte) ([]byte, []string, error) {
+	settings := map[string]interface{}{}
+	if len(strings.TrimSpace(string(existing))) > 0 {
+		if err := json.Unmarshal(existing, &settings); err != nil {
+			return nil, nil, fmt.Errorf("invalid settings JSON: %w", err)
+		}
+	}
+
+	hooks, ok := settings["hooks"].(map[string]interface{})
+	if !ok {
+		if _, exists := settings["hooks"]; exists {
+			return nil, nil, fmt.Errorf("invalid settings JSON: \"hooks\" is not an object")
+		}
+		hooks = map[string]interface{}{}
+	}
+
+	var added []string
+	for _, h := range claudeCodeHooks {
+		matchers, _ := hooks[h.event].([]interface{})
+		if hasHookCommand(matchers, h.command) {
+			continue
+		}
+		matchers = append(matchers, map[string]interface{}{
+			"hooks": []interface{}{
+				map[string]interface{}{"type": "command", "command": h.command},
+			},
+		})
+		hooks[h.event] = matchers
+		added = append(added, h.event)
+	}
+	settings["hooks"] = hooks
+
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	return append(data, '\n'), added, nil
+}
+
+// hasHookCommand reports whether any matcher group already runs command
+func hasHookCommand(matchers []interface{}, command string) bool {
+	for _, m := range matchers {
+		group, _ := m.(map[string]interface{})
+		entries, _ := group["hooks"].([]interface{})
+		for _, e := range entries {
+			entry, _ := e.(map[string]interface{})
+			if entry["command"] == command {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// integrateClaudeCode writes the amem hooks into the Claude Code settings file at path.
+// Returns the hook events that were added.
+func integrateClaudeCode(path string) ([]string, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	updated, added, err := addClaudeCodeHooks(existing)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update %s: %w", path, err)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, updated, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return added, nil
+}
+
+// hookInput is the subset of the JSON Claude Code passes to hooks on stdin
+type hookInput struct {
+	HookEventName  string `json:"hook_event_name"`
+	Prompt         string `json:"prompt"`
+	TranscriptPath string `json:"transcript_path"`
+}
+
+// readHookInput parses hook input from r. Empty input is allowed.
+func readHookInput(r io.Reader) (*hookInput, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hook input: %w", err)
+	}
+	var input hookInput
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return &input, nil
+	}
+	if err := json.Unmarshal(data, &input); err != nil {
+		return nil, fmt.Errorf("invalid hook input: %w", err)
+	}
+	return &input, nil
+}
+
+// stopWords are common words ignored when turning a prompt into search keywords
+var stopWords = map[string]bool{
+	"about": true, "after": true, "again": true, "also": true, "because": true, "been": true,
+	"before": true, "being": true, "between": true, "both": true, "could": true, "does": true,
+	"doing": true, "each": true, "from": true, "have": true, "having": true, "here": true,
+	"into": true, "just": true, "like": true, "make": true, "more": true, "most": true,
+	"need": true, "only": true, "other": true, "over": true, "please": true, "should": true,
+	"some": true, "such": true, "than": true, "that": true, "their": true, "them": true,
+	"then": true, "there": true, "these": true, "they": true, "this": true, "those": true,
+	"through": true, "under": true, "until": true, "very": true, "want": true, "were": true,
+	"what": true, "when": true, "where": true, "which": true, "while": true, "will": true,
+	"with": true, "would": true, "your": true,
+}
+
+// maxPromptKeywords caps how many keywords are searched for a single prompt
+const maxPromptKeywords = 8
+
+// promptKeywords extracts distinctive words from a prompt to search memory with
+func promptKeywords(prompt string) []string {
+	words := strings.FieldsFunc(strings.ToLower(prompt), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-' && r != '_'
+	})
+
+	seen := map[string]bool{}
+	var keywords []string
+	for _, w := range words {
+		w = strings.Trim(w, "-_")
+		if len([]rune(w)) < 4 || stopWords[w] || seen[w] {
+			continue
+		}
+		seen[w] = true
+		keywords = append(keywords, w)
+		if len(keywords) == maxPromptKeywords {
+			break
+		}
+	}
+	return keywords
+}
@@ -0,0 +1,271 @@
+// Package keyagent caches encryption keys in memory for a limited time and
+// serves them to amem commands over a unix socket, like ssh-agent, so keychains
+// that prompt for approval don't prompt on every command.
+package keyagent
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultTTL is how long the agent keeps a key after fetching it.
+const DefaultTTL = 15 * time.Minute
+
+// promptTimeout leaves time for the user to answer a keychain prompt.
+const promptTimeout = 2 * time.Minute
+
+// dialTimeout bounds how long commands wait on an agent before using the keychain.
+const dialTimeout = 500 * time.Millisecond
+
+// request is one line sent by a client.
+type request struct {
+	Op      string `json:"op"` // "get" or "clear"
+	Account string `json:"account,omitempty"`
+}
+
+// response is the agent's one-line reply.
+type response struct {
+	Key   string `json:"key,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type entry struct {
+	key     string
+	expires time.Time
+}
+
+// Agent caches keys fetched with Fetch for TTL.
+type Agent struct {
+	TTL   time.Duration
+	Fetch func(account string) (string, error)
+
+	mu    sync.Mutex
+	cache map[string]entry
+	now   func() time.Time
+}
+
+// New creates an agent that fetches keys with fetch and keeps them for ttl.
+func New(ttl time.Duration, fetch func(account string) (string, error)) *Agent {
+	return &Agent{TTL: ttl, Fetch: fetch, cache: map[string]entry{}, now: time.Now}
+}
+
+// SocketPath returns the agent's socket: $AMEM_AGENT_SOCK if set, otherwise a
+// per-user path in the temp directory.
+func SocketPath() string {
+	if path := os.Getenv("AMEM_AGENT_SOCK"); path != "" {
+		return path
+	}
+	return defaultSocketPath()
+}
+
+func defaultSocketPath() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("amem-%d", os.Getuid()), "agent.sock")
+}
+
+// Listen creates the socket at path, readable only by the current user.
+// A socket left behind by an agent that's no longer running is replaced.
+func Listen(path string) (net.Listener, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+	// Anyone who can reach the socket can read keys, so the shared temp directory
+	// must not hold someone else's socket directory
+	if path == defaultSocketPath() {
+		if err := checkSocketDir(dir); err != nil {
+			return nil, err
+		}
+	}
+
+	if conn, err := net.DialTimeout("unix", path, dialTimeout); err == nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("an agent is already running at %s", path)
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// checkSocketDir returns an error unless dir, in the shared temp directory, is a directory
+// only the current user can use. Another user could create it first, with a socket of
+// their own in it. Windows has no mode bits or owners to check.
+func checkSocketDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if !info.IsDir() || !ownedByUser(info) {
+		return fmt.Errorf("socket directory %s is not a directory owned by you", dir)
+	}
+	if info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("socket directory %s is accessible by other users", dir)
+	}
+	return nil
+}
+
+// checkSocket returns an error unless path is a socket the current user owns, so keys
+// are never taken from an agent someone else planted there.
+func checkSocket(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if path == defaultSocketPath() {
+		if err := checkSocketDir(filepath.Dir(path)); err != nil {
+			return err
+		}
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type() != os.ModeSocket || !ownedByUser(info) {
+		return fmt.Errorf("%s is not a socket owned by you", path)
+	}
+	return nil
+}
+
+// Serve answers clients on listener until ctx is canceled.
+func (a *Agent) Serve(ctx context.Context, listener net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		_ = listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept connection: %w", err)
+		}
+		go a.handle(conn)
+	}
+}
+
+func (a *Agent) handle(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(promptTimeout))
+
+	var req request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		return
+	}
+
+	var resp response
+	switch req.Op {
+	case "get":
+		key, err := a.get(req.Account)
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		resp.Key = key
+	case "clear":
+		a.clear()
+	default:
+		resp.Error = fmt.Sprintf("unknown op %q", req.Op)
+	}
+
+	_ = json.NewEncoder(conn).Encode(resp)
+}
+
+// get returns account's cached key, fetching it if missing or expired.
+// The lock is held while fetching so concurrent commands share one keychain prompt.
+func (a *Agent) get(account string) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for acct, e := range a.cache {
+		if now.After(e.expires) {
+			delete(a.cache, acct)
+		}
+	}
+
+	if e, ok := a.cache[account]; ok {
+		return e.key, nil
+	}
+
+	key, err := a.Fetch(account)
+	if err != nil {
+		return "", err
+	}
+	a.cache[account] = entry{key: key, expires: now.Add(a.TTL)}
+	return key, nil
+}
+
+func (a *Agent) clear() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	clear(a.cache)
+}
+
+// Get asks the agent at SocketPath for account's key.
+// Returns an error if no agent is running or it couldn't get the key.
+func Get(account string) (string, error) {
+	resp, err := call(request{Op: "get", Account: account})
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", errors.New(resp.Error)
+	}
+	return resp.Key, nil
+}
+
+// Clear makes the agent at SocketPath forget all cached keys.
+func Clear() error {
+	resp, err := call(request{Op: "clear"})
+	if err != nil {
+		return err
+	}
+	if resp.Error != "" {
+		return errors.New(resp.Error)
+	}
+	return nil
+}
+
+func call(req request) (*response, error) {
+	path := SocketPath()
+	if err := checkSocket(path); err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	conn, err := net.DialTimeout("unix", path, dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to agent: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	_ = conn.SetDeadline(time.Now().Add(promptTimeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("failed to send request to agent: %w", err)
+	}
+
+	var resp response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read agent response: %w", err)
+	}
+	return &resp, nil
+}
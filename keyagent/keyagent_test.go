@@ -0,0 +1,167 @@
+package keyagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// startAgent runs an agent on a temp socket that AMEM_AGENT_SOCK points at
+func startAgent(t *testing.T, a *Agent) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "agent.sock")
+	t.Setenv("AMEM_AGENT_SOCK", path)
+
+	listener, err := Listen(path)
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- a.Serve(ctx, listener) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve failed: %v", err)
+		}
+	})
+}
+
+func TestGetCachesUntilTTL(t *testing.T) {
+	fetches := 0
+	a := New(time.Minute, func(account string) (string, error) {
+		fetches++
+		return "key-for-" + account, nil
+	})
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	startAgent(t, a)
+
+	for range 3 {
+		key, err := Get("global")
+		if err != nil {
+			t.Fatalf("Get failed: %v", err)
+		}
+		if key != "key-for-global" {
+			t.Errorf("Get() = %q, want %q", key, "key-for-global")
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("fetched %d times, want 1", fetches)
+	}
+
+	if _, err := Get("local:/proj"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetched %d times, want 2 (one per account)", fetches)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, err := Get("global"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if fetches != 3 {
+		t.Errorf("fetched %d times, want 3 (expired key refetched)", fetches)
+	}
+}
+
+func TestClear(t *testing.T) {
+	fetches := 0
+	a := New(time.Hour, func(account string) (string, error) {
+		fetches++
+		return "k", nil
+	})
+	startAgent(t, a)
+
+	if _, err := Get("global"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if err := Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if _, err := Get("global"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("fetched %d times, want 2 (cleared key refetched)", fetches)
+	}
+}
+
+func TestGetFetchError(t *testing.T) {
+	fetches := 0
+	a := New(time.Hour, func(account string) (string, error) {
+		fetches++
+		return "", errors.New("user denied access")
+	})
+	startAgent(t, a)
+
+	for range 2 {
+		_, err := Get("global")
+		if err == nil || err.Error() != "user denied access" {
+			t.Errorf("Get() error = %v, want %q", err, "user denied access")
+		}
+	}
+	if fetches != 2 {
+		t.Errorf("fetched %d times, want 2 (errors aren't cached)", fetches)
+	}
+}
+
+func TestGetWithoutAgent(t *testing.T) {
+	t.Setenv("AMEM_AGENT_SOCK", filepath.Join(t.TempDir(), "missing.sock"))
+
+	if _, err := Get("global"); err == nil {
+		t.Error("Get() without an agent should fail")
+	}
+}
+
+func TestListenRefusesSecondAgent(t *testing.T) {
+	startAgent(t, New(time.Hour, func(string) (string, error) { return "k", nil }))
+
+	if _, err := Listen(SocketPath()); err == nil {
+		t.Error("Listen() with an agent already running should fail")
+	}
+}
+
+func TestGetRefusesSymlinkedSocket(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket owners are not checked on Windows")
+	}
+	startAgent(t, New(time.Hour, func(string) (string, error) { return "k", nil }))
+
+	link := filepath.Join(t.TempDir(), "link.sock")
+	if err := os.Symlink(SocketPath(), link); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AMEM_AGENT_SOCK", link)
+
+	if _, err := Get("global"); err == nil {
+		t.Error("Get() through a symlinked socket should fail")
+	}
+}
+
+func TestListenRefusesSymlinkedDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("socket owners are not checked on Windows")
+	}
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("AMEM_AGENT_SOCK", "")
+	target := filepath.Join(tmp, "elsewhere")
+	if err := os.Mkdir(target, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(target, filepath.Join(tmp, fmt.Sprintf("amem-%d", os.Getuid()))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Listen(SocketPath()); err == nil {
+		t.Error("Listen() in a symlinked socket directory should fail")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package keyagent
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByUser reports whether the file described by info belongs to the current user.
+func ownedByUser(info os.FileInfo) bool {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(st.Uid) == os.Getuid()
+}
@@ -0,0 +1,134 @@
+// Package keybundle seals a config and its encryption key into a
+// passphrase-protected bundle for moving a database to another machine.
+package keybundle
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// magic starts every bundle so other files are rejected early.
+const magic = "AMEMKEY1"
+
+// pemType labels armored bundles.
+const pemType = "AMEM KEY BUNDLE"
+
+const (
+	saltSize  = 16
+	nonceSize = 12
+)
+
+// iterations is the PBKDF2 work factor for new bundles (lowered in tests).
+var iterations = 600_000
+
+// maxIterations bounds the work a crafted bundle can make Open do.
+const maxIterations = 10_000_000
+
+// ErrPassphrase is returned when a bundle can't be opened with the given passphrase.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted bundle")
+
+// Bundle is what's needed to open a database on another machine.
+type Bundle struct {
+	// Scope is "local" or "global", where the config is installed on import
+	Scope string `json:"scope"`
+	// Config is the config file, as written (paths are not expanded or resolved)
+	Config json.RawMessage `json:"config"`
+	// Key is the encryption key stored in the keychain
+	Key string `json:"key"`
+}
+
+// Seal encrypts b with passphrase. With armor, the result is ASCII text that
+// survives being pasted into email or chat.
+func Seal(b *Bundle, passphrase string, armor bool) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase cannot be empty")
+	}
+
+	plaintext, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal bundle: %w", err)
+	}
+
+	salt := make([]byte, saltSize)
+	_, _ = rand.Read(salt)
+	nonce := make([]byte, nonceSize)
+	_, _ = rand.Read(nonce)
+
+	header := make([]byte, 0, len(magic)+4+saltSize+nonceSize)
+	header = append(header, magic...)
+	header = binary.BigEndian.AppendUint32(header, uint32(iterations))
+	header = append(header, salt...)
+	header = append(header, nonce...)
+
+	aead, err := bundleCipher(passphrase, salt, iterations)
+	if err != nil {
+		return nil, err
+	}
+	// The header is authenticated so its parameters can't be tampered with
+	data := aead.Seal(header, nonce, plaintext, header)
+
+	if armor {
+		return pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: data}), nil
+	}
+	return data, nil
+}
+
+// Open decrypts a bundle made by Seal, armored or not.
+func Open(data []byte, passphrase string) (*Bundle, error) {
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != pemType {
+			return nil, fmt.Errorf("not a key bundle (found %q)", block.Type)
+		}
+		data = block.Bytes
+	}
+
+	headerSize := len(magic) + 4 + saltSize + nonceSize
+	if len(data) < headerSize || !bytes.HasPrefix(data, []byte(magic)) {
+		return nil, fmt.Errorf("not a key bundle")
+	}
+	header := data[:headerSize]
+	rest := header[len(magic):]
+	iter := int(binary.BigEndian.Uint32(rest))
+	if iter < 1 || iter > maxIterations {
+		return nil, fmt.Errorf("not a key bundle")
+	}
+	salt := rest[4 : 4+saltSize]
+	nonce := rest[4+saltSize:]
+
+	aead, err := bundleCipher(passphrase, salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+
+	var b Bundle
+	if err := json.Unmarshal(plaintext, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse bundle: %w", err)
+	}
+	return &b, nil
+}
+
+// bundleCipher derives the AEAD that encrypts a bundle with passphrase.
+func bundleCipher(passphrase string, salt []byte, iter int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iter, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
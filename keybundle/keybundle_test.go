@@ -0,0 +1,76 @@
+package keybundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestSealOpen(t *testing.T) {
+	iterations = 1000
+
+	b := &Bundle{
+		Scope:  "local",
+		Config: json.RawMessage(`{"db_path":"../amem.db"}`),
+		Key:    "testkey123456789012",
+	}
+
+	for _, armor := range []bool{false, true} {
+		data, err := Seal(b, "correct horse", armor)
+		if err != nil {
+			t.Fatalf("Seal(armor=%v) failed: %v", armor, err)
+		}
+		if armor != bytes.HasPrefix(data, []byte("-----BEGIN AMEM KEY BUNDLE-----")) {
+			t.Errorf("Seal(armor=%v) armored = %v", armor, !armor)
+		}
+		if bytes.Contains(data, []byte(b.Key)) {
+			t.Errorf("Seal(armor=%v) output contains the key in plain text", armor)
+		}
+
+		got, err := Open(data, "correct horse")
+		if err != nil {
+			t.Fatalf("Open(armor=%v) failed: %v", armor, err)
+		}
+		if got.Scope != b.Scope || got.Key != b.Key || string(got.Config) != string(b.Config) {
+			t.Errorf("Open(armor=%v) = %+v, want %+v", armor, got, b)
+		}
+
+		if _, err := Open(data, "wrong"); !errors.Is(err, ErrPassphrase) {
+			t.Errorf("Open(armor=%v) with wrong passphrase error = %v, want ErrPassphrase", armor, err)
+		}
+	}
+}
+
+func TestOpenRejectsTampering(t *testing.T) {
+	iterations = 1000
+
+	data, err := Seal(&Bundle{Scope: "global", Key: "k"}, "pass", false)
+	if err != nil {
+		t.Fatalf("Seal failed: %v", err)
+	}
+
+	// Changing the header (here, the iteration count) must be detected
+	data[len(magic)+3]++
+	if _, err := Open(data, "pass"); err == nil {
+		t.Error("Open of a tampered bundle should fail")
+	}
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	for _, data := range []string{
+		"",
+		`{"db_path":"amem.db"}`,
+		"-----BEGIN CERTIFICATE-----\nAAAA\n-----END CERTIFICATE-----\n",
+	} {
+		if _, err := Open([]byte(data), "pass"); err == nil || errors.Is(err, ErrPassphrase) {
+			t.Errorf("Open(%q) error = %v, want a not-a-bundle error", data, err)
+		}
+	}
+}
+
+func TestSealRequiresPassphrase(t *testing.T) {
+	if _, err := Seal(&Bundle{Key: "k"}, "", false); err == nil {
+		t.Error("Seal with an empty passphrase should fail")
+	}
+}
@@ -0,0 +1,101 @@
+package keyring
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fallbackDir returns the directory holding keys that the OS keychain couldn't store.
+func fallbackDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config directory: %w", err)
+	}
+	return filepath.Join(dir, "amem", "keys"), nil
+}
+
+// accountFileName turns an account into a file name that is valid on every OS.
+// Accounts like "local:C:\Users\me\project" contain characters Windows forbids
+// in file names, so those are replaced, and a hash of the original account keeps
+// accounts that differ only in replaced characters from colliding.
+func accountFileName(account string) string {
+	readable := strings.Map(func(r rune) rune {
+		if r < 32 || strings.ContainsRune(`<>:"/\|?*`, r) {
+			return '_'
+		}
+		return r
+	}, account)
+	readable = strings.Trim(readable, ". _")
+	if len(readable) > 64 {
+		readable = readable[len(readable)-64:]
+	}
+
+	sum := sha256.Sum256([]byte(account))
+	return readable + "-" + hex.EncodeToString(sum[:4]) + ".key"
+}
+
+// fallbackPath returns the file holding account's key when the keychain can't.
+func fallbackPath(account string) (string, error) {
+	dir, err := fallbackDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, accountFileName(account)), nil
+}
+
+// setFallback stores key, protected for the current user, in account's fallback file.
+func setFallback(account, key string) error {
+	path, err := fallbackPath(account)
+	if err != nil {
+		return err
+	}
+
+	protected, err := protect([]byte(key))
+	if err != nil {
+		return fmt.Errorf("failed to protect key: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create key directory: %w", err)
+	}
+	if err := os.WriteFile(path, protected, 0o600); err != nil {
+		return fmt.Errorf("failed to write key file: %w", err)
+	}
+	return nil
+}
+
+// getFallback reads account's key from its fallback file.
+func getFallback(account string) (string, error) {
+	path, err := fallbackPath(account)
+	if err != nil {
+		return "", err
+	}
+
+	protected, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key, err := unprotect(protected)
+	if err != nil {
+		return "", fmt.Errorf("failed to unprotect key: %w", err)
+	}
+	return string(key), nil
+}
+
+// deleteFallback removes account's fallback file, if any.
+func deleteFallback(account string) error {
+	path, err := fallbackPath(account)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove key file: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package keyring
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -10,30 +11,63 @@ import (
 const service = "amem"
 
 // Set stores an encryption key in the OS keychain.
+// On Windows, keys the Credential Manager rejects are stored in a DPAPI-protected file instead.
 // For global profiles, use account = profile_name.
 // For local configs, use account = "local:{absolute_path}".
 func Set(account, key string) error {
-	return keyring.Set(service, account, key)
+	err := keyring.Set(service, account, key)
+	if err == nil || !hasFallback {
+		return err
+	}
+
+	if fallbackErr := setFallback(account, key); fallbackErr != nil {
+		return fmt.Errorf("failed to store key in keychain (%v) or key file: %w", err, fallbackErr)
+	}
+	return nil
 }
 
 // Get retrieves an encryption key from the OS keychain.
-// Falls back to AMEM_ENCRYPTION_KEY env var if keychain fails.
+// Falls back to the protected key file on Windows, then to AMEM_ENCRYPTION_KEY env var if keychain fails.
 // For global profiles, use account = profile_name.
 // For local configs, use account = "local:{absolute_path}".
 func Get(account string) (string, error) {
-	key, err := keyring.Get(service, account)
+	key, _, err := Lookup(account)
+	return key, err
+}
+
+// Lookup is Get, also reporting whether the key came from AMEM_ENCRYPTION_KEY
+// because the keychain didn't have it.
+func Lookup(account string) (key string, fromEnv bool, err error) {
+	key, err = keyring.Get(service, account)
+	if err != nil && hasFallback {
+		if fileKey, fileErr := getFallback(account); fileErr == nil {
+			return fileKey, false, nil
+		}
+	}
 	if err != nil {
 		// Fallback to env var
 		envKey := os.Getenv("AMEM_ENCRYPTION_KEY")
 		if envKey == "" {
-			return "", fmt.Errorf("key not found in keychain and AMEM_ENCRYPTION_KEY not set: %w", err)
+			return "", false, fmt.Errorf("key not found in keychain and AMEM_ENCRYPTION_KEY not set: %w", err)
 		}
-		return envKey, nil
+		return envKey, true, nil
 	}
-	return key, nil
+	return key, false, nil
 }
 
-// Delete removes an encryption key from the OS keychain.
+// Delete removes an encryption key from the OS keychain (and its key file on Windows).
 func Delete(account string) error {
-	return keyring.Delete(service, account)
+	err := keyring.Delete(service, account)
+	if !hasFallback {
+		return err
+	}
+
+	if fileErr := deleteFallback(account); fileErr != nil {
+		return fileErr
+	}
+	// The key may only have been in the file
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil
+	}
+	return err
 }
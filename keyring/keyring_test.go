@@ -112,3 +112,48 @@ func TestDelete(t *testing.T) {
 		t.Error("Get() after Delete() should have failed, but didn't")
 	}
 }
+
+func TestAccountFileName(t *testing.T) {
+	tests := []struct {
+		account  string
+		readable string
+	}{
+		{"default", "default"},
+		{`local:C:\Users\me\project\.amem\config.json`, `local_C__Users_me_project_.amem_config.json`},
+		{"local:/home/me/project/.amem/config.json", "local__home_me_project_.amem_config.json"},
+		{`local:\\server\share\.amem\config.json`, `local___server_share_.amem_config.json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.account, func(t *testing.T) {
+			name := accountFileName(tt.account)
+			if strings.ContainsAny(name, `<>:"/\|?*`) {
+				t.Errorf("accountFileName(%q) = %q, contains a character invalid in Windows file names", tt.account, name)
+			}
+			if !strings.HasPrefix(name, tt.readable+"-") || !strings.HasSuffix(name, ".key") {
+				t.Errorf("accountFileName(%q) = %q, want %q-<hash>.key", tt.account, name, tt.readable)
+			}
+		})
+	}
+}
+
+func TestAccountFileNameDistinct(t *testing.T) {
+	// These only differ in characters that get replaced
+	a := accountFileName(`local:C:\proj`)
+	b := accountFileName(`local:C:/proj`)
+	c := accountFileName(`local_C__proj`)
+	if a == b || a == c || b == c {
+		t.Errorf("accountFileName collided: %q, %q, %q", a, b, c)
+	}
+}
+
+func TestAccountFileNameLong(t *testing.T) {
+	account := "local:C:\\" + strings.Repeat("deep\\", 50) + ".amem\\config.json"
+	name := accountFileName(account)
+	if len(name) > 100 {
+		t.Errorf("accountFileName() length = %d, want a short name", len(name))
+	}
+	if !strings.Contains(name, "config.json") {
+		t.Errorf("accountFileName() = %q, want the end of the path kept", name)
+	}
+}
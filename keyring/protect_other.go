@@ -0,0 +1,18 @@
+//go:build !windows
+
+package keyring
+
+import "errors"
+
+// hasFallback is false where the OS keychain accepts keys of any length.
+const hasFallback = false
+
+var errNoProtection = errors.New("protected key files are only supported on Windows")
+
+func protect(data []byte) ([]byte, error) {
+	return nil, errNoProtection
+}
+
+func unprotect(data []byte) ([]byte, error) {
+	return nil, errNoProtection
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package keyring
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// hasFallback is true where keys the keychain rejects can be kept in a protected file.
+// Windows Credential Manager rejects long keys, and DPAPI ties the file to the user's login.
+const hasFallback = true
+
+// protect encrypts data with DPAPI so only the current Windows user can decrypt it.
+func protect(data []byte) ([]byte, error) {
+	return cryptData(data, true)
+}
+
+// unprotect decrypts data encrypted by protect.
+func unprotect(data []byte) ([]byte, error) {
+	return cryptData(data, false)
+}
+
+func cryptData(data []byte, encrypt bool) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	in := windows.DataBlob{Size: uint32(len(data)), Data: &data[0]}
+	var out windows.DataBlob
+
+	var err error
+	if encrypt {
+		err = windows.CryptProtectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	} else {
+		err = windows.CryptUnprotectData(&in, nil, nil, 0, nil, windows.CRYPTPROTECT_UI_FORBIDDEN, &out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _, _ = windows.LocalFree(windows.Handle(unsafe.Pointer(out.Data))) }()
+
+	result := make([]byte, out.Size)
+	copy(result, unsafe.Slice(out.Data, out.Size))
+	return result, nil
+}
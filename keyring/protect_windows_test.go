@@ -0,0 +1,34 @@
+//go:build windows
+
+package keyring
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFallbackRoundTrip(t *testing.T) {
+	t.Setenv("APPDATA", t.TempDir())
+
+	account := `local:C:\Users\me\project\.amem\config.json`
+	key := strings.Repeat("k", 4096) // too long for Credential Manager
+
+	if err := setFallback(account, key); err != nil {
+		t.Fatalf("setFallback failed: %v", err)
+	}
+
+	got, err := getFallback(account)
+	if err != nil {
+		t.Fatalf("getFallback failed: %v", err)
+	}
+	if got != key {
+		t.Errorf("getFallback() returned a different key")
+	}
+
+	if err := deleteFallback(account); err != nil {
+		t.Fatalf("deleteFallback failed: %v", err)
+	}
+	if _, err := getFallback(account); err == nil {
+		t.Error("getFallback() after deleteFallback() should have failed")
+	}
+}
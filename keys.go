@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"amem/config"
+	"amem/db"
+	"amem/keybundle"
+	"amem/keyring"
+	"github.com/urfave/cli/v3"
+)
+
+// keysCommand builds the 'keys' command, which manages the independent keys that can unlock a database
+func keysCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "keys",
+		Aliases: []string{"key"},
+		Usage:   "Manage key slots and move keys between machines",
+		Commands: []*cli.Command{
+			{
+				Name:  "list",
+				Usage: "List the database's key slots",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return withDB(func(database *db.DB) error {
+						slots, err := database.KeySlots()
+						if err != nil {
+							return err
+						}
+						if slots == nil {
+							fmt.Println("No key slots (the database is unlocked by a single key)")
+							return nil
+						}
+						for _, name := range slots {
+							if name == database.KeySlot() {
+								fmt.Printf("%s (current)\n", name)
+							} else {
+								fmt.Println(name)
+							}
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "add",
+				Usage:     "Add a key slot that unlocks the database with a new key",
+				ArgsUsage: "<name>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "new-key",
+						Usage: "Key for the new slot (prompted for if not given)",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("a key slot name is required")
+					}
+
+					newKey := cmd.String("new-key")
+					if newKey == "" {
+						var err error
+						newKey, err = securePromptWithConfirmation("Key for " + name)
+						if err != nil {
+							return fmt.Errorf("failed to read key: %w", err)
+						}
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						enabling := database.KeySlot() == ""
+						if err := database.AddKeySlot(name, newKey); err != nil {
+							return fmt.Errorf("failed to add key slot: %w", err)
+						}
+						if enabling {
+							fmt.Printf("✓ Enabled key slots; your current key is slot %q\n", db.DefaultKeySlot)
+							fmt.Printf("Keep %s with the database; it can't be opened without it.\n", db.KeySlotsPath(database.Path()))
+						}
+						fmt.Printf("✓ Added key slot %q\n", name)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "remove",
+				Usage:     "Revoke a key slot's key",
+				ArgsUsage: "<name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("a key slot name is required")
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						if err := database.RemoveKeySlot(name); err != nil {
+							return fmt.Errorf("failed to remove key slot: %w", err)
+						}
+						fmt.Printf("✓ Removed key slot %q\n", name)
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "export",
+				Usage:     "Write the config and its encryption key to a passphrase-protected bundle",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "armor",
+						Usage: "Write the bundle as ASCII text instead of binary",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path := cmd.Args().First()
+					if path == "" {
+						return fmt.Errorf("an output file is required")
+					}
+
+					scope, configPath, err := currentConfigPath()
+					if err != nil {
+						return err
+					}
+					raw, err := os.ReadFile(configPath)
+					if err != nil {
+						return fmt.Errorf("failed to read config: %w", err)
+					}
+					cfg, err := loadConfig()
+					if err != nil {
+						return err
+					}
+
+					passphrase, err := securePromptWithConfirmation("Bundle passphrase")
+					if err != nil {
+						return fmt.Errorf("failed to read passphrase: %w", err)
+					}
+
+					data, err := keybundle.Seal(&keybundle.Bundle{
+						Scope:  scope,
+						Config: raw,
+						Key:    cfg.EncryptionKey,
+					}, passphrase, cmd.Bool("armor"))
+					if err != nil {
+						return err
+					}
+					if err := os.WriteFile(path, data, 0o600); err != nil {
+						return fmt.Errorf("failed to write bundle: %w", err)
+					}
+
+					fmt.Printf("✓ Exported %s config and key to %s\n", scope, path)
+					fmt.Printf("Copy the database (%s) separately; the bundle only holds what's needed to open it.\n", cfg.DBPath)
+					return nil
+				},
+			},
+			{
+				Name:      "import",
+				Usage:     "Install the config and encryption key from a bundle made by 'keys export'",
+				ArgsUsage: "<file>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "db-path",
+						Usage: "Use this database path instead of the one in the bundle",
+					},
+					&cli.BoolFlag{
+						Name:  "force",
+						Usage: "Overwrite an existing config",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					path := cmd.Args().First()
+					if path == "" {
+						return fmt.Errorf("a bundle file is required")
+					}
+					data, err := os.ReadFile(path)
+					if err != nil {
+						return fmt.Errorf("failed to read bundle: %w", err)
+					}
+
+					passphrase, err := securePrompt("Bundle passphrase")
+					if err != nil {
+						return fmt.Errorf("failed to read passphrase: %w", err)
+					}
+					bundle, err := keybundle.Open(data, passphrase)
+					if err != nil {
+						return fmt.Errorf("failed to open bundle: %w", err)
+					}
+
+					var cfg config.Config
+					if err := json.Unmarshal(bundle.Config, &cfg); err != nil {
+						return fmt.Errorf("invalid config in bundle: %w", err)
+					}
+					if dbPath := cmd.String("db-path"); dbPath != "" {
+						absDBPath, err := filepath.Abs(dbPath)
+						if err != nil {
+							return fmt.Errorf("failed to resolve absolute path: %w", err)
+						}
+						cfg.DBPath = absDBPath
+					}
+
+					// Install where 'amem init' would for the bundle's scope
+					cwd, err := os.Getwd()
+					if err != nil {
+						return fmt.Errorf("failed to get current directory: %w", err)
+					}
+					var configPath, keyringAccount string
+					switch bundle.Scope {
+					case "local":
+						configPath = config.LocalPath(cwd)
+						keyringAccount = "local:" + cwd
+						if filepath.IsAbs(cfg.DBPath) {
+							cfg.DBPath = config.RelativeDBPath(filepath.Dir(configPath), cfg.DBPath)
+						}
+					case "global":
+						configPath, err = config.GlobalPath()
+						if err != nil {
+							return fmt.Errorf("failed to get global config path: %w", err)
+						}
+						keyringAccount = "global"
+					default:
+						return fmt.Errorf("unknown config scope %q in bundle", bundle.Scope)
+					}
+
+					if _, err := os.Stat(configPath); err == nil && !cmd.Bool("force") {
+						return fmt.Errorf("config already exists at %s (use --force to overwrite)", configPath)
+					}
+
+					if err := keyring.Set(keyringAccount, bundle.Key); err != nil {
+						return fmt.Errorf("failed to store encryption key in keyring: %w", err)
+					}
+					if err := config.Write(configPath, &cfg); err != nil {
+						return fmt.Errorf("failed to write config: %w", err)
+					}
+
+					fmt.Printf("✓ Imported %s config to %s\n", bundle.Scope, configPath)
+					fmt.Printf("✓ Encryption key saved to keyring\n")
+
+					dbPath, err := config.ExpandPath(cfg.DBPath)
+					if err == nil && bundle.Scope == "local" {
+						dbPath = config.ResolveDBPath(filepath.Dir(configPath), dbPath)
+					}
+					if _, statErr := os.Stat(dbPath); err != nil || statErr != nil {
+						fmt.Fprintf(os.Stderr, "Warning: no database at %s yet; copy it there or use --db-path\n", dbPath)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// currentConfigPath returns the scope ("local" or "global") and path of the config amem would use here
+func currentConfigPath() (string, string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get current directory: %w", err)
+	}
+	if localPath, err := config.FindLocal(cwd); err == nil {
+		return "local", localPath, nil
+	}
+
+	globalPath, err := config.GlobalPath()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get global config path: %w", err)
+	}
+	if _, err := os.Stat(globalPath); err != nil {
+		return "", "", fmt.Errorf("no config found: run 'amem init' to create one")
+	}
+	return "global", globalPath, nil
+}
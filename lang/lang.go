@@ -0,0 +1,60 @@
+// Package lang guesses which language a text is written in from the common words in it,
+// so observations can be tagged without the writer naming their language every time.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords are each language's most common short words, keyed by ISO 639-1 code.
+// Words shared between languages count for all of them.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "that", "it", "with", "for", "on", "this", "be", "have", "has", "not", "but", "they", "he", "she", "you", "at", "from", "by", "an", "will", "would"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "sich", "auf", "für", "den", "dem", "von", "zu", "auch", "es", "ich", "sie", "wir", "aber", "wird", "sind", "bei", "noch", "nach"},
+	"fr": {"le", "la", "les", "et", "est", "une", "des", "du", "pas", "que", "qui", "dans", "pour", "avec", "sur", "ce", "il", "elle", "nous", "vous", "sont", "mais", "au", "aux", "ne", "se", "très"},
+	"es": {"el", "la", "los", "las", "y", "es", "una", "del", "que", "en", "por", "con", "para", "no", "se", "su", "lo", "muy", "pero", "está", "son", "como", "más", "al"},
+	"it": {"il", "lo", "gli", "e", "è", "una", "della", "che", "di", "per", "con", "non", "sono", "del", "ma", "anche", "molto", "nel", "alla", "questo"},
+	"nl": {"de", "het", "een", "en", "is", "van", "niet", "met", "op", "voor", "dat", "zijn", "ook", "maar", "te", "bij", "wordt", "naar", "heeft", "ik", "wij"},
+	"pt": {"o", "os", "as", "e", "é", "uma", "do", "da", "dos", "das", "que", "não", "com", "para", "em", "por", "mas", "muito", "são", "está", "também"},
+}
+
+// languages maps each stopword to the languages it belongs to.
+var languages = map[string][]string{}
+
+func init() {
+	for code, words := range stopwords {
+		for _, w := range words {
+			languages[w] = append(languages[w], code)
+		}
+	}
+}
+
+// minMatches is how many common words a text needs before its language is guessed.
+const minMatches = 2
+
+// Detect returns the ISO 639-1 code of the language text is most likely written in, or ""
+// if it can't tell: the text is too short, or as close to one language as another.
+func Detect(text string) string {
+	counts := map[string]int{}
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !unicode.IsLetter(r) })
+	for _, w := range words {
+		for _, code := range languages[w] {
+			counts[code]++
+		}
+	}
+
+	best, bestCount, runnerUp := "", 0, 0
+	for code, n := range counts {
+		switch {
+		case n > bestCount:
+			best, bestCount, runnerUp = code, n, bestCount
+		case n > runnerUp:
+			runnerUp = n
+		}
+	}
+	if bestCount < minMatches || bestCount == runnerUp {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,26 @@
+package lang
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"She moved to Berlin in March and is looking for a flat", "en"},
+		{"Sie ist im März nach Berlin gezogen und sucht eine Wohnung", "de"},
+		{"Elle a déménagé à Paris et elle cherche un appartement avec vue", "fr"},
+		{"Se mudó a Madrid en marzo y busca un piso con terraza", "es"},
+		{"Si è trasferita a Roma e cerca una casa con giardino per il cane", "it"},
+		{"Ze is in maart naar Utrecht verhuisd en zoekt een huis met tuin", "nl"},
+		{"Ela mudou-se para Lisboa em março e procura uma casa com jardim", "pt"},
+		// Too few common words to tell
+		{"Likes green tea", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Detect(tt.text); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -3,14 +3,22 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"iter"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"amem/config"
 	"amem/db"
+	"amem/gitrepo"
+	"amem/keyagent"
 	"amem/keyring"
+	"amem/tools"
 	"amem/view"
 	"github.com/urfave/cli/v3"
 	"golang.org/x/term"
@@ -18,6 +26,13 @@ import (
 
 var version = "dev"
 
+// exitWarnings is the exit code for 'check --strict' finding warnings, distinct from
+// the 1 used for failures so scripts can tell them apart.
+const exitWarnings = 2
+
+// errWarnings is returned by 'check --strict' when it finds warnings
+var errWarnings = errors.New("check found warnings")
+
 // stdinReader is a global buffered reader for stdin, reused across calls to avoid buffering issues
 var stdinReader *bufio.Reader
 
@@ -26,24 +41,117 @@ func resetStdinReader() {
 	stdinReader = nil
 }
 
-// agentDocsContent is the documentation shown by 'amem agent-docs'
-const agentDocsContent = `<memory>
-	- If the user instructs you to use your memory, use the 'amem' utility.
-	- Run 'amem help' to see the available commands.
-	- Use 'amem search' to find relevant memories based on the conversation and user's request.
-	- As the conversation progresses, use 'amem add' to add new memories.
-	- Be judicious with the memories you add, making sure each is likely to have long-term value.
-	- Prefer proper relationships over relational observations.
-</memory>`
+// dbOptions holds database options from global flags, set before any command runs
+var dbOptions = db.DefaultOptions()
+
+// dbOverride and dbKey are the database path and key from --db and --key, if given
+var dbOverride, dbKey string
+
+// keyOverride is the encryption key from --encryption-key or --key-stdin, if given
+var keyOverride string
+
+// loadConfig loads config, using keyOverride instead of the keychain when it's set
+func loadConfig() (*config.LoadedConfig, error) {
+	if dbOverride != "" {
+		return adHocConfig()
+	}
+	if keyOverride != "" {
+		return config.LoadWithKey(keyOverride)
+	}
+	return config.Load()
+}
+
+// adHocConfig is the config for a database given with --db, which skips the config files:
+// there are no settings, and the key comes from --key, --encryption-key, --key-stdin, or AMEM_ENCRYPTION_KEY.
+func adHocConfig() (*config.LoadedConfig, error) {
+	if _, err := os.Stat(dbOverride); err != nil {
+		return nil, fmt.Errorf("failed to open --db: %w", err)
+	}
+
+	key, fromEnv := dbKey, false
+	if key == "" {
+		key = keyOverride
+	}
+	if key == "" {
+		key, fromEnv = os.Getenv("AMEM_ENCRYPTION_KEY"), true
+	}
+	if key == "" {
+		return nil, fmt.Errorf("--db needs a key: use --key or set AMEM_ENCRYPTION_KEY")
+	}
+
+	return &config.LoadedConfig{
+		Config:        config.Config{DBPath: dbOverride},
+		EncryptionKey: key,
+		KeyFromEnv:    fromEnv,
+	}, nil
+}
+
+// readKeyStdin reads an encryption key from the first line of stdin
+func readKeyStdin() (string, error) {
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+	line, err := stdinReader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read key from stdin: %w", err)
+	}
+	key := strings.TrimRight(line, "\r\n")
+	if key == "" {
+		return "", fmt.Errorf("no key provided on stdin")
+	}
+	return key, nil
+}
 
 // withDB loads config, opens database, executes fn, and handles cleanup
 func withDB(fn func(*db.DB) error) error {
-	cfg, err := config.Load()
+	return withConfigDB(func(_ *config.LoadedConfig, database *db.DB) error {
+		return fn(database)
+	})
+}
+
+// withWriteDB is withDB for commands that modify the database.
+// It holds the database's write lock while fn runs so concurrent amem processes can't interleave writes.
+func withWriteDB(fn func(*db.DB) error) error {
+	return withDB(func(database *db.DB) error {
+		return database.Locked(func() error {
+			return fn(database)
+		})
+	})
+}
+
+// withSearchDB is withDB for search commands, explaining each query to stderr when --explain is set,
+// including archived observations when --include-archived is, requiring the --require keywords,
+// and keeping to observations in the --lang language
+func withSearchDB(cmd *cli.Command, fn func(*db.DB) error) error {
+	return withDB(func(database *db.DB) error {
+		if cmd.Bool("explain") {
+			database.SetExplain(os.Stderr)
+		}
+		database.SetIncludeArchived(cmd.Bool("include-archived"))
+		database.SetRequired(cmd.StringSlice("require"))
+		if err := database.SetLang(cmd.String("lang")); err != nil {
+			return err
+		}
+		return fn(database)
+	})
+}
+
+// openOptions returns dbOptions with the settings cfg adds
+func openOptions(cfg *config.Config) db.Options {
+	opts := dbOptions
+	opts.SkipMigrationBackup = cfg.SkipMigrationBackup
+	opts.CheckIntegrity = opts.CheckIntegrity || cfg.CheckIntegrity
+	return opts
+}
+
+// withConfigDB is withDB for commands that also need the loaded config
+func withConfigDB(fn func(*config.LoadedConfig, *db.DB) error) error {
+	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	database, err := db.Open(cfg.DBPath, cfg.EncryptionKey)
+	database, err := db.OpenWithOptions(cfg.DBPath, cfg.EncryptionKey, openOptions(&cfg.Config))
 	if err != nil {
 		return err
 	}
@@ -52,8 +160,197 @@ func withDB(fn func(*db.DB) error) error {
 			fmt.Fprintf(os.Stderr, "Warning: failed to close database: %v\n", err)
 		}
 	}()
+	if snap := database.MigrationBackup(); snap != nil {
+		fmt.Fprintf(os.Stderr, "Backed up the database to %s before upgrading its schema\n", snap.Path)
+	}
+
+	if err := database.SetQuota(cfg.Quota); err != nil {
+		return err
+	}
+	if err := database.SetRules(cfg.Rules); err != nil {
+		return err
+	}
+	database.SetRecordRetrievals(cfg.RecordRetrievals)
+
+	return fn(cfg, database)
+}
+
+// dbFlags are the --db and --key flags of add, search, and delete, for scripting against
+// a database amem isn't configured for, like a test fixture or a restored backup
+func dbFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.StringFlag{
+			Name:  "db",
+			Usage: "Use the database at this path instead of the configured one",
+		},
+		&cli.StringFlag{
+			Name:  "key",
+			Usage: "The key for the --db database (default: AMEM_ENCRYPTION_KEY)",
+		},
+	}
+}
+
+// useDBFlags is the Before of the commands with dbFlags, applying them
+func useDBFlags(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+	dbOverride, dbKey = cmd.String("db"), cmd.String("key")
+	return ctx, nil
+}
+
+// timestampFlag is the --timestamp flag shared by the add commands, for backfilling
+// records with when they really happened
+func timestampFlag(what string) cli.Flag {
+	return &cli.StringFlag{
+		Name:  "timestamp",
+		Usage: fmt.Sprintf("When the %s was made instead of now, as a duration ago (e.g. 3h) or local date/time (e.g. 2025-01-31 14:00)", what),
+	}
+}
+
+// addTimestamp parses the --timestamp flag, returning the zero time for now if it's not set
+func addTimestamp(cmd *cli.Command) (time.Time, error) {
+	if !cmd.IsSet("timestamp") {
+		return time.Time{}, nil
+	}
+	now := time.Now()
+	t, err := parseTimeFlag("timestamp", cmd.String("timestamp"), now)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t.After(now) {
+		return time.Time{}, fmt.Errorf("--timestamp cannot be in the future")
+	}
+	return t, nil
+}
+
+// truncateFlag is the --truncate flag of the add commands
+func truncateFlag() cli.Flag {
+	return &cli.BoolFlag{
+		Name:  "truncate",
+		Usage: "Cut text longer than the config's length rules to fit instead of failing",
+	}
+}
+
+// truncated returns cut, noting on stderr when it's shorter than the original text
+func truncated(what, text, cut string) string {
+	if cut != text {
+		fmt.Fprintf(os.Stderr, "Truncated %s to %d characters\n", what, utf8.RuneCountInString(cut))
+	}
+	return cut
+}
+
+// outputFlags are the --max-width, --truncate, and --porcelain flags of commands that list results
+func outputFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.IntFlag{
+			Name:  "max-width",
+			Usage: "Wrap result lines longer than this many characters (default: the terminal's width, or no limit when not printing to one)",
+		},
+		&cli.BoolFlag{
+			Name:  "truncate",
+			Usage: "Cut result lines longer than --max-width short instead of wrapping them",
+		},
+		&cli.BoolFlag{
+			Name:  "porcelain",
+			Usage: "Print one tab-separated line per result with no headers, in a format that won't change between versions, for scripts",
+		},
+	}
+}
+
+// useOutputFlags applies the outputFlags of cmd to the view
+func useOutputFlags(cmd *cli.Command) {
+	width := cmd.Int("max-width")
+	if !cmd.IsSet("max-width") {
+		// Not a terminal, like a pipe or a file, gets whole lines
+		if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil {
+			width = w
+		}
+	}
+	view.SetMaxWidth(width, cmd.Bool("truncate"))
+	view.SetPorcelain(cmd.Bool("porcelain"))
+}
+
+// checkWarnings returns problems with a working setup that 'check' should point out:
+// a database git would commit, and a key taken from the environment instead of the keychain.
+func checkWarnings(cfg *config.LoadedConfig) []string {
+	var warnings []string
+
+	if root, err := gitrepo.FindRoot(filepath.Dir(cfg.DBPath)); err == nil {
+		ignored, err := gitrepo.IsIgnored(root, cfg.DBPath)
+		switch {
+		case err != nil:
+			warnings = append(warnings, fmt.Sprintf("Could not check whether git ignores the database: %v", err))
+		case !ignored:
+			warnings = append(warnings, fmt.Sprintf("Database is inside the git repository at %s and is not ignored", root))
+		}
+	}
+
+	if cfg.KeyFromEnv {
+		warnings = append(warnings, "Encryption key came from AMEM_ENCRYPTION_KEY because the keychain doesn't have it")
+	}
+
+	return warnings
+}
+
+// printRepairReport prints what 'check --repair' fixed
+func printRepairReport(report *db.RepairReport) {
+	if report.MigrationsRerun {
+		fmt.Printf("✓ Rebuilt schema_migrations and migrated to version %d\n", report.SchemaVersion)
+	} else {
+		fmt.Printf("✓ Schema version %d\n", report.SchemaVersion)
+	}
+	if report.ForeignKeysEnabled {
+		fmt.Printf("✓ Re-enabled foreign keys\n")
+	}
+	for _, name := range report.RecreatedIndexes {
+		fmt.Printf("✓ Recreated index %s\n", name)
+	}
+	if report.OrphanedObservations > 0 {
+		fmt.Printf("✓ Removed %d orphaned observations\n", report.OrphanedObservations)
+	}
+	if report.OrphanedRelationships > 0 {
+		fmt.Printf("✓ Removed %d orphaned relationships\n", report.OrphanedRelationships)
+	}
+}
 
-	return fn(database)
+// printCascade prints what deleting an entity removes along with it
+func printCascade(c *db.Cascade) {
+	fmt.Printf("Entity '%s' [%d] cascades to %d observations and %d relationships\n",
+		c.Entity.Text, c.Entity.ID, len(c.Observations), len(c.Relationships))
+	for _, o := range c.Observations {
+		fmt.Printf("  %s\n", o.Format(true))
+	}
+	for _, r := range c.Relationships {
+		fmt.Printf("  %s\n", r.Format(true))
+	}
+}
+
+// recordIDs passes observations through from seq, appending each one's ID to ids
+func recordIDs(seq iter.Seq2[db.Observation, error], ids *[]int64) iter.Seq2[db.Observation, error] {
+	return func(yield func(db.Observation, error) bool) {
+		for o, err := range seq {
+			if err == nil {
+				*ids = append(*ids, o.ID)
+			}
+			if !yield(o, err) {
+				return
+			}
+		}
+	}
+}
+
+// markAccessed records that context was shown, for the least-accessed quota policy and
+// retrieval stats. Recent memories shown without keywords weren't searched for, so they
+// aren't logged as a retrieval.
+func markAccessed(database *db.DB, keywords []string, observations []db.Observation) error {
+	ids := make([]int64, len(observations))
+	for i, o := range observations {
+		ids[i] = o.ID
+	}
+	return database.Locked(func() error {
+		if len(keywords) == 0 {
+			return database.MarkObservationsAccessed(ids)
+		}
+		return database.Retrieved("", keywords, ids)
+	})
 }
 
 func prompt(message string, defaultValue string) (string, error) {
@@ -138,6 +435,59 @@ func buildCommand() *cli.Command {
 	return &cli.Command{
 		Name:  "amem",
 		Usage: "A command-line tool that gives an LLM agent memory",
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:  "lock-timeout",
+				Usage: "How long to wait for a database locked by another process (e.g. 500ms, 30s)",
+				Value: db.DefaultLockTimeout,
+			},
+			&cli.StringFlag{
+				Name:  "encryption-key",
+				Usage: "Open the database with this key instead of looking it up in the keychain",
+			},
+			&cli.BoolFlag{
+				Name:  "key-stdin",
+				Usage: "Read the encryption key from the first line of stdin instead of the keychain",
+			},
+			&cli.BoolFlag{
+				Name:  "check-integrity",
+				Usage: "Verify every page of the database when opening it, failing if any are damaged",
+			},
+			&cli.BoolFlag{
+				Name:  "utc",
+				Usage: "Show timestamps in UTC instead of the local time zone",
+			},
+			&cli.BoolFlag{
+				Name:  "relative-time",
+				Usage: "Show recent timestamps as how long ago they were, like \"2h ago\"",
+			},
+		},
+		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+			timeout := cmd.Duration("lock-timeout")
+			if timeout < 0 {
+				return ctx, fmt.Errorf("--lock-timeout cannot be negative")
+			}
+			dbOptions = db.DefaultOptions()
+			dbOptions.LockTimeout = timeout
+			dbOptions.CheckIntegrity = cmd.Bool("check-integrity")
+			dbOverride, dbKey = "", ""
+			view.SetMaxWidth(0, false)
+			view.SetPorcelain(false)
+			view.SetTimes(cmd.Bool("utc"), cmd.Bool("relative-time"))
+
+			keyOverride = cmd.String("encryption-key")
+			if cmd.Bool("key-stdin") {
+				if keyOverride != "" {
+					return ctx, fmt.Errorf("use only one of --encryption-key and --key-stdin")
+				}
+				key, err := readKeyStdin()
+				if err != nil {
+					return ctx, err
+				}
+				keyOverride = key
+			}
+			return ctx, nil
+		},
 		Commands: []*cli.Command{
 			{
 				Name:  "help",
@@ -149,38 +499,84 @@ func buildCommand() *cli.Command {
 			{
 				Name:  "agent-docs",
 				Usage: "Show documentation to put in, e.g., AGENTS.md",
-				Action: func(ctx context.Context, cmd *cli.Command) error {
-					fmt.Print(agentDocsContent)
-					return nil
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "target",
+						Usage: "Tool to tailor the docs for (agents-md, claude, cursor, copilot)",
+						Value: "agents-md",
+					},
+					&cli.BoolFlag{
+						Name:  "install",
+						Usage: "Insert or update the docs in the repository's AGENTS.md or CLAUDE.md",
+					},
 				},
-			},
-			{
-				Name:  "version",
-				Usage: "Display the version",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					fmt.Println(version)
+					if cmd.Bool("install") {
+						cwd, err := os.Getwd()
+						if err != nil {
+							return fmt.Errorf("failed to get current directory: %w", err)
+						}
+						path, change, err := installAgentDocs(cwd, cmd.String("target"))
+						if err != nil {
+							return err
+						}
+						fmt.Printf("%s: %s\n", path, change)
+						return nil
+					}
+
+					content, err := agentDocs(cmd.String("target"))
+					if err != nil {
+						return err
+					}
+					fmt.Print(content)
 					return nil
 				},
 			},
+			versionCommand(),
 			{
 				Name:  "init",
 				Usage: "Start or use a memory database",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "local",
+						Usage: "Create a local config for this project without asking, at the git repository root if there is one",
+					},
+					&cli.BoolFlag{
+						Name:  "global",
+						Usage: "Create the global config without asking",
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
-					// Prompt for config scope
-					configScope, err := prompt("Global or local config?", "local")
-					if err != nil {
-						return fmt.Errorf("failed to read config scope: %w", err)
+					var useLocal bool
+					switch {
+					case cmd.Bool("local") && cmd.Bool("global"):
+						return fmt.Errorf("cannot specify both --local and --global")
+					case cmd.Bool("local"), cmd.Bool("global"):
+						useLocal = cmd.Bool("local")
+					default:
+						// Prompt for config scope
+						configScope, err := prompt("Global or local config?", "local")
+						if err != nil {
+							return fmt.Errorf("failed to read config scope: %w", err)
+						}
+						useLocal = configScope == "local"
 					}
-					useLocal := configScope == "local"
 
-					// Prompt for database path
-					var defaultPath string
+					// A local config belongs to the whole project, so it goes at the root of
+					// the git repository rather than whichever subdirectory init runs in
+					var projectDir string
 					if useLocal {
 						cwd, err := os.Getwd()
 						if err != nil {
 							return fmt.Errorf("failed to get current directory: %w", err)
 						}
-						defaultPath = filepath.Join(cwd, "amem.db")
+						projectDir = config.ProjectDir(cwd)
+					}
+
+					// Prompt for database path
+					var defaultPath string
+					if useLocal {
+						defaultPath = filepath.Join(projectDir, "amem.db")
 					} else {
 						homeDir, err := os.UserHomeDir()
 						if err != nil {
@@ -193,22 +589,21 @@ func buildCommand() *cli.Command {
 						return fmt.Errorf("failed to read db-path: %w", err)
 					}
 
-					// Prompt for encryption key
-					encryptionKey, err := securePromptWithConfirmation("Encryption key")
-					if err != nil {
-						return fmt.Errorf("failed to read encryption-key: %w", err)
+					// Prompt for encryption key unless one was given with --encryption-key or --key-stdin
+					encryptionKey := keyOverride
+					if encryptionKey == "" {
+						encryptionKey, err = securePromptWithConfirmation("Encryption key")
+						if err != nil {
+							return fmt.Errorf("failed to read encryption-key: %w", err)
+						}
 					}
 
 					// Determine config path
 					var configPath string
 					var keyringAccount string
 					if useLocal {
-						cwd, err := os.Getwd()
-						if err != nil {
-							return fmt.Errorf("failed to get current directory: %w", err)
-						}
-						configPath = config.LocalPath(cwd)
-						keyringAccount = "local:" + cwd
+						configPath = config.LocalPath(projectDir)
+						keyringAccount = "local:" + projectDir
 					} else {
 						var err error
 						configPath, err = config.GlobalPath()
@@ -246,9 +641,9 @@ func buildCommand() *cli.Command {
 					}
 
 					// Show summary and confirm
-					configType := "global"
+					configType := config.ScopeGlobal
 					if useLocal {
-						configType = "local"
+						configType = config.ScopeLocal
 					}
 					fmt.Printf("\nSummary:\n")
 					fmt.Printf("  Config type: %s\n", configType)
@@ -276,10 +671,14 @@ func buildCommand() *cli.Command {
 						}
 					}()
 
-					// Save config
+					// Save config. Local configs store the path relative to .amem when the
+					// database is inside the project, so the project can be checked out anywhere.
 					cfg := &config.Config{
 						DBPath: absDBPath,
 					}
+					if useLocal {
+						cfg.DBPath = config.RelativeDBPath(filepath.Dir(configPath), absDBPath)
+					}
 					if err := config.Write(configPath, cfg); err != nil {
 						return fmt.Errorf("failed to write config: %w", err)
 					}
@@ -289,9 +688,19 @@ func buildCommand() *cli.Command {
 						return fmt.Errorf("failed to save encryption key: %w", err)
 					}
 
+					// Record the database so 'amem dbs list' and 'amem search --everywhere' can find it from anywhere
+					if err := config.Register(config.RegisteredDB{ConfigPath: configPath, DBPath: absDBPath, Scope: configType, Created: time.Now().UTC()}); err != nil {
+						fmt.Fprintf(os.Stderr, "Warning: failed to register database: %v\n", err)
+					}
+
 					fmt.Printf("Database initialized at %s\n", absDBPath)
 					fmt.Printf("Config saved to %s\n", configPath)
-					return nil
+
+					amemDir := ""
+					if useLocal {
+						amemDir = filepath.Dir(configPath)
+					}
+					return offerGitignore(absDBPath, amemDir)
 				},
 			},
 			{
@@ -308,7 +717,7 @@ func buildCommand() *cli.Command {
 					newKey := cmd.String("new-key")
 
 					// Load config to get current key
-					cfg, err := config.Load()
+					cfg, err := loadConfig()
 					if err != nil {
 						return fmt.Errorf("failed to load config: %w", err)
 					}
@@ -340,7 +749,7 @@ func buildCommand() *cli.Command {
 
 					// Prompt for confirmation
 					fmt.Println("WARNING: This will re-encrypt the entire database with a new key.")
-					fmt.Println("Make sure you have a backup before proceeding.")
+					fmt.Println("A backup that opens with the current key is saved first.")
 					confirmation, err := prompt("Continue? Type 'yes' to confirm", "")
 					if err != nil {
 						return fmt.Errorf("failed to read confirmation: %w", err)
@@ -351,7 +760,7 @@ func buildCommand() *cli.Command {
 					}
 
 					// Open database with current key
-					database, err := db.Open(cfg.DBPath, cfg.EncryptionKey)
+					database, err := db.OpenWithOptions(cfg.DBPath, cfg.EncryptionKey, openOptions(&cfg.Config))
 					if err != nil {
 						return fmt.Errorf("failed to open database with current key: %w", err)
 					}
@@ -361,11 +770,21 @@ func buildCommand() *cli.Command {
 						}
 					}()
 
+					// If the new key is lost, the backup still opens with the current one
+					backupPath := fmt.Sprintf("%s.%s.pre-rekey", cfg.DBPath, time.Now().UTC().Format("20060102T150405Z"))
+					if err := database.BackupTo(backupPath); err != nil {
+						return err
+					}
+					fmt.Printf("✓ Backed up to %s (it opens with the current key)\n", backupPath)
+
 					// Rekey the database
-					if err := database.Rekey(newKey); err != nil {
+					if err := database.Locked(func() error { return database.Rekey(newKey) }); err != nil {
 						return fmt.Errorf("failed to rekey database: %w", err)
 					}
 
+					// A running agent would keep handing out the old key
+					_ = keyagent.Clear()
+
 					// Update keyring with new key
 					if err := keyring.Set(keyringAccount, newKey); err != nil {
 						// Database is already rekeyed, so we can't fail here
@@ -397,7 +816,19 @@ func buildCommand() *cli.Command {
 			{
 				Name:  "check",
 				Usage: "Check the status of the database and its encryption",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "repair",
+						Usage: "Repair the schema, indexes, and orphaned rows",
+					},
+					&cli.BoolFlag{
+						Name:  "strict",
+						Usage: fmt.Sprintf("Exit with status %d if there are warnings (failures exit with 1)", exitWarnings),
+					},
+				},
 				Action: func(ctx context.Context, cmd *cli.Command) error {
+					repair := cmd.Bool("repair")
+
 					// Discover config
 					cwd, err := os.Getwd()
 					if err != nil {
@@ -424,7 +855,7 @@ func buildCommand() *cli.Command {
 					}
 
 					// Load config
-					cfg, err = config.Load()
+					cfg, err = loadConfig()
 					if err != nil {
 						return fmt.Errorf("failed to load config: %w", err)
 					}
@@ -441,8 +872,11 @@ func buildCommand() *cli.Command {
 					}
 					fmt.Printf("✓ Database file exists\n")
 
-					// Try to open database (validates encryption key)
-					database, err := db.Open(cfg.DBPath, cfg.EncryptionKey)
+					// Try to open database (validates encryption key).
+					// When repairing, migrations are left to Repair, since a damaged schema can make them fail.
+					opts := openOptions(&cfg.Config)
+					opts.SkipMigrations = repair
+					database, err := db.OpenWithOptions(cfg.DBPath, cfg.EncryptionKey, opts)
 					if err != nil {
 						return fmt.Errorf("✗ Failed to open database: %w", err)
 					}
@@ -454,6 +888,19 @@ func buildCommand() *cli.Command {
 
 					fmt.Printf("✓ Encryption key valid\n")
 
+					if repair {
+						var report *db.RepairReport
+						err := database.Locked(func() error {
+							var err error
+							report, err = database.Repair()
+							return err
+						})
+						if err != nil {
+							return fmt.Errorf("✗ Repair failed: %w", err)
+						}
+						printRepairReport(report)
+					}
+
 					// Get counts
 					entityCount, err := database.CountEntities()
 					if err != nil {
@@ -475,32 +922,318 @@ func buildCommand() *cli.Command {
 					fmt.Printf("  Observations: %d\n", obsCount)
 					fmt.Printf("  Relationships: %d\n", relCount)
 
+					warnings := checkWarnings(cfg)
+					if len(warnings) > 0 {
+						fmt.Printf("\nWarnings:\n")
+						for _, warning := range warnings {
+							fmt.Printf("  ⚠ %s\n", warning)
+						}
+						if cmd.Bool("strict") {
+							return fmt.Errorf("%w: %d", errWarnings, len(warnings))
+						}
+					}
+
 					return nil
 				},
 			},
 			{
-				Name:  "add",
-				Usage: "Add entities, observations, or relationships",
+				Name:  "enforce-quota",
+				Usage: "Evict observations until the database is within its configured quota",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return withWriteDB(func(database *db.DB) error {
+						if database.Quota() == nil {
+							return fmt.Errorf("no quota configured (set \"quota\" in the config file)")
+						}
+						evicted, err := database.EnforceQuota()
+						if err != nil {
+							return fmt.Errorf("evicted %d observations: %w", evicted, err)
+						}
+						fmt.Printf("Evicted %d observations\n", evicted)
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "index",
+				Usage: "Build optional search indexes for large databases",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "trigram",
+						Usage: "Build (or rebuild) the trigram index used for keyword search of observations",
+					},
+					&cli.BoolFlag{
+						Name:  "embeddings",
+						Usage: "Embed new and changed observations with the configured embedding model, for semantic features",
+					},
+					&cli.BoolFlag{
+						Name:  "rebuild",
+						Usage: "Re-embed observations embedded with a different model, after changing the model in the config (implies --embeddings)",
+					},
+					&cli.IntFlag{
+						Name:  "batch-size",
+						Usage: "Observations to embed per request to the embedding provider",
+						Value: 32,
+					},
+					&cli.BoolFlag{
+						Name:  "drop",
+						Usage: "Remove the selected index instead of building it",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					if cmd.Bool("embeddings") || cmd.Bool("rebuild") {
+						return indexEmbeddings(ctx, cmd)
+					}
+					if !cmd.Bool("trigram") {
+						return fmt.Errorf("no index selected: use --trigram or --embeddings")
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						if cmd.Bool("drop") {
+							if err := database.DropTrigramIndex(); err != nil {
+								return err
+							}
+							fmt.Println("Dropped trigram index")
+							return nil
+						}
+
+						indexed, err := database.BuildTrigramIndex()
+						if err != nil {
+							return err
+						}
+						fmt.Printf("Indexed %d observations\n", indexed)
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "integrate",
+				Usage: "Set up amem with agent tools",
+				Commands: []*cli.Command{
+					{
+						Name:  "claude-code",
+						Usage: "Add Claude Code hooks that recall memories and save new ones automatically",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "global",
+								Usage: "Write to user settings (~/.claude/settings.json) instead of the project",
+							},
+						},
+						Action: func(ctx context.Context, cmd *cli.Command) error {
+							cwd, err := os.Getwd()
+							if err != nil {
+								return fmt.Errorf("failed to get current directory: %w", err)
+							}
+							root, err := gitrepo.FindRoot(cwd)
+							if err != nil {
+								root = cwd
+							}
+
+							path, err := claudeCodeSettingsPath(root, cmd.Bool("global"))
+							if err != nil {
+								return err
+							}
+							added, err := integrateClaudeCode(path)
+							if err != nil {
+								return err
+							}
+
+							if len(added) == 0 {
+								fmt.Printf("%s: amem hooks already installed\n", path)
+								return nil
+							}
+							fmt.Printf("%s: added amem hooks for %s\n", path, strings.Join(added, ", "))
+							return nil
+						},
+					},
+				},
+			},
+			{
+				Name:  "schema",
+				Usage: "Print JSON tool definitions for wiring amem into agents",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "format",
+						Usage: "Tool definition format (openai, anthropic)",
+						Value: tools.FormatOpenAI,
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					defs, err := tools.Definitions(cmd.String("format"))
+					if err != nil {
+						return err
+					}
+					data, err := json.MarshalIndent(defs, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal tool definitions: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				},
+			},
+			serveCommand(),
+			agentCommand(),
+			keysCommand(),
+			snapshotCommand(),
+			undoCommand(),
+			changedCommand(),
+			getCommand(),
+			graphCommand(),
+			doctorCommand(),
+			queryCommand(),
+			sqlCommand(),
+			dbsCommand(),
+			exportCommand(),
+			backupCommand(),
+			applyCommand(),
+			statsCommand(),
+			markCommand(),
+			archiveCommand(),
+			existsCommand(),
+			touchCommand(),
+			mergeCommand(),
+			configCommand(),
+			enableCommand(),
+			disableCommand(),
+			relatedCommand(),
+			topicsCommand(),
+			watchCommand(),
+			toolCommand(),
+			manCommand(),
+			selfUpdateCommand(),
+			{
+				Name:      "context",
+				Usage:     "Print memories to give an agent context, recent or matching keywords",
+				ArgsUsage: "[keywords...]",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Maximum observations and relationships to show",
+						Value: 20,
+					},
+					&cli.BoolFlag{
+						Name:  "hook",
+						Usage: "Read Claude Code hook input from stdin and never fail the hook",
+					},
+					&cli.BoolFlag{
+						Name:  "with-ids",
+						Usage: "Show database IDs with results",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					keywords := cmd.Args().Slice()
+					limit := int(cmd.Int("limit"))
+					hook := cmd.Bool("hook")
+					withIDs := cmd.Bool("with-ids")
+
+					// Hooks receive JSON on stdin; don't wait for input from a terminal
+					if hook && !term.IsTerminal(int(os.Stdin.Fd())) {
+						input, err := readHookInput(os.Stdin)
+						if err != nil {
+							fmt.Fprintf(os.Stderr, "Warning: amem context: %v\n", err)
+							return nil
+						}
+						keywords = append(keywords, promptKeywords(input.Prompt)...)
+					}
+
+					err := withDB(func(database *db.DB) error {
+						var entities []db.Entity
+						var observations []db.Observation
+						var relationships []db.Relationship
+						var err error
+
+						if len(keywords) > 0 {
+							entities, observations, relationships, err = database.SearchAll(keywords, true)
+						} else {
+							observations, err = database.SearchObservations("", nil, true)
+							if err == nil {
+								relationships, err = database.SearchRelationships("", "", "", nil, true)
+							}
+						}
+						if err != nil {
+							return err
+						}
+
+						if limit > 0 {
+							if len(entities) > limit {
+								entities = entities[:limit]
+							}
+							if len(observations) > limit {
+								observations = observations[:limit]
+							}
+							if len(relationships) > limit {
+								relationships = relationships[:limit]
+							}
+						}
+
+						if hook {
+							if len(entities)+len(observations)+len(relationships) == 0 {
+								return nil
+							}
+							fmt.Println("Relevant memories from amem:")
+						}
+						view.FormatAll(entities, observations, relationships, withIDs)
+						return markAccessed(database, keywords, observations)
+					})
+					if err != nil && hook {
+						// A missing or locked database must not break the agent session
+						fmt.Fprintf(os.Stderr, "Warning: amem context: %v\n", err)
+						return nil
+					}
+					return err
+				},
+			},
+			{
+				Name:   "add",
+				Usage:  "Add entities, observations, or relationships",
+				Flags:  dbFlags(),
+				Before: useDBFlags,
 				Commands: []*cli.Command{
 					{
 						Name:      "entity",
 						Usage:     "Add one or more entities to the database",
 						ArgsUsage: "[entity names...]",
+						Flags: []cli.Flag{
+							truncateFlag(),
+							&cli.BoolFlag{
+								Name:  "atomic",
+								Usage: "Add all the entities or, if one can't be added, none of them",
+							},
+						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							entities := cmd.Args().Slice()
 							if len(entities) == 0 {
 								return fmt.Errorf("at least one entity name is required")
 							}
 
-							return withDB(func(database *db.DB) error {
-								for _, entity := range entities {
-									_, err := database.AddEntity(entity)
+							return withWriteDB(func(database *db.DB) error {
+								var added []string
+								add := func(database *db.DB) error {
+									for _, entity := range entities {
+										if cmd.Bool("truncate") {
+											entity = truncated("entity", entity, database.Rules().TruncateEntity(entity))
+										}
+										_, err := database.AddEntity(entity)
+										if err != nil {
+											return fmt.Errorf("failed to add entity '%s': %w", entity, err)
+										}
+										added = append(added, entity)
+									}
+									return nil
+								}
+
+								var err error
+								if cmd.Bool("atomic") {
+									err = database.Transaction(add)
 									if err != nil {
-										return fmt.Errorf("failed to add entity '%s': %w", entity, err)
+										return fmt.Errorf("%w (no entities were added)", err)
 									}
+								} else {
+									err = add(database)
+								}
+								for _, entity := range added {
 									fmt.Printf("Added entity: %s\n", entity)
 								}
-								return nil
+								return err
 							})
 						},
 					},
@@ -514,21 +1247,91 @@ func buildCommand() *cli.Command {
 								Required: true,
 							},
 							&cli.StringFlag{
-								Name:     "text",
-								Usage:    "Observation text",
-								Required: true,
+								Name:  "text",
+								Usage: "Observation text",
+							},
+							&cli.BoolFlag{
+								Name:  "edit",
+								Usage: "Write the observation in $EDITOR, starting from --text if given",
 							},
+							&cli.IntFlag{
+								Name:  "importance",
+								Usage: "Importance of the observation (higher is kept longer under a quota)",
+							},
+							&cli.BoolFlag{
+								Name:  "allow-secrets",
+								Usage: "Add the observation even if it looks like it contains a credential",
+							},
+							&cli.BoolFlag{
+								Name:  "unique",
+								Usage: "Skip adding the observation if the entity already has one with the same text, printing its ID",
+							},
+							timestampFlag("observation"),
+							&cli.StringFlag{
+								Name:  "lang",
+								Usage: "Language the observation is written in, as an ISO 639-1 code like de, or auto to detect it",
+							},
+							truncateFlag(),
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							entity := cmd.String("entity")
 							text := cmd.String("text")
+							if cmd.Bool("edit") {
+								var err error
+								text, err = editText(text, fmt.Sprintf("Write the observation about '%s' above.\nLines starting with # are ignored; save an empty file to cancel.", entity))
+								if err != nil {
+									return err
+								}
+								if text == "" {
+									return fmt.Errorf("observation is empty, nothing added")
+								}
+							} else if text == "" {
+								return fmt.Errorf("--text is required (or use --edit to write it in $EDITOR)")
+							}
+							timestamp, err := addTimestamp(cmd)
+							if err != nil {
+								return err
+							}
+							opts := db.ObservationOptions{
+								Importance:   int(cmd.Int("importance")),
+								AllowSecrets: cmd.Bool("allow-secrets"),
+								Timestamp:    timestamp,
+								Lang:         cmd.String("lang"),
+							}
 
-							return withDB(func(database *db.DB) error {
-								_, err := database.AddObservation(entity, text)
+							return withWriteDB(func(database *db.DB) error {
+								if cmd.Bool("truncate") {
+									rules := database.Rules()
+									entity = truncated("entity", entity, rules.TruncateEntity(entity))
+									text = truncated("observation", text, rules.TruncateObservation(text))
+								}
+								if cmd.Bool("unique") {
+									id, err := database.FindObservation(entity, text)
+									if err == nil {
+										fmt.Printf("Observation about '%s' already exists with ID %d\n", entity, id)
+										return nil
+									}
+									if !errors.Is(err, db.ErrNotFound) {
+										return err
+									}
+								}
+								id, err := database.AddObservationWithOptions(entity, text, opts)
+								if errors.Is(err, db.ErrSecret) {
+									return fmt.Errorf("%w (pass --allow-secrets to add it anyway)", err)
+								}
 								if err != nil {
 									return err
 								}
+								if rules := database.Rules(); rules != nil && rules.Secrets == db.SecretsWarn && !opts.AllowSecrets {
+									for _, kind := range db.FindSecrets(text) {
+										fmt.Fprintf(os.Stderr, "Warning: observation looks like it contains a credential (%s)\n", kind)
+									}
+								}
 
+								if cmd.Bool("unique") {
+									fmt.Printf("Added observation about '%s' with ID %d\n", entity, id)
+									return nil
+								}
 								fmt.Printf("Added observation about '%s'\n", entity)
 								return nil
 							})
@@ -553,14 +1356,26 @@ func buildCommand() *cli.Command {
 								Usage:    "Relationship type",
 								Required: true,
 							},
+							timestampFlag("relationship"),
+							truncateFlag(),
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							from := cmd.String("from")
 							to := cmd.String("to")
 							relType := cmd.String("type")
+							timestamp, err := addTimestamp(cmd)
+							if err != nil {
+								return err
+							}
 
-							return withDB(func(database *db.DB) error {
-								_, err := database.AddRelationship(from, to, relType)
+							return withWriteDB(func(database *db.DB) error {
+								if cmd.Bool("truncate") {
+									rules := database.Rules()
+									from = truncated("entity", from, rules.TruncateEntity(from))
+									to = truncated("entity", to, rules.TruncateEntity(to))
+									relType = truncated("relationship type", relType, rules.TruncateRelationshipType(relType))
+								}
+								_, err := database.AddRelationshipWithOptions(from, to, relType, db.RelationshipOptions{Timestamp: timestamp})
 								if err != nil {
 									return err
 								}
@@ -589,6 +1404,14 @@ func buildCommand() *cli.Command {
 								Name:  "all",
 								Usage: "Match all keywords (AND logic)",
 							},
+							&cli.StringFlag{
+								Name:  "since",
+								Usage: "Only entities created at or after this duration ago (e.g. 2h) or date/time (e.g. 2025-01-31 14:00)",
+							},
+							&cli.StringFlag{
+								Name:  "before",
+								Usage: "Only entities created before this duration ago (e.g. 30m) or date/time (e.g. 2025-01-31 15:00)",
+							},
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							keywords := cmd.Args().Slice()
@@ -603,13 +1426,30 @@ func buildCommand() *cli.Command {
 							// Default to union (any)
 							useUnion := !useAll
 
-							return withDB(func(database *db.DB) error {
-								results, err := database.SearchEntities(keywords, useUnion)
+							var filter db.EntityFilter
+							now := time.Now()
+							if value := cmd.String("since"); value != "" {
+								since, err := parseTimeFlag("since", value, now)
 								if err != nil {
 									return err
 								}
-								view.FormatEntities(results, withIDs)
-								return nil
+								filter.CreatedSince = since
+							}
+							if value := cmd.String("before"); value != "" {
+								before, err := parseTimeFlag("before", value, now)
+								if err != nil {
+									return err
+								}
+								filter.CreatedBefore = before
+							}
+
+							return withSearchDB(cmd, func(database *db.DB) error {
+								count, err := database.CountSearchEntitiesFiltered(keywords, useUnion, filter)
+								if err != nil {
+									return err
+								}
+								results := database.SearchEntitiesFilteredIter(keywords, useUnion, filter)
+								return view.StreamEntities(view.Stream[db.Entity]{Count: count, Rows: results}, withIDs)
 							})
 						},
 					},
@@ -629,6 +1469,10 @@ func buildCommand() *cli.Command {
 								Name:  "all",
 								Usage: "Match all keywords (AND logic)",
 							},
+							&cli.BoolFlag{
+								Name:  "fts",
+								Usage: "Match whole words, prefixes like tea*, and \"quoted phrases\" with the full-text index (needs 'amem enable fts')",
+							},
 						},
 						ArgsUsage: "[keywords...]",
 						Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -645,13 +1489,35 @@ func buildCommand() *cli.Command {
 							// Default to union (any)
 							useUnion := !useAll
 
-							return withDB(func(database *db.DB) error {
-								results, err := database.SearchObservations(entityText, keywords, useUnion)
+							if cmd.Bool("fts") {
+								if len(keywords) == 0 {
+									return fmt.Errorf("--fts needs at least one keyword")
+								}
+								joiner := " OR "
+								if useAll {
+									joiner = " "
+								}
+								return withSearchDB(cmd, func(database *db.DB) error {
+									observations, err := database.SearchFullText(entityText, strings.Join(keywords, joiner))
+									if err != nil {
+										return err
+									}
+									view.FormatObservations(observations, withIDs)
+									return nil
+								})
+							}
+
+							return withSearchDB(cmd, func(database *db.DB) error {
+								count, err := database.CountSearchObservations(entityText, keywords, useUnion)
 								if err != nil {
 									return err
 								}
-								view.FormatObservations(results, withIDs)
-								return nil
+								var ids []int64
+								results := view.Snippets(recordIDs(database.SearchObservationsIter(entityText, keywords, useUnion), &ids), keywords, cmd.Int("snippet"))
+								if err := view.StreamObservations(view.Stream[db.Observation]{Count: count, Rows: results}, withIDs); err != nil {
+									return err
+								}
+								return database.Retrieved(entityText, keywords, ids)
 							})
 						},
 					},
@@ -667,6 +1533,10 @@ func buildCommand() *cli.Command {
 								Name:  "from",
 								Usage: "Search for relationships from an entity",
 							},
+							&cli.StringFlag{
+								Name:  "with",
+								Usage: "Search for relationships to or from an entity",
+							},
 							&cli.StringFlag{
 								Name:  "type",
 								Usage: "Search for relationships of a specific type",
@@ -685,6 +1555,7 @@ func buildCommand() *cli.Command {
 							keywords := cmd.Args().Slice()
 							fromText := cmd.String("from")
 							toText := cmd.String("to")
+							withText := cmd.String("with")
 							relType := cmd.String("type")
 							withIDs := cmd.Bool("with-ids")
 							useAny := cmd.Bool("any")
@@ -693,26 +1564,58 @@ func buildCommand() *cli.Command {
 							if useAny && useAll {
 								return fmt.Errorf("cannot specify both --any and --all")
 							}
+							if withText != "" && (fromText != "" || toText != "") {
+								return fmt.Errorf("use --with instead of --from and --to, not with them")
+							}
 
 							// Default to union (any)
 							useUnion := !useAll
 
-							return withDB(func(database *db.DB) error {
-								results, err := database.SearchRelationships(fromText, toText, relType, keywords, useUnion)
+							return withSearchDB(cmd, func(database *db.DB) error {
+								var count int
+								var results iter.Seq2[db.Relationship, error]
+								var err error
+								if withText != "" {
+									count, err = database.CountSearchRelationshipsWith(withText, relType, keywords, useUnion)
+									results = database.SearchRelationshipsWithIter(withText, relType, keywords, useUnion)
+								} else {
+									count, err = database.CountSearchRelationships(fromText, toText, relType, keywords, useUnion)
+									results = database.SearchRelationshipsIter(fromText, toText, relType, keywords, useUnion)
+								}
 								if err != nil {
 									return err
 								}
-								view.FormatRelationships(results, withIDs)
-								return nil
+								return view.StreamRelationships(view.Stream[db.Relationship]{Count: count, Rows: results}, withIDs)
 							})
 						},
 					},
 				},
-				Flags: []cli.Flag{
+				Flags: append([]cli.Flag{
 					&cli.BoolFlag{
 						Name:  "with-ids",
 						Usage: "Show database IDs with results",
 					},
+					&cli.BoolFlag{
+						Name:  "explain",
+						Usage: "Print each query's SQL, SQLite query plan, and time taken to stderr",
+					},
+					&cli.BoolFlag{
+						Name:  "include-archived",
+						Usage: "Include observations archived with 'amem archive'",
+					},
+					&cli.StringFlag{
+						Name:  "lang",
+						Usage: "Only show observations tagged with this language, as an ISO 639-1 code like de",
+					},
+					&cli.IntFlag{
+						Name:  "snippet",
+						Usage: "Show only about this many characters of longer observations, around the first keyword in them",
+					},
+					&cli.BoolFlag{
+						Name:  "everywhere",
+						Usage: "Search the global database and every local one 'amem init' has created, showing where each result is from",
+						Local: true,
+					},
 					&cli.BoolFlag{
 						Name:  "any",
 						Usage: "Match any keyword (OR logic, default)",
@@ -721,6 +1624,14 @@ func buildCommand() *cli.Command {
 						Name:  "all",
 						Usage: "Match all keywords (AND logic)",
 					},
+					&cli.StringSliceFlag{
+						Name:  "require",
+						Usage: "Only show results containing this keyword as well as matching the others, even with --any (repeatable)",
+					},
+				}, append(dbFlags(), outputFlags()...)...),
+				Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
+					useOutputFlags(cmd)
+					return useDBFlags(ctx, cmd)
 				},
 				ArgsUsage: "[keywords...]",
 				Action: func(ctx context.Context, cmd *cli.Command) error {
@@ -736,20 +1647,47 @@ func buildCommand() *cli.Command {
 					// Default to union (any)
 					useUnion := !useAll
 
-					return withDB(func(database *db.DB) error {
-						entities, observations, relationships, err := database.SearchAll(keywords, useUnion)
+					if cmd.Bool("everywhere") {
+						if dbOverride != "" {
+							return fmt.Errorf("cannot specify both --db and --everywhere")
+						}
+						return searchEverywhere(keywords, cmd.StringSlice("require"), useUnion, withIDs, cmd.Int("snippet"))
+					}
+
+					return withSearchDB(cmd, func(database *db.DB) error {
+						entityCount, err := database.CountSearchEntities(keywords, useUnion)
+						if err != nil {
+							return err
+						}
+						observationCount, err := database.CountSearchObservations("", keywords, useUnion)
+						if err != nil {
+							return err
+						}
+						relationshipCount, err := database.CountSearchRelationships("", "", "", keywords, useUnion)
 						if err != nil {
 							return err
 						}
 
-						view.FormatAll(entities, observations, relationships, withIDs)
-						return nil
+						var ids []int64
+						err = view.StreamAll(
+							view.Stream[db.Entity]{Count: entityCount, Rows: database.SearchEntitiesIter(keywords, useUnion)},
+							view.Stream[db.Observation]{Count: observationCount, Rows: view.Snippets(recordIDs(database.SearchObservationsIter("", keywords, useUnion), &ids), keywords, cmd.Int("snippet"))},
+							view.Stream[db.Relationship]{Count: relationshipCount, Rows: database.SearchRelationshipsIter("", "", "", keywords, useUnion)},
+							withIDs,
+						)
+						if err != nil {
+							return err
+						}
+						// Mark only after reading, so the write doesn't wait on our own open query
+						return database.Retrieved("", keywords, ids)
 					})
 				},
 			},
 			{
-				Name:  "delete",
-				Usage: "Delete entities, observations, or relationships",
+				Name:   "delete",
+				Usage:  "Delete entities, observations, or relationships",
+				Flags:  dbFlags(),
+				Before: useDBFlags,
 				Commands: []*cli.Command{
 					{
 						Name:      "entity",
@@ -760,10 +1698,21 @@ func buildCommand() *cli.Command {
 								Name:  "ids",
 								Usage: "Delete by IDs",
 							},
+							&cli.StringFlag{
+								Name:  "cascade-report",
+								Value: "text",
+								Usage: "Report the observations and relationships deleted with each entity as text or json",
+							},
+							&cli.BoolFlag{
+								Name:  "dry-run",
+								Usage: "Report what would be deleted without deleting anything",
+							},
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							entityName := cmd.Args().First()
 							ids := cmd.IntSlice("ids")
+							format := cmd.String("cascade-report")
+							dryRun := cmd.Bool("dry-run")
 
 							// Validate that only one method is provided
 							if entityName != "" && len(ids) > 0 {
@@ -772,21 +1721,54 @@ func buildCommand() *cli.Command {
 							if entityName == "" && len(ids) == 0 {
 								return fmt.Errorf("must specify either entity name or --ids")
 							}
+							if format != "text" && format != "json" {
+								return fmt.Errorf("unknown cascade report format %q: use text or json", format)
+							}
 
-							return withDB(func(database *db.DB) error {
+							return withWriteDB(func(database *db.DB) error {
+								// Look everything up first, so nothing is deleted if any entity is missing
+								var cascades []*db.Cascade
 								if entityName != "" {
-									// Delete by name
-									if err := database.DeleteEntityByText(entityName); err != nil {
+									cascade, err := database.EntityCascadeByText(entityName)
+									if err != nil {
 										return err
 									}
-									fmt.Printf("Deleted entity: %s\n", entityName)
+									cascades = append(cascades, cascade)
 								} else {
-									// Delete by IDs
 									for _, id := range ids {
-										if err := database.DeleteEntity(int64(id)); err != nil {
+										cascade, err := database.EntityCascade(int64(id))
+										if err != nil {
 											return fmt.Errorf("failed to delete entity ID %d: %w", id, err)
 										}
-										fmt.Printf("Deleted entity ID %d\n", id)
+										cascades = append(cascades, cascade)
+									}
+								}
+
+								if format == "json" {
+									data, err := json.MarshalIndent(cascades, "", "  ")
+									if err != nil {
+										return fmt.Errorf("failed to marshal cascade report: %w", err)
+									}
+									fmt.Println(string(data))
+								}
+
+								for _, cascade := range cascades {
+									if format == "text" {
+										printCascade(cascade)
+									}
+									if dryRun {
+										continue
+									}
+									if err := database.DeleteEntity(cascade.Entity.ID); err != nil {
+										return fmt.Errorf("failed to delete entity '%s': %w", cascade.Entity.Text, err)
+									}
+									if format != "text" {
+										continue
+									}
+									if entityName != "" {
+										fmt.Printf("Deleted entity: %s\n", entityName)
+									} else {
+										fmt.Printf("Deleted entity ID %d\n", cascade.Entity.ID)
 									}
 								}
 								return nil
@@ -806,7 +1788,7 @@ func buildCommand() *cli.Command {
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							ids := cmd.IntSlice("ids")
 
-							return withDB(func(database *db.DB) error {
+							return withWriteDB(func(database *db.DB) error {
 								for _, id := range ids {
 									if err := database.DeleteObservation(int64(id)); err != nil {
 										return fmt.Errorf("failed to delete observation ID %d: %w", id, err)
@@ -830,7 +1812,7 @@ func buildCommand() *cli.Command {
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							ids := cmd.IntSlice("ids")
 
-							return withDB(func(database *db.DB) error {
+							return withWriteDB(func(database *db.DB) error {
 								for _, id := range ids {
 									if err := database.DeleteRelationship(int64(id)); err != nil {
 										return fmt.Errorf("failed to delete relationship ID %d: %w", id, err)
@@ -849,24 +1831,91 @@ func buildCommand() *cli.Command {
 				Commands: []*cli.Command{
 					{
 						Name:      "entity",
-						Usage:     "Change an entity's name",
+						Usage:     "Change an entity's name or note",
 						ArgsUsage: "[entity name]",
 						Flags: []cli.Flag{
 							&cli.StringFlag{
-								Name:     "new-name",
-								Usage:    "New name for the entity",
-								Required: true,
+								Name:  "new-name",
+								Usage: "New name for the entity",
+							},
+							&cli.BoolFlag{
+								Name:  "merge-on-conflict",
+								Usage: "If an entity already has the new name, merge this one into it instead of failing",
+							},
+							&cli.StringFlag{
+								Name:  "note",
+								Usage: "Replace the entity's note, a Markdown description kept alongside its observations (\"\" removes it)",
+							},
+							&cli.BoolFlag{
+								Name:  "note-edit",
+								Usage: "Edit the entity's note in $EDITOR",
 							},
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							entityName := cmd.Args().First()
 							newName := cmd.String("new-name")
+							note := cmd.String("note")
+							setNote := cmd.IsSet("note")
 
 							if entityName == "" {
 								return fmt.Errorf("entity name is required")
 							}
+							if newName == "" && !setNote && !cmd.Bool("note-edit") {
+								return fmt.Errorf("at least one of --new-name, --note, or --note-edit must be provided")
+							}
+							if setNote && cmd.Bool("note-edit") {
+								return fmt.Errorf("use only one of --note and --note-edit")
+							}
+
+							// Edit before taking the write lock, so other writers aren't held up while the editor is open
+							if cmd.Bool("note-edit") {
+								err := withDB(func(database *db.DB) error {
+									current, err := database.Note(entityName)
+									if err != nil {
+										return err
+									}
+									note, err = editTextWith(noteComment, current, fmt.Sprintf("Edit the note on '%s' above, in Markdown.\nLines starting with %s are ignored; save an empty file to remove the note.", entityName, noteComment))
+									if err != nil {
+										return err
+									}
+									setNote = note != current
+									return nil
+								})
+								if err != nil {
+									return err
+								}
+								if !setNote && newName == "" {
+									fmt.Printf("Note on '%s' unchanged\n", entityName)
+									return nil
+								}
+							}
+
+							return withWriteDB(func(database *db.DB) error {
+								if setNote {
+									if err := database.SetNote(entityName, note); err != nil {
+										return err
+									}
+									fmt.Printf("Updated note on '%s'\n", entityName)
+								}
+								if newName == "" {
+									return nil
+								}
+
+								exists, err := database.EntityExists(newName)
+								if err != nil {
+									return err
+								}
+								if exists && newName != entityName {
+									if !cmd.Bool("merge-on-conflict") {
+										return fmt.Errorf("entity '%s' already exists (pass --merge-on-conflict to merge '%s' into it)", newName, entityName)
+									}
+									if err := database.MergeEntityInto(entityName, newName); err != nil {
+										return err
+									}
+									fmt.Printf("Merged entity '%s' into '%s'\n", entityName, newName)
+									return nil
+								}
 
-							return withDB(func(database *db.DB) error {
 								if err := database.UpdateEntity(entityName, newName); err != nil {
 									return err
 								}
@@ -888,22 +1937,66 @@ func buildCommand() *cli.Command {
 								Name:  "new-text",
 								Usage: "New text for the observation",
 							},
+							&cli.BoolFlag{
+								Name:  "edit",
+								Usage: "Edit the observation's text in $EDITOR",
+							},
 							&cli.IntFlag{
 								Name:  "new-entity-id",
 								Usage: "New entity ID for the observation",
 							},
+							&cli.StringFlag{
+								Name:  "new-entity",
+								Usage: "New entity name for the observation, created if it doesn't exist",
+							},
 						},
 						Action: func(ctx context.Context, cmd *cli.Command) error {
 							id := cmd.Int("id")
 							newText := cmd.String("new-text")
 							newEntityID := cmd.Int("new-entity-id")
+							newEntity := cmd.String("new-entity")
+							edit := cmd.Bool("edit")
 
 							// At least one flag must be provided
-							if newText == "" && newEntityID == 0 {
-								return fmt.Errorf("at least one of --new-text or --new-entity-id must be provided")
+							if newText == "" && newEntityID == 0 && newEntity == "" && !edit {
+								return fmt.Errorf("at least one of --new-text, --edit, --new-entity, or --new-entity-id must be provided")
+							}
+							if newText != "" && edit {
+								return fmt.Errorf("use only one of --new-text and --edit")
+							}
+							if newEntity != "" && newEntityID != 0 {
+								return fmt.Errorf("use only one of --new-entity and --new-entity-id")
+							}
+
+							// Edit before taking the write lock, so other writers aren't held up while the editor is open
+							if edit {
+								err := withDB(func(database *db.DB) error {
+									o, err := database.GetObservation(int64(id))
+									if err != nil {
+										return err
+									}
+									newText, err = editText(o.Text, fmt.Sprintf("Edit observation %d about '%s' above.\nLines starting with # are ignored; save an empty file to cancel.", id, o.EntityText))
+									if err != nil {
+										return err
+									}
+									if newText == "" {
+										return fmt.Errorf("observation is empty, nothing changed")
+									}
+									if newText == o.Text {
+										newText = ""
+									}
+									return nil
+								})
+								if err != nil {
+									return err
+								}
+								if newText == "" && newEntityID == 0 && newEntity == "" {
+									fmt.Printf("Observation ID %d unchanged\n", id)
+									return nil
+								}
 							}
 
-							return withDB(func(database *db.DB) error {
+							return withWriteDB(func(database *db.DB) error {
 								if newText != "" {
 									if err := database.UpdateObservation(int64(id), newText); err != nil {
 										return err
@@ -914,6 +2007,11 @@ func buildCommand() *cli.Command {
 										return err
 									}
 								}
+								if newEntity != "" {
+									if err := database.UpdateObservationEntityByText(int64(id), newEntity); err != nil {
+										return err
+									}
+								}
 								fmt.Printf("Updated observation ID %d\n", id)
 								return nil
 							})
@@ -925,11 +2023,32 @@ func buildCommand() *cli.Command {
 	}
 }
 
+// corruptHelp tells users what to do when the integrity check finds their database damaged
+const corruptHelp = `
+The damaged pages can't be read, but the rest of the database can. Without
+--check-integrity (and "check_integrity" in the config) amem opens it as before:
+1. Save what's still readable with 'amem export > memories.json'.
+2. Restore the newest snapshot from before the damage with 'amem snapshot list'
+   and 'amem snapshot restore <name>', or copy back a backup that passes
+   'amem backup verify <file>'.
+`
+
 func main() {
+	db.SetAppVersion(version)
 	cmd := buildCommand()
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
+		// The record not existing is an answer, not a failure
+		if errors.Is(err, errNotExist) {
+			os.Exit(exitNotExist)
+		}
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, db.ErrCorrupt) {
+			fmt.Fprint(os.Stderr, corruptHelp)
+		}
+		if errors.Is(err, errWarnings) {
+			os.Exit(exitWarnings)
+		}
 		os.Exit(1)
 	}
 }
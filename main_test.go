@@ -3,9 +3,24 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"amem/db"
+	"amem/server"
+	"amem/tools"
 	"github.com/urfave/cli/v3"
 )
 
@@ -13,7 +28,7 @@ func TestTopLevelCommands(t *testing.T) {
 	cmd := buildCommand()
 
 	expectedCommands := []string{
-		"help", "agent-docs", "version", "init", "change-encryption-key", "check", "add", "search", "delete", "edit",
+		"help", "agent-docs", "version", "init", "change-encryption-key", "check", "enforce-quota", "index", "integrate", "schema", "serve", "agent", "keys", "snapshot", "undo", "changed", "get", "graph", "doctor", "query", "sql", "dbs", "export", "backup", "apply", "stats", "mark", "archive", "exists", "touch", "merge", "config", "enable", "disable", "related", "topics", "watch", "tool", "man", "self-update", "context", "add", "search", "delete", "edit",
 	}
 
 	if len(cmd.Commands) != len(expectedCommands) {
@@ -103,6 +118,290 @@ func TestAgentDocsCommand(t *testing.T) {
 	}
 }
 
+func TestAgentDocsTargets(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	for target, expected := range agentDocsTargets {
+		content, err := agentDocs(target)
+		if err != nil {
+			t.Errorf("agentDocs(%q) failed: %v", target, err)
+			continue
+		}
+		if content != expected {
+			t.Errorf("agentDocs(%q) did not return built-in content", target)
+		}
+	}
+
+	if _, err := agentDocs("emacs"); err == nil {
+		t.Error("Expected error for unknown target")
+	}
+}
+
+func TestAgentDocsTemplateOverride(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	templatePath, err := agentDocsTemplatePath("claude")
+	if err != nil {
+		t.Fatalf("agentDocsTemplatePath failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(templatePath), 0o755); err != nil {
+		t.Fatalf("Failed to create template dir: %v", err)
+	}
+	if err := os.WriteFile(templatePath, []byte("custom docs"), 0o644); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	content, err := agentDocs("claude")
+	if err != nil {
+		t.Fatalf("agentDocs failed: %v", err)
+	}
+	if content != "custom docs" {
+		t.Errorf("Expected custom template, got %q", content)
+	}
+
+	// Other targets still use built-in content
+	content, _ = agentDocs("cursor")
+	if content != cursorDocsContent {
+		t.Error("Expected built-in content for target without a template")
+	}
+}
+
+func TestUpsertAgentDocsBlock(t *testing.T) {
+	block := agentDocsStartMarker + "\nnew docs\n" + agentDocsEndMarker + "\n"
+
+	tests := []struct {
+		name     string
+		existing string
+		expected string
+	}{
+		{"empty file", "", block},
+		{"appends to existing text", "# Project\n", "# Project\n\n" + block},
+		{"appends without trailing newline", "# Project", "# Project\n\n" + block},
+		{
+			"replaces existing block",
+			"# Project\n\n" + agentDocsStartMarker + "\nold docs\n" + agentDocsEndMarker + "\n\n## More\n",
+			"# Project\n\n" + block + "\n## More\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := upsertAgentDocsBlock(tt.existing, "new docs")
+			if err != nil {
+				t.Fatalf("upsertAgentDocsBlock failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+
+			// Applying again must not change anything
+			again, _ := upsertAgentDocsBlock(got, "new docs")
+			if again != got {
+				t.Errorf("upsert is not idempotent: %q", again)
+			}
+		})
+	}
+
+	if _, err := upsertAgentDocsBlock(agentDocsStartMarker+"\nunterminated", "docs"); err == nil {
+		t.Error("Expected error for missing end marker")
+	}
+}
+
+func TestInstallAgentDocs(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatalf("Failed to create .git: %v", err)
+	}
+	subDir := filepath.Join(root, "src")
+	if err := os.Mkdir(subDir, 0o755); err != nil {
+		t.Fatalf("Failed to create subdir: %v", err)
+	}
+
+	// Creates AGENTS.md at the repository root
+	path, change, err := installAgentDocs(subDir, "agents-md")
+	if err != nil {
+		t.Fatalf("installAgentDocs failed: %v", err)
+	}
+	if path != filepath.Join(root, "AGENTS.md") || change != "created" {
+		t.Errorf("Expected AGENTS.md to be created, got %s (%s)", path, change)
+	}
+
+	// Second install is a no-op
+	_, change, err = installAgentDocs(subDir, "agents-md")
+	if err != nil {
+		t.Fatalf("installAgentDocs failed: %v", err)
+	}
+	if change != "already up to date" {
+		t.Errorf("Expected no change on reinstall, got %s", change)
+	}
+
+	data, _ := os.ReadFile(path)
+	if bytes.Count(data, []byte(agentDocsStartMarker)) != 1 {
+		t.Errorf("Expected exactly one amem block, got:\n%s", data)
+	}
+}
+
+func TestInstallAgentDocsPrefersExistingClaudeMD(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	root := t.TempDir()
+	claudePath := filepath.Join(root, "CLAUDE.md")
+	if err := os.WriteFile(claudePath, []byte("# Rules\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write CLAUDE.md: %v", err)
+	}
+
+	path, change, err := installAgentDocs(root, "agents-md")
+	if err != nil {
+		t.Fatalf("installAgentDocs failed: %v", err)
+	}
+	if path != claudePath || change != "updated" {
+		t.Errorf("Expected CLAUDE.md to be updated, got %s (%s)", path, change)
+	}
+
+	data, _ := os.ReadFile(claudePath)
+	if !bytes.HasPrefix(data, []byte("# Rules\n")) || !bytes.Contains(data, []byte(agentDocsContent)) {
+		t.Errorf("Expected existing content to be kept and docs appended, got:\n%s", data)
+	}
+}
+
+func TestAddClaudeCodeHooks(t *testing.T) {
+	existing := []byte(`{
+  "model": "opus",
+  "hooks": {
+    "SessionStart": [
+      {"hooks": [{"type": "command", "command": "echo hello"}]}
+    ]
+  }
+}`)
+
+	updated, added, err := addClaudeCodeHooks(existing)
+	if err != nil {
+		t.Fatalf("addClaudeCodeHooks failed: %v", err)
+	}
+	if len(added) != len(claudeCodeHooks) {
+		t.Errorf("Expected %d hooks added, got %v", len(claudeCodeHooks), added)
+	}
+
+	var settings map[string]interface{}
+	if err := json.Unmarshal(updated, &settings); err != nil {
+		t.Fatalf("Updated settings are not valid JSON: %v", err)
+	}
+	if settings["model"] != "opus" {
+		t.Error("Expected unrelated settings to be preserved")
+	}
+	hooks := settings["hooks"].(map[string]interface{})
+	sessionStart := hooks["SessionStart"].([]interface{})
+	if len(sessionStart) != 2 {
+		t.Errorf("Expected existing SessionStart hook to be kept alongside amem's, got %d groups", len(sessionStart))
+	}
+	// Memories are saved after each reply, as well as recalled
+	stop, _ := hooks["Stop"].([]interface{})
+	if !hasHookCommand(stop, "amem watch --hook") {
+		t.Errorf("Expected a Stop hook that saves memories, got %v", hooks["Stop"])
+	}
+
+	// Running again adds nothing
+	_, added, err = addClaudeCodeHooks(updated)
+	if err != nil {
+		t.Fatalf("addClaudeCodeHooks failed: %v", err)
+	}
+	if len(added) != 0 {
+		t.Errorf("Expected no hooks added on second run, got %v", added)
+	}
+
+	if _, _, err := addClaudeCodeHooks([]byte(`{"hooks": []}`)); err == nil {
+		t.Error("Expected error when hooks is not an object")
+	}
+}
+
+func TestPromptKeywords(t *testing.T) {
+	keywords := promptKeywords("What did we decide about the Postgres migration for billing-service? Please check.")
+
+	expected := []string{"decide", "postgres", "migration", "billing-service", "check"}
+	if len(keywords) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, keywords)
+	}
+	for i := range expected {
+		if keywords[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, keywords)
+			break
+		}
+	}
+
+	if got := promptKeywords(""); len(got) != 0 {
+		t.Errorf("Expected no keywords for empty prompt, got %v", got)
+	}
+}
+
+func TestCheckUIAddr(t *testing.T) {
+	none := &server.Config{}
+	readOnly := &server.Config{Tokens: []server.Token{{Name: "ci", Token: "secret", Scopes: []string{server.ScopeRead}}}}
+	writer := &server.Config{Tokens: []server.Token{{Name: "me", Token: "secret"}}}
+
+	tests := []struct {
+		addr   string
+		limits *server.Config
+		ok     bool
+	}{
+		{"127.0.0.1:8080", none, true},
+		{"[::1]:8080", none, true},
+		{"localhost:8080", none, true},
+		{":8080", none, false},
+		{"0.0.0.0:8080", none, false},
+		{"192.168.1.5:8080", readOnly, false},
+		{":8080", writer, true},
+	}
+	for _, tt := range tests {
+		err := checkUIAddr(tt.addr, tt.limits)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkUIAddr(%q, %d tokens) = %v, want ok %v", tt.addr, len(tt.limits.Tokens), err, tt.ok)
+		}
+	}
+}
+
+func TestToolSchemasMatchCommands(t *testing.T) {
+	// Each tool mirrors a command; its parameters must be flags of that command,
+	// except for the positional arguments and --any/--all
+	commandPaths := map[string][]string{
+		"amem_add_entities":         {"add", "entity"},
+		"amem_add_observation":      {"add", "observation"},
+		"amem_add_relationship":     {"add", "relationship"},
+		"amem_search":               {"search"},
+		"amem_search_entities":      {"search", "entities"},
+		"amem_search_observations":  {"search", "observations"},
+		"amem_search_relationships": {"search", "relationships"},
+	}
+	positional := map[string]bool{"names": true, "keywords": true, "match": true}
+
+	root := buildCommand()
+	for _, tool := range tools.All {
+		path, ok := commandPaths[tool.Name]
+		if !ok {
+			t.Errorf("No command mapped for tool %s", tool.Name)
+			continue
+		}
+
+		c := root
+		for _, name := range path {
+			c = findCommand(c.Commands, name)
+			if c == nil {
+				t.Fatalf("Command %v not found for tool %s", path, tool.Name)
+			}
+		}
+
+		for param := range tool.Parameters.Properties {
+			if positional[param] {
+				continue
+			}
+			if findFlag(c.Flags, param) == nil {
+				t.Errorf("Tool %s parameter %q has no matching flag on %v", tool.Name, param, path)
+			}
+		}
+	}
+}
+
 func TestInitCommand(t *testing.T) {
 	cmd := buildCommand()
 	initCmd := findCommand(cmd.Commands, "init")
@@ -114,9 +413,13 @@ func TestInitCommand(t *testing.T) {
 		t.Errorf("Unexpected init usage: %s", initCmd.Usage)
 	}
 
-	// Check that init has no flags (fully interactive)
-	if len(initCmd.Flags) != 0 {
-		t.Errorf("Expected 0 flags, got %d", len(initCmd.Flags))
+	// Init is interactive apart from flags that answer the scope prompt
+	var names []string
+	for _, f := range initCmd.Flags {
+		names = append(names, f.Names()[0])
+	}
+	if strings.Join(names, ",") != "local,global" {
+		t.Errorf("Expected flags local and global, got %v", names)
 	}
 }
 
@@ -191,7 +494,7 @@ func TestAddObservationSubcommand(t *testing.T) {
 		required bool
 	}{
 		"entity": {"Entity the observation is about", true},
-		"text":   {"Observation text", true},
+		"text":   {"Observation text", false},
 	}
 
 	for name, expected := range expectedFlags {
@@ -455,11 +758,11 @@ func TestEditEntitySubcommand(t *testing.T) {
 		t.Fatal("edit entity subcommand not found")
 	}
 
-	if entityCmd.Usage != "Change an entity's name" {
+	if entityCmd.Usage != "Change an entity's name or note" {
 		t.Errorf("Unexpected usage: %s", entityCmd.Usage)
 	}
 
-	// Check --new-name flag (required)
+	// Check --new-name flag (optional, since --note or --note-edit can be given instead)
 	flag := findFlag(entityCmd.Flags, "new-name")
 	if flag == nil {
 		t.Fatal("new-name flag not found")
@@ -468,8 +771,13 @@ func TestEditEntitySubcommand(t *testing.T) {
 	if !ok {
 		t.Fatal("new-name is not a StringFlag")
 	}
-	if !strFlag.Required {
-		t.Error("new-name flag should be required")
+	if strFlag.Required {
+		t.Error("new-name flag should be optional")
+	}
+	for _, name := range []string{"note", "note-edit"} {
+		if findFlag(entityCmd.Flags, name) == nil {
+			t.Errorf("%s flag not found", name)
+		}
 	}
 }
 
@@ -493,6 +801,7 @@ func TestEditObservationSubcommand(t *testing.T) {
 		"id":            {"IntFlag", true},
 		"new-text":      {"StringFlag", false},
 		"new-entity-id": {"IntFlag", false},
+		"new-entity":    {"StringFlag", false},
 	}
 
 	for name, expected := range expectedFlags {
@@ -843,6 +1152,56 @@ func TestSecurePromptWithConfirmation(t *testing.T) {
 	}
 }
 
+func TestOfferGitignore(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	root := t.TempDir()
+	if err := exec.Command("git", "init", "--quiet", root).Run(); err != nil {
+		t.Fatalf("git init failed: %v", err)
+	}
+
+	// Answer the prompt with the default (yes)
+	oldStdin := os.Stdin
+	defer func() { os.Stdin = oldStdin }()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdin = r
+	go func() {
+		defer func() { _ = w.Close() }()
+		_, _ = w.Write([]byte("\n"))
+	}()
+
+	oldStdout := os.Stdout
+	defer func() { os.Stdout = oldStdout }()
+	_, wOut, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = wOut
+	defer func() { _ = wOut.Close() }()
+
+	if err := offerGitignore(filepath.Join(root, "amem.db"), filepath.Join(root, ".amem")); err != nil {
+		t.Fatalf("offerGitignore failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, ".gitignore"))
+	if err != nil {
+		t.Fatalf("Failed to read .gitignore: %v", err)
+	}
+	if string(data) != "/amem.db\n/.amem/\n" {
+		t.Errorf("Unexpected .gitignore contents: %q", data)
+	}
+
+	// Once ignored, there is nothing to ask
+	if err := offerGitignore(filepath.Join(root, "amem.db"), filepath.Join(root, ".amem")); err != nil {
+		t.Errorf("offerGitignore failed: %v", err)
+	}
+}
+
 // Helper functions
 func findCommand(commands []*cli.Command, name string) *cli.Command {
 	for _, cmd := range commands {
@@ -861,3 +1220,172 @@ func findFlag(flags []cli.Flag, name string) cli.Flag {
 	}
 	return nil
 }
+
+func TestParseTimeFlag(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2h", now.Add(-2 * time.Hour)},
+		{"30m", now.Add(-30 * time.Minute)},
+		{"2025-03-01", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"2025-03-01 14:30", time.Date(2025, 3, 1, 14, 30, 0, 0, time.UTC)},
+		{"2025-03-01T14:30:00Z", time.Date(2025, 3, 1, 14, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseTimeFlag("since", tt.value, now)
+		if err != nil {
+			t.Errorf("parseTimeFlag(%q) failed: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseTimeFlag(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "-2h"} {
+		if _, err := parseTimeFlag("since", value, now); err == nil {
+			t.Errorf("parseTimeFlag(%q) should fail", value)
+		}
+	}
+}
+
+func TestGraphDOT(t *testing.T) {
+	graph := &db.Subgraph{
+		Root: "Project X",
+		Entities: []db.Entity{
+			{ID: 1, Text: "Alice"},
+			{ID: 2, Text: `Project "X"`},
+		},
+		Relationships: []db.Relationship{{FromID: 1, ToID: 2, Type: "works on"}},
+	}
+
+	got := graphDOT(graph)
+	for _, want := range []string{`n2 [label="Project \"X\""];`, `n1 -> n2 [label="works on"];`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("graphDOT() missing %q:\n%s", want, got)
+		}
+	}
+
+	got = graphMermaid(graph)
+	for _, want := range []string{`n2["Project #quot;X#quot;"]`, `n1 -->|"works on"| n2`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("graphMermaid() missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	var buf bytes.Buffer
+	printTable(&buf, &db.QueryResult{
+		Columns: []string{"text", "n"},
+		Rows:    [][]any{{"Alice", int64(2)}, {"Bob\nSmith", nil}},
+	})
+
+	want := "text       | n\n" +
+		"-----------+-----\n" +
+		"Alice      | 2\n" +
+		`Bob\nSmith | NULL` + "\n" +
+		"(2 rows)\n"
+	if buf.String() != want {
+		t.Errorf("printTable() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v1.2.0", "v1.1.9", true},
+		{"v1.10.0", "v1.9.0", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.1.0", "v1.2.0", false},
+		{"nightly", "v1.0.0", false},
+	}
+	for _, tt := range tests {
+		if got := newerVersion(tt.latest, tt.current); got != tt.want {
+			t.Errorf("newerVersion(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+func TestSelfUpdate(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho new\n")
+	sum := sha256.Sum256(binary)
+	var checksum atomic.Value
+	checksum.Store(hex.EncodeToString(sum[:]))
+	name := fmt.Sprintf("amem-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			fmt.Fprintf(w, `{"tag_name":"v2.0.0","assets":[{"name":%q,"browser_download_url":"%s/bin"},{"name":%q,"browser_download_url":"%s/sum"}]}`,
+				name, srv.URL, name+".sha256", srv.URL)
+		case "/bin":
+			_, _ = w.Write(binary)
+		case "/sum":
+			fmt.Fprintf(w, "%s  %s\n", checksum.Load(), name)
+		}
+	}))
+	defer srv.Close()
+
+	exe := filepath.Join(t.TempDir(), "amem")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := selfUpdate(context.Background(), srv.URL+"/latest", "dev", exe, false); err == nil {
+		t.Error("Expected development builds to refuse to update")
+	}
+	if err := selfUpdate(context.Background(), srv.URL+"/latest", "v1.0.0", exe, true); err != nil {
+		t.Fatalf("selfUpdate --check failed: %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Error("Expected --check to leave the binary alone")
+	}
+
+	// A bad checksum changes nothing
+	checksum.Store(strings.Repeat("0", 64))
+	if err := selfUpdate(context.Background(), srv.URL+"/latest", "v1.0.0", exe, false); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old" {
+		t.Error("Expected a failed update to leave the binary alone")
+	}
+
+	checksum.Store(hex.EncodeToString(sum[:]))
+	if err := selfUpdate(context.Background(), srv.URL+"/latest", "v1.0.0", exe, false); err != nil {
+		t.Fatalf("selfUpdate failed: %v", err)
+	}
+	if data, _ := os.ReadFile(exe); !bytes.Equal(data, binary) {
+		t.Errorf("Expected the binary replaced, got %q", data)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(exe))
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary files left behind, got %d entries", len(entries))
+	}
+}
+
+func TestDownloadLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(bytes.Repeat([]byte("x"), 100))
+	}))
+	defer srv.Close()
+
+	defer func(n int64) { maxDownloadBytes = n }(maxDownloadBytes)
+	maxDownloadBytes = 100
+	if data, err := download(context.Background(), srv.URL); err != nil || len(data) != 100 {
+		t.Fatalf("Expected a download at the limit to succeed, got %d bytes, %v", len(data), err)
+	}
+	maxDownloadBytes = 99
+	if _, err := download(context.Background(), srv.URL); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Errorf("Expected a download over the limit to fail, got %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/urfave/cli/v3"
+)
+
+// manCommand builds the 'man' command, which renders manual pages from the command tree
+func manCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "man",
+		Usage: "Print the amem(1) manual page, or write a page per command to a directory",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Write amem.1 and a page per command, like amem-add-entity.1, to this directory",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			root := cmd.Root()
+			dir := cmd.String("dir")
+			if dir == "" {
+				writeManPage(os.Stdout, []*cli.Command{root}, true)
+				return nil
+			}
+
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return fmt.Errorf("failed to create %s: %w", dir, err)
+			}
+			count := 0
+			var walk func(path []*cli.Command) error
+			walk = func(path []*cli.Command) error {
+				names := make([]string, len(path))
+				for i, c := range path {
+					names[i] = c.Name
+				}
+				file := filepath.Join(dir, strings.Join(names, "-")+".1")
+				f, err := os.Create(file)
+				if err != nil {
+					return fmt.Errorf("failed to create %s: %w", file, err)
+				}
+				writeManPage(f, path, false)
+				if err := f.Close(); err != nil {
+					return fmt.Errorf("failed to write %s: %w", file, err)
+				}
+				count++
+
+				for _, sub := range visibleCommands(path[len(path)-1]) {
+					if err := walk(append(path[:len(path):len(path)], sub)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}
+			if err := walk([]*cli.Command{root}); err != nil {
+				return err
+			}
+			fmt.Printf("✓ Wrote %d manual pages to %s\n", count, dir)
+			return nil
+		},
+	}
+}
+
+// writeManPage writes the roff page for the last command in path. With nested set, the page
+// documents every command below it too; otherwise it lists them with their own pages under SEE ALSO.
+func writeManPage(w io.Writer, path []*cli.Command, nested bool) {
+	cmd := path[len(path)-1]
+	names := make([]string, len(path))
+	for i, c := range path {
+		names[i] = c.Name
+	}
+	name := strings.Join(names, " ")
+	page := strings.Join(names, "-")
+
+	fmt.Fprintf(w, ".TH %s 1 \"\" \"amem %s\" \"User Commands\"\n", strings.ToUpper(page), roffEscape(version))
+	fmt.Fprintf(w, ".SH NAME\n%s \\- %s\n", roffEscape(page), roffEscape(cmd.Usage))
+
+	fmt.Fprintf(w, ".SH SYNOPSIS\n\\fB%s\\fR", roffEscape(name))
+	if len(visibleFlags(cmd)) > 0 {
+		fmt.Fprint(w, " [\\fIoptions\\fR]")
+	}
+	if len(visibleCommands(cmd)) > 0 {
+		fmt.Fprint(w, " \\fIcommand\\fR")
+	}
+	if cmd.ArgsUsage != "" {
+		fmt.Fprintf(w, " %s", roffEscape(cmd.ArgsUsage))
+	}
+	fmt.Fprintln(w)
+
+	if cmd.Description != "" {
+		fmt.Fprintf(w, ".SH DESCRIPTION\n.nf\n%s\n.fi\n", roffText(cmd.Description))
+	}
+
+	if flags := visibleFlags(cmd); len(flags) > 0 {
+		fmt.Fprintln(w, ".SH OPTIONS")
+		writeManFlags(w, flags)
+	}
+
+	subs := visibleCommands(cmd)
+	if len(subs) == 0 {
+		return
+	}
+	fmt.Fprintln(w, ".SH COMMANDS")
+	var walk func(prefix string, commands []*cli.Command)
+	walk = func(prefix string, commands []*cli.Command) {
+		for _, sub := range commands {
+			full := strings.TrimSpace(prefix + " " + sub.Name)
+			fmt.Fprintf(w, ".TP\n\\fB%s\\fR", roffEscape(full))
+			if sub.ArgsUsage != "" {
+				fmt.Fprintf(w, " %s", roffEscape(sub.ArgsUsage))
+			}
+			fmt.Fprintf(w, "\n%s\n", roffEscape(sub.Usage))
+			if !nested {
+				continue
+			}
+			if flags := visibleFlags(sub); len(flags) > 0 {
+				fmt.Fprintln(w, ".RS")
+				writeManFlags(w, flags)
+				fmt.Fprintln(w, ".RE")
+			}
+			walk(full, visibleCommands(sub))
+		}
+	}
+	walk("", subs)
+
+	if !nested {
+		fmt.Fprintln(w, ".SH SEE ALSO")
+		refs := make([]string, len(subs))
+		for i, sub := range subs {
+			refs[i] = fmt.Sprintf("\\fB%s\\-%s\\fR(1)", roffEscape(page), roffEscape(sub.Name))
+		}
+		fmt.Fprintln(w, strings.Join(refs, ", "))
+	}
+}
+
+// writeManFlags writes a tagged paragraph for each flag
+func writeManFlags(w io.Writer, flags []cli.Flag) {
+	for _, f := range flags {
+		var names []string
+		for _, n := range f.Names() {
+			dashes := "\\-\\-"
+			if len(n) == 1 {
+				dashes = "\\-"
+			}
+			names = append(names, "\\fB"+dashes+roffEscape(n)+"\\fR")
+		}
+		label := strings.Join(names, ", ")
+
+		var usage string
+		if doc, ok := f.(cli.DocGenerationFlag); ok {
+			usage = doc.GetUsage()
+			if doc.TakesValue() {
+				label += " \\fI" + doc.TypeName() + "\\fR"
+				if value := strings.Trim(doc.GetValue(), `"`); value != "" && value != "0" {
+					usage += fmt.Sprintf(" (default: %s)", value)
+				}
+			}
+		}
+		fmt.Fprintf(w, ".TP\n%s\n%s\n", label, roffEscape(usage))
+	}
+}
+
+// visibleCommands returns cmd's subcommands that appear in help
+func visibleCommands(cmd *cli.Command) []*cli.Command {
+	var commands []*cli.Command
+	for _, c := range cmd.Commands {
+		if !c.Hidden && c.Name != "help" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+// visibleFlags returns cmd's flags that appear in help, other than --help itself
+func visibleFlags(cmd *cli.Command) []cli.Flag {
+	var flags []cli.Flag
+	for _, f := range cmd.Flags {
+		if v, ok := f.(cli.VisibleFlag); ok && !v.IsVisible() {
+			continue
+		}
+		if f.Names()[0] == "help" {
+			continue
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// roffEscape escapes text for use inside a roff line
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\e`)
+	return strings.ReplaceAll(text, "-", `\-`)
+}
+
+// roffText escapes multi-line text, guarding lines that roff would read as requests
+func roffText(text string) string {
+	lines := strings.Split(roffEscape(text), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, ".") || strings.HasPrefix(line, "'") {
+			lines[i] = `\&` + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
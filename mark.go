@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// markCommand builds the 'mark' command, which records feedback on an observation
+func markCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "mark",
+		Usage: "Mark an observation helpful or stale; helpful ones rank first in searches, stale ones last",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:     "id",
+				Usage:    "Observation ID",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  "helpful",
+				Usage: "Mark the observation helpful",
+			},
+			&cli.BoolFlag{
+				Name:  "stale",
+				Usage: "Mark the observation stale, a candidate for 'amem doctor --stale'",
+			},
+			&cli.BoolFlag{
+				Name:  "clear",
+				Usage: "Remove the observation's marks",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			id := int64(cmd.Int("id"))
+			var chosen []string
+			for _, name := range []string{"helpful", "stale", "clear"} {
+				if cmd.Bool(name) {
+					chosen = append(chosen, name)
+				}
+			}
+			if len(chosen) != 1 {
+				return fmt.Errorf("specify exactly one of --helpful, --stale, or --clear")
+			}
+
+			return withWriteDB(func(database *db.DB) error {
+				if chosen[0] == "clear" {
+					if err := database.ClearMarks(id); err != nil {
+						return err
+					}
+					fmt.Printf("Cleared marks on observation ID %d\n", id)
+					return nil
+				}
+				if err := database.Mark(id, chosen[0]); err != nil {
+					return err
+				}
+				fmt.Printf("Marked observation ID %d %s\n", id, chosen[0])
+				return nil
+			})
+		},
+	}
+}
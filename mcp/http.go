@@ -0,0 +1,184 @@
+package mcp
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+
+	"amem/origin"
+)
+
+// maxMessageBytes bounds the size of a single HTTP request body.
+const maxMessageBytes = 4 * 1024 * 1024
+
+// HTTPHandler serves MCP over HTTP:
+//   - POST /mcp is the streamable HTTP transport (one JSON-RPC message or batch per request)
+//   - GET /sse and POST /messages are the older HTTP+SSE transport
+func (s *Server) HTTPHandler() http.Handler {
+	sessions := &sseSessions{streams: map[string]chan []byte{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mcp", s.handleStreamable)
+	mux.HandleFunc("/sse", sessions.handleStream)
+	mux.HandleFunc("/messages", func(w http.ResponseWriter, r *http.Request) {
+		sessions.handleMessage(s, w, r)
+	})
+	return mux
+}
+
+func (s *Server) handleStreamable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		// No server-initiated messages, so there is no stream to GET
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !checkMessageRequest(w, r) {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxMessageBytes {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	out := s.Handle(r.Context(), body)
+	if out == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/event-stream") && !strings.Contains(accept, "application/json") {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", out)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(out)
+}
+
+// sseSessions tracks open streams for the HTTP+SSE transport, where responses
+// to POSTed messages are delivered on the client's event stream.
+type sseSessions struct {
+	mu      sync.Mutex
+	streams map[string]chan []byte
+}
+
+func (ss *sseSessions) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+	stream := make(chan []byte, 16)
+	ss.mu.Lock()
+	ss.streams[id] = stream
+	ss.mu.Unlock()
+	defer func() {
+		ss.mu.Lock()
+		delete(ss.streams, id)
+		ss.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	_, _ = fmt.Fprintf(w, "event: endpoint\ndata: /messages?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case out := <-stream:
+			_, _ = fmt.Fprintf(w, "event: message\ndata: %s\n\n", out)
+			flusher.Flush()
+		}
+	}
+}
+
+func (ss *sseSessions) handleMessage(s *Server, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ss.mu.Lock()
+	stream, ok := ss.streams[r.URL.Query().Get("sessionId")]
+	ss.mu.Unlock()
+	if !ok {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	if !checkMessageRequest(w, r) {
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageBytes+1))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxMessageBytes {
+		http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if out := s.Handle(r.Context(), body); out != nil {
+		select {
+		case stream <- out:
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// checkMessageRequest rejects a POSTed message, writing the error to w, if a page on
+// another site sent it or its body isn't declared as JSON. Pages can only send JSON to
+// another site after a CORS preflight, which this server never approves.
+func checkMessageRequest(w http.ResponseWriter, r *http.Request) bool {
+	if err := origin.CheckOrigin(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		http.Error(w, "the body must be sent with Content-Type application/json", http.StatusUnsupportedMediaType)
+		return false
+	}
+	return true
+}
+
+func newSessionID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,231 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"amem/db"
+	"amem/tools"
+)
+
+// ProtocolVersions lists supported MCP protocol versions, newest first.
+var ProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// JSON-RPC error codes
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers MCP requests using the amem tools.
+type Server struct {
+	db      *db.DB
+	version string
+}
+
+// NewServer creates an MCP server backed by database.
+// version is reported to clients as the server version.
+func NewServer(database *db.DB, version string) *Server {
+	return &Server{db: database, version: version}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type toolContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type toolResult struct {
+	Content []toolContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// Handle processes one JSON-RPC message (or batch) and returns the encoded response.
+// Returns nil for notifications, which get no response.
+func (s *Server) Handle(ctx context.Context, message []byte) []byte {
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return encode(errorResponse(nil, codeParseError, "parse error"))
+		}
+		var responses []*response
+		for _, m := range batch {
+			if r := s.handleOne(ctx, m); r != nil {
+				responses = append(responses, r)
+			}
+		}
+		if len(responses) == 0 {
+			return nil
+		}
+		return encode(responses)
+	}
+
+	r := s.handleOne(ctx, trimmed)
+	if r == nil {
+		return nil
+	}
+	return encode(r)
+}
+
+func (s *Server) handleOne(ctx context.Context, message []byte) *response {
+	var req request
+	if err := json.Unmarshal(message, &req); err != nil {
+		return errorResponse(nil, codeParseError, "parse error")
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, codeInvalidRequest, "invalid request")
+	}
+
+	// Notifications have no ID and get no response
+	isNotification := len(req.ID) == 0 || string(req.ID) == "null"
+
+	result, rpcErr := s.dispatch(ctx, req)
+	if isNotification {
+		return nil
+	}
+	if rpcErr != nil {
+		return &response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func (s *Server) dispatch(ctx context.Context, req request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		var params struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		return map[string]interface{}{
+			"protocolVersion": negotiateVersion(params.ProtocolVersion),
+			"capabilities": map[string]interface{}{
+				"tools": map[string]interface{}{},
+			},
+			"serverInfo": map[string]interface{}{
+				"name":    "amem",
+				"version": s.version,
+			},
+		}, nil
+
+	case "notifications/initialized", "notifications/cancelled":
+		return map[string]interface{}{}, nil
+
+	case "ping":
+		return map[string]interface{}{}, nil
+
+	case "tools/list":
+		list := make([]map[string]interface{}, 0, len(tools.All))
+		for _, t := range tools.All {
+			list = append(list, map[string]interface{}{
+				"name":        t.Name,
+				"description": t.Description,
+				"inputSchema": t.Parameters,
+			})
+		}
+		return map[string]interface{}{"tools": list}, nil
+
+	case "tools/call":
+		var params struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil || params.Name == "" {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "invalid params: tool name is required"}
+		}
+		if _, ok := tools.Find(params.Name); !ok {
+			return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown tool '%s'", params.Name)}
+		}
+		// Tool failures are reported in the result so the model can see and react to them
+		return s.callTool(params.Name, params.Arguments), nil
+	}
+
+	return nil, &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+}
+
+func (s *Server) callTool(name string, args json.RawMessage) toolResult {
+	result, err := tools.Call(s.db, name, args)
+	if err != nil {
+		return toolResult{Content: []toolContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return toolResult{Content: []toolContent{{Type: "text", Text: err.Error()}}, IsError: true}
+	}
+	return toolResult{Content: []toolContent{{Type: "text", Text: string(data)}}}
+}
+
+// ServeStdio serves newline-delimited JSON-RPC messages from r, writing responses to w,
+// until r is exhausted or ctx is cancelled.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if out := s.Handle(ctx, line); out != nil {
+			if _, err := w.Write(append(out, '\n')); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read request: %w", err)
+	}
+	return nil
+}
+
+// negotiateVersion returns the client's version if supported, otherwise the newest supported version.
+func negotiateVersion(requested string) string {
+	for _, v := range ProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return ProtocolVersions[0]
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+func encode(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprintf(`{"jsonrpc":"2.0","id":null,"error":{"code":-32603,"message":%q}}`, err.Error()))
+	}
+	return data
+}
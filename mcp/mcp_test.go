@@ -0,0 +1,239 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"amem/db"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	database, err := db.Init(t.TempDir()+"/test_mcp.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return NewServer(database, "test")
+}
+
+func call(t *testing.T, s *Server, message string) map[string]interface{} {
+	t.Helper()
+	out := s.Handle(context.Background(), []byte(message))
+	if out == nil {
+		t.Fatalf("Expected a response to %s", message)
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("Invalid response JSON %s: %v", out, err)
+	}
+	return resp
+}
+
+func TestInitialize(t *testing.T) {
+	s := newTestServer(t)
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26"}}`)
+	result := resp["result"].(map[string]interface{})
+	if result["protocolVersion"] != "2025-03-26" {
+		t.Errorf("Expected requested protocol version, got %v", result["protocolVersion"])
+	}
+	if _, ok := result["capabilities"].(map[string]interface{})["tools"]; !ok {
+		t.Error("Expected tools capability")
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"initialize","params":{"protocolVersion":"1999-01-01"}}`)
+	if resp["result"].(map[string]interface{})["protocolVersion"] != ProtocolVersions[0] {
+		t.Error("Expected newest protocol version for unsupported request")
+	}
+}
+
+func TestNotificationHasNoResponse(t *testing.T) {
+	s := newTestServer(t)
+	if out := s.Handle(context.Background(), []byte(`{"jsonrpc":"2.0","method":"notifications/initialized"}`)); out != nil {
+		t.Errorf("Expected no response to notification, got %s", out)
+	}
+}
+
+func TestToolsListAndCall(t *testing.T) {
+	s := newTestServer(t)
+
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	list := resp["result"].(map[string]interface{})["tools"].([]interface{})
+	if len(list) == 0 {
+		t.Fatal("Expected tools to be listed")
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"amem_add_observation","arguments":{"entity":"Alice","text":"Likes tea"}}}`)
+	if resp["result"].(map[string]interface{})["isError"] == true {
+		t.Fatalf("add_observation failed: %v", resp)
+	}
+
+	resp = call(t, s, `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"amem_search","arguments":{"keywords":["tea"]}}}`)
+	content := resp["result"].(map[string]interface{})["content"].([]interface{})
+	text := content[0].(map[string]interface{})["text"].(string)
+	if !strings.Contains(text, "Likes tea") {
+		t.Errorf("Expected search to find observation, got %s", text)
+	}
+
+	// Tool errors are reported in the result
+	resp = call(t, s, `{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"amem_add_observation","arguments":{"entity":"Alice"}}}`)
+	if resp["result"].(map[string]interface{})["isError"] != true {
+		t.Errorf("Expected isError for missing argument, got %v", resp)
+	}
+}
+
+func TestUnknownMethod(t *testing.T) {
+	s := newTestServer(t)
+	resp := call(t, s, `{"jsonrpc":"2.0","id":1,"method":"resources/list"}`)
+	if resp["error"].(map[string]interface{})["code"].(float64) != codeMethodNotFound {
+		t.Errorf("Expected method not found, got %v", resp)
+	}
+}
+
+func TestParseError(t *testing.T) {
+	s := newTestServer(t)
+	resp := call(t, s, `{not json`)
+	if resp["error"].(map[string]interface{})["code"].(float64) != codeParseError {
+		t.Errorf("Expected parse error, got %v", resp)
+	}
+}
+
+func TestServeStdio(t *testing.T) {
+	s := newTestServer(t)
+	in := strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}` + "\n" +
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}` + "\n" +
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}` + "\n")
+	var out bytes.Buffer
+
+	if err := s.ServeStdio(context.Background(), in, &out); err != nil {
+		t.Fatalf("ServeStdio failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Errorf("Expected 2 responses, got %d: %s", len(lines), out.String())
+	}
+}
+
+func TestStreamableHTTP(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t).HTTPHandler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON response, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	resp, err = http.Post(ts.URL+"/mcp", "application/json", strings.NewReader(`{"jsonrpc":"2.0","method":"notifications/initialized"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202 for notification, got %d", resp.StatusCode)
+	}
+
+	resp, err = http.Get(ts.URL + "/mcp")
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", resp.StatusCode)
+	}
+}
+
+func TestStreamableHTTPRejectsOtherSites(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t).HTTPHandler())
+	defer ts.Close()
+
+	post := func(contentType, origin string) int {
+		t.Helper()
+		req, err := http.NewRequest("POST", ts.URL+"/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		req.Header.Set("Content-Type", contentType)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Pages on other sites can send text/plain without a preflight
+	if status := post("text/plain", ""); status != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a text/plain body, got %d", status)
+	}
+	if status := post("application/json", "https://evil.example"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for another site's Origin, got %d", status)
+	}
+	if status := post("application/json", ts.URL); status != http.StatusOK {
+		t.Errorf("Expected 200 for the server's own Origin, got %d", status)
+	}
+}
+
+func TestSSETransport(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t).HTTPHandler())
+	defer ts.Close()
+
+	stream, err := http.Get(ts.URL + "/sse")
+	if err != nil {
+		t.Fatalf("GET /sse failed: %v", err)
+	}
+	defer func() { _ = stream.Body.Close() }()
+	reader := bufio.NewReader(stream.Body)
+
+	readEvent := func() (string, string) {
+		var event, data string
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Failed to read event: %v", err)
+			}
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				return event, data
+			}
+		}
+	}
+
+	event, endpoint := readEvent()
+	if event != "endpoint" || !strings.HasPrefix(endpoint, "/messages?sessionId=") {
+		t.Fatalf("Expected endpoint event, got %s %s", event, endpoint)
+	}
+
+	resp, err := http.Post(ts.URL+endpoint, "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"ping"}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202, got %d", resp.StatusCode)
+	}
+
+	event, data := readEvent()
+	if event != "message" || !strings.Contains(data, `"id":7`) {
+		t.Errorf("Expected response on stream, got %s %s", event, data)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// mergeCommand builds the 'merge' command, which merges one entity into another
+func mergeCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "merge",
+		Usage: "Merge records into one another",
+		Commands: []*cli.Command{
+			{
+				Name:      "entity",
+				Usage:     "Merge an entity's observations, relationships, and note into another entity",
+				ArgsUsage: "<entity name>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:     "into",
+						Usage:    "Name of the entity to merge into",
+						Required: true,
+					},
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "List what the merge would re-point, drop, and collapse without merging",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					entityName := cmd.Args().First()
+					into := cmd.String("into")
+					if entityName == "" {
+						return fmt.Errorf("entity name is required")
+					}
+
+					if cmd.Bool("dry-run") {
+						return withDB(func(database *db.DB) error {
+							preview, err := database.PreviewMergeInto(entityName, into)
+							if err != nil {
+								return err
+							}
+							printMergePreview(preview)
+							return nil
+						})
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						if err := database.MergeEntityInto(entityName, into); err != nil {
+							return err
+						}
+						fmt.Printf("Merged entity '%s' into '%s'\n", entityName, into)
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// printMergePreview prints what a merge would change
+func printMergePreview(p *db.MergePreview) {
+	fmt.Printf("Merging '%s' [%d] into '%s' [%d] would:\n", p.Merge.Text, p.Merge.ID, p.Keep.Text, p.Keep.ID)
+	fmt.Printf("  Re-point %d observations\n", len(p.Observations))
+	for _, o := range p.Observations {
+		fmt.Printf("    %s\n", o.Format(true))
+	}
+	fmt.Printf("  Re-point %d relationships\n", len(p.Repointed))
+	for _, r := range p.Repointed {
+		fmt.Printf("    %s\n", r.Format(true))
+	}
+	fmt.Printf("  Drop %d relationships between the two\n", len(p.Dropped))
+	for _, r := range p.Dropped {
+		fmt.Printf("    %s\n", r.Format(true))
+	}
+	fmt.Printf("  Collapse %d duplicate relationships\n", len(p.Collapsed))
+	for _, c := range p.Collapsed {
+		fmt.Printf("    %s into [%d]\n", c.Format(true), c.Into)
+	}
+	if p.NoteMoved {
+		fmt.Printf("  Move the note of '%s'\n", p.Merge.Text)
+	}
+}
@@ -0,0 +1,49 @@
+// Package origin keeps web pages on other sites from using amem's HTTP endpoints through
+// a visitor's browser, with cross-site requests or by rebinding their DNS name to a local
+// server.
+package origin
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// CheckOrigin returns an error if r was sent by a web page on another site. Clients other
+// than browsers send no Origin header, so they pass.
+func CheckOrigin(r *http.Request) error {
+	o := r.Header.Get("Origin")
+	if o == "" {
+		return nil
+	}
+	u, err := url.Parse(o)
+	if err != nil || u.Host == "" || !strings.EqualFold(u.Host, r.Host) {
+		return errors.New("requests from other sites are not allowed")
+	}
+	return nil
+}
+
+// CheckLocal returns an error unless r was sent to this machine by name, as well as
+// passing CheckOrigin. A page whose DNS name was rebound to 127.0.0.1 is on the same
+// site as the server, but still sends its own name as the Host.
+func CheckLocal(r *http.Request) error {
+	if !IsLoopback(r.Host) {
+		return errors.New("requests must be sent to localhost or a loopback address")
+	}
+	return CheckOrigin(r)
+}
+
+// IsLoopback reports whether host, with or without a port, names the loopback interface.
+func IsLoopback(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
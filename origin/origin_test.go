@@ -0,0 +1,50 @@
+package origin
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCheckLocal(t *testing.T) {
+	tests := []struct {
+		host   string
+		origin string
+		ok     bool
+	}{
+		{"127.0.0.1:8080", "", true},
+		{"localhost:8080", "http://localhost:8080", true},
+		{"[::1]:8080", "", true},
+		{"LOCALHOST", "", true},
+		// A rebound name still arrives as the Host
+		{"evil.example:8080", "http://evil.example:8080", false},
+		{"192.168.1.5:8080", "", false},
+		{"127.0.0.1:8080", "http://evil.example", false},
+		{"127.0.0.1:8080", "null", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/mcp", nil)
+		r.Host = tt.host
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if err := CheckLocal(r); (err == nil) != tt.ok {
+			t.Errorf("CheckLocal(Host %q, Origin %q) = %v, want ok %v", tt.host, tt.origin, err, tt.ok)
+		}
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	r := httptest.NewRequest("POST", "/mcp", nil)
+	r.Host = "memory.example.com"
+	if err := CheckOrigin(r); err != nil {
+		t.Errorf("Expected a request without an Origin to pass, got %v", err)
+	}
+	r.Header.Set("Origin", "https://memory.example.com")
+	if err := CheckOrigin(r); err != nil {
+		t.Errorf("Expected a same-site request to pass, got %v", err)
+	}
+	r.Header.Set("Origin", "https://evil.example")
+	if err := CheckOrigin(r); err == nil {
+		t.Error("Expected a cross-site request to fail")
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"amem/db"
+	"amem/query"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// queryCommand builds the 'query' command, which searches with the query language
+func queryCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "query",
+		Usage:     "Search with a query like 'entity:Alice AND (coffee OR tea) since:7d type:knows'",
+		ArgsUsage: "<expression>",
+		Description: `Keywords match anywhere in a record's text and are combined with AND, OR,
+NOT, and parentheses. Adjacent keywords are ANDed, and "-word" means NOT word.
+Quote phrases with spaces: "green tea".
+
+Fields narrow the search:
+  entity:NAME   entities, observations about an entity, and relationships involving it
+  from:NAME     relationships from an entity
+  to:NAME       relationships to an entity
+  type:TYPE     relationships of a type
+  since:WHEN    records created since a duration ago (2h, 7d, 2w) or a date (2025-01-31)
+  before:WHEN   records created before a duration ago or a date
+
+since: and before: can't be used under OR or NOT.`,
+		Flags: append([]cli.Flag{
+			&cli.BoolFlag{
+				Name:  "with-ids",
+				Usage: "Show database IDs with results",
+			},
+			&cli.BoolFlag{
+				Name:  "explain",
+				Usage: "Print each query's SQL, SQLite query plan, and time taken to stderr",
+			},
+			&cli.BoolFlag{
+				Name:  "include-archived",
+				Usage: "Include observations archived with 'amem archive'",
+			},
+		}, outputFlags()...),
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			useOutputFlags(cmd)
+
+			expr := strings.Join(cmd.Args().Slice(), " ")
+			if strings.TrimSpace(expr) == "" {
+				return fmt.Errorf("usage: amem query <expression>")
+			}
+			q, err := query.Parse(expr, time.Now())
+			if err != nil {
+				return fmt.Errorf("invalid query: %w", err)
+			}
+
+			return withSearchDB(cmd, func(database *db.DB) error {
+				entities, observations, relationships, err := database.RunQuery(q)
+				if err != nil {
+					return err
+				}
+				view.FormatAll(entities, observations, relationships, cmd.Bool("with-ids"))
+				return nil
+			})
+		},
+	}
+}
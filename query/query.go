@@ -0,0 +1,385 @@
+// Package query parses amem's search language, which combines keywords with
+// AND, OR, NOT, and parentheses, and filters records with fields:
+//
+//	entity:Alice AND (coffee OR tea) since:7d type:knows
+//
+// Adjacent terms are ANDed. Operators must be uppercase; "-word" is NOT word.
+// Phrases with spaces are quoted: "green tea", entity:"Project X".
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Fields that filter by a record's text.
+const (
+	// FieldEntity matches entities, observations about an entity, and relationships involving one
+	FieldEntity = "entity"
+	// FieldFrom matches relationships from an entity
+	FieldFrom = "from"
+	// FieldTo matches relationships to an entity
+	FieldTo = "to"
+	// FieldType matches relationships of a type
+	FieldType = "type"
+)
+
+// Fields that filter by when a record was created. They can only be ANDed with the rest of a query.
+const (
+	fieldSince  = "since"
+	fieldBefore = "before"
+)
+
+// Expr is a node of a parsed query: And, Or, Not, Term, or Field.
+type Expr interface {
+	expr()
+}
+
+// And matches records both sides match.
+type And struct{ Left, Right Expr }
+
+// Or matches records either side matches.
+type Or struct{ Left, Right Expr }
+
+// Not matches records Expr doesn't.
+type Not struct{ Expr Expr }
+
+// Term is a keyword, matched anywhere in a record's text.
+type Term struct{ Text string }
+
+// Field matches records whose Name (FieldEntity, FieldFrom, FieldTo, or FieldType) contains Value.
+type Field struct{ Name, Value string }
+
+func (And) expr()   {}
+func (Or) expr()    {}
+func (Not) expr()   {}
+func (Term) expr()  {}
+func (Field) expr() {}
+
+// Query is a parsed query.
+type Query struct {
+	// Expr is the query's condition, or nil if it only has time filters
+	Expr Expr
+	// Since and Before bound when matching records were created; zero means unbounded
+	Since, Before time.Time
+}
+
+// Parse parses a query, resolving relative times like since:7d against now.
+func Parse(input string, now time.Time) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+
+	q := &Query{}
+	q.Expr, err = q.extractTimes(expr, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNoTimes(q.Expr); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// extractTimes moves since: and before: fields ANDed at the top of expr into q, returning what's left.
+func (q *Query) extractTimes(expr Expr, now time.Time) (Expr, error) {
+	switch e := expr.(type) {
+	case And:
+		left, err := q.extractTimes(e.Left, now)
+		if err != nil {
+			return nil, err
+		}
+		right, err := q.extractTimes(e.Right, now)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case left == nil:
+			return right, nil
+		case right == nil:
+			return left, nil
+		}
+		return And{left, right}, nil
+	case Field:
+		if e.Name != fieldSince && e.Name != fieldBefore {
+			return e, nil
+		}
+		t, err := ParseTime(e.Value, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s:%s: %w", e.Name, e.Value, err)
+		}
+		if e.Name == fieldSince {
+			q.Since = t
+		} else {
+			q.Before = t
+		}
+		return nil, nil
+	}
+	return expr, nil
+}
+
+// checkNoTimes returns an error if since: or before: is left in expr, under OR or NOT.
+func checkNoTimes(expr Expr) error {
+	switch e := expr.(type) {
+	case And:
+		return firstErr(checkNoTimes(e.Left), checkNoTimes(e.Right))
+	case Or:
+		return firstErr(checkNoTimes(e.Left), checkNoTimes(e.Right))
+	case Not:
+		return checkNoTimes(e.Expr)
+	case Field:
+		if e.Name == fieldSince || e.Name == fieldBefore {
+			return fmt.Errorf("%s: can't be used with OR or NOT", e.Name)
+		}
+	}
+	return nil
+}
+
+// firstErr returns the first non-nil error.
+func firstErr(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseTime parses a time as a duration before now (2h, 7d, 2w) or a local date/time
+// (2025-01-31, 2025-01-31 14:00, or RFC 3339).
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	if n := len(value); n > 1 && (value[n-1] == 'd' || value[n-1] == 'w') {
+		if count, err := strconv.Atoi(value[:n-1]); err == nil && count >= 0 {
+			days := count
+			if value[n-1] == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("duration cannot be negative")
+		}
+		return now.Add(-d), nil
+	}
+
+	for _, layout := range []string{time.RFC3339, time.DateTime, "2006-01-02 15:04", time.DateOnly} {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("use a duration like 2h or 7d, or a date like 2025-01-31")
+}
+
+// token is a lexed piece of a query.
+type token struct {
+	// kind is one of "(", ")", "AND", "OR", "NOT", "term", or "field"
+	kind  string
+	text  string
+	field string
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case "term":
+		return strconv.Quote(t.text)
+	case "field":
+		return t.field + ":" + strconv.Quote(t.text)
+	}
+	return "'" + t.kind + "'"
+}
+
+// knownFields are the field names a query may use.
+var knownFields = map[string]bool{
+	FieldEntity: true, FieldFrom: true, FieldTo: true, FieldType: true, fieldSince: true, fieldBefore: true,
+}
+
+// lex splits a query into tokens.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	i := 0
+
+	// readQuoted reads a quoted string starting at runes[i], which is '"'
+	readQuoted := func() (string, error) {
+		end := i + 1
+		for end < len(runes) && runes[end] != '"' {
+			end++
+		}
+		if end == len(runes) {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		s := string(runes[i+1 : end])
+		i = end + 1
+		return s, nil
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, token{kind: string(r)})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && runes[i+1] != ')':
+			tokens = append(tokens, token{kind: "NOT"})
+			i++
+		case r == '"':
+			s, err := readQuoted()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: "term", text: s})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`()"`, runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if name, value, ok := strings.Cut(word, ":"); ok && name != "" && isLetters(name) {
+				if !knownFields[strings.ToLower(name)] {
+					return nil, fmt.Errorf("unknown field %q (use entity, from, to, type, since, or before)", name)
+				}
+				if value == "" && i < len(runes) && runes[i] == '"' {
+					var err error
+					if value, err = readQuoted(); err != nil {
+						return nil, err
+					}
+				}
+				if value == "" {
+					return nil, fmt.Errorf("%s: needs a value", name)
+				}
+				tokens = append(tokens, token{kind: "field", field: strings.ToLower(name), text: value})
+				continue
+			}
+
+			switch word {
+			case "AND", "OR", "NOT":
+				tokens = append(tokens, token{kind: word})
+			default:
+				tokens = append(tokens, token{kind: "term", text: word})
+			}
+		}
+	}
+	return tokens, nil
+}
+
+// isLetters reports whether s is all letters.
+func isLetters(s string) bool {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// parser builds an Expr from tokens by recursive descent. From loosest to tightest,
+// the grammar is: or = and {OR and}; and = unary {[AND] unary}; unary = NOT unary | primary;
+// primary = ( or ) | term | field.
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].kind
+	}
+	return ""
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "OR" {
+		p.pos++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = Or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek() {
+		case "AND":
+			p.pos++
+		case "NOT", "(", "term", "field":
+			// Adjacent terms are ANDed
+		default:
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = And{left, right}
+	}
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.peek() == "NOT" {
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{e}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	if p.pos == len(p.tokens) {
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	t := p.tokens[p.pos]
+	p.pos++
+
+	switch t.kind {
+	case "(":
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing ')'")
+		}
+		p.pos++
+		return e, nil
+	case "term":
+		return Term{t.text}, nil
+	case "field":
+		return Field{Name: t.field, Value: t.text}, nil
+	}
+	return nil, fmt.Errorf("unexpected %s", t)
+}
@@ -0,0 +1,94 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		input string
+		want  *Query
+	}{
+		{"coffee", &Query{Expr: Term{"coffee"}}},
+		{"coffee tea", &Query{Expr: And{Term{"coffee"}, Term{"tea"}}}},
+		{"coffee OR tea", &Query{Expr: Or{Term{"coffee"}, Term{"tea"}}}},
+		{"a OR b c", &Query{Expr: Or{Term{"a"}, And{Term{"b"}, Term{"c"}}}}},
+		{"(a OR b) c", &Query{Expr: And{Or{Term{"a"}, Term{"b"}}, Term{"c"}}}},
+		{"NOT coffee", &Query{Expr: Not{Term{"coffee"}}}},
+		{"-coffee tea", &Query{Expr: And{Not{Term{"coffee"}}, Term{"tea"}}}},
+		{"e-mail", &Query{Expr: Term{"e-mail"}}},
+		{"and or", &Query{Expr: And{Term{"and"}, Term{"or"}}}},
+		{`"green tea"`, &Query{Expr: Term{"green tea"}}},
+		{`entity:"Project X"`, &Query{Expr: Field{FieldEntity, "Project X"}}},
+		{
+			"entity:Alice AND (coffee OR tea) since:7d type:knows",
+			&Query{
+				Expr:  And{And{Field{FieldEntity, "Alice"}, Or{Term{"coffee"}, Term{"tea"}}}, Field{FieldType, "knows"}},
+				Since: now.AddDate(0, 0, -7),
+			},
+		},
+		{"since:2025-03-01 before:2h", &Query{Since: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC), Before: now.Add(-2 * time.Hour)}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input, now)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]string{
+		"":                      "empty query",
+		"(coffee":               "missing ')'",
+		"coffee)":               "unexpected ')'",
+		"coffee OR":             "unexpected end",
+		`"green tea`:            "unterminated quote",
+		"color:red":             "unknown field",
+		"entity:":               "needs a value",
+		"since:yesterday":       "invalid since:yesterday",
+		"coffee OR since:2h":    "can't be used with OR or NOT",
+		"NOT before:2025-01-01": "can't be used with OR or NOT",
+	}
+	for input, want := range tests {
+		_, err := Parse(input, now)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want it to mention %q", input, err, want)
+		}
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := map[string]time.Time{
+		"2h":               now.Add(-2 * time.Hour),
+		"7d":               time.Date(2025, 3, 3, 12, 0, 0, 0, time.UTC),
+		"2w":               time.Date(2025, 2, 24, 12, 0, 0, 0, time.UTC),
+		"2025-03-01":       time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		"2025-03-01 14:30": time.Date(2025, 3, 1, 14, 30, 0, 0, time.UTC),
+	}
+	for value, want := range tests {
+		got, err := ParseTime(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseTime(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"d", "-2h", "-3d", "soon"} {
+		if _, err := ParseTime(value, now); err == nil {
+			t.Errorf("ParseTime(%q) should fail", value)
+		}
+	}
+}
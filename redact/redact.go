@@ -0,0 +1,77 @@
+// Package redact masks sensitive values, like email addresses and API keys, in text
+// that leaves the database as plaintext, so exports can be shared safely.
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// builtins are the rules that can be named without a pattern.
+var builtins = map[string]string{
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	// Common key prefixes (OpenAI, Anthropic, GitHub, Slack, AWS, Google), then anything
+	// labeled as a key, token, or secret
+	"api_key": `\b(?:sk-[A-Za-z0-9_-]{16,}|gh[pousr]_[A-Za-z0-9]{20,}|xox[abpr]-[A-Za-z0-9-]{10,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35})\b|(?i:\b(?:api[_-]?key|token|secret|password)\b\s*[:=]\s*)\S+`,
+	"phone":   `(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]?\d{3}[\s.-]?\d{4}\b`,
+}
+
+// Builtins returns the names of the rules that need no pattern, sorted.
+func Builtins() []string {
+	names := make([]string, 0, len(builtins))
+	for name := range builtins {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Rule replaces every match of a regular expression.
+type Rule struct {
+	// Name labels the rule. Without a Pattern, it names a builtin rule
+	Name string `json:"name"`
+	// Pattern is the regular expression to redact
+	Pattern string `json:"pattern,omitempty"`
+	// Replacement replaces each match, "[REDACTED <name>]" by default
+	Replacement string `json:"replacement,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// Rules are applied in order.
+type Rules []Rule
+
+// Validate checks that every rule is usable, compiling its pattern.
+func (rs Rules) Validate() error {
+	for i := range rs {
+		r := &rs[i]
+		pattern := r.Pattern
+		if pattern == "" {
+			builtin, ok := builtins[r.Name]
+			if !ok {
+				return fmt.Errorf("rule %q has no pattern and isn't a builtin (%s)", r.Name, strings.Join(Builtins(), ", "))
+			}
+			pattern = builtin
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern for rule %q: %w", r.Name, err)
+		}
+		r.re = re
+	}
+	return nil
+}
+
+// Apply returns text with every rule's matches replaced. Rules must be validated first.
+func (rs Rules) Apply(text string) string {
+	for _, r := range rs {
+		replacement := r.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED " + r.Name + "]"
+		}
+		text = r.re.ReplaceAllLiteralString(text, replacement)
+	}
+	return text
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// relatedCommand builds the 'related' command, which finds the neighbourhood of an observation by embedding similarity
+func relatedCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "related",
+		Usage: "Find the observations and entities most similar in meaning to an observation (needs 'amem index --embeddings')",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:     "id",
+				Usage:    "Observation ID",
+				Required: true,
+			},
+			&cli.IntFlag{
+				Name:  "limit",
+				Usage: "Most similar observations to show",
+				Value: 10,
+			},
+			&cli.BoolFlag{
+				Name:  "with-ids",
+				Usage: "Include IDs in output",
+			},
+			&cli.BoolFlag{
+				Name:  "include-archived",
+				Usage: "Include archived observations",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the results as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			id := int64(cmd.Int("id"))
+			limit := int(cmd.Int("limit"))
+			if limit < 1 {
+				return fmt.Errorf("--limit must be at least 1")
+			}
+
+			return withDB(func(database *db.DB) error {
+				database.SetIncludeArchived(cmd.Bool("include-archived"))
+				related, err := database.RelatedTo(id, limit)
+				if errors.Is(err, db.ErrNotFound) {
+					if exists, _ := database.ObservationExists(id); exists {
+						return fmt.Errorf("observation %d isn't embedded yet: run 'amem index --embeddings'", id)
+					}
+				}
+				if err != nil {
+					return err
+				}
+
+				if cmd.Bool("json") {
+					data, err := json.MarshalIndent(related, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal related records: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+
+				view.FormatRelated(related, cmd.Bool("with-ids"))
+				return nil
+			})
+		},
+	}
+}
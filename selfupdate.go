@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// latestReleaseURL is the GitHub API endpoint describing the newest release
+var latestReleaseURL = "https://api.github.com/repos/mybuddymichael/amem/releases/latest"
+
+// maxDownloadBytes bounds what self-update reads from any one download, so a bad
+// server can't fill memory
+var maxDownloadBytes int64 = 256 << 20
+
+// githubRelease is the part of a GitHub release self-update reads
+type githubRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+// assetURL returns the download URL of the release's asset with the given name
+func (r *githubRelease) assetURL(name string) (string, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL, true
+		}
+	}
+	return "", false
+}
+
+// selfUpdateCommand builds the 'self-update' command, which replaces the binary with the latest release
+func selfUpdateCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "self-update",
+		Usage: "Replace this binary with the latest release from GitHub, after checking its SHA-256 checksum",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "check",
+				Usage: "Only report whether a newer release is available",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			exe, err := os.Executable()
+			if err != nil {
+				return fmt.Errorf("failed to find this binary: %w", err)
+			}
+			if exe, err = filepath.EvalSymlinks(exe); err != nil {
+				return fmt.Errorf("failed to find this binary: %w", err)
+			}
+			if strings.Contains(exe, "/Cellar/") {
+				return fmt.Errorf("amem was installed with Homebrew; run 'brew upgrade amem' instead")
+			}
+			return selfUpdate(ctx, latestReleaseURL, version, exe, cmd.Bool("check"))
+		},
+	}
+}
+
+// selfUpdate replaces the binary at exe with the release at releaseURL if it's newer than current
+func selfUpdate(ctx context.Context, releaseURL, current, exe string, checkOnly bool) error {
+	if _, ok := parseVersion(current); !ok {
+		return fmt.Errorf("this is a development build (%s); install a release to self-update", current)
+	}
+
+	var release githubRelease
+	data, err := download(ctx, releaseURL)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &release); err != nil {
+		return fmt.Errorf("failed to read release: %w", err)
+	}
+	if !newerVersion(release.TagName, current) {
+		fmt.Printf("✓ amem %s is the latest version\n", current)
+		return nil
+	}
+	if checkOnly {
+		fmt.Printf("amem %s is available (this is %s). Run 'amem self-update' to install it.\n", release.TagName, current)
+		return nil
+	}
+
+	name := fmt.Sprintf("amem-%s-%s", runtime.GOOS, runtime.GOARCH)
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	binaryURL, ok := release.assetURL(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", release.TagName, runtime.GOOS, runtime.GOARCH)
+	}
+	sumURL, ok := release.assetURL(name + ".sha256")
+	if !ok {
+		return fmt.Errorf("release %s has no checksum for %s, so it can't be verified", release.TagName, name)
+	}
+
+	sum, err := download(ctx, sumURL)
+	if err != nil {
+		return err
+	}
+	fields := strings.Fields(string(sum))
+	if len(fields) == 0 {
+		return fmt.Errorf("checksum for %s is empty", name)
+	}
+	binary, err := download(ctx, binaryURL)
+	if err != nil {
+		return err
+	}
+	actual := sha256.Sum256(binary)
+	if !strings.EqualFold(hex.EncodeToString(actual[:]), fields[0]) {
+		return fmt.Errorf("checksum mismatch for %s: the download may be corrupt or tampered with, so nothing was changed", name)
+	}
+
+	if err := replaceExecutable(exe, binary); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Updated amem from %s to %s\n", current, release.TagName)
+	return nil
+}
+
+// download returns the body of url, failing on any status but 200
+func download(ctx context.Context, url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(resp.Body, maxDownloadBytes+1)); err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if int64(buf.Len()) > maxDownloadBytes {
+		return nil, fmt.Errorf("failed to download %s: larger than %d bytes", url, maxDownloadBytes)
+	}
+	return buf.Bytes(), nil
+}
+
+// replaceExecutable atomically replaces exe with binary by writing it alongside and renaming it over
+func replaceExecutable(exe string, binary []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".amem-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write update next to %s: %w", exe, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write update: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o755); err != nil {
+		return fmt.Errorf("failed to make update executable: %w", err)
+	}
+
+	// Windows won't replace a running executable, but will rename it out of the way
+	if runtime.GOOS == "windows" {
+		old := exe + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(exe, old); err != nil {
+			return fmt.Errorf("failed to move %s aside: %w", exe, err)
+		}
+		if err := os.Rename(tmp.Name(), exe); err != nil {
+			// Put the old executable back rather than leave none
+			_ = os.Rename(old, exe)
+			return fmt.Errorf("failed to replace %s: %w", exe, err)
+		}
+		return nil
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", exe, err)
+	}
+	return nil
+}
+
+// parseVersion parses a release version like v1.2.3 into its numbers
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
+
+// newerVersion reports whether release version latest is newer than current
+func newerVersion(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, _ := parseVersion(current)
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
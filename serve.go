@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"amem/config"
+	"amem/db"
+	"amem/mcp"
+	"amem/server"
+	"github.com/urfave/cli/v3"
+)
+
+// serveCommand builds the 'serve' command, which keeps the database open for long-running clients
+func serveCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "serve",
+		Usage: "Serve the memory database to agents over MCP or HTTP",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "mcp",
+				Usage: "Serve the Model Context Protocol (over stdio unless --http is set)",
+			},
+			&cli.StringFlag{
+				Name:  "http",
+				Usage: "Listen for HTTP on this address (e.g. :8080, which is 127.0.0.1:8080; use 0.0.0.0:8080 for every interface) instead of stdio; serves the search API, /healthz, and /readyz",
+			},
+			&cli.BoolFlag{
+				Name:  "ui",
+				Usage: "Serve a web dashboard at /ui/ for browsing and editing memories (requires --http on a loopback address, unless the config has a token with the write scope)",
+			},
+			&cli.IntFlag{
+				Name:  "rate-limit",
+				Usage: "Maximum HTTP requests per minute per client (0 = unlimited; overrides config)",
+			},
+			&cli.IntFlag{
+				Name:  "write-limit",
+				Usage: "Maximum HTTP writes per minute per client (0 = unlimited; overrides config)",
+			},
+			&cli.IntFlag{
+				Name:  "max-payload",
+				Usage: "Maximum HTTP request body size in bytes (overrides config)",
+			},
+			&cli.DurationFlag{
+				Name:  "maintenance-interval",
+				Usage: "How often to analyze, vacuum, and enforce the quota while serving (0 = never; overrides config)",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			useMCP := cmd.Bool("mcp")
+			addr := cmd.String("http")
+
+			if !useMCP && addr == "" {
+				return fmt.Errorf("nothing to serve: use --mcp for stdio or --http for HTTP")
+			}
+			if cmd.Bool("ui") && addr == "" {
+				return fmt.Errorf("--ui requires --http")
+			}
+			if addr != "" {
+				addr = loopbackByDefault(addr)
+			}
+
+			ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			// Clients read concurrently, so they get a pool of connections
+			dbOptions = dbOptions.Concurrent()
+			return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+				// Clients write concurrently; funnel their writes through one ordered writer
+				stopWrites := database.StartWriteQueue()
+				defer stopWrites()
+
+				every := db.DefaultMaintenanceInterval
+				if cfg.Maintenance != nil {
+					every = cfg.Maintenance.Every()
+				}
+				if cmd.IsSet("maintenance-interval") {
+					every = cmd.Duration("maintenance-interval")
+				}
+				if every < 0 {
+					return fmt.Errorf("--maintenance-interval cannot be negative")
+				}
+				if every > 0 {
+					backup := cfg.Maintenance != nil && cfg.Maintenance.Backup
+					stopMaintenance := database.StartMaintenance(every, func(report *db.MaintenanceReport, err error) {
+						logMaintenance(report, err)
+						// Backups are copied from the open database, so clients needn't wait for them
+						if backup {
+							if err := saveBackup(os.Stderr, database, cfg.Backup); err != nil {
+								fmt.Fprintf(os.Stderr, "Warning: backup failed: %v\n", err)
+							}
+						}
+					})
+					defer stopMaintenance()
+				}
+
+				if addr == "" {
+					return mcp.NewServer(database, version).ServeStdio(ctx, os.Stdin, os.Stdout)
+				}
+
+				var limits server.Config
+				if cfg.Server != nil {
+					limits = *cfg.Server
+				}
+				if cmd.IsSet("rate-limit") {
+					limits.RequestsPerMinute = int(cmd.Int("rate-limit"))
+				}
+				if cmd.IsSet("write-limit") {
+					limits.WritesPerMinute = int(cmd.Int("write-limit"))
+				}
+				if cmd.IsSet("max-payload") {
+					limits.MaxPayloadBytes = int64(cmd.Int("max-payload"))
+				}
+
+				if cmd.Bool("ui") {
+					if err := checkUIAddr(addr, &limits); err != nil {
+						return err
+					}
+				}
+
+				srv := server.New(database, server.Options{MCP: useMCP, UI: cmd.Bool("ui"), Version: version, Limits: limits})
+				return listenAndServe(ctx, addr, srv.Handler())
+			})
+		},
+	}
+}
+
+// loopbackByDefault binds addr to the loopback interface if it names no host, like ":8080",
+// so serving to other machines takes asking for it, as with "0.0.0.0:8080".
+func loopbackByDefault(addr string) string {
+	if host, port, err := net.SplitHostPort(addr); err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// checkUIAddr refuses to serve the dashboard, which can edit memories, on addr unless a
+// token is needed to write or addr only accepts connections from this machine.
+func checkUIAddr(addr string, limits *server.Config) error {
+	if limits.HasWriteToken() {
+		return nil
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --http address %q: %w", addr, err)
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return nil
+	}
+	return fmt.Errorf("--ui lets anyone who can reach %s edit memories: listen on this machine only with --http 127.0.0.1:%s, or add a token with the write scope to the config's server.tokens", addr, port)
+}
+
+// logMaintenance reports the result of scheduled maintenance on stderr, which stays clear of stdio MCP
+func logMaintenance(report *db.MaintenanceReport, err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: maintenance failed: %v\n", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Maintenance: analyzed, freed %d pages, evicted %d observations\n", report.FreedPages, report.Evicted)
+}
+
+// listenAndServe serves handler on addr until ctx is cancelled, then shuts down gracefully
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+		// Cancel long-lived streams when shutting down
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- srv.ListenAndServe()
+	}()
+	fmt.Fprintf(os.Stderr, "Listening on %s\n", addr)
+
+	select {
+	case err := <-errCh:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down server: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"amem/db"
+)
+
+// Page sizes for the search API.
+const (
+	defaultPageSize = 50
+	maxPageSize     = 500
+)
+
+// page is the body returned by the search API. NextCursor is empty on the last page.
+type page struct {
+	Items      interface{} `json:"items"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// searchParams are the query parameters shared by the search endpoints.
+type searchParams struct {
+	keywords []string
+	useUnion bool
+	page     db.Page
+}
+
+// encodeCursor makes an opaque cursor pointing below id in a kind of result.
+func encodeCursor(kind string, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(kind + ":" + strconv.FormatInt(id, 10)))
+}
+
+// decodeCursor returns the ID a cursor from encodeCursor points below.
+func decodeCursor(kind, cursor string) (int64, error) {
+	invalid := errors.New("invalid cursor")
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, invalid
+	}
+	cursorKind, idText, ok := strings.Cut(string(data), ":")
+	if !ok || cursorKind != kind {
+		return 0, invalid
+	}
+	id, err := strconv.ParseInt(idText, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, invalid
+	}
+	return id, nil
+}
+
+// parseSearch reads keywords (repeated q), match, limit, and cursor parameters.
+// One more result than the limit is requested to learn whether there's a next page.
+func parseSearch(r *http.Request, kind string) (searchParams, error) {
+	query := r.URL.Query()
+	params := searchParams{keywords: query["q"], useUnion: true}
+
+	switch query.Get("match") {
+	case "", "any":
+	case "all":
+		params.useUnion = false
+	default:
+		return params, fmt.Errorf("invalid match '%s' (use any or all)", query.Get("match"))
+	}
+
+	limit := defaultPageSize
+	if text := query.Get("limit"); text != "" {
+		n, err := strconv.Atoi(text)
+		if err != nil || n < 1 || n > maxPageSize {
+			return params, fmt.Errorf("limit must be between 1 and %d", maxPageSize)
+		}
+		limit = n
+	}
+	params.page.Limit = limit + 1
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		id, err := decodeCursor(kind, cursor)
+		if err != nil {
+			return params, err
+		}
+		params.page.BeforeID = id
+	}
+
+	return params, nil
+}
+
+// pageOf trims the extra result requested by parseSearch and sets the next cursor.
+func pageOf[T any](kind string, results []T, params searchParams, id func(T) int64) page {
+	if results == nil {
+		results = []T{}
+	}
+	p := page{Items: results}
+	if limit := params.page.Limit - 1; len(results) > limit {
+		results = results[:limit]
+		p.Items = results
+		p.NextCursor = encodeCursor(kind, id(results[limit-1]))
+	}
+	return p
+}
+
+func (s *Server) handleEntities(w http.ResponseWriter, r *http.Request) {
+	params, err := parseSearch(r, "entities")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := s.db.SearchEntitiesPage(params.keywords, params.useUnion, params.page)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pageOf("entities", results, params, func(e db.Entity) int64 { return e.ID }))
+}
+
+func (s *Server) handleObservations(w http.ResponseWriter, r *http.Request) {
+	params, err := parseSearch(r, "observations")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	results, err := s.db.SearchObservationsPage(r.URL.Query().Get("about"), params.keywords, params.useUnion, params.page)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	p := pageOf("observations", results, params, func(o db.Observation) int64 { return o.ID })
+	shown := p.Items.([]db.Observation)
+	ids := make([]int64, len(shown))
+	for i, o := range shown {
+		ids[i] = o.ID
+	}
+	if err := s.db.Retrieved(r.URL.Query().Get("about"), params.keywords, ids); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, p)
+}
+
+func (s *Server) handleRelationships(w http.ResponseWriter, r *http.Request) {
+	params, err := parseSearch(r, "relationships")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	query := r.URL.Query()
+	results, err := s.db.SearchRelationshipsPage(query.Get("from"), query.Get("to"), query.Get("type"), params.keywords, params.useUnion, params.page)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pageOf("relationships", results, params, func(rel db.Relationship) int64 { return rel.ID }))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
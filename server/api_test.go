@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"amem/db"
+)
+
+func getPage(t *testing.T, base, path string, query url.Values) (int, []db.Observation, string) {
+	t.Helper()
+	resp, err := http.Get(base + path + "?" + query.Encode())
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Items      []db.Observation `json:"items"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatalf("Failed to decode page: %v", err)
+		}
+	}
+	return resp.StatusCode, body.Items, body.NextCursor
+}
+
+func TestObservationsPagination(t *testing.T) {
+	database := newTestDB(t)
+	for _, text := range []string{"one", "two", "three"} {
+		if _, err := database.AddObservation("Alice", text); err != nil {
+			t.Fatalf("AddObservation failed: %v", err)
+		}
+	}
+
+	ts := httptest.NewServer(New(database, Options{}).Handler())
+	defer ts.Close()
+
+	status, items, cursor := getPage(t, ts.URL, "/api/observations", url.Values{"about": {"Alice"}, "limit": {"2"}})
+	if status != http.StatusOK || len(items) != 2 || cursor == "" {
+		t.Fatalf("Expected first page of 2 with a cursor, got %d %+v %q", status, items, cursor)
+	}
+
+	// New records don't shift the next page
+	if _, err := database.AddObservation("Alice", "four"); err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+
+	status, items, next := getPage(t, ts.URL, "/api/observations", url.Values{"about": {"Alice"}, "limit": {"2"}, "cursor": {cursor}})
+	if status != http.StatusOK || len(items) != 1 || items[0].Text != "one" {
+		t.Fatalf("Expected last page with 'one', got %d %+v", status, items)
+	}
+	if next != "" {
+		t.Errorf("Expected no cursor on the last page, got %q", next)
+	}
+}
+
+func TestSearchAPIErrors(t *testing.T) {
+	ts := httptest.NewServer(New(newTestDB(t), Options{}).Handler())
+	defer ts.Close()
+
+	cases := []struct {
+		path  string
+		query url.Values
+	}{
+		{"/api/entities", url.Values{"cursor": {"not-a-cursor"}}},
+		{"/api/entities", url.Values{"cursor": {encodeCursor("observations", 5)}}},
+		{"/api/relationships", url.Values{"limit": {"0"}}},
+		{"/api/observations", url.Values{"match": {"some"}}},
+	}
+	for _, c := range cases {
+		if status, _, _ := getPage(t, ts.URL, c.path, c.query); status != http.StatusBadRequest {
+			t.Errorf("%s?%s: expected 400, got %d", c.path, c.query.Encode(), status)
+		}
+	}
+
+	status, items, _ := getPage(t, ts.URL, "/api/entities", nil)
+	if status != http.StatusOK || items == nil {
+		t.Errorf("Expected empty list of entities, got %d %v", status, items)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	id, err := decodeCursor("entities", encodeCursor("entities", 42))
+	if err != nil || id != 42 {
+		t.Errorf("Expected 42, got %d (err %v)", id, err)
+	}
+}
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"amem/db"
+)
+
+// observationBody is the body of requests that add or change an observation.
+type observationBody struct {
+	Entity string `json:"entity"`
+	Text   string `json:"text"`
+}
+
+// handleAddObservation adds an observation about an entity, creating the entity if needed.
+func (s *Server) handleAddObservation(w http.ResponseWriter, r *http.Request) {
+	if !requireJSON(w, r) {
+		return
+	}
+	var body observationBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if body.Entity == "" || body.Text == "" {
+		writeError(w, http.StatusBadRequest, errors.New("entity and text are required"))
+		return
+	}
+
+	var o *db.Observation
+	err := s.db.Locked(func() error {
+		id, err := s.db.AddObservation(body.Entity, body.Text)
+		if err != nil {
+			return err
+		}
+		o, err = s.db.GetObservation(id)
+		return err
+	})
+	if err != nil {
+		writeWriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, o)
+}
+
+// handleUpdateObservation changes an observation's text.
+func (s *Server) handleUpdateObservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid observation ID"))
+		return
+	}
+	if !requireJSON(w, r) {
+		return
+	}
+	var body observationBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err))
+		return
+	}
+	if body.Text == "" {
+		writeError(w, http.StatusBadRequest, errors.New("text is required"))
+		return
+	}
+
+	var o *db.Observation
+	err = s.db.Locked(func() error {
+		if _, err := s.db.GetObservation(id); err != nil {
+			return err
+		}
+		if err := s.db.UpdateObservation(id, body.Text); err != nil {
+			return err
+		}
+		o, err = s.db.GetObservation(id)
+		return err
+	})
+	if err != nil {
+		writeWriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, o)
+}
+
+// handleDeleteObservation deletes an observation.
+func (s *Server) handleDeleteObservation(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid observation ID"))
+		return
+	}
+
+	err = s.db.Locked(func() error {
+		if _, err := s.db.GetObservation(id); err != nil {
+			return err
+		}
+		return s.db.DeleteObservation(id)
+	})
+	if err != nil {
+		writeWriteError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// requireJSON rejects r unless its body is declared as JSON, writing the error to w. A
+// web page can only send another site a JSON body after a CORS preflight, which this
+// server never approves, so forms on other sites can't post to it.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("the body must be sent with Content-Type application/json"))
+		return false
+	}
+	return true
+}
+
+// writeWriteError reports a failed write with a status that says whose fault it was.
+func writeWriteError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, db.ErrNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, db.ErrRuleViolation):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	default:
+		writeError(w, http.StatusInternalServerError, err)
+	}
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"amem/db"
+)
+
+func request(t *testing.T, method, url, body string) (int, []byte) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, url, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, data
+}
+
+func TestObservationWrites(t *testing.T) {
+	database := newTestDB(t)
+	ts := httptest.NewServer(New(database, Options{UI: true}).Handler())
+	defer ts.Close()
+
+	status, data := request(t, "POST", ts.URL+"/api/observations", `{"entity":"Alice","text":"likes tea"}`)
+	if status != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d %s", status, data)
+	}
+	var o db.Observation
+	if err := json.Unmarshal(data, &o); err != nil || o.EntityText != "Alice" || o.Text != "likes tea" {
+		t.Fatalf("Expected the new observation, got %s (%v)", data, err)
+	}
+
+	id := ts.URL + "/api/observations/" + strconv.FormatInt(o.ID, 10)
+	if status, data := request(t, "PATCH", id, `{"text":"likes coffee"}`); status != http.StatusOK || !strings.Contains(string(data), "likes coffee") {
+		t.Fatalf("Expected the updated observation, got %d %s", status, data)
+	}
+
+	if status, _ := request(t, "DELETE", id, ""); status != http.StatusNoContent {
+		t.Fatalf("Expected 204, got %d", status)
+	}
+	if status, _ := request(t, "DELETE", id, ""); status != http.StatusNotFound {
+		t.Errorf("Expected 404 deleting a missing observation, got %d", status)
+	}
+	if status, _ := request(t, "POST", ts.URL+"/api/observations", `{"entity":"Alice"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 without text, got %d", status)
+	}
+}
+
+func TestObservationWritesRequireJSON(t *testing.T) {
+	database := newTestDB(t)
+	ts := httptest.NewServer(New(database, Options{UI: true}).Handler())
+	defer ts.Close()
+
+	// A form on another site can post text/plain or form bodies without a preflight
+	for _, contentType := range []string{"", "text/plain", "application/x-www-form-urlencoded"} {
+		req, err := http.NewRequest("POST", ts.URL+"/api/observations", strings.NewReader(`{"entity":"Alice","text":"likes tea"}`))
+		if err != nil {
+			t.Fatalf("Failed to build request: %v", err)
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		if resp.StatusCode != http.StatusUnsupportedMediaType {
+			t.Errorf("Expected 415 for Content-Type %q, got %d", contentType, resp.StatusCode)
+		}
+	}
+	if count, _ := database.CountObservations(); count != 0 {
+		t.Errorf("Expected no observations added, got %d", count)
+	}
+
+	req, _ := http.NewRequest("POST", ts.URL+"/api/observations", strings.NewReader(`{"entity":"Alice","text":"likes tea"}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("Expected 201 for JSON with a charset, got %d", resp.StatusCode)
+	}
+}
+
+func TestObservationWritesFromOtherSites(t *testing.T) {
+	database := newTestDB(t)
+	id, err := database.AddObservation("Alice", "likes tea")
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	path := "/api/observations/" + strconv.FormatInt(id, 10)
+
+	send := func(srv *Server, host, origin, token string) int {
+		t.Helper()
+		req := httptest.NewRequest("DELETE", path, nil)
+		req.Host = host
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// DELETE has no body to require JSON of, so the Host and Origin keep other sites out
+	open := New(database, Options{UI: true})
+	if status := send(open, "127.0.0.1:8080", "http://evil.example", ""); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a cross-site DELETE, got %d", status)
+	}
+	if status := send(open, "evil.example:8080", "http://evil.example:8080", ""); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a DELETE from a rebound name, got %d", status)
+	}
+	protected := New(database, Options{UI: true, Limits: Config{Tokens: []Token{{Name: "ui", Token: "secret"}}}})
+	if status := send(protected, "memory.example.com", "http://evil.example", "secret"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a cross-site DELETE with a token, got %d", status)
+	}
+	if _, err := database.GetObservation(id); err != nil {
+		t.Fatalf("Expected the observation to survive: %v", err)
+	}
+
+	if status := send(open, "localhost:8080", "http://localhost:8080", ""); status != http.StatusNoContent {
+		t.Errorf("Expected 204 for the dashboard's own DELETE, got %d", status)
+	}
+}
+
+func TestUIMountedOnlyWhenEnabled(t *testing.T) {
+	off := httptest.NewServer(New(newTestDB(t), Options{}).Handler())
+	defer off.Close()
+	if status, _ := request(t, "GET", off.URL+"/ui/", ""); status != http.StatusNotFound {
+		t.Errorf("Expected 404 for /ui/ without UI, got %d", status)
+	}
+	if status, _ := request(t, "POST", off.URL+"/api/observations", `{"entity":"A","text":"b"}`); status == http.StatusCreated {
+		t.Errorf("Expected writes to be unavailable without UI")
+	}
+
+	// The dashboard's assets load without a token; its API calls still need one
+	on := httptest.NewServer(New(newTestDB(t), Options{UI: true, Limits: Config{Tokens: []Token{{Name: "ui", Token: "secret"}}}}).Handler())
+	defer on.Close()
+	for _, path := range []string{"/ui/", "/ui/app.js", "/ui/style.css"} {
+		if status, _ := request(t, "GET", on.URL+path, ""); status != http.StatusOK {
+			t.Errorf("Expected 200 for %s, got %d", path, status)
+		}
+	}
+	if status, _ := request(t, "POST", on.URL+"/api/observations", `{"entity":"A","text":"b"}`); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an unauthenticated write, got %d", status)
+	}
+}
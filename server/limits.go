@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"amem/origin"
+	"amem/tools"
+)
+
+// DefaultMaxPayloadBytes bounds request bodies when no limit is configured.
+const DefaultMaxPayloadBytes = 4 * 1024 * 1024
+
+// Scopes a token can be granted.
+const (
+	// ScopeRead allows searches
+	ScopeRead = "read"
+	// ScopeWrite allows adding and changing records
+	ScopeWrite = "write"
+	// ScopeAdmin allows deleting records
+	ScopeAdmin = "admin"
+)
+
+// Token is an API token clients present as "Authorization: Bearer <token>".
+// Limits of zero fall back to the server-wide limits. A token without scopes
+// has all of them.
+type Token struct {
+	Name              string   `json:"name"`
+	Token             string   `json:"token"`
+	Scopes            []string `json:"scopes,omitempty"`
+	RequestsPerMinute int      `json:"requests_per_minute,omitempty"`
+	WritesPerMinute   int      `json:"writes_per_minute,omitempty"`
+}
+
+// Config is the "server" section of the config file.
+type Config struct {
+	Tokens            []Token `json:"tokens,omitempty"`
+	RequestsPerMinute int     `json:"requests_per_minute,omitempty"`
+	WritesPerMinute   int     `json:"writes_per_minute,omitempty"`
+	MaxPayloadBytes   int64   `json:"max_payload_bytes,omitempty"`
+}
+
+// Validate checks that every token has a name, a value, and known scopes.
+func (c *Config) Validate() error {
+	for _, t := range c.Tokens {
+		if t.Name == "" || t.Token == "" {
+			return fmt.Errorf("tokens need a name and a token")
+		}
+		for _, scope := range t.Scopes {
+			if scope != ScopeRead && scope != ScopeWrite && scope != ScopeAdmin {
+				return fmt.Errorf("token '%s' has unknown scope '%s' (use %s, %s, or %s)", t.Name, scope, ScopeRead, ScopeWrite, ScopeAdmin)
+			}
+		}
+	}
+	return nil
+}
+
+// HasWriteToken reports whether a token grants the write scope. Without one, either
+// anyone who can reach the server may write, or no one may.
+func (c *Config) HasWriteToken() bool {
+	return slices.ContainsFunc(c.Tokens, func(t Token) bool {
+		return client{scopes: t.Scopes}.allows(ScopeWrite)
+	})
+}
+
+// bucket is a token bucket refilled continuously at perMinute/60 per second.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// limiter tracks one bucket per client key. A bucket idle for a minute has refilled,
+// so it's dropped and recreated full on the client's next request, keeping the map
+// from growing with every address that ever connected.
+type limiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	now     func() time.Time
+	swept   time.Time
+}
+
+func newLimiter() *limiter {
+	return &limiter{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// allow takes one token from key's bucket, reporting whether one was available.
+// A perMinute of zero or less means unlimited.
+func (l *limiter) allow(key string, perMinute int) bool {
+	if perMinute <= 0 {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.swept) >= time.Minute {
+		for k, b := range l.buckets {
+			if now.Sub(b.last) >= time.Minute {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(perMinute), last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens += now.Sub(b.last).Minutes() * float64(perMinute)
+	if b.tokens > float64(perMinute) {
+		b.tokens = float64(perMinute)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// client identifies who made a request and which limits apply to them.
+type client struct {
+	key               string
+	requestsPerMinute int
+	writesPerMinute   int
+	// scopes is empty when the client may do anything
+	scopes []string
+}
+
+// allows reports whether the client was granted scope.
+func (c client) allows(scope string) bool {
+	return len(c.scopes) == 0 || slices.Contains(c.scopes, scope)
+}
+
+// identify authenticates r against the configured tokens. With no tokens
+// configured, clients are identified by remote address.
+func (s *Server) identify(r *http.Request) (client, bool) {
+	cfg := s.opts.Limits
+	if len(cfg.Tokens) == 0 {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return client{key: "addr:" + host, requestsPerMinute: cfg.RequestsPerMinute, writesPerMinute: cfg.WritesPerMinute}, true
+	}
+
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return client{}, false
+	}
+	for _, t := range cfg.Tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(t.Token)) == 1 {
+			c := client{key: "token:" + t.Name, requestsPerMinute: t.RequestsPerMinute, writesPerMinute: t.WritesPerMinute, scopes: t.Scopes}
+			if c.requestsPerMinute == 0 {
+				c.requestsPerMinute = cfg.RequestsPerMinute
+			}
+			if c.writesPerMinute == 0 {
+				c.writesPerMinute = cfg.WritesPerMinute
+			}
+			return c, true
+		}
+	}
+	return client{}, false
+}
+
+// limit refuses requests from other sites, authenticates requests, or without tokens
+// checks they're from this machine, caps their size, checks the client's scopes, and applies per-client request and write rate
+// limits before passing them to next.
+func (s *Server) limit(next http.Handler) http.Handler {
+	maxBytes := s.opts.Limits.MaxPayloadBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxPayloadBytes
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Without tokens, anyone who reaches the server can use it, so it only answers
+		// clients on this machine; pages on other sites are never answered
+		check := origin.CheckOrigin
+		if len(s.opts.Limits.Tokens) == 0 {
+			check = origin.CheckLocal
+		}
+		if err := check(r); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		c, ok := s.identify(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "missing or invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		if r.ContentLength > maxBytes {
+			http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		if !s.limiter.allow("requests:"+c.key, c.requestsPerMinute) {
+			tooManyRequests(w)
+			return
+		}
+
+		var body []byte
+		if r.Body != nil && r.Method != http.MethodGet {
+			var err error
+			body, err = io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			if err != nil {
+				http.Error(w, "failed to read request", http.StatusBadRequest)
+				return
+			}
+			if int64(len(body)) > maxBytes {
+				http.Error(w, "request too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		scope := requiredScope(r, body)
+		if !c.allows(scope) {
+			http.Error(w, fmt.Sprintf("token lacks the %s scope", scope), http.StatusForbidden)
+			return
+		}
+		if scope != ScopeRead && !s.limiter.allow("writes:"+c.key, c.writesPerMinute) {
+			tooManyRequests(w)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requiredScope returns the scope a request needs: deletes need admin, other
+// changes need write, and everything else needs read. MCP messages need write
+// when they call a write tool.
+func requiredScope(r *http.Request, body []byte) string {
+	if r.URL.Path != "/mcp" && r.URL.Path != "/messages" {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+			return ScopeRead
+		case http.MethodDelete:
+			return ScopeAdmin
+		}
+		return ScopeWrite
+	}
+
+	type call struct {
+		Method string `json:"method"`
+		Params struct {
+			Name string `json:"name"`
+		} `json:"params"`
+	}
+
+	var calls []call
+	if err := json.Unmarshal(body, &calls); err != nil {
+		var single call
+		if err := json.Unmarshal(body, &single); err != nil {
+			return ScopeRead
+		}
+		calls = []call{single}
+	}
+
+	for _, c := range calls {
+		if c.Method != "tools/call" {
+			continue
+		}
+		if t, ok := tools.Find(c.Params.Name); ok && t.Write {
+			return ScopeWrite
+		}
+	}
+	return ScopeRead
+}
+
+func tooManyRequests(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "60")
+	http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+}
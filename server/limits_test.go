@@ -0,0 +1,247 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const addObservation = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"amem_add_observation","arguments":{"entity":"Alice","text":"Likes tea"}}}`
+
+const searchAll = `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"amem_search","arguments":{"keywords":["alice"]}}}`
+
+func post(t *testing.T, url, token, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/mcp", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /mcp failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestLimiter(t *testing.T) {
+	l := newLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !l.allow("a", 2) {
+			t.Fatalf("Request %d should be allowed", i+1)
+		}
+	}
+	if l.allow("a", 2) {
+		t.Error("Third request should be limited")
+	}
+	if !l.allow("b", 2) {
+		t.Error("Other clients should have their own bucket")
+	}
+
+	now = now.Add(30 * time.Second)
+	if !l.allow("a", 2) {
+		t.Error("Bucket should refill over time")
+	}
+	if !l.allow("a", 0) {
+		t.Error("Zero limit should be unlimited")
+	}
+}
+
+func TestLimiterDropsIdleBuckets(t *testing.T) {
+	l := newLimiter()
+	now := time.Now()
+	l.now = func() time.Time { return now }
+
+	for i := range 100 {
+		l.allow(strconv.Itoa(i), 2)
+	}
+	now = now.Add(time.Minute)
+	l.allow("a", 2)
+	l.allow("a", 2)
+	if len(l.buckets) != 1 {
+		t.Errorf("Expected idle buckets to be dropped, %d left", len(l.buckets))
+	}
+
+	now = now.Add(time.Minute)
+	if !l.allow("a", 2) || !l.allow("a", 2) {
+		t.Error("Dropped bucket should come back full")
+	}
+	if l.allow("a", 2) {
+		t.Error("Recreated bucket should still be limited")
+	}
+}
+
+func TestLocalOnlyWithoutTokens(t *testing.T) {
+	database := newTestDB(t)
+	send := func(srv *Server, host, token string) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/mcp", strings.NewReader(addObservation))
+		req.Host = host
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		srv.Handler().ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// A page whose DNS name is rebound to the server still sends its name as the Host
+	open := New(database, Options{MCP: true})
+	if status := send(open, "evil.example:8080", ""); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a rebound name without tokens, got %d", status)
+	}
+	if status := send(open, "localhost:8080", ""); status != http.StatusOK {
+		t.Errorf("Expected 200 for localhost, got %d", status)
+	}
+
+	// Tokens keep other sites out, so any name can reach a server with them
+	protected := New(database, Options{MCP: true, Limits: Config{Tokens: []Token{{Name: "agent", Token: "secret"}}}})
+	if status := send(protected, "memory.example.com", "secret"); status != http.StatusOK {
+		t.Errorf("Expected 200 with a token, got %d", status)
+	}
+}
+
+func TestTokenAuthentication(t *testing.T) {
+	srv := New(newTestDB(t), Options{MCP: true, Limits: Config{
+		Tokens: []Token{{Name: "agent", Token: "secret"}},
+	}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := post(t, ts.URL, "", searchAll); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without token, got %d", status)
+	}
+	if status := post(t, ts.URL, "wrong", searchAll); status != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", status)
+	}
+	if status := post(t, ts.URL, "secret", searchAll); status != http.StatusOK {
+		t.Errorf("Expected 200 with valid token, got %d", status)
+	}
+
+	// Health checks don't need a token
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 for /healthz, got %d", resp.StatusCode)
+	}
+}
+
+func TestWriteLimit(t *testing.T) {
+	srv := New(newTestDB(t), Options{MCP: true, Limits: Config{
+		Tokens: []Token{
+			{Name: "slow", Token: "slow-token", WritesPerMinute: 1},
+			{Name: "fast", Token: "fast-token"},
+		},
+	}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := post(t, ts.URL, "slow-token", addObservation); status != http.StatusOK {
+		t.Fatalf("Expected first write to succeed, got %d", status)
+	}
+	if status := post(t, ts.URL, "slow-token", addObservation); status != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for second write, got %d", status)
+	}
+	if status := post(t, ts.URL, "slow-token", searchAll); status != http.StatusOK {
+		t.Errorf("Reads should not count against the write limit, got %d", status)
+	}
+	if status := post(t, ts.URL, "fast-token", addObservation); status != http.StatusOK {
+		t.Errorf("Other tokens should not be limited, got %d", status)
+	}
+}
+
+func TestRequestLimit(t *testing.T) {
+	srv := New(newTestDB(t), Options{MCP: true, Limits: Config{RequestsPerMinute: 1}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := post(t, ts.URL, "", searchAll); status != http.StatusOK {
+		t.Fatalf("Expected first request to succeed, got %d", status)
+	}
+	if status := post(t, ts.URL, "", searchAll); status != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for second request, got %d", status)
+	}
+}
+
+func TestMaxPayload(t *testing.T) {
+	srv := New(newTestDB(t), Options{MCP: true, Limits: Config{MaxPayloadBytes: 64}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := post(t, ts.URL, "", addObservation); status != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for oversized payload, got %d", status)
+	}
+}
+
+func TestScopes(t *testing.T) {
+	database := newTestDB(t)
+	id, err := database.AddObservation("Alice", "likes tea")
+	if err != nil {
+		t.Fatalf("AddObservation failed: %v", err)
+	}
+	srv := New(database, Options{MCP: true, UI: true, Limits: Config{
+		Tokens: []Token{
+			{Name: "recall", Token: "read-token", Scopes: []string{ScopeRead}},
+			{Name: "agent", Token: "write-token", Scopes: []string{ScopeRead, ScopeWrite}},
+			{Name: "owner", Token: "admin-token", Scopes: []string{ScopeRead, ScopeWrite, ScopeAdmin}},
+		},
+	}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	if status := post(t, ts.URL, "read-token", searchAll); status != http.StatusOK {
+		t.Errorf("Expected a read token to search, got %d", status)
+	}
+	if status := post(t, ts.URL, "read-token", addObservation); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a write with a read token, got %d", status)
+	}
+	if status := post(t, ts.URL, "write-token", addObservation); status != http.StatusOK {
+		t.Errorf("Expected a write token to write, got %d", status)
+	}
+
+	del := func(token string) int {
+		req, _ := http.NewRequest(http.MethodDelete, ts.URL+"/api/observations/"+strconv.FormatInt(id, 10), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("DELETE failed: %v", err)
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := del("write-token"); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a delete with a write token, got %d", status)
+	}
+	if status := del("admin-token"); status != http.StatusNoContent {
+		t.Errorf("Expected an admin token to delete, got %d", status)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	valid := Config{Tokens: []Token{{Name: "a", Token: "t", Scopes: []string{ScopeRead}}, {Name: "b", Token: "u"}}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	for _, cfg := range []Config{
+		{Tokens: []Token{{Name: "a", Token: "t", Scopes: []string{"delete"}}}},
+		{Tokens: []Token{{Name: "a"}}},
+	} {
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"amem/db"
+)
+
+type openAPIDoc struct {
+	Paths      map[string]map[string]json.RawMessage `json:"paths"`
+	Components struct {
+		Schemas map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"schemas"`
+	} `json:"components"`
+}
+
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	var doc openAPIDoc
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("openapi.json is invalid: %v", err)
+	}
+
+	var documented []string
+	for path, item := range doc.Paths {
+		for method := range item {
+			if method != "parameters" {
+				documented = append(documented, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+
+	var served []string
+	for _, route := range New(newTestDB(t), Options{UI: true}).apiRoutes() {
+		served = append(served, route.pattern)
+	}
+	served = append(served, "GET /healthz", "GET /readyz")
+
+	slices.Sort(documented)
+	slices.Sort(served)
+	if !slices.Equal(documented, served) {
+		t.Errorf("openapi.json documents %v, but the server serves %v", documented, served)
+	}
+
+	// Record schemas list exactly the fields the API returns
+	for name, record := range map[string]any{"Entity": db.Entity{}, "Observation": db.Observation{}, "Relationship": db.Relationship{}} {
+		var fields []string
+		typ := reflect.TypeOf(record)
+		for i := range typ.NumField() {
+			fields = append(fields, strings.Split(typ.Field(i).Tag.Get("json"), ",")[0])
+		}
+		var properties []string
+		for property := range doc.Components.Schemas[name].Properties {
+			properties = append(properties, property)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("Schema %s has %v, but db.%s has %v", name, properties, name, fields)
+		}
+	}
+}
+
+func TestOpenAPIServedWithoutToken(t *testing.T) {
+	ts := httptest.NewServer(New(newTestDB(t), Options{Limits: Config{Tokens: []Token{{Name: "a", Token: "secret"}}}}).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/openapi.json")
+	if err != nil {
+		t.Fatalf("GET /openapi.json failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected JSON with 200, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+}
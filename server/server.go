@@ -0,0 +1,146 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"amem/db"
+	"amem/mcp"
+)
+
+// Options configures which endpoints the server exposes.
+type Options struct {
+	// MCP mounts the MCP endpoints (/mcp, /sse, /messages)
+	MCP bool
+	// Version is reported to clients
+	Version string
+	// Limits configures authentication, rate limits, and payload size
+	Limits Config
+	// UI serves the web dashboard at /ui/ and mounts the write endpoints it edits with
+	UI bool
+}
+
+// Server exposes a memory database over HTTP.
+type Server struct {
+	db      *db.DB
+	opts    Options
+	limiter *limiter
+}
+
+// New creates a server for database.
+func New(database *db.DB, opts Options) *Server {
+	return &Server{db: database, opts: opts, limiter: newLimiter()}
+}
+
+// Handler returns the HTTP handler for all enabled endpoints.
+// Health endpoints and the OpenAPI document are exempt from authentication
+// and rate limits so supervisors and SDK generators can always reach them.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", s.handleHealth)
+	mux.HandleFunc("/readyz", s.handleReady)
+
+	mux.HandleFunc("GET /openapi.json", handleOpenAPI)
+
+	for _, route := range s.apiRoutes() {
+		mux.Handle(route.pattern, s.limit(route.handler))
+	}
+
+	if s.opts.UI {
+		mux.Handle("GET /ui/", uiHandler())
+		mux.Handle("GET /{$}", http.RedirectHandler("/ui/", http.StatusFound))
+	}
+
+	if s.opts.MCP {
+		mcpHandler := s.limit(mcp.NewServer(s.db, s.opts.Version).HTTPHandler())
+		mux.Handle("/mcp", mcpHandler)
+		mux.Handle("/sse", mcpHandler)
+		mux.Handle("/messages", mcpHandler)
+	}
+
+	return mux
+}
+
+// apiRoute is an authenticated, rate-limited API endpoint.
+type apiRoute struct {
+	pattern string
+	handler http.HandlerFunc
+}
+
+// apiRoutes returns the enabled API endpoints. Each must be described in openapi.json.
+func (s *Server) apiRoutes() []apiRoute {
+	routes := []apiRoute{
+		{"GET /api/entities", s.handleEntities},
+		{"GET /api/observations", s.handleObservations},
+		{"GET /api/relationships", s.handleRelationships},
+	}
+	if s.opts.UI {
+		routes = append(routes,
+			apiRoute{"POST /api/observations", s.handleAddObservation},
+			apiRoute{"PATCH /api/observations/{id}", s.handleUpdateObservation},
+			apiRoute{"DELETE /api/observations/{id}", s.handleDeleteObservation},
+		)
+	}
+	return routes
+}
+
+// handleHealth reports liveness: the process is up and serving requests.
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// readiness is the body returned by /readyz
+type readiness struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// handleReady reports readiness: the database is reachable, the key decrypts it,
+// and its schema matches this build.
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	result := readiness{Status: "ok", Checks: map[string]string{}}
+	fail := func(check string, err error) {
+		result.Status = "unavailable"
+		result.Checks[check] = err.Error()
+	}
+
+	if err := s.db.Ping(ctx); err != nil {
+		fail("database", err)
+	} else {
+		result.Checks["database"] = "ok"
+	}
+
+	if _, err := s.db.IsEncrypted(); err != nil {
+		fail("encryption_key", err)
+	} else {
+		result.Checks["encryption_key"] = "ok"
+	}
+
+	version, err := s.db.SchemaVersion()
+	switch {
+	case err != nil:
+		fail("schema", err)
+	case version != db.LatestSchemaVersion():
+		fail("schema", fmt.Errorf("schema version %d, expected %d", version, db.LatestSchemaVersion()))
+	default:
+		result.Checks["schema"] = fmt.Sprintf("ok (version %d)", version)
+	}
+
+	status := http.StatusOK
+	if result.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, result)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"amem/db"
+)
+
+func newTestDB(t *testing.T) *db.DB {
+	t.Helper()
+	database, err := db.Init(t.TempDir()+"/test_server.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+	return database
+}
+
+func TestHealthz(t *testing.T) {
+	ts := httptest.NewServer(New(newTestDB(t), Options{}).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatalf("GET /healthz failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	database := newTestDB(t)
+	ts := httptest.NewServer(New(database, Options{}).Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	var body readiness
+	_ = json.NewDecoder(resp.Body).Decode(&body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || body.Status != "ok" {
+		t.Errorf("Expected ready, got %d %+v", resp.StatusCode, body)
+	}
+	for _, check := range []string{"database", "encryption_key", "schema"} {
+		if _, ok := body.Checks[check]; !ok {
+			t.Errorf("Expected %s check in %+v", check, body.Checks)
+		}
+	}
+
+	// A closed database is not ready
+	_ = database.Close()
+	resp, err = http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatalf("GET /readyz failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 for closed database, got %d", resp.StatusCode)
+	}
+}
+
+func TestMCPMountedOnlyWhenEnabled(t *testing.T) {
+	database := newTestDB(t)
+
+	without := httptest.NewServer(New(database, Options{}).Handler())
+	defer without.Close()
+	resp, err := http.Get(without.URL + "/mcp")
+	if err != nil {
+		t.Fatalf("GET /mcp failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 without MCP, got %d", resp.StatusCode)
+	}
+
+	with := httptest.NewServer(New(database, Options{MCP: true}).Handler())
+	defer with.Close()
+	resp, err = http.Get(with.URL + "/mcp")
+	if err != nil {
+		t.Fatalf("GET /mcp failed: %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected MCP endpoint with MCP enabled, got %d", resp.StatusCode)
+	}
+}
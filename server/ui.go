@@ -0,0 +1,22 @@
+package server
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+// uiFiles holds the web dashboard's static assets.
+//
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler serves the dashboard. Its assets hold no memories, so they're served
+// without authentication; the dashboard sends the user's token with its API calls.
+func uiHandler() http.Handler {
+	assets, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui/", http.FileServerFS(assets))
+}
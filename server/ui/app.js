@@ -0,0 +1,139 @@
+// A small dashboard over amem's HTTP API. Everything it shows comes from /api,
+// so it sees exactly what an API client with the same token would.
+
+const $ = (id) => document.getElementById(id);
+const token = $("token");
+token.value = localStorage.getItem("amem-token") || "";
+token.addEventListener("change", () => localStorage.setItem("amem-token", token.value));
+
+let current = null;
+
+async function api(method, path, body) {
+  const headers = {};
+  if (token.value) headers.Authorization = "Bearer " + token.value;
+  if (body) headers["Content-Type"] = "application/json";
+  const resp = await fetch(path, { method, headers, body: body && JSON.stringify(body) });
+  if (!resp.ok) {
+    const text = await resp.text();
+    let message = text;
+    try { message = JSON.parse(text).error || text; } catch (e) {}
+    throw new Error(resp.status + ": " + message);
+  }
+  return resp.status === 204 ? null : resp.json();
+}
+
+function showError(err) {
+  $("error").textContent = err ? err.message : "";
+  $("error").hidden = !err;
+}
+
+function item(text, id) {
+  const li = document.createElement("li");
+  li.append(text);
+  if (id) {
+    const span = document.createElement("span");
+    span.className = "id";
+    span.textContent = " [" + id + "]";
+    li.append(span);
+  }
+  return li;
+}
+
+function entityLink(name) {
+  const a = document.createElement("a");
+  a.textContent = name;
+  a.addEventListener("click", () => openEntity(name));
+  return a;
+}
+
+async function search(query) {
+  const params = new URLSearchParams();
+  for (const word of query.split(/\s+/).filter(Boolean)) params.append("q", word);
+  const [entities, observations] = await Promise.all([
+    api("GET", "/api/entities?" + params),
+    api("GET", "/api/observations?" + params),
+  ]);
+
+  const results = $("results");
+  results.replaceChildren();
+  const heading = (text) => { const h = document.createElement("h3"); h.textContent = text; results.append(h); };
+  const list = document.createElement("ul");
+  heading("Entities");
+  for (const e of entities.items) list.append(item(entityLink(e.text), e.id));
+  results.append(list);
+
+  const obs = document.createElement("ul");
+  heading("Observations");
+  for (const o of observations.items) {
+    const li = item(o.text, o.id);
+    li.prepend(entityLink(o.entity), ": ");
+    obs.append(li);
+  }
+  results.append(obs);
+}
+
+async function openEntity(name) {
+  current = name;
+  const about = new URLSearchParams({ about: name, limit: "500" });
+  const [observations, from, to] = await Promise.all([
+    api("GET", "/api/observations?" + about),
+    api("GET", "/api/relationships?" + new URLSearchParams({ from: name, limit: "500" })),
+    api("GET", "/api/relationships?" + new URLSearchParams({ to: name, limit: "500" })),
+  ]);
+
+  $("dossier").hidden = false;
+  $("entity").textContent = name;
+
+  const list = $("observations");
+  list.replaceChildren();
+  for (const o of observations.items) {
+    const li = item(o.text, o.id);
+    const edit = document.createElement("button");
+    edit.textContent = "Edit";
+    edit.addEventListener("click", () => run(async () => {
+      const text = prompt("Observation", o.text);
+      if (text && text !== o.text) await api("PATCH", "/api/observations/" + o.id, { text });
+    }));
+    const del = document.createElement("button");
+    del.textContent = "Delete";
+    del.addEventListener("click", () => run(async () => {
+      if (confirm("Delete this observation?")) await api("DELETE", "/api/observations/" + o.id);
+    }));
+    li.append(edit, del);
+    list.append(li);
+  }
+
+  const rels = $("relationships");
+  rels.replaceChildren();
+  for (const r of [...from.items, ...to.items]) {
+    const li = item("", r.id);
+    li.prepend(entityLink(r.from), " " + r.type + " ", entityLink(r.to));
+    rels.append(li);
+  }
+}
+
+// run performs an edit, then refreshes the open entity
+async function run(fn) {
+  try {
+    await fn();
+    showError(null);
+    if (current) await openEntity(current);
+  } catch (err) {
+    showError(err);
+  }
+}
+
+$("search").addEventListener("submit", (event) => {
+  event.preventDefault();
+  search($("query").value).then(() => showError(null), showError);
+});
+
+$("add").addEventListener("submit", (event) => {
+  event.preventDefault();
+  const text = $("text").value.trim();
+  if (!text || !current) return;
+  run(async () => {
+    await api("POST", "/api/observations", { entity: current, text });
+    $("text").value = "";
+  });
+});
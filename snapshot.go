@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// snapshotCommand builds the 'snapshot' command, which manages checkpoints of the database
+func snapshotCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "snapshot",
+		Usage: "Create, list, and restore named copies of the database",
+		Commands: []*cli.Command{
+			{
+				Name:      "create",
+				Usage:     "Save a copy of the database under a name",
+				ArgsUsage: "<name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("a snapshot name is required")
+					}
+
+					return withDB(func(database *db.DB) error {
+						snap, err := database.CreateSnapshot(name)
+						if err != nil {
+							return err
+						}
+						fmt.Printf("✓ Created snapshot %q (%s)\n", snap.Name, snap.Path)
+						return nil
+					})
+				},
+			},
+			{
+				Name:  "list",
+				Usage: "List snapshots, oldest first",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					return withDB(func(database *db.DB) error {
+						snapshots, err := database.Snapshots()
+						if err != nil {
+							return err
+						}
+						if len(snapshots) == 0 {
+							fmt.Println("No snapshots")
+							return nil
+						}
+						for _, snap := range snapshots {
+							fmt.Printf("%s  %s  (%d bytes)\n", snap.Created.Local().Format("2006-01-02 15:04:05"), snap.Name, snap.Size)
+						}
+						return nil
+					})
+				},
+			},
+			{
+				Name:      "restore",
+				Usage:     "Replace the database with the newest snapshot of a name",
+				ArgsUsage: "<name>",
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					name := cmd.Args().First()
+					if name == "" {
+						return fmt.Errorf("a snapshot name is required")
+					}
+
+					return withWriteDB(func(database *db.DB) error {
+						// Find it first, since the snapshot below could share its name
+						snap, err := database.FindSnapshot(name)
+						if err != nil {
+							return err
+						}
+
+						// Keep what's being replaced so the restore can be undone
+						current, err := database.CreateSnapshot("pre-restore")
+						if err != nil {
+							return fmt.Errorf("failed to snapshot the current database: %w", err)
+						}
+
+						if err := database.RestoreSnapshot(snap); err != nil {
+							return err
+						}
+						fmt.Printf("✓ Restored snapshot %q from %s\n", snap.Name, snap.Created.Local().Format("2006-01-02 15:04:05"))
+						fmt.Printf("The previous contents were saved as snapshot %q\n", current.Name)
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// sqlCommand builds the 'sql' command, which runs a read-only SQL statement
+func sqlCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "sql",
+		Usage:     "Run a read-only SQL statement against the database, for analyses search can't express",
+		ArgsUsage: "<statement>",
+		Description: `Only statements that read are allowed, like SELECT and WITH. To see the
+tables, run: amem sql "SELECT name, sql FROM sqlite_master WHERE type = 'table'"`,
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the columns and rows as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			statement := strings.Join(cmd.Args().Slice(), " ")
+			if strings.TrimSpace(statement) == "" {
+				return fmt.Errorf("usage: amem sql <statement>")
+			}
+
+			return withDB(func(database *db.DB) error {
+				result, err := database.ReadOnlyQuery(ctx, statement)
+				if err != nil {
+					return err
+				}
+
+				if cmd.Bool("json") {
+					data, err := json.MarshalIndent(result, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal rows: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+				printTable(os.Stdout, result)
+				return nil
+			})
+		},
+	}
+}
+
+// printTable writes a query result as aligned columns under a header, followed by the row count.
+func printTable(w io.Writer, result *db.QueryResult) {
+	cells := make([][]string, 0, len(result.Rows)+1)
+	cells = append(cells, result.Columns)
+	for _, row := range result.Rows {
+		line := make([]string, len(row))
+		for i, v := range row {
+			if v == nil {
+				line[i] = "NULL"
+			} else {
+				// Newlines would break the table's rows apart
+				line[i] = strings.ReplaceAll(fmt.Sprint(v), "\n", `\n`)
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	widths := make([]int, len(result.Columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	writeLine := func(line []string) {
+		padded := make([]string, len(line))
+		for i, cell := range line {
+			padded[i] = cell + strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell))
+		}
+		_, _ = fmt.Fprintln(w, strings.TrimRight(strings.Join(padded, " | "), " "))
+	}
+
+	writeLine(cells[0])
+	rule := make([]string, len(widths))
+	for i, width := range widths {
+		rule[i] = strings.Repeat("-", width)
+	}
+	_, _ = fmt.Fprintln(w, strings.Join(rule, "-+-"))
+	for _, line := range cells[1:] {
+		writeLine(line)
+	}
+
+	if len(result.Rows) == 1 {
+		_, _ = fmt.Fprintln(w, "(1 row)")
+	} else {
+		_, _ = fmt.Fprintf(w, "(%d rows)\n", len(result.Rows))
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"amem/config"
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// statsCommand builds the 'stats' command, which reports on how the memory store is used
+func statsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "stats",
+		Usage: "Report on how memories are used",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "by",
+				Usage: "Count observations and relationships added per day, week, month, or year",
+				Value: "month",
+				Local: true,
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the counts as JSON",
+				Local: true,
+			},
+			&cli.BoolFlag{
+				Name:  "prometheus",
+				Usage: "Print the store's size and record counts in Prometheus exposition format, e.g. for node_exporter's textfile collector",
+				Local: true,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Bool("prometheus") {
+				if cmd.Bool("json") {
+					return fmt.Errorf("use only one of --json and --prometheus")
+				}
+				return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+					m, err := database.Metrics()
+					if err != nil {
+						return err
+					}
+					fmt.Print(prometheusMetrics(cfg.DBPath, m))
+					return nil
+				})
+			}
+
+			return withDB(func(database *db.DB) error {
+				periods, err := database.Growth(cmd.String("by"))
+				if err != nil {
+					return err
+				}
+
+				if cmd.Bool("json") {
+					data, err := json.MarshalIndent(periods, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal stats: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+
+				printGrowth(periods)
+				return nil
+			})
+		},
+		Commands: []*cli.Command{
+			{
+				Name:  "retrieval",
+				Usage: "Show the most and least retrieved observations and searches that found nothing (needs \"record_retrievals\": true in the config)",
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:  "limit",
+						Usage: "Observations and queries to list in each ranking",
+						Value: 10,
+					},
+					&cli.BoolFlag{
+						Name:  "json",
+						Usage: "Print the stats as JSON",
+					},
+					&cli.BoolFlag{
+						Name:  "reset",
+						Usage: "Delete the recorded searches instead",
+					},
+				},
+				Action: func(ctx context.Context, cmd *cli.Command) error {
+					limit := int(cmd.Int("limit"))
+					if limit < 1 {
+						return fmt.Errorf("--limit must be at least 1")
+					}
+
+					return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+						if cmd.Bool("reset") {
+							if err := database.Locked(database.ClearRetrievals); err != nil {
+								return err
+							}
+							fmt.Println("✓ Cleared retrieval stats")
+							return nil
+						}
+
+						stats, err := database.RetrievalStats(limit)
+						if err != nil {
+							return err
+						}
+
+						if cmd.Bool("json") {
+							data, err := json.MarshalIndent(stats, "", "  ")
+							if err != nil {
+								return fmt.Errorf("failed to marshal stats: %w", err)
+							}
+							fmt.Println(string(data))
+							return nil
+						}
+
+						if !cfg.RecordRetrievals && stats.Searches == 0 {
+							fmt.Println("No searches recorded. Set \"record_retrievals\": true in the config to record them.")
+							return nil
+						}
+						printRetrievalStats(stats)
+						return nil
+					})
+				},
+			},
+		},
+	}
+}
+
+// prometheusMetrics formats m in the Prometheus text exposition format, labelled with the
+// database's path so stores from several projects can be told apart
+func prometheusMetrics(dbPath string, m *db.Metrics) string {
+	label := fmt.Sprintf(`{db="%s"}`, strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(dbPath))
+
+	var b strings.Builder
+	gauge := func(name, help string, value any) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s%s %v\n", name, help, name, name, label, value)
+	}
+	gauge("amem_entities", "Number of entities.", m.Entities)
+	gauge("amem_observations", "Number of observations, including archived ones.", m.Observations)
+	gauge("amem_archived_observations", "Number of archived observations.", m.ArchivedObservations)
+	gauge("amem_relationships", "Number of relationships.", m.Relationships)
+	gauge("amem_database_used_bytes", "Size of the database excluding free pages.", m.UsedBytes)
+	if !m.LastChange.IsZero() {
+		gauge("amem_last_change_timestamp_seconds", "When a record was last created or updated, as a Unix time.", m.LastChange.Unix())
+	}
+	return b.String()
+}
+
+// printGrowth prints how many observations and relationships were added in each period
+func printGrowth(periods []db.GrowthPeriod) {
+	if len(periods) == 0 {
+		fmt.Println("No observations or relationships yet")
+		return
+	}
+	fmt.Printf("%-10s %12s %13s\n", "Period", "Observations", "Relationships")
+	for _, p := range periods {
+		fmt.Printf("%-10s %12d %13d\n", p.Period, p.Observations, p.Relationships)
+	}
+}
+
+// printRetrievalStats prints retrieval stats for people
+func printRetrievalStats(stats *db.RetrievalStats) {
+	fmt.Printf("Searches: %d\n", stats.Searches)
+	fmt.Printf("Zero-result searches: %d (%.1f%%)\n", stats.ZeroResults, 100*stats.ZeroResultRate())
+
+	printRanking := func(title string, observations []db.RetrievedObservation) {
+		fmt.Printf("\n%s:\n", title)
+		if len(observations) == 0 {
+			fmt.Println("  (none)")
+		}
+		for _, o := range observations {
+			fmt.Printf("  %4d× [%d] %s: %s\n", o.Retrieved, o.ID, o.Entity, o.Text)
+		}
+	}
+	printRanking("Most retrieved", stats.Most)
+	printRanking("Least retrieved", stats.Least)
+
+	fmt.Println("\nSearches that found nothing:")
+	if len(stats.ZeroResultQueries) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, q := range stats.ZeroResultQueries {
+		query := q.Query
+		if q.About != "" {
+			query = fmt.Sprintf("%s (about %s)", query, q.About)
+		}
+		fmt.Printf("  %4d× %s\n", q.Count, query)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"amem/db"
+	"amem/tools"
+	"github.com/urfave/cli/v3"
+)
+
+// toolResponse is what 'amem tool' prints: the tool's result, or the error it failed with
+type toolResponse struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// toolCommand builds the 'tool' command, a JSON interface to the same tools MCP serves
+func toolCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tool",
+		Usage: "Run JSON tool invocations read from stdin and print the results as JSON, for agent frameworks",
+		Description: `Reads a JSON object naming a tool as "op", with its arguments:
+
+  {"op": "search", "keywords": ["tea"], "match": "any"}
+  {"op": "add_observation", "entity": "Alice", "text": "Likes tea"}
+
+The tools and their arguments are those 'amem schema' prints, named with or
+without their "amem_" prefix. Prints {"result": ...} on success, or
+{"error": "..."} on failure.
+
+To run many invocations while opening the database only once, give a JSON
+array of them, which prints an array of results in the same order, or one
+per line (NDJSON), which prints each result on a line as soon as it's ready,
+so the pipe can be kept open. A failed invocation doesn't stop the rest.
+Exits with status 1 if any failed.`,
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if stdinReader == nil {
+				stdinReader = bufio.NewReader(os.Stdin)
+			}
+
+			return withDB(func(database *db.DB) error {
+				failed, total, err := runToolInvocations(database, stdinReader, os.Stdout)
+				if err != nil {
+					return err
+				}
+				if failed == 1 && total == 1 {
+					return fmt.Errorf("tool invocation failed")
+				}
+				if failed > 0 {
+					return fmt.Errorf("%d of %d tool invocations failed", failed, total)
+				}
+				return nil
+			})
+		},
+	}
+}
+
+// runToolInvocations runs the invocations read from r, a JSON array of them or a stream of
+// them, writing a toolResponse for each to w, and returns how many failed out of how many.
+// An array's responses are written as an array once all have run; a stream's, one per line as each does.
+func runToolInvocations(database *db.DB, r *bufio.Reader, w io.Writer) (failed, total int, err error) {
+	respond := func(invocation json.RawMessage) toolResponse {
+		total++
+		result, err := tools.Invoke(database, invocation)
+		if err != nil {
+			failed++
+			return toolResponse{Error: err.Error()}
+		}
+		return toolResponse{Result: result}
+	}
+
+	decoder := json.NewDecoder(r)
+	encoder := json.NewEncoder(w)
+	if startsArray(r) {
+		var invocations []json.RawMessage
+		if err := decoder.Decode(&invocations); err != nil {
+			return 0, 0, fmt.Errorf("invalid invocations: %w", err)
+		}
+		responses := make([]toolResponse, len(invocations))
+		for i, invocation := range invocations {
+			responses[i] = respond(invocation)
+		}
+		return failed, total, encoder.Encode(responses)
+	}
+
+	for {
+		var invocation json.RawMessage
+		if err := decoder.Decode(&invocation); err == io.EOF {
+			break
+		} else if err != nil {
+			// The stream can't be read past invalid JSON
+			_ = encoder.Encode(toolResponse{Error: fmt.Sprintf("invalid invocation: %v", err)})
+			return failed + 1, total + 1, nil
+		}
+		if err := encoder.Encode(respond(invocation)); err != nil {
+			return failed, total, fmt.Errorf("failed to write result: %w", err)
+		}
+	}
+	if total == 0 {
+		return 0, 0, fmt.Errorf("no tool invocation on stdin")
+	}
+	return failed, total, nil
+}
+
+// startsArray reports whether the next character from r, after any whitespace, starts a JSON array
+func startsArray(r *bufio.Reader) bool {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return false
+		}
+		if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			_ = r.UnreadByte()
+			return c == '['
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"amem/db"
+)
+
+// args holds the union of all tool parameters.
+type args struct {
+	Names      []string `json:"names"`
+	Entity     string   `json:"entity"`
+	Text       string   `json:"text"`
+	Importance int      `json:"importance"`
+	Lang       string   `json:"lang"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	Type       string   `json:"type"`
+	About      string   `json:"about"`
+	Keywords   []string `json:"keywords"`
+	Match      string   `json:"match"`
+}
+
+// SearchResult is returned by the search tools. Empty sections are omitted.
+type SearchResult struct {
+	Entities      []db.Entity       `json:"entities,omitempty"`
+	Observations  []db.Observation  `json:"observations,omitempty"`
+	Relationships []db.Relationship `json:"relationships,omitempty"`
+}
+
+// AddResult is returned by the add tools.
+type AddResult struct {
+	IDs []int64 `json:"ids"`
+}
+
+// Call runs the named tool against database with JSON-encoded arguments.
+// The result is ready to be marshaled to JSON.
+func Call(database *db.DB, name string, rawArgs json.RawMessage) (interface{}, error) {
+	tool, ok := Find(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown tool '%s'", name)
+	}
+
+	if len(rawArgs) == 0 {
+		rawArgs = json.RawMessage("{}")
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(rawArgs, &present); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	for _, required := range tool.Parameters.Required {
+		if _, ok := present[required]; !ok {
+			return nil, fmt.Errorf("missing required argument '%s'", required)
+		}
+	}
+	var a args
+	if err := json.Unmarshal(rawArgs, &a); err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+
+	useUnion := true
+	switch a.Match {
+	case "", "any":
+	case "all":
+		useUnion = false
+	default:
+		return nil, fmt.Errorf("invalid match '%s' (use any or all)", a.Match)
+	}
+
+	if !tool.Write {
+		return call(database, name, a, useUnion)
+	}
+
+	// Hold the write lock so concurrent callers and other amem processes can't interleave writes
+	var result interface{}
+	err := database.Locked(func() error {
+		var err error
+		result, err = call(database, name, a, useUnion)
+		return err
+	})
+	return result, err
+}
+
+// Invoke runs a tool invocation in the form 'amem tool' reads: a JSON object with the tool's
+// name as "op", with or without its "amem_" prefix, alongside its arguments, like
+// {"op": "search", "keywords": ["tea"]}.
+func Invoke(database *db.DB, invocation json.RawMessage) (interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(invocation, &fields); err != nil {
+		return nil, fmt.Errorf("invalid invocation: %w", err)
+	}
+	var op string
+	if err := json.Unmarshal(fields["op"], &op); err != nil || op == "" {
+		return nil, fmt.Errorf("invocation needs an \"op\" naming the tool")
+	}
+	delete(fields, "op")
+
+	rawArgs, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid arguments: %w", err)
+	}
+	if !strings.HasPrefix(op, "amem_") {
+		op = "amem_" + op
+	}
+	return Call(database, op, rawArgs)
+}
+
+// call dispatches a tool call with validated arguments.
+func call(database *db.DB, name string, a args, useUnion bool) (interface{}, error) {
+	switch name {
+	case "amem_add_entities":
+		if len(a.Names) == 0 {
+			return nil, fmt.Errorf("at least one entity name is required")
+		}
+		ids, err := database.AddEntities(a.Names)
+		if err != nil {
+			return nil, err
+		}
+		return AddResult{IDs: ids}, nil
+
+	case "amem_add_observation":
+		id, err := database.AddObservationWithOptions(a.Entity, a.Text, db.ObservationOptions{Importance: a.Importance, Lang: a.Lang})
+		if err != nil {
+			return nil, err
+		}
+		return AddResult{IDs: []int64{id}}, nil
+
+	case "amem_add_relationship":
+		id, err := database.AddRelationship(a.From, a.To, a.Type)
+		if err != nil {
+			return nil, err
+		}
+		return AddResult{IDs: []int64{id}}, nil
+
+	case "amem_search":
+		entities, observations, relationships, err := database.SearchAll(a.Keywords, useUnion)
+		if err != nil {
+			return nil, err
+		}
+		return SearchResult{Entities: entities, Observations: observations, Relationships: relationships}, markAccessed(database, "", a.Keywords, observations)
+
+	case "amem_search_entities":
+		entities, err := database.SearchEntities(a.Keywords, useUnion)
+		if err != nil {
+			return nil, err
+		}
+		return SearchResult{Entities: entities}, nil
+
+	case "amem_search_observations":
+		observations, err := database.SearchObservations(a.About, a.Keywords, useUnion)
+		if err != nil {
+			return nil, err
+		}
+		return SearchResult{Observations: observations}, markAccessed(database, a.About, a.Keywords, observations)
+
+	case "amem_search_relationships":
+		relationships, err := database.SearchRelationships(a.From, a.To, a.Type, a.Keywords, useUnion)
+		if err != nil {
+			return nil, err
+		}
+		return SearchResult{Relationships: relationships}, nil
+	}
+
+	return nil, fmt.Errorf("tool '%s' is not implemented", name)
+}
+
+// markAccessed records that observations were returned, for the least-accessed quota policy and retrieval stats.
+// Searches don't hold the write lock, so it takes it for the write.
+func markAccessed(database *db.DB, about string, keywords []string, observations []db.Observation) error {
+	ids := make([]int64, len(observations))
+	for i, o := range observations {
+		ids[i] = o.ID
+	}
+	return database.Locked(func() error { return database.Retrieved(about, keywords, ids) })
+}
@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"amem/db"
+)
+
+func TestCall(t *testing.T) {
+	database, err := db.Init(t.TempDir()+"/test_tools.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if _, err := Call(database, "amem_add_relationship", json.RawMessage(`{"from":"Alice","to":"Bob","type":"knows"}`)); err != nil {
+		t.Fatalf("add_relationship failed: %v", err)
+	}
+
+	result, err := Call(database, "amem_search_entities", json.RawMessage(`{"keywords":["Alice","Bob"],"match":"all"}`))
+	if err != nil {
+		t.Fatalf("search_entities failed: %v", err)
+	}
+	if n := len(result.(SearchResult).Entities); n != 0 {
+		t.Errorf("Expected no entity matching both keywords, got %d", n)
+	}
+
+	result, err = Call(database, "amem_search_entities", json.RawMessage(`{"keywords":["Alice","Bob"]}`))
+	if err != nil {
+		t.Fatalf("search_entities failed: %v", err)
+	}
+	if n := len(result.(SearchResult).Entities); n != 2 {
+		t.Errorf("Expected 2 entities matching any keyword, got %d", n)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	database, err := db.Init(t.TempDir()+"/test_tools_errors.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	tests := []struct {
+		name string
+		tool string
+		args string
+	}{
+		{"unknown tool", "amem_drop_tables", `{}`},
+		{"missing required", "amem_add_relationship", `{"from":"Alice","to":"Bob"}`},
+		{"invalid match", "amem_search", `{"match":"some"}`},
+		{"invalid JSON", "amem_search", `[1,2]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Call(database, tt.tool, json.RawMessage(tt.args)); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	database, err := db.Init(t.TempDir()+"/test_tools_invoke.db", "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if _, err := Invoke(database, json.RawMessage(`{"op":"add_observation","entity":"Alice","text":"Likes tea"}`)); err != nil {
+		t.Fatalf("add_observation failed: %v", err)
+	}
+	result, err := Invoke(database, json.RawMessage(`{"op":"amem_search_observations","keywords":["tea"]}`))
+	if err != nil {
+		t.Fatalf("search_observations failed: %v", err)
+	}
+	if n := len(result.(SearchResult).Observations); n != 1 {
+		t.Errorf("Expected 1 observation, got %d", n)
+	}
+
+	for _, invocation := range []string{`{"keywords":["tea"]}`, `{"op":"forget"}`, `{"op":"add_observation","entity":"Alice"}`, `[1]`} {
+		if _, err := Invoke(database, json.RawMessage(invocation)); err == nil {
+			t.Errorf("Expected %s to fail", invocation)
+		}
+	}
+}
+
+func TestSearchMarksAccessUnderLock(t *testing.T) {
+	path := t.TempDir() + "/test_tools_lock.db"
+	database, err := db.Init(path, "testkey123456789012")
+	if err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	_, _ = database.AddObservation("Alice", "Likes tea")
+	_ = database.Close()
+	database, err = db.OpenWithOptions(path, "testkey123456789012", db.Options{LockTimeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	// Searches record what they returned, a write that must wait for other writers like any other
+	if err := os.WriteFile(db.LockPath(path), []byte(strconv.Itoa(os.Getppid())), 0o600); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	_, err = Call(database, "amem_search_observations", json.RawMessage(`{"keywords":["tea"]}`))
+	var locked *db.LockedError
+	if !errors.As(err, &locked) {
+		t.Errorf("Expected the search to wait for the write lock, got %v", err)
+	}
+
+	_ = os.Remove(db.LockPath(path))
+	if _, err := Call(database, "amem_search_observations", json.RawMessage(`{"keywords":["tea"]}`)); err != nil {
+		t.Errorf("Search failed: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package tools
+
+import "fmt"
+
+// Schema is the subset of JSON Schema used to describe tool parameters.
+type Schema struct {
+	Type        string             `json:"type"`
+	Description string             `json:"description,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+}
+
+// Tool describes an amem operation an agent can call.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  *Schema
+	// Write is true for tools that modify the database
+	Write bool
+}
+
+// Formats accepted by Definitions.
+const (
+	FormatOpenAI    = "openai"
+	FormatAnthropic = "anthropic"
+)
+
+func str(description string) *Schema {
+	return &Schema{Type: "string", Description: description}
+}
+
+func strList(description string) *Schema {
+	return &Schema{Type: "array", Description: description, Items: &Schema{Type: "string"}}
+}
+
+func match() *Schema {
+	return &Schema{
+		Type:        "string",
+		Description: "Match any keyword (OR logic, default) or all keywords (AND logic)",
+		Enum:        []string{"any", "all"},
+	}
+}
+
+// All lists every tool, mirroring the 'add' and 'search' commands.
+var All = []Tool{
+	{
+		Name:        "amem_add_entities",
+		Write:       true,
+		Description: "Add one or more entities (people, places, things, etc.) to memory. Existing entities are left unchanged, and if any can't be added, none are.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"names": strList("Entity names"),
+			},
+			Required: []string{"names"},
+		},
+	},
+	{
+		Name:        "amem_add_observation",
+		Write:       true,
+		Description: "Add an observation (a note) about an entity. Creates the entity if it doesn't exist.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"entity":     str("Entity the observation is about"),
+				"text":       str("Observation text"),
+				"importance": {Type: "integer", Description: "Importance of the observation (higher is kept longer under a quota)"},
+				"lang":       str("Language the observation is written in, as an ISO 639-1 code like de, or auto to detect it"),
+			},
+			Required: []string{"entity", "text"},
+		},
+	},
+	{
+		Name:        "amem_add_relationship",
+		Write:       true,
+		Description: "Add a relationship between two entities. Creates the entities if they don't exist.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"from": str("Source entity"),
+				"to":   str("Target entity"),
+				"type": str("Relationship type"),
+			},
+			Required: []string{"from", "to", "type"},
+		},
+	},
+	{
+		Name:        "amem_search",
+		Description: "Search entities, observations, and relationships for mentions of keywords.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"keywords": strList("Keywords to search for"),
+				"match":    match(),
+			},
+		},
+	},
+	{
+		Name:        "amem_search_entities",
+		Description: "Search only entities.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"keywords": strList("Keywords to search for"),
+				"match":    match(),
+			},
+		},
+	},
+	{
+		Name:        "amem_search_observations",
+		Description: "Search observations, optionally only those about an entity.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"about":    str("Search for observations about an entity"),
+				"keywords": strList("Keywords to search for"),
+				"match":    match(),
+			},
+		},
+	},
+	{
+		Name:        "amem_search_relationships",
+		Description: "Search relationships, optionally filtered by source, target, or type.",
+		Parameters: &Schema{
+			Type: "object",
+			Properties: map[string]*Schema{
+				"from":     str("Search for relationships from an entity"),
+				"to":       str("Search for relationships to an entity"),
+				"type":     str("Search for relationships of a specific type"),
+				"keywords": strList("Keywords to search for"),
+				"match":    match(),
+			},
+		},
+	},
+}
+
+// Find returns the tool with the given name.
+func Find(name string) (*Tool, bool) {
+	for i := range All {
+		if All[i].Name == name {
+			return &All[i], true
+		}
+	}
+	return nil, false
+}
+
+// Definitions returns the tool definitions in the given provider format,
+// ready to be marshaled to JSON.
+func Definitions(format string) ([]map[string]interface{}, error) {
+	defs := make([]map[string]interface{}, 0, len(All))
+	for _, t := range All {
+		switch format {
+		case FormatOpenAI:
+			defs = append(defs, map[string]interface{}{
+				"type": "function",
+				"function": map[string]interface{}{
+					"name":        t.Name,
+					"description": t.Description,
+					"parameters":  t.Parameters,
+				},
+			})
+		case FormatAnthropic:
+			defs = append(defs, map[string]interface{}{
+				"name":         t.Name,
+				"description":  t.Description,
+				"input_schema": t.Parameters,
+			})
+		default:
+			return nil, fmt.Errorf("unknown format '%s' (use %s or %s)", format, FormatOpenAI, FormatAnthropic)
+		}
+	}
+	return defs, nil
+}
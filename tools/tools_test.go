@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestDefinitionsOpenAI(t *testing.T) {
+	defs, err := Definitions(FormatOpenAI)
+	if err != nil {
+		t.Fatalf("Definitions failed: %v", err)
+	}
+	if len(defs) != len(All) {
+		t.Fatalf("Expected %d definitions, got %d", len(All), len(defs))
+	}
+
+	data, err := json.Marshal(defs)
+	if err != nil {
+		t.Fatalf("Failed to marshal: %v", err)
+	}
+
+	var parsed []struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name       string `json:"name"`
+			Parameters struct {
+				Type string `json:"type"`
+			} `json:"parameters"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("Failed to unmarshal: %v", err)
+	}
+
+	// OpenAI restricts function names to this pattern
+	validName := regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+	for _, d := range parsed {
+		if d.Type != "function" {
+			t.Errorf("Expected type function, got %s", d.Type)
+		}
+		if !validName.MatchString(d.Function.Name) {
+			t.Errorf("Invalid function name %q", d.Function.Name)
+		}
+		if d.Function.Parameters.Type != "object" {
+			t.Errorf("Expected object parameters for %s", d.Function.Name)
+		}
+	}
+}
+
+func TestDefinitionsAnthropic(t *testing.T) {
+	defs, err := Definitions(FormatAnthropic)
+	if err != nil {
+		t.Fatalf("Definitions failed: %v", err)
+	}
+	for _, d := range defs {
+		if _, ok := d["input_schema"]; !ok {
+			t.Errorf("Expected input_schema in %v", d["name"])
+		}
+	}
+}
+
+func TestDefinitionsUnknownFormat(t *testing.T) {
+	if _, err := Definitions("gemini"); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
+
+func TestRequiredParametersExist(t *testing.T) {
+	for _, tool := range All {
+		for _, name := range tool.Parameters.Required {
+			if _, ok := tool.Parameters.Properties[name]; !ok {
+				t.Errorf("%s requires undefined parameter %q", tool.Name, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"amem/config"
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// topicsCommand builds the 'topics' command, which gives an overview of what the store is about
+func topicsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "topics",
+		Usage: "Group observations into topics by meaning and show a few from each (needs 'amem index --embeddings')",
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:  "count",
+				Usage: "Topics to find (default: based on how many observations there are)",
+			},
+			&cli.IntFlag{
+				Name:  "samples",
+				Usage: "Observations to show from each topic, those closest to its centre",
+				Value: 3,
+			},
+			&cli.BoolFlag{
+				Name:  "with-ids",
+				Usage: "Include IDs in output",
+			},
+			&cli.BoolFlag{
+				Name:  "include-archived",
+				Usage: "Include archived observations",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the topics as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			count, samples := int(cmd.Int("count")), int(cmd.Int("samples"))
+			if count < 0 {
+				return fmt.Errorf("--count can't be negative")
+			}
+			if samples < 1 {
+				return fmt.Errorf("--samples must be at least 1")
+			}
+
+			return withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+				embedder, err := newEmbedder(cfg, database)
+				if err != nil {
+					return err
+				}
+				database.SetIncludeArchived(cmd.Bool("include-archived"))
+				topics, err := database.Topics(embedder.Model(), count, samples)
+				if err != nil {
+					return err
+				}
+
+				if cmd.Bool("json") {
+					data, err := json.MarshalIndent(topics, "", "  ")
+					if err != nil {
+						return fmt.Errorf("failed to marshal topics: %w", err)
+					}
+					fmt.Println(string(data))
+					return nil
+				}
+
+				if len(topics) == 0 {
+					fmt.Printf("No observations are embedded with %s: run 'amem index --embeddings'\n", embedder.Model())
+					return nil
+				}
+				view.FormatTopics(topics, cmd.Bool("with-ids"))
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// touchCommand builds the 'touch' command, which makes still-true observations recent again
+func touchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "touch",
+		Usage: "Mark observations as updated now without changing them, so they rank as recent again",
+		Flags: []cli.Flag{
+			&cli.IntSliceFlag{
+				Name:     "ids",
+				Usage:    "Observation IDs",
+				Required: true,
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			ids := make([]int64, len(cmd.IntSlice("ids")))
+			for i, id := range cmd.IntSlice("ids") {
+				ids[i] = int64(id)
+			}
+
+			return withWriteDB(func(database *db.DB) error {
+				n, err := database.Touch(ids)
+				if err != nil {
+					return err
+				}
+				fmt.Printf("Touched %d observations\n", n)
+				return nil
+			})
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"amem/db"
+	"amem/view"
+	"github.com/urfave/cli/v3"
+)
+
+// undoCommand builds the 'undo' command, which reverts the most recent change to the database
+func undoCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "undo",
+		Usage: "Revert the most recent add, edit, or delete",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "dry-run",
+				Usage: "Show what would be reverted without changing anything",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if cmd.Bool("dry-run") {
+				return withDB(func(database *db.DB) error {
+					batch, err := database.LastBatch()
+					if errors.Is(err, db.ErrNothingToUndo) {
+						fmt.Println("Nothing to undo")
+						return nil
+					}
+					if err != nil {
+						return err
+					}
+					printBatch("Would revert", batch)
+					return nil
+				})
+			}
+
+			return withWriteDB(func(database *db.DB) error {
+				batch, err := database.Undo()
+				if errors.Is(err, db.ErrNothingToUndo) {
+					fmt.Println("Nothing to undo")
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				printBatch("Reverted", batch)
+				return nil
+			})
+		},
+	}
+}
+
+// printBatch lists the changes in a history batch
+func printBatch(verb string, batch *db.Batch) {
+	fmt.Printf("%s %d changes from %s:\n", verb, len(batch.Changes), view.Time(batch.Timestamp))
+	for _, c := range batch.Changes {
+		fmt.Printf("  %s\n", c.Description)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+
+	"amem/config"
+	"amem/db"
+	"github.com/urfave/cli/v3"
+)
+
+// commit and buildDate can be set at build time with -ldflags "-X main.commit=... -X main.buildDate=...";
+// otherwise they come from the version control info Go stamps into the binary.
+var (
+	commit    = ""
+	buildDate = ""
+)
+
+// versionInfo is what 'amem version --json' prints, for bug reports and compatibility checks
+type versionInfo struct {
+	Version            string `json:"version"`
+	Commit             string `json:"commit,omitempty"`
+	BuildDate          string `json:"build_date,omitempty"`
+	GoVersion          string `json:"go_version"`
+	Platform           string `json:"platform"`
+	Driver             string `json:"driver,omitempty"`
+	SQLiteVersion      string `json:"sqlite_version,omitempty"`
+	SQLCipherVersion   string `json:"sqlcipher_version,omitempty"`
+	LatestSchema       int    `json:"latest_schema_version"`
+	Database           string `json:"database,omitempty"`
+	DatabaseSchema     int    `json:"database_schema_version,omitempty"`
+	DatabaseError      string `json:"database_error,omitempty"`
+	EngineVersionError string `json:"engine_version_error,omitempty"`
+}
+
+// versionCommand builds the 'version' command
+func versionCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "version",
+		Usage: "Display the version",
+		Flags: []cli.Flag{
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "Print the version with build, driver, and schema details as JSON",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			if !cmd.Bool("json") {
+				fmt.Println(version)
+				return nil
+			}
+
+			data, err := json.MarshalIndent(buildVersionInfo(), "", "  ")
+			if err != nil {
+				return fmt.Errorf("failed to marshal version: %w", err)
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+}
+
+// buildVersionInfo gathers version details. Problems opening the configured database
+// are reported in the result rather than failing, since it's most wanted when things are broken.
+func buildVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:      version,
+		Commit:       commit,
+		BuildDate:    buildDate,
+		GoVersion:    runtime.Version(),
+		Platform:     runtime.GOOS + "/" + runtime.GOARCH,
+		LatestSchema: db.LatestSchemaVersion(),
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range build.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = setting.Value
+			case setting.Key == "vcs.time" && info.BuildDate == "":
+				info.BuildDate = setting.Value
+			}
+		}
+		for _, dep := range build.Deps {
+			if dep.Path == "github.com/mutecomm/go-sqlcipher/v4" {
+				info.Driver = dep.Path + " " + dep.Version
+			}
+		}
+	}
+
+	var err error
+	if info.SQLiteVersion, info.SQLCipherVersion, err = db.EngineVersions(); err != nil {
+		info.EngineVersionError = err.Error()
+	}
+
+	err = withConfigDB(func(cfg *config.LoadedConfig, database *db.DB) error {
+		info.Database = cfg.DBPath
+		info.DatabaseSchema, err = database.SchemaVersion()
+		return err
+	})
+	if err != nil {
+		info.DatabaseError = err.Error()
+	}
+	return info
+}
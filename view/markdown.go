@@ -0,0 +1,70 @@
+package view
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"amem/db"
+)
+
+// Markdown renders memories as a markdown digest to paste into a prompt or a wiki:
+// each entity as a section with its observations as bullets, then the relationships
+// as a list. Entities only mentioned by an observation get a section too.
+func Markdown(title string, entities []db.Entity, observations []db.Observation, relationships []db.Relationship) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n", title)
+
+	if len(entities) == 0 && len(observations) == 0 && len(relationships) == 0 {
+		b.WriteString("\nNothing remembered yet.\n")
+		return b.String()
+	}
+
+	byEntity := map[string][]db.Observation{}
+	names := map[string]bool{}
+	for _, e := range entities {
+		names[e.Text] = true
+	}
+	for _, o := range observations {
+		byEntity[o.EntityText] = append(byEntity[o.EntityText], o)
+		names[o.EntityText] = true
+	}
+
+	if len(names) > 0 {
+		b.WriteString("\n## Entities\n")
+		for _, name := range slices.Sorted(maps.Keys(names)) {
+			fmt.Fprintf(&b, "\n### %s\n", name)
+			obs := byEntity[name]
+			if len(obs) == 0 {
+				continue
+			}
+			b.WriteString("\n")
+			slices.SortFunc(obs, func(a, b db.Observation) int {
+				return cmp.Or(cmp.Compare(a.Timestamp, b.Timestamp), cmp.Compare(a.ID, b.ID))
+			})
+			for _, o := range obs {
+				fmt.Fprintf(&b, "- %s\n", bulletText(o.Text))
+			}
+		}
+	}
+
+	if len(relationships) > 0 {
+		rels := slices.Clone(relationships)
+		slices.SortFunc(rels, func(a, b db.Relationship) int {
+			return cmp.Or(cmp.Compare(a.FromText, b.FromText), cmp.Compare(a.Type, b.Type), cmp.Compare(a.ToText, b.ToText))
+		})
+		b.WriteString("\n## Relationships\n\n")
+		for _, r := range rels {
+			fmt.Fprintf(&b, "- %s *%s* %s\n", r.FromText, r.Type, r.ToText)
+		}
+	}
+
+	return b.String()
+}
+
+// bulletText indents the continuation lines of text so a multi-line observation stays in its bullet.
+func bulletText(text string) string {
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n  ")
+}
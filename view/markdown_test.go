@@ -0,0 +1,50 @@
+package view
+
+import (
+	"testing"
+
+	"amem/db"
+)
+
+func TestMarkdown(t *testing.T) {
+	entities := []db.Entity{{ID: 1, Text: "Bob"}, {ID: 2, Text: "Alice"}}
+	observations := []db.Observation{
+		{ID: 2, EntityText: "Alice", Text: "Drinks tea\nbut only green", Timestamp: "2024-01-16T10:00:00Z"},
+		{ID: 1, EntityText: "Alice", Text: "Drinks coffee", Timestamp: "2024-01-15T10:00:00Z"},
+		{ID: 3, EntityText: "Carol", Text: "Runs the cafe", Timestamp: "2024-01-15T10:00:00Z"},
+	}
+	relationships := []db.Relationship{
+		{FromText: "Bob", Type: "knows", ToText: "Carol"},
+		{FromText: "Alice", Type: "knows", ToText: "Bob"},
+	}
+
+	got := Markdown("Memories about coffee", entities, observations, relationships)
+	want := `# Memories about coffee
+
+## Entities
+
+### Alice
+
+- Drinks coffee
+- Drinks tea
+  but only green
+
+### Bob
+
+### Carol
+
+- Runs the cafe
+
+## Relationships
+
+- Alice *knows* Bob
+- Bob *knows* Carol
+`
+	if got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+
+	if got := Markdown("Memories", nil, nil, nil); got != "# Memories\n\nNothing remembered yet.\n" {
+		t.Errorf("Markdown() with no memories = %q", got)
+	}
+}
@@ -0,0 +1,52 @@
+package view
+
+import (
+	"strconv"
+	"strings"
+
+	"amem/db"
+)
+
+// porcelain is whether results print in the porcelain format. Set with SetPorcelain.
+var porcelain bool
+
+// SetPorcelain turns the porcelain format on or off. In it, there are no headers, blank
+// lines, or messages about empty results, just one tab-separated line per result:
+//
+//	entity        ID  text  created_at
+//	observation   ID  entity  text  timestamp
+//	relationship  ID  from  type  to  timestamp
+//
+// Results from several databases have the database as an extra last field. Tabs, newlines,
+// and backslashes in fields are escaped as \t, \n, and \\. IDs are always included, and
+// neither the fields nor their order will change, so scripts can rely on them; new fields
+// are only ever added at the end.
+func SetPorcelain(on bool) {
+	porcelain = on
+}
+
+// porcelainEscaper escapes the characters that would break a porcelain line apart.
+var porcelainEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// porcelainLine returns the porcelain line for result.
+func porcelainLine(result any) string {
+	var fields []string
+	switch r := result.(type) {
+	case db.Entity:
+		fields = []string{"entity", strconv.FormatInt(r.ID, 10), r.Text, r.CreatedAt}
+	case db.Observation:
+		fields = []string{"observation", strconv.FormatInt(r.ID, 10), r.EntityText, r.Text, r.Timestamp}
+	case db.Relationship:
+		fields = []string{"relationship", strconv.FormatInt(r.ID, 10), r.FromText, r.Type, r.ToText, r.Timestamp}
+	case Sourced[db.Entity]:
+		return porcelainLine(r.Result) + "\t" + porcelainEscaper.Replace(strings.TrimSpace(r.Source))
+	case Sourced[db.Observation]:
+		return porcelainLine(r.Result) + "\t" + porcelainEscaper.Replace(strings.TrimSpace(r.Source))
+	case Sourced[db.Relationship]:
+		return porcelainLine(r.Result) + "\t" + porcelainEscaper.Replace(strings.TrimSpace(r.Source))
+	}
+	for i, f := range fields {
+		fields[i] = porcelainEscaper.Replace(f)
+	}
+	return strings.Join(fields, "\t")
+}
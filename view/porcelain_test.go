@@ -0,0 +1,51 @@
+package view
+
+import (
+	"testing"
+
+	"amem/db"
+)
+
+func TestPorcelain(t *testing.T) {
+	defer SetPorcelain(false)
+	SetPorcelain(true)
+
+	output := captureOutput(func() {
+		FormatAll(
+			[]db.Entity{{ID: 1, Text: "Initech", CreatedAt: "2025-01-01"}},
+			[]db.Observation{{ID: 2, EntityText: "Initech", Text: "Moved\tbilling\nto Fly", Timestamp: "2025-01-02"}},
+			[]db.Relationship{{ID: 3, FromText: "Alice", Type: "works at", ToText: "Initech", Timestamp: "2025-01-03"}},
+			false,
+		)
+	})
+
+	expected := "entity\t1\tInitech\t2025-01-01\n" +
+		"observation\t2\tInitech\tMoved\\tbilling\\nto Fly\t2025-01-02\n" +
+		"relationship\t3\tAlice\tworks at\tInitech\t2025-01-03\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+
+	// No header or message for empty results
+	if output := captureOutput(func() { FormatObservations(nil, true) }); output != "" {
+		t.Errorf("Expected no output for no results, got %q", output)
+	}
+}
+
+func TestPorcelainSourced(t *testing.T) {
+	defer SetPorcelain(false)
+	SetPorcelain(true)
+
+	output := captureOutput(func() {
+		FormatAllSourced(
+			[]Sourced[db.Entity]{{Source: "global", Result: db.Entity{ID: 1, Text: "Initech"}}},
+			nil,
+			nil,
+			true,
+		)
+	})
+
+	if expected := "entity\t1\tInitech\t\tglobal\n"; output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
@@ -0,0 +1,41 @@
+package view
+
+import (
+	"fmt"
+
+	"amem/db"
+)
+
+// FormatRelated prints an observation's neighbourhood: the most similar observations, each
+// cut to the chunk that matched and scored by similarity, then the entities they're about.
+func FormatRelated(related *db.Related, withIDs bool) {
+	if len(related.Observations) == 0 {
+		fmt.Println("No related observations found")
+		return
+	}
+
+	fmt.Printf("Found %d related observations:\n", len(related.Observations))
+	for _, s := range related.Observations {
+		o := s.Observation
+		o.Text = ChunkSnippet(o.Text, s.Start, s.End)
+		fmt.Println(fit(fmt.Sprintf("%.2f  %s", s.Score, withTimes(o).Format(withIDs))))
+	}
+
+	if len(related.Entities) > 0 {
+		fmt.Printf("\nRelated entities:\n")
+		for _, e := range related.Entities {
+			fmt.Println(fit(fmt.Sprintf("%.2f  %s", e.Score, e.Entity)))
+		}
+	}
+}
+
+// FormatTopics prints each topic's size and its sample observations.
+func FormatTopics(topics []db.Topic, withIDs bool) {
+	fmt.Printf("Found %d topics:\n", len(topics))
+	for i, t := range topics {
+		fmt.Printf("\n%d. %d observations:\n", i+1, t.Size)
+		for _, o := range t.Samples {
+			fmt.Println(fit("  " + withTimes(o).Format(withIDs)))
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package view
+
+import (
+	"iter"
+	"strings"
+	"unicode/utf8"
+
+	"amem/db"
+)
+
+// ellipsis marks where Snippet cut text.
+const ellipsis = "…"
+
+// Snippet returns about window characters of text centred on the first keyword in it, with
+// ellipses where it was cut. Text no longer than window, or any text if window isn't positive,
+// is returned whole. If no keyword is in text, the snippet is its start.
+func Snippet(text string, keywords []string, window int) string {
+	runes := []rune(text)
+	if window <= 0 || len(runes) <= window {
+		return text
+	}
+
+	start := 0
+	// strings.ToLower maps rune by rune, so rune offsets in lower are offsets in text
+	lower := strings.ToLower(text)
+	for _, keyword := range keywords {
+		i := strings.Index(lower, strings.ToLower(keyword))
+		if keyword == "" || i < 0 {
+			continue
+		}
+		match := utf8.RuneCountInString(keyword)
+		start = utf8.RuneCountInString(lower[:i]) - (window-match)/2
+		break
+	}
+	start = max(0, min(start, len(runes)-window))
+	end := start + window
+
+	snippet := strings.TrimSpace(string(runes[start:end]))
+	if start > 0 {
+		snippet = ellipsis + snippet
+	}
+	if end < len(runes) {
+		snippet += ellipsis
+	}
+	return snippet
+}
+
+// Snippets passes observations through from seq with their text cut to Snippet's.
+func Snippets(seq iter.Seq2[db.Observation, error], keywords []string, window int) iter.Seq2[db.Observation, error] {
+	return func(yield func(db.Observation, error) bool) {
+		for o, err := range seq {
+			o.Text = Snippet(o.Text, keywords, window)
+			if !yield(o, err) {
+				return
+			}
+		}
+	}
+}
+
+// ChunkSnippet returns text[start:end], the chunk of a long observation that a vector search
+// matched, with ellipses where the rest was left out. If the offsets don't fit text, as when
+// it was edited after it was embedded, text is returned whole.
+func ChunkSnippet(text string, start, end int) string {
+	if start < 0 || end > len(text) || start >= end || !utf8.ValidString(text[start:end]) {
+		return text
+	}
+
+	snippet := strings.TrimSpace(text[start:end])
+	if strings.TrimSpace(text[:start]) != "" {
+		snippet = ellipsis + snippet
+	}
+	if strings.TrimSpace(text[end:]) != "" {
+		snippet += ellipsis
+	}
+	return snippet
+}
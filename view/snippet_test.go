@@ -0,0 +1,59 @@
+package view
+
+import "testing"
+
+func TestSnippet(t *testing.T) {
+	text := "The team moved the billing service from Heroku to Fly in March after the outage"
+
+	tests := []struct {
+		name     string
+		keywords []string
+		window   int
+		want     string
+	}{
+		{"no window", []string{"Fly"}, 0, text},
+		{"short text", []string{"Fly"}, 200, text},
+		{"around keyword", []string{"heroku"}, 20, "…e from Heroku to Fly…"},
+		{"first keyword found", []string{"nowhere", "outage"}, 20, "…rch after the outage"},
+		{"no keyword found", []string{"nowhere"}, 12, "The team mov…"},
+		{"at start", []string{"team"}, 12, "The team mov…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Snippet(text, tt.keywords, tt.window); got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnippetMultibyte(t *testing.T) {
+	text := "Ærlig talt liker Åse kaffe mer enn te om morgenen"
+	if got := Snippet(text, []string{"åse"}, 10); got != "…er Åse kaf…" {
+		t.Errorf("Snippet() = %q", got)
+	}
+}
+
+func TestChunkSnippet(t *testing.T) {
+	text := "Likes tea. Drives a truck. Lives in Oslo"
+
+	tests := []struct {
+		name       string
+		start, end int
+		want       string
+	}{
+		{"whole", 0, len(text), text},
+		{"start", 0, 10, "Likes tea.…"},
+		{"middle", 11, 26, "…Drives a truck.…"},
+		{"end", 27, len(text), "…Lives in Oslo"},
+		{"out of range", 27, 100, text},
+		{"empty", 5, 5, text},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ChunkSnippet(text, tt.start, tt.end); got != tt.want {
+				t.Errorf("ChunkSnippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package view
+
+import (
+	"fmt"
+	"time"
+
+	"amem/db"
+)
+
+// timeLayout is how the database stores timestamps, always in UTC.
+const timeLayout = time.DateTime
+
+// storedLayouts are the layouts a stored timestamp may be in: the database's own, and
+// RFC 3339 for ones imported from elsewhere.
+var storedLayouts = []string{timeLayout, "2006-01-02 15:04:05.999999999", time.RFC3339Nano}
+
+// location is the time zone timestamps are shown in and relativeTimes is whether recent
+// ones are shown as how long ago they were. Both are set with SetTimes.
+var (
+	location      = time.Local
+	relativeTimes bool
+)
+
+// now is the current time, replaced in tests.
+var now = time.Now
+
+// SetTimes sets how timestamps are shown: in UTC if utc is set, otherwise in the local
+// time zone, and as how long ago they were, like "2h ago", if relative is set.
+// Porcelain output always keeps timestamps as stored, in UTC.
+func SetTimes(utc, relative bool) {
+	location = time.Local
+	if utc {
+		location = time.UTC
+	}
+	relativeTimes = relative
+}
+
+// Time returns the stored UTC timestamp ts as SetTimes says to show it.
+// Timestamps that can't be parsed are returned as they are.
+func Time(ts string) string {
+	var t time.Time
+	var err error
+	for _, layout := range storedLayouts {
+		if t, err = time.Parse(layout, ts); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return ts
+	}
+
+	if relativeTimes {
+		if ago, ok := relative(now().Sub(t)); ok {
+			return ago
+		}
+		return t.In(location).Format(time.DateOnly)
+	}
+	return t.In(location).Format(timeLayout)
+}
+
+// relative returns d, a time in the past, as a short "ago" form, or false if it's in the
+// future or more than a month ago, when a date says more.
+func relative(d time.Duration) (string, bool) {
+	switch {
+	case d < 0 || d >= 30*24*time.Hour:
+		return "", false
+	case d < time.Minute:
+		return "just now", true
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute)), true
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour)), true
+	default:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour))), true
+	}
+}
+
+// withTimes returns result with its timestamps as Time shows them.
+func withTimes[T any](result T) T {
+	var shown any = result
+	switch r := shown.(type) {
+	case db.Entity:
+		r.CreatedAt, r.UpdatedAt = Time(r.CreatedAt), Time(r.UpdatedAt)
+		shown = r
+	case db.Observation:
+		r.Timestamp, r.UpdatedAt = Time(r.Timestamp), Time(r.UpdatedAt)
+		shown = r
+	case db.Relationship:
+		r.Timestamp, r.UpdatedAt = Time(r.Timestamp), Time(r.UpdatedAt)
+		shown = r
+	case Sourced[db.Entity]:
+		r.Result = withTimes(r.Result)
+		shown = r
+	case Sourced[db.Observation]:
+		r.Result = withTimes(r.Result)
+		shown = r
+	case Sourced[db.Relationship]:
+		r.Result = withTimes(r.Result)
+		shown = r
+	}
+	return shown.(T)
+}
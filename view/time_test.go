@@ -0,0 +1,71 @@
+package view
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"amem/db"
+)
+
+// TestMain shows timestamps in UTC so expected output doesn't depend on where tests run.
+func TestMain(m *testing.M) {
+	location = time.UTC
+	os.Exit(m.Run())
+}
+
+func TestTime(t *testing.T) {
+	defer SetTimes(true, false)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC) }
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		location *time.Location
+		relative bool
+		ts       string
+		want     string
+	}{
+		{"utc", time.UTC, false, "2025-03-10 09:30:00", "2025-03-10 09:30:00"},
+		{"local", newYork, false, "2025-03-10 09:30:00", "2025-03-10 05:30:00"},
+		{"rfc 3339", newYork, false, "2025-03-10T09:30:00Z", "2025-03-10 05:30:00"},
+		{"unparseable", newYork, false, "yesterday", "yesterday"},
+		{"just now", time.UTC, true, "2025-03-10 11:59:30", "just now"},
+		{"minutes", time.UTC, true, "2025-03-10 11:15:00", "45m ago"},
+		{"hours", time.UTC, true, "2025-03-10 09:30:00", "2h ago"},
+		{"days", time.UTC, true, "2025-03-07 12:00:00", "3d ago"},
+		{"old", newYork, true, "2025-01-01 02:00:00", "2024-12-31"},
+		{"future", time.UTC, true, "2025-03-11 12:00:00", "2025-03-11"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			location, relativeTimes = tt.location, tt.relative
+			if got := Time(tt.ts); got != tt.want {
+				t.Errorf("Time(%q) = %q, want %q", tt.ts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatObservationsTimes(t *testing.T) {
+	defer SetTimes(true, false)
+	defer SetPorcelain(false)
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC) }
+	observations := []db.Observation{{ID: 1, EntityText: "Alice", Text: "Likes tea", Timestamp: "2025-03-10 09:30:00"}}
+
+	SetTimes(true, true)
+	if output := captureOutput(func() { FormatObservations(observations, false) }); !strings.Contains(output, "Alice: Likes tea (2h ago)") {
+		t.Errorf("Expected a relative timestamp, got %q", output)
+	}
+
+	SetPorcelain(true)
+	if output := captureOutput(func() { FormatObservations(observations, false) }); !strings.Contains(output, "2025-03-10 09:30:00") {
+		t.Errorf("Expected porcelain output to keep the stored timestamp, got %q", output)
+	}
+}
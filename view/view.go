@@ -2,72 +2,188 @@ package view
 
 import (
 	"fmt"
+	"iter"
+	"strings"
+	"unicode/utf8"
 
 	"amem/db"
 )
 
-// FormatEntities prints a formatted list of entities with a header.
-func FormatEntities(entities []db.Entity, withIDs bool) {
-	if len(entities) == 0 {
-		fmt.Println("No entities found")
-		return
+// Stream is a result set printed as it is read: Count is known up front for
+// the header, and rows arrive one at a time from Rows.
+type Stream[T any] struct {
+	Count int
+	Rows  iter.Seq2[T, error]
+}
+
+// formatter is any result type that can print itself.
+type formatter interface {
+	Format(withID bool) string
+}
+
+// sliceStream makes a Stream from results already in memory.
+func sliceStream[T any](results []T) Stream[T] {
+	return Stream[T]{
+		Count: len(results),
+		Rows: func(yield func(T, error) bool) {
+			for _, r := range results {
+				if !yield(r, nil) {
+					return
+				}
+			}
+		},
 	}
-	fmt.Printf("Found %d entities:\n", len(entities))
-	for _, e := range entities {
-		fmt.Println(e.Format(withIDs))
+}
+
+// printRows prints each row of s on its own line, fitted to the width set with SetMaxWidth,
+// or as porcelain lines if SetPorcelain is on.
+func printRows[T formatter](s Stream[T], withIDs bool) error {
+	for r, err := range s.Rows {
+		if err != nil {
+			return err
+		}
+		if porcelain {
+			fmt.Println(porcelainLine(r))
+			continue
+		}
+		fmt.Println(fit(withTimes(r).Format(withIDs)))
 	}
+	return nil
 }
 
-// FormatObservations prints a formatted list of observations with a header.
-func FormatObservations(observations []db.Observation, withIDs bool) {
-	if len(observations) == 0 {
-		fmt.Println("No observations found")
-		return
+// streamList prints a header with the count, then the rows, or empty if there are none.
+func streamList[T formatter](s Stream[T], noun string, withIDs bool) error {
+	if porcelain {
+		return printRows(s, withIDs)
 	}
-	fmt.Printf("Found %d observations:\n", len(observations))
-	for _, o := range observations {
-		fmt.Println(o.Format(withIDs))
+	if s.Count == 0 {
+		fmt.Printf("No %s found\n", noun)
+		return nil
 	}
+	fmt.Printf("Found %d %s:\n", s.Count, noun)
+	return printRows(s, withIDs)
+}
+
+// FormatEntities prints a formatted list of entities with a header.
+func FormatEntities(entities []db.Entity, withIDs bool) {
+	_ = StreamEntities(sliceStream(entities), withIDs)
+}
+
+// StreamEntities is FormatEntities for entities read as they are printed.
+func StreamEntities(entities Stream[db.Entity], withIDs bool) error {
+	return streamList(entities, "entities", withIDs)
+}
+
+// FormatObservations prints a formatted list of observations with a header.
+func FormatObservations(observations []db.Observation, withIDs bool) {
+	_ = StreamObservations(sliceStream(observations), withIDs)
+}
+
+// StreamObservations is FormatObservations for observations read as they are printed.
+func StreamObservations(observations Stream[db.Observation], withIDs bool) error {
+	return streamList(observations, "observations", withIDs)
 }
 
 // FormatRelationships prints a formatted list of relationships with a header.
 func FormatRelationships(relationships []db.Relationship, withIDs bool) {
-	if len(relationships) == 0 {
-		fmt.Println("No relationships found")
-		return
-	}
-	fmt.Printf("Found %d relationships:\n", len(relationships))
-	for _, r := range relationships {
-		fmt.Println(r.Format(withIDs))
-	}
+	_ = StreamRelationships(sliceStream(relationships), withIDs)
+}
+
+// StreamRelationships is FormatRelationships for relationships read as they are printed.
+func StreamRelationships(relationships Stream[db.Relationship], withIDs bool) error {
+	return streamList(relationships, "relationships", withIDs)
 }
 
 // FormatAll prints all search results with section headers.
 func FormatAll(entities []db.Entity, observations []db.Observation, relationships []db.Relationship, withIDs bool) {
-	totalResults := len(entities) + len(observations) + len(relationships)
+	_ = StreamAll(sliceStream(entities), sliceStream(observations), sliceStream(relationships), withIDs)
+}
+
+// StreamAll is FormatAll for results read as they are printed.
+func StreamAll(entities Stream[db.Entity], observations Stream[db.Observation], relationships Stream[db.Relationship], withIDs bool) error {
+	return streamAll(entities, observations, relationships, withIDs)
+}
+
+// Sourced is a result labelled with the database it came from.
+type Sourced[T formatter] struct {
+	Source string
+	Result T
+}
+
+// Format returns the result's representation after its source.
+func (s Sourced[T]) Format(withID bool) string {
+	return s.Source + "  " + s.Result.Format(withID)
+}
+
+// FormatAllSourced is FormatAll for results from several databases,
+// with each result's source in a column before it.
+func FormatAllSourced(entities []Sourced[db.Entity], observations []Sourced[db.Observation], relationships []Sourced[db.Relationship], withIDs bool) {
+	width := max(sourceWidth(entities), sourceWidth(observations), sourceWidth(relationships))
+	_ = streamAll(
+		sliceStream(padSources(entities, width)),
+		sliceStream(padSources(observations, width)),
+		sliceStream(padSources(relationships, width)),
+		withIDs,
+	)
+}
+
+// sourceWidth returns the length of the longest source of results.
+func sourceWidth[T formatter](results []Sourced[T]) int {
+	width := 0
+	for _, r := range results {
+		width = max(width, utf8.RuneCountInString(r.Source))
+	}
+	return width
+}
+
+// padSources returns a copy of results with each source padded with spaces to width.
+func padSources[T formatter](results []Sourced[T], width int) []Sourced[T] {
+	padded := make([]Sourced[T], len(results))
+	for i, r := range results {
+		r.Source += strings.Repeat(" ", width-utf8.RuneCountInString(r.Source))
+		padded[i] = r
+	}
+	return padded
+}
+
+// streamAll prints all search results with section headers, or as porcelain lines if SetPorcelain is on.
+func streamAll[E, O, R formatter](entities Stream[E], observations Stream[O], relationships Stream[R], withIDs bool) error {
+	if porcelain {
+		if err := printRows(entities, withIDs); err != nil {
+			return err
+		}
+		if err := printRows(observations, withIDs); err != nil {
+			return err
+		}
+		return printRows(relationships, withIDs)
+	}
+
+	totalResults := entities.Count + observations.Count + relationships.Count
 	if totalResults == 0 {
 		fmt.Println("No results found")
-		return
+		return nil
 	}
 
-	if len(entities) > 0 {
-		fmt.Printf("\nEntities (%d):\n", len(entities))
-		for _, e := range entities {
-			fmt.Println(e.Format(withIDs))
+	if entities.Count > 0 {
+		fmt.Printf("\nEntities (%d):\n", entities.Count)
+		if err := printRows(entities, withIDs); err != nil {
+			return err
 		}
 	}
 
-	if len(observations) > 0 {
-		fmt.Printf("\nObservations (%d):\n", len(observations))
-		for _, o := range observations {
-			fmt.Println(o.Format(withIDs))
+	if observations.Count > 0 {
+		fmt.Printf("\nObservations (%d):\n", observations.Count)
+		if err := printRows(observations, withIDs); err != nil {
+			return err
 		}
 	}
 
-	if len(relationships) > 0 {
-		fmt.Printf("\nRelationships (%d):\n", len(relationships))
-		for _, r := range relationships {
-			fmt.Println(r.Format(withIDs))
+	if relationships.Count > 0 {
+		fmt.Printf("\nRelationships (%d):\n", relationships.Count)
+		if err := printRows(relationships, withIDs); err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
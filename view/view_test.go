@@ -2,6 +2,7 @@ package view
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"strings"
@@ -280,3 +281,51 @@ func TestFormatAllPopulated(t *testing.T) {
 		t.Errorf("Expected IDs in output, got '%s'", output)
 	}
 }
+
+func TestStreamObservationsError(t *testing.T) {
+	failing := Stream[db.Observation]{
+		Count: 2,
+		Rows: func(yield func(db.Observation, error) bool) {
+			if !yield(db.Observation{ID: 1, EntityText: "Alice", Text: "Likes coffee"}, nil) {
+				return
+			}
+			yield(db.Observation{}, errors.New("read failed"))
+		},
+	}
+
+	var err error
+	output := captureOutput(func() {
+		err = StreamObservations(failing, false)
+	})
+
+	if !strings.Contains(output, "Found 2 observations:") || !strings.Contains(output, "Alice: Likes coffee") {
+		t.Errorf("Expected header and first row before the error, got '%s'", output)
+	}
+	if err == nil || err.Error() != "read failed" {
+		t.Errorf("Expected read error, got %v", err)
+	}
+}
+
+func TestFormatAllSourced(t *testing.T) {
+	entities := []Sourced[db.Entity]{
+		{Source: "global", Result: db.Entity{ID: 1, Text: "Alice"}},
+	}
+	observations := []Sourced[db.Observation]{
+		{Source: "/home/me/project", Result: db.Observation{ID: 3, EntityText: "Alice", Text: "Likes coffee", Timestamp: "2024-01-15 10:30:00"}},
+	}
+
+	output := captureOutput(func() {
+		FormatAllSourced(entities, observations, nil, true)
+	})
+
+	expected := "\nEntities (1):\n" +
+		"global            [1] Alice\n" +
+		"\nObservations (1):\n" +
+		"/home/me/project  [3] Alice: Likes coffee (2024-01-15 10:30:00)\n"
+	if output != expected {
+		t.Errorf("Expected '%s', got '%s'", expected, output)
+	}
+	if entities[0].Source != "global" {
+		t.Errorf("FormatAllSourced should not change its arguments, got source %q", entities[0].Source)
+	}
+}
@@ -0,0 +1,60 @@
+package view
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// wrapIndent starts each line a wrapped row continues onto.
+const wrapIndent = "    "
+
+// maxWidth is the most characters a printed row may take, or 0 for no limit, and truncate
+// is whether longer rows are cut instead of wrapped. Both are set with SetMaxWidth.
+var (
+	maxWidth int
+	truncate bool
+)
+
+// SetMaxWidth limits printed rows to width characters, wrapping longer ones at spaces onto
+// indented lines, or cutting them short with an ellipsis if cut is set. A width of 0 or less
+// removes the limit.
+func SetMaxWidth(width int, cut bool) {
+	maxWidth = max(width, 0)
+	truncate = cut
+}
+
+// fit returns line fitted to the width set with SetMaxWidth.
+func fit(line string) string {
+	if maxWidth == 0 || utf8.RuneCountInString(line) <= maxWidth {
+		return line
+	}
+	if truncate {
+		return string([]rune(line)[:max(maxWidth-1, 0)]) + ellipsis
+	}
+	return wrap(line, maxWidth)
+}
+
+// wrap breaks line into lines of at most width characters, at the last space that fits
+// where there is one, with every line after the first indented by wrapIndent.
+func wrap(line string, width int) string {
+	runes := []rune(line)
+	var lines []string
+	limit := width
+	for len(runes) > limit {
+		cut := limit
+		for i := limit; i > 0; i-- {
+			if runes[i] == ' ' {
+				cut = i
+				break
+			}
+		}
+		lines = append(lines, strings.TrimRight(string(runes[:cut]), " "))
+		for cut < len(runes) && runes[cut] == ' ' {
+			cut++
+		}
+		runes = runes[cut:]
+		limit = max(width-len(wrapIndent), 1)
+	}
+	lines = append(lines, string(runes))
+	return strings.Join(lines, "\n"+wrapIndent)
+}
@@ -0,0 +1,47 @@
+package view
+
+import (
+	"testing"
+
+	"amem/db"
+)
+
+func TestFit(t *testing.T) {
+	defer SetMaxWidth(0, false)
+	line := "[1] Initech: Moved billing from Heroku to Fly"
+
+	tests := []struct {
+		name  string
+		width int
+		cut   bool
+		want  string
+	}{
+		{"no limit", 0, false, line},
+		{"fits", 80, false, line},
+		{"wrapped", 24, false, "[1] Initech: Moved\n    billing from Heroku\n    to Fly"},
+		{"truncated", 24, true, "[1] Initech: Moved bill…"},
+		{"word longer than width", 8, false, "[1]\n    Init\n    ech:\n    Move\n    d\n    bill\n    ing\n    from\n    Hero\n    ku\n    to\n    Fly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetMaxWidth(tt.width, tt.cut)
+			if got := fit(line); got != tt.want {
+				t.Errorf("fit() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatObservationsMaxWidth(t *testing.T) {
+	defer SetMaxWidth(0, false)
+	SetMaxWidth(20, true)
+
+	output := captureOutput(func() {
+		FormatObservations([]db.Observation{{ID: 1, EntityText: "Initech", Text: "Moved billing from Heroku to Fly", Timestamp: "2025-01-01"}}, true)
+	})
+
+	expected := "Found 1 observations:\n[1] Initech: Moved …\n"
+	if output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"amem/db"
+	"github.com/urfave/cli/v3"
+	"golang.org/x/term"
+)
+
+// memoryBlockPattern matches the fenced ```amem blocks memories are written in, in transcripts
+var memoryBlockPattern = regexp.MustCompile("(?s)```amem[ \t]*\r?\n(.*?)```")
+
+// watchCommand builds the 'watch' command, which adds memories from transcripts as they're written
+func watchCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "watch",
+		Usage: "Add memories from the transcript files in a directory as they're written",
+		Description: "Checks every file under --dir (except hidden ones) when it starts and then every\n" +
+			"--interval, extracting memories from the new and changed ones. By default memories\n" +
+			"are fenced blocks in the 'amem apply' format, which an agent can write in its replies:\n\n" +
+			"  ```amem\n" +
+			"  observations:\n" +
+			"    - entity: Project X\n" +
+			"      text: Ships in March\n" +
+			"  ```\n\n" +
+			"In .jsonl files, like Claude Code's, blocks are found in each line's JSON strings.\n" +
+			"With --extractor, the command is given each file on stdin instead, and must print\n" +
+			"a document in the same format.\n\n" +
+			"Records that already exist are skipped, so a file that grows is extracted again\n" +
+			"without adding its memories twice.\n\n" +
+			"With --hook, as a Claude Code Stop hook, the session's transcript is read once instead\n" +
+			"(see 'amem integrate claude-code').",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "dir",
+				Usage: "Directory of transcript files (required without --hook)",
+			},
+			&cli.DurationFlag{
+				Name:  "interval",
+				Usage: "How often to check for new and changed files",
+				Value: 5 * time.Second,
+			},
+			&cli.BoolFlag{
+				Name:  "once",
+				Usage: "Check the files once and exit, e.g. from cron",
+			},
+			&cli.StringFlag{
+				Name:  "extractor",
+				Usage: "Command that reads a transcript on stdin and prints the memories in it as an 'amem apply' document",
+			},
+			&cli.BoolFlag{
+				Name:  "hook",
+				Usage: "Read Claude Code hook input from stdin, add the memories in its transcript, and never fail the hook",
+			},
+		},
+		Action: func(ctx context.Context, cmd *cli.Command) error {
+			extractor := strings.Fields(cmd.String("extractor"))
+			if cmd.Bool("hook") {
+				return watchHook(ctx, extractor)
+			}
+			if cmd.String("dir") == "" {
+				return fmt.Errorf("--dir is required")
+			}
+
+			dir, err := filepath.Abs(cmd.String("dir"))
+			if err != nil {
+				return fmt.Errorf("invalid --dir: %w", err)
+			}
+			if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+				return fmt.Errorf("%s is not a directory", dir)
+			}
+			interval := cmd.Duration("interval")
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+
+			return withDB(func(database *db.DB) error {
+				if cmd.Bool("once") {
+					if failed := ingestTranscripts(ctx, database, dir, extractor); failed > 0 {
+						return fmt.Errorf("failed to extract memories from %d files", failed)
+					}
+					return nil
+				}
+
+				ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+				defer stop()
+
+				fmt.Fprintf(os.Stderr, "Watching %s for memories every %s\n", dir, interval)
+				for {
+					// Failures are reported and retried on the next check
+					ingestTranscripts(ctx, database, dir, extractor)
+					select {
+					case <-ctx.Done():
+						return nil
+					case <-time.After(interval):
+					}
+				}
+			})
+		},
+	}
+}
+
+// watchHook adds the memories in the transcript named by the Claude Code hook input on
+// stdin. Failures are reported but never returned, so they can't break the agent session.
+func watchHook(ctx context.Context, extractor []string) error {
+	// Hooks receive JSON on stdin; don't wait for input from a terminal
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		return nil
+	}
+	input, err := readHookInput(os.Stdin)
+	if err == nil && input.TranscriptPath != "" {
+		err = withDB(func(database *db.DB) error {
+			_, err := ingestTranscript(ctx, database, input.TranscriptPath, extractor)
+			return err
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: amem watch: %v\n", err)
+	}
+	return nil
+}
+
+// ingestTranscripts extracts memories from each new or changed file under dir, reporting
+// what each added and any failures, and returns how many failed
+func ingestTranscripts(ctx context.Context, database *db.DB, dir string, extractor []string) int {
+	failed := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != dir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		result, err := ingestTranscript(ctx, database, path, extractor)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", path, err)
+			failed++
+		} else if result != nil && result.EntitiesAdded+result.ObservationsAdded+result.RelationshipsAdded > 0 {
+			fmt.Printf("✓ %s: added %d entities, %d observations, %d relationships\n",
+				path, result.EntitiesAdded, result.ObservationsAdded, result.RelationshipsAdded)
+		}
+		return ctx.Err()
+	})
+	if err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %v\n", dir, err)
+		failed++
+	}
+	return failed
+}
+
+// ingestTranscript applies the memories extracted from the file at path, unless it hasn't
+// changed since it last was. Returns nil if it hadn't.
+func ingestTranscript(ctx context.Context, database *db.DB, path string, extractor []string) (*db.ApplyResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	if previous, err := database.IngestedHash(path); err != nil || previous == hash {
+		return nil, err
+	}
+
+	doc, err := extractMemories(ctx, data, path, extractor)
+	if err != nil {
+		return nil, err
+	}
+	var result *db.ApplyResult
+	err = database.Locked(func() error {
+		var err error
+		if result, err = database.Apply(doc); err != nil {
+			return err
+		}
+		return database.SetIngested(path, hash)
+	})
+	return result, err
+}
+
+// extractMemories returns the memories in the transcript data, read from path: the output
+// of the extractor command if there is one, and otherwise its fenced amem blocks
+func extractMemories(ctx context.Context, data []byte, path string, extractor []string) (*db.Document, error) {
+	if len(extractor) > 0 {
+		cmd := exec.CommandContext(ctx, extractor[0], extractor[1:]...)
+		cmd.Stdin, cmd.Stderr = bytes.NewReader(data), os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("extractor %s failed: %w", extractor[0], err)
+		}
+		return parseDocument(out, "from "+extractor[0])
+	}
+
+	texts := []string{string(data)}
+	if filepath.Ext(path) == ".jsonl" {
+		texts = jsonlStrings(data)
+	}
+	doc := &db.Document{}
+	for _, text := range texts {
+		for _, match := range memoryBlockPattern.FindAllStringSubmatch(text, -1) {
+			block, err := parseDocument([]byte(match[1]), "in "+path)
+			if err != nil {
+				return nil, err
+			}
+			doc.Entities = append(doc.Entities, block.Entities...)
+			doc.Observations = append(doc.Observations, block.Observations...)
+			doc.Relationships = append(doc.Relationships, block.Relationships...)
+		}
+	}
+	return doc, nil
+}
+
+// jsonlStrings returns every string in each line of data that's JSON, where fenced blocks
+// are escaped onto one line, and each other line as it is
+func jsonlStrings(data []byte) []string {
+	var texts []string
+	var collect func(v any)
+	collect = func(v any) {
+		switch v := v.(type) {
+		case string:
+			texts = append(texts, v)
+		case []any:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]any:
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				collect(v[key])
+			}
+		}
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var v any
+		if err := json.Unmarshal(line, &v); err != nil {
+			texts = append(texts, string(line))
+			continue
+		}
+		collect(v)
+	}
+	return texts
+}